@@ -3,16 +3,24 @@
 package main
 
 import (
-	_ "golang/docs"
-	"golang/internal/config"
-	"golang/internal/controllers"
-	"golang/internal/db"
-	"golang/internal/logger"
-	"golang/internal/repository"
-	"golang/internal/server"
-	"golang/internal/services"
+	"context"
+	_ "github.com/Viktor2805/nucount/docs"
+	"github.com/Viktor2805/nucount/internal/config"
+	"github.com/Viktor2805/nucount/internal/controllers"
+	"github.com/Viktor2805/nucount/internal/db"
+	"github.com/Viktor2805/nucount/internal/logger"
+	"github.com/Viktor2805/nucount/internal/migrations"
+	"github.com/Viktor2805/nucount/internal/repository"
+	sequence "github.com/Viktor2805/nucount/internal/repository/sequence"
+	"github.com/Viktor2805/nucount/internal/server"
+	"github.com/Viktor2805/nucount/internal/services"
+	"github.com/Viktor2805/nucount/internal/services/job"
+	"github.com/Viktor2805/nucount/internal/services/webhook"
+	"io"
 	_ "net/http/pprof"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"go.uber.org/zap"
 )
@@ -20,6 +28,17 @@ import (
 // @title           Swagger Example API
 // @version         1.0
 // @description     This is a sample server celler server.
+// @description     Endpoints that take a file accept it as the "file" field of a multipart form or as the raw request body
+// @description     (application/octet-stream, optionally with Content-Encoding: gzip). Either way the upload is streamed, not
+// @description     buffered, and classified by its content: files compressed with gzip, bgzip, bzip2 or zstd are decompressed
+// @description     on the fly, and content of a format an endpoint does not accept is refused with 415.
+// @description     The results of the nucleotide analyses are cached by the SHA-256 of the upload and the parameters, with
+// @description     their defaults filled in. The digest is only known once the upload has been read, so only a client that
+// @description     declares it in the X-Content-SHA256 header is served a stored result without the file being analysed;
+// @description     an undeclared upload is always analysed again. Each response carries the digest of its upload in
+// @description     X-Content-SHA256, to be declared when sending the same file again. A declared digest that does not
+// @description     match the upload is refused with 400. The ETag is the digest of the response body, and If-None-Match
+// @description     is honoured.
 // @termsOfService  http://swagger.io/terms/
 
 // @contact.name   API Support
@@ -59,8 +78,32 @@ func main() {
 		}
 	}()
 
+	migrator, err := migrations.New(db.DB, migrations.WithLogger(log.With(zap.String("component", "migrations"))))
+	if err != nil {
+		log.Fatal("migrations init failed", zap.Error(err))
+	}
+
+	sequences := sequence.NewSequenceRepository(db.DB)
+
+	// "app migrate ..." manages the schema instead of serving.
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(context.Background(), migrator, sequences, os.Args[2:], os.Stdout); err != nil {
+			log.Fatal("migrate failed", zap.Error(err))
+		}
+		return
+	}
+
+	if cfg.Database.AutoMigrate {
+		if err := migrateUp(context.Background(), migrator, sequences, io.Discard); err != nil {
+			log.Fatal("migrate up failed", zap.Error(err))
+		}
+	}
+	if err := migrator.Check(context.Background()); err != nil {
+		log.Fatal("refusing to start: run app migrate up, or fix the schema", zap.Error(err))
+	}
+
 	repositories := repository.NewRepositories(db.DB)
-	services := services.NewServices(repositories)
+	services := services.NewServices(repositories, cfg)
 	controllers := controllers.NewControllers(services)
 
 	server := server.NewServer(
@@ -69,5 +112,46 @@ func main() {
 		server.WithControllers(controllers),
 	)
 
+	// Jobs interrupted by the shutdown are put back in the queue for the
+	// other replicas; interrupted webhook deliveries are retried by them.
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	defer stop()
+
+	worker := job.NewWorker(
+		repositories.JobRepo,
+		controllers.NucleotideController,
+		job.WithWorkers(cfg.Jobs.Workers),
+		job.WithPollInterval(cfg.Jobs.PollInterval),
+		job.WithHeartbeat(cfg.Jobs.Heartbeat),
+		job.WithLease(cfg.Jobs.Lease),
+		job.WithMaxAttempts(cfg.Jobs.MaxAttempts),
+		job.WithLogger(log.With(zap.String("component", "jobs"))),
+	)
+	workerDone := make(chan struct{})
+	go func() {
+		defer close(workerDone)
+		worker.Run(ctx)
+	}()
+
+	dispatcher := webhook.NewDispatcher(
+		repositories.WebhookRepo,
+		cfg.Webhooks.Secrets,
+		webhook.WithPollInterval(cfg.Jobs.PollInterval),
+		webhook.WithTimeout(cfg.Webhooks.Timeout),
+		webhook.WithBackoff(cfg.Webhooks.Backoff, cfg.Webhooks.MaxBackoff),
+		webhook.WithMaxAttempts(cfg.Webhooks.MaxAttempts),
+		webhook.WithAllowedNetworks(cfg.Webhooks.AllowedNetworks...),
+		webhook.WithLogger(log.With(zap.String("component", "webhooks"))),
+	)
+	dispatcherDone := make(chan struct{})
+	go func() {
+		defer close(dispatcherDone)
+		dispatcher.Run(ctx)
+	}()
+
 	server.StartServer()
+
+	stop()
+	<-workerDone
+	<-dispatcherDone
 }
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Viktor2805/nucount/internal/migrations"
+	"github.com/Viktor2805/nucount/internal/models"
+	sequence "github.com/Viktor2805/nucount/internal/repository/sequence"
+)
+
+const migrateUsage = `usage: app migrate <command>
+
+commands:
+  up          apply every migration not yet applied, then backfill
+  down [N]    revert the latest N applied migrations (default 1)
+  status      list the migrations and when they were applied
+  backfill    move the bases of sequences imported before they were stored
+              in chunks into chunks`
+
+// runMigrate runs the migrate command with args, the arguments after
+// "migrate", and writes its report to w.
+func runMigrate(ctx context.Context, m *migrations.Migrator, seqs *sequence.SequenceRepository, args []string, w io.Writer) error {
+	if len(args) == 0 {
+		return fmt.Errorf("missing command\n%s", migrateUsage)
+	}
+
+	switch args[0] {
+	case "up":
+		if len(args) > 1 {
+			return fmt.Errorf("up takes no arguments\n%s", migrateUsage)
+		}
+		return migrateUp(ctx, m, seqs, w)
+
+	case "down":
+		steps := 1
+		if len(args) > 2 {
+			return fmt.Errorf("down takes at most one argument\n%s", migrateUsage)
+		}
+		if len(args) == 2 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n < 1 {
+				return fmt.Errorf("invalid number of migrations %q\n%s", args[1], migrateUsage)
+			}
+			steps = n
+		}
+		reverted, err := m.Down(ctx, steps)
+		for _, mig := range reverted {
+			fmt.Fprintf(w, "reverted %06d_%s\n", mig.Version, mig.Name)
+		}
+		if err == nil && len(reverted) == 0 {
+			fmt.Fprintln(w, "no migration to revert")
+		}
+		return err
+
+	case "status":
+		statuses, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "VERSION\tNAME\tAPPLIED")
+		for _, s := range statuses {
+			applied := "pending"
+			switch {
+			case s.Unknown:
+				applied = s.AppliedAt.Format(time.RFC3339) + " (no such migration in this binary)"
+			case s.AppliedAt != nil:
+				applied = s.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(tw, "%06d\t%s\t%s\n", s.Version, s.Name, applied)
+		}
+		return tw.Flush()
+
+	case "backfill":
+		if len(args) > 1 {
+			return fmt.Errorf("backfill takes no arguments\n%s", migrateUsage)
+		}
+		return backfill(ctx, seqs, w)
+
+	default:
+		return fmt.Errorf("unknown command %q\n%s", args[0], migrateUsage)
+	}
+}
+
+// migrateUp applies the pending migrations, then backfills the sequences,
+// and writes its report to w.
+func migrateUp(ctx context.Context, m *migrations.Migrator, seqs *sequence.SequenceRepository, w io.Writer) error {
+	applied, err := m.Up(ctx)
+	for _, mig := range applied {
+		fmt.Fprintf(w, "applied %06d_%s\n", mig.Version, mig.Name)
+	}
+	if err != nil {
+		return err
+	}
+	if len(applied) == 0 {
+		fmt.Fprintln(w, "no migration to apply")
+	}
+	return backfill(ctx, seqs, w)
+}
+
+// backfill moves the sequences still stored in the sequence column into
+// chunks, and writes each to w. Replicas backfilling at the same time share
+// the work.
+func backfill(ctx context.Context, seqs *sequence.SequenceRepository, w io.Writer) error {
+	n, err := seqs.Backfill(ctx, func(s *models.Sequence) {
+		fmt.Fprintf(w, "backfilled %s (%d bases)\n", s.Accession, s.Length)
+	})
+	if err == nil && n == 0 {
+		fmt.Fprintln(w, "no sequence to backfill")
+	}
+	return err
+}
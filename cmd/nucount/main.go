@@ -0,0 +1,270 @@
+// Command nucount runs the analyses of the API on local files, with the same
+// code as the server, so that its results are identical to the server's:
+//
+//	nucount <analysis> [flags] [file|glob|-]...
+//
+// The analyses are count, gc-profile, kmers, dinucleotides and cpg-islands,
+// and their flags are the query parameters of their endpoints. Files may be
+// FASTA, FASTQ or 2bit, compressed or not, as for an upload; "-" or no file
+// reads standard input. Several files are analyzed in parallel and printed in
+// the order given, as a TSV or JSON Lines table with a row per file or per
+// record, or as the JSON response bodies of the API.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+
+	"github.com/Viktor2805/nucount/internal/analyses"
+	nucleotide "github.com/Viktor2805/nucount/internal/services/nucleotide"
+)
+
+// Output formats.
+const (
+	formatTSV      = "tsv"
+	formatJSON     = "json"
+	formatResponse = "response"
+)
+
+// stdin names standard input among the files.
+const stdin = "-"
+
+// param is a query parameter of an analysis, offered as a flag of the same
+// name.
+type param struct {
+	name  string
+	usage string
+	bool  bool // may be given without a value, meaning true
+}
+
+var precisionParam = param{name: "precision", usage: "decimal places of derived metrics (0-10, default 2)"}
+
+// params are the query parameters of each analysis; see the API documentation.
+var params = map[string][]param{
+	analyses.Count: {
+		{name: "records", usage: "include per-record counts (FASTA only)", bool: true},
+		precisionParam,
+	},
+	analyses.GCProfile: {
+		{name: "window", usage: "window size in bases (default 1000)"},
+		{name: "step", usage: "distance between window starts (default: window size)"},
+		precisionParam,
+	},
+	analyses.Kmers: {
+		{name: "k", usage: "k-mer length (1-31, default 21)"},
+		{name: "canonical", usage: "merge k-mers with their reverse complement", bool: true},
+		{name: "top", usage: "number of most frequent k-mers to return (0-1000, default 10)"},
+	},
+	analyses.Dinucleotides: {precisionParam},
+	analyses.CpGIslands: {
+		{name: "min_length", usage: "window length in bases (2-10000000, default 200)"},
+		{name: "min_gc", usage: "minimum GC percentage (0-100, default 50)"},
+		{name: "min_oe", usage: "minimum CpG observed/expected ratio (default 0.6)"},
+		precisionParam,
+	},
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	code := run(ctx, os.Args[1:], os.Stdin, os.Stdout, os.Stderr)
+	stop()
+	os.Exit(code)
+}
+
+// run runs the command with args and returns its exit code: 0 on success, 1
+// if any file failed and 2 on a usage error.
+func run(ctx context.Context, args []string, in io.Reader, out, errOut io.Writer) int {
+	if len(args) == 0 || params[args[0]] == nil {
+		usage(errOut)
+		return 2
+	}
+	name := args[0]
+
+	query := url.Values{}
+	fs := flag.NewFlagSet("nucount "+name, flag.ContinueOnError)
+	fs.SetOutput(errOut)
+	format := fs.String("format", formatTSV, "output format: tsv, json (JSON Lines) or response (API response bodies)")
+	per := fs.String("per", "", "table rows: one per file or per record (default: "+tables[name][0].level+")")
+	jobs := fs.Int("j", runtime.NumCPU(), "number of files analyzed in parallel")
+	workers := fs.Int("workers", 1, "goroutines counting each file; <= 0 uses one per CPU")
+	for _, p := range params[name] {
+		set := func(v string) error {
+			query.Set(p.name, v)
+			return nil
+		}
+		if p.bool {
+			fs.BoolFunc(p.name, p.usage, set)
+		} else {
+			fs.Func(p.name, p.usage, set)
+		}
+	}
+	if err := fs.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+
+	var t table
+	switch *format {
+	case formatTSV, formatJSON:
+		var err error
+		if t, err = tableOf(name, *per, query); err != nil {
+			fmt.Fprintln(errOut, "nucount:", err)
+			return 2
+		}
+	case formatResponse:
+	default:
+		fmt.Fprintf(errOut, "nucount: unknown format %q, expected tsv, json or response\n", *format)
+		return 2
+	}
+
+	files, err := expand(fs.Args())
+	if err != nil {
+		fmt.Fprintln(errOut, "nucount:", err)
+		return 2
+	}
+
+	// Local files are trusted: they may decompress to any size.
+	opts := []nucleotide.Option{
+		nucleotide.WithMaxDecompressedSize(0),
+		nucleotide.WithWorkers(*workers),
+	}
+	a := analyses.New(
+		nucleotide.NewCounter(opts...),
+		nucleotide.NewKmerCounter(opts...),
+		nucleotide.NewDinucleotideAnalyzer(opts...),
+	)
+
+	var w output
+	switch *format {
+	case formatTSV:
+		w = newTSVOutput(out, t)
+	case formatJSON:
+		w = newJSONOutput(out, t)
+	default:
+		w = newResponseOutput(out)
+	}
+
+	code := 0
+	for res := range analyze(ctx, a, name, query, files, in, *jobs) {
+		if res.err == nil {
+			res.err = w.write(res.file, res.body)
+		}
+		if res.err != nil {
+			fmt.Fprintf(errOut, "nucount: %s: %v\n", res.file, res.err)
+			code = 1
+		}
+	}
+	return code
+}
+
+func usage(w io.Writer) {
+	names := make([]string, 0, len(params))
+	for name := range params {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	fmt.Fprintf(w, `Usage: nucount <analysis> [flags] [file|glob|-]...
+
+Runs an analysis of the API on local FASTA, FASTQ or 2bit files, compressed or
+not, and prints a table of the results. Without files, or for "-", standard
+input is read.
+
+Analyses: %s
+
+Run "nucount <analysis> -h" for the flags of an analysis.
+`, strings.Join(names, ", "))
+}
+
+// expand returns the files named by args, with globs expanded in lexical
+// order. A glob that matches nothing is an error, as is reading standard input
+// twice.
+func expand(args []string) ([]string, error) {
+	if len(args) == 0 {
+		return []string{stdin}, nil
+	}
+	var files []string
+	stdins := 0
+	for _, arg := range args {
+		if arg == stdin {
+			stdins++
+		}
+		if arg == stdin || !strings.ContainsAny(arg, `*?[\`) {
+			files = append(files, arg)
+			continue
+		}
+		matches, err := filepath.Glob(arg)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", arg, err)
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("%s: no files match", arg)
+		}
+		files = append(files, matches...)
+	}
+	if stdins > 1 {
+		return nil, errors.New("standard input given more than once")
+	}
+	return files, nil
+}
+
+// result is the outcome of the analysis of one file.
+type result struct {
+	file string
+	body []byte // response body
+	err  error
+}
+
+// analyze runs the analysis on files, jobs at a time, and sends the results in
+// the order of files.
+func analyze(ctx context.Context, a *analyses.Analyzer, name string, query url.Values, files []string, in io.Reader, jobs int) <-chan result {
+	pending := make([]chan result, len(files))
+	for i := range pending {
+		pending[i] = make(chan result, 1)
+	}
+	sem := make(chan struct{}, max(jobs, 1))
+	go func() {
+		for i, file := range files {
+			sem <- struct{}{}
+			go func() {
+				defer func() { <-sem }()
+				body, err := analyzeFile(ctx, a, name, query, file, in)
+				pending[i] <- result{file: file, body: body, err: err}
+			}()
+		}
+	}()
+
+	results := make(chan result)
+	go func() {
+		defer close(results)
+		for _, ch := range pending {
+			results <- <-ch
+		}
+	}()
+	return results
+}
+
+func analyzeFile(ctx context.Context, a *analyses.Analyzer, name string, query url.Values, file string, in io.Reader) ([]byte, error) {
+	r := in
+	if file != stdin {
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+	body, _, err := a.Analyze(ctx, name, query, r)
+	return body, err
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Viktor2805/nucount/internal/controllers"
+	history "github.com/Viktor2805/nucount/internal/services/analysis"
+	nucleotide "github.com/Viktor2805/nucount/internal/services/nucleotide"
+	"github.com/Viktor2805/nucount/internal/testutil"
+
+	"github.com/gin-gonic/gin"
+)
+
+const fasta = ">r1 first\nACGTCGCGNNacgt\n>r2\nGGGCCCATCGAT\n"
+
+// serve returns the response body of the endpoint of an analysis.
+func serve(t *testing.T, analysis, query, body string) []byte {
+	t.Helper()
+	c := controllers.NewNucleotideController(
+		nucleotide.NewCounter(),
+		nucleotide.NewKmerCounter(),
+		nucleotide.NewDinucleotideAnalyzer(),
+		history.NewHistory(&testutil.AnalysisRepo{}),
+	)
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/count", c.Count)
+	router.POST("/gc-profile", c.GCProfile)
+	router.POST("/kmers", c.Kmers)
+	router.POST("/dinucleotides", c.Dinucleotides)
+	router.POST("/cpg-islands", c.CpGIslands)
+
+	req := httptest.NewRequest(http.MethodPost, "/"+analysis+"?"+query, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/octet-stream")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("%s: status %d: %s", analysis, rec.Code, rec.Body)
+	}
+	return rec.Body.Bytes()
+}
+
+func TestRun_SameAsServer(t *testing.T) {
+	tests := []struct {
+		analysis string
+		query    string
+		flags    []string
+	}{
+		{"count", "records=true&precision=4", []string{"-records", "-precision", "4"}},
+		{"gc-profile", "window=4&step=2", []string{"-window", "4", "-step", "2"}},
+		{"kmers", "k=3&canonical=true&top=5", []string{"-k", "3", "-canonical", "-top", "5"}},
+		{"dinucleotides", "precision=3", []string{"-precision=3"}},
+		{"cpg-islands", "min_length=4&min_gc=10&min_oe=0.1", []string{"-min_length", "4", "-min_gc", "10", "-min_oe", "0.1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.analysis, func(t *testing.T) {
+			args := append([]string{tt.analysis, "-format", "response"}, tt.flags...)
+			var out, errOut bytes.Buffer
+			if code := run(context.Background(), args, strings.NewReader(fasta), &out, &errOut); code != 0 {
+				t.Fatalf("exit code %d: %s", code, errOut.String())
+			}
+
+			var line struct {
+				File   string          `json:"file"`
+				Result json.RawMessage `json:"result"`
+			}
+			if err := json.Unmarshal(out.Bytes(), &line); err != nil {
+				t.Fatal(err)
+			}
+			if line.File != stdin {
+				t.Errorf("file = %q, want %q", line.File, stdin)
+			}
+			if want := serve(t, tt.analysis, tt.query, fasta); !bytes.Equal(line.Result, want) {
+				t.Errorf("result = %s, want %s", line.Result, want)
+			}
+		})
+	}
+}
+
+func TestRun_Tables(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"a.fa": fasta,
+		"b.fa": ">x\nCGCGCGCGATAT\n",
+		"c.fq": "@q\nACGT\n+\nIIII\n",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	a, b, fq := filepath.Join(dir, "a.fa"), filepath.Join(dir, "b.fa"), filepath.Join(dir, "c.fq")
+
+	tests := []struct {
+		name string
+		args []string
+		code int
+		want string
+	}{
+		{
+			name: "per file in order of the globs",
+			args: []string{"dinucleotides", "-j", "4", filepath.Join(dir, "*.fa"), a},
+			want: "file\tlength\tpairs\tAA\tAC\tAG\tAT\tCA\tCC\tCG\tCT\tGA\tGC\tGG\tGT\tTA\tTC\tTG\tTT\tcpg_observed_expected\n" +
+				a + "\t26\t21\t0\t2\t0\t2\t1\t2\t5\t0\t1\t2\t2\t2\t0\t2\t0\t0\t1.88\n" +
+				b + "\t12\t11\t0\t0\t0\t2\t0\t0\t4\t0\t1\t3\t0\t0\t1\t0\t0\t0\t3\n" +
+				a + "\t26\t21\t0\t2\t0\t2\t1\t2\t5\t0\t1\t2\t2\t2\t0\t2\t0\t0\t1.88\n",
+		},
+		{
+			name: "as JSON Lines",
+			args: []string{"kmers", "-format", "json", "-k", "2", "-top", "1", b},
+			want: `{"file":"` + b + `","k":2,"canonical":false,"total":11,"distinct":5,"rank":1,"kmer":"CG","count":4}` + "\n",
+		},
+		{
+			name: "records of count",
+			args: []string{"count", "-per", "record", "-precision", "1", a},
+			want: strings.Join(compositionColumns("file", "id", "description"), "\t") + "\n" +
+				a + "\tr1\tfirst\t2\t4\t4\t2\t0\t2\t0\t0\t0\t0\t0\t0\t0\t0\t0\t0\t0\t0\t12\t14\t66.7\t33.3\t0\t0\t1\t0.1\t\n" +
+				a + "\tr2\t\t2\t4\t4\t2\t0\t0\t0\t0\t0\t0\t0\t0\t0\t0\t0\t0\t0\t0\t12\t12\t66.7\t33.3\t0\t0\t1\t0\t\n",
+		},
+		{
+			name: "failed file",
+			args: []string{"count", "-per", "record", fq, b},
+			code: 1,
+			want: strings.Join(compositionColumns("file", "id", "description"), "\t") + "\n" +
+				b + "\tx\t\t2\t4\t4\t2\t0\t0\t0\t0\t0\t0\t0\t0\t0\t0\t0\t0\t0\t0\t12\t12\t66.67\t33.33\t0\t0\t1\t0\t\n",
+		},
+		{name: "no table", args: []string{"kmers", "-per", "record", a}, code: 2},
+		{name: "no match", args: []string{"count", filepath.Join(dir, "*.fasta")}, code: 2},
+		{name: "unknown analysis", args: []string{"gc"}, code: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out, errOut bytes.Buffer
+			if code := run(context.Background(), tt.args, strings.NewReader(""), &out, &errOut); code != tt.code {
+				t.Fatalf("exit code = %d, want %d: %s", code, tt.code, errOut.String())
+			}
+			if out.String() != tt.want {
+				t.Errorf("output:\n%s\nwant:\n%s", out.String(), tt.want)
+			}
+		})
+	}
+}
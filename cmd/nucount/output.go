@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// output prints the response bodies of the analyzed files. The output of a
+// file is flushed once it has been written, for the consumers of a pipe.
+type output interface {
+	write(file string, body []byte) error
+}
+
+// tsvOutput prints a table as tab-separated values under a header line.
+type tsvOutput struct {
+	w      *bufio.Writer
+	t      table
+	header bool
+}
+
+func newTSVOutput(w io.Writer, t table) *tsvOutput {
+	return &tsvOutput{w: bufio.NewWriter(w), t: t}
+}
+
+// tsvEscaper escapes the characters that would break a TSV line.
+var tsvEscaper = strings.NewReplacer(`\`, `\\`, "\t", `\t`, "\n", `\n`, "\r", `\r`)
+
+func (o *tsvOutput) write(file string, body []byte) error {
+	rows, err := o.t.rows(file, body)
+	if err != nil {
+		return err
+	}
+	if !o.header {
+		o.header = true
+		o.w.WriteString(strings.Join(o.t.columns, "\t") + "\n")
+	}
+	for _, row := range rows {
+		for i, cell := range row {
+			if i > 0 {
+				o.w.WriteByte('\t')
+			}
+			o.w.WriteString(tsvCell(cell))
+		}
+		o.w.WriteByte('\n')
+	}
+	return o.w.Flush()
+}
+
+func tsvCell(cell any) string {
+	switch v := cell.(type) {
+	case nil:
+		return ""
+	case string:
+		return tsvEscaper.Replace(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// jsonOutput prints a table as JSON Lines, an object per row keyed by column.
+type jsonOutput struct {
+	w *bufio.Writer
+	t table
+}
+
+func newJSONOutput(w io.Writer, t table) *jsonOutput {
+	return &jsonOutput{w: bufio.NewWriter(w), t: t}
+}
+
+func (o *jsonOutput) write(file string, body []byte) error {
+	rows, err := o.t.rows(file, body)
+	if err != nil {
+		return err
+	}
+	for _, row := range rows {
+		// Written field by field to keep the order of the columns.
+		o.w.WriteByte('{')
+		for i, cell := range row {
+			if i > 0 {
+				o.w.WriteByte(',')
+			}
+			key, _ := json.Marshal(o.t.columns[i])
+			value, err := json.Marshal(cell)
+			if err != nil {
+				return err
+			}
+			o.w.Write(key)
+			o.w.WriteByte(':')
+			o.w.Write(value)
+		}
+		o.w.WriteString("}\n")
+	}
+	return o.w.Flush()
+}
+
+// responseOutput prints a JSON line per file holding the response body of the
+// API, as is.
+type responseOutput struct {
+	w *bufio.Writer
+}
+
+func newResponseOutput(w io.Writer) *responseOutput {
+	return &responseOutput{w: bufio.NewWriter(w)}
+}
+
+func (o *responseOutput) write(file string, body []byte) error {
+	name, _ := json.Marshal(file)
+	fmt.Fprintf(o.w, `{"file":%s,"result":%s}`+"\n", name, body)
+	return o.w.Flush()
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+
+	"github.com/Viktor2805/nucount/internal/analyses"
+	nucleotide "github.com/Viktor2805/nucount/internal/services/nucleotide"
+)
+
+// Rows of the tables.
+const (
+	perFile   = "file"
+	perRecord = "record"
+)
+
+// table is a tabular view of the response bodies of an analysis. A cell is a
+// string, an int, a float64, a bool or nil for a value a row does not have.
+type table struct {
+	level   string // perFile or perRecord
+	columns []string
+	rows    func(file string, body []byte) ([][]any, error)
+}
+
+// tables are the tables of each analysis, the first of which is the default.
+// gc-profile has a row per window and cpg-islands a row per island, each
+// labelled with its record; kmers has a row per top k-mer of a file.
+var tables = map[string][]table{
+	analyses.Count: {
+		{level: perFile, columns: compositionColumns("file", "format"), rows: countFileRows},
+		{level: perRecord, columns: compositionColumns("file", "id", "description"), rows: countRecordRows},
+	},
+	analyses.GCProfile: {
+		{
+			level:   perRecord,
+			columns: []string{"file", "id", "start", "end", "gc", "skew", "cumulative_skew"},
+			rows:    gcProfileRows,
+		},
+	},
+	analyses.Kmers: {
+		{
+			level:   perFile,
+			columns: []string{"file", "k", "canonical", "total", "distinct", "rank", "kmer", "count"},
+			rows:    kmerRows,
+		},
+	},
+	analyses.Dinucleotides: {
+		{level: perFile, columns: dinucleotideColumns("file"), rows: dinucleotideFileRows},
+		{level: perRecord, columns: dinucleotideColumns("file", "id", "description"), rows: dinucleotideRecordRows},
+	},
+	analyses.CpGIslands: {
+		{
+			level:   perRecord,
+			columns: []string{"file", "id", "start", "end", "length", "gc_percent", "cpg_observed_expected"},
+			rows:    cpgIslandRows,
+		},
+	},
+}
+
+// tableOf returns the table of the analysis with a row per level, or the
+// default table if level is empty. The per-record table of count asks for the
+// records of FASTA files.
+func tableOf(analysis, level string, query url.Values) (table, error) {
+	ts := tables[analysis]
+	if level == "" {
+		level = ts[0].level
+	}
+	for _, t := range ts {
+		if t.level != level {
+			continue
+		}
+		if analysis == analyses.Count && level == perRecord {
+			if v := query.Get("records"); v == "" {
+				query.Set("records", "true")
+			} else if ok, err := strconv.ParseBool(v); err == nil && !ok {
+				return table{}, fmt.Errorf("%s per %s needs records", analysis, level)
+			}
+		}
+		return t, nil
+	}
+	return table{}, fmt.Errorf("%s has no table per %s", analysis, level)
+}
+
+func compositionColumns(leading ...string) []string {
+	return append(leading,
+		"A", "C", "G", "T", "U",
+		"N", "R", "Y", "S", "W", "K", "M", "B", "D", "H", "V",
+		"gaps", "invalid", "total", "length",
+		"gc_percent", "at_percent", "gc_skew", "at_skew", "purine_pyrimidine_ratio", "n_fraction",
+		"masked",
+	)
+}
+
+func compositionCells(c analyses.CompositionDTO, masked *int) []any {
+	b, a, m := c.Bases, c.Ambiguous, c.Metrics
+	cells := []any{
+		b.A, b.C, b.G, b.T, b.U,
+		a.N, a.R, a.Y, a.S, a.W, a.K, a.M, a.B, a.D, a.H, a.V,
+		c.Gaps, c.Invalid, c.Total, c.Length,
+		m.GCPercent, m.ATPercent, m.GCSkew, m.ATSkew, m.PurinePyrimidineRatio, m.NFraction,
+		nil,
+	}
+	if masked != nil {
+		cells[len(cells)-1] = *masked
+	}
+	return cells
+}
+
+func countFileRows(file string, body []byte) ([][]any, error) {
+	var resp analyses.NucleotideCountResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, err
+	}
+	return [][]any{append([]any{file, resp.Format}, compositionCells(resp.CompositionDTO, resp.Masked)...)}, nil
+}
+
+func countRecordRows(file string, body []byte) ([][]any, error) {
+	var resp analyses.NucleotideCountResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, err
+	}
+	if resp.FASTQ != nil {
+		return nil, fmt.Errorf("%s has no per-record counts", resp.Format)
+	}
+	rows := make([][]any, 0, len(resp.Records))
+	for _, r := range resp.Records {
+		rows = append(rows, append([]any{file, r.ID, r.Description}, compositionCells(r.CompositionDTO, r.Masked)...))
+	}
+	return rows, nil
+}
+
+func gcProfileRows(file string, body []byte) ([][]any, error) {
+	var resp analyses.GCProfileResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, err
+	}
+	var rows [][]any
+	for _, r := range resp.Records {
+		for i := range r.Start {
+			rows = append(rows, []any{file, r.ID, r.Start[i], r.End[i], r.GC[i], r.Skew[i], r.CumulativeSkew[i]})
+		}
+	}
+	return rows, nil
+}
+
+func kmerRows(file string, body []byte) ([][]any, error) {
+	var resp analyses.KmerResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, err
+	}
+	rows := make([][]any, 0, len(resp.Top))
+	for i, kc := range resp.Top {
+		rows = append(rows, []any{file, resp.K, resp.Canonical, resp.Total, resp.Distinct, i + 1, kc.Kmer, kc.Count})
+	}
+	return rows, nil
+}
+
+func dinucleotideColumns(leading ...string) []string {
+	columns := append(leading, "length", "pairs")
+	columns = append(columns, nucleotide.DinucleotideNames[:]...)
+	return append(columns, "cpg_observed_expected")
+}
+
+func dinucleotideCells(d analyses.DinucleotideDTO) []any {
+	cells := []any{d.Length, d.Pairs}
+	for _, name := range nucleotide.DinucleotideNames {
+		cells = append(cells, d.Counts[name])
+	}
+	return append(cells, d.CpGObservedExpected)
+}
+
+func dinucleotideFileRows(file string, body []byte) ([][]any, error) {
+	var resp analyses.DinucleotideResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, err
+	}
+	return [][]any{append([]any{file}, dinucleotideCells(resp.Total)...)}, nil
+}
+
+func dinucleotideRecordRows(file string, body []byte) ([][]any, error) {
+	var resp analyses.DinucleotideResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, err
+	}
+	rows := make([][]any, 0, len(resp.Records))
+	for _, r := range resp.Records {
+		rows = append(rows, append([]any{file, r.ID, r.Description}, dinucleotideCells(r)...))
+	}
+	return rows, nil
+}
+
+func cpgIslandRows(file string, body []byte) ([][]any, error) {
+	var resp analyses.CpGIslandResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, err
+	}
+	var rows [][]any
+	for _, r := range resp.Records {
+		for _, isl := range r.Islands {
+			rows = append(rows, []any{file, r.ID, isl.Start, isl.End, isl.Length, isl.GCPercent, isl.ObsExp})
+		}
+	}
+	return rows, nil
+}
@@ -24,85 +24,2361 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/nucleotides/count": {
+        "/analyses": {
+            "get": {
+                "description": "Every successful response of the nucleotide endpoints, CpG islands in BED or GFF3 included, and every\nsucceeded job is recorded with the uploaded file's name, size and SHA-256, the detected format, the timings\nand the result. An upload analysed again with the same parameters and result is recorded once. Analyses\nare listed newest first, without their results. from and to take an RFC 3339 time or a date; a date as to\nincludes that whole day (UTC).",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "analyses"
+                ],
+                "summary": "List past analyses",
+                "parameters": [
+                    {
+                        "enum": [
+                            "count",
+                            "gc-profile",
+                            "kmers",
+                            "dinucleotides",
+                            "cpg-islands"
+                        ],
+                        "type": "string",
+                        "description": "Analysis name",
+                        "name": "analysis",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Part of the file name, case-insensitive",
+                        "name": "filename",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "SHA-256 of the upload, hex",
+                        "name": "sha256",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Recorded at or after, e.g. 2026-01-31 or 2026-01-31T12:00:00Z",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Recorded before, or on a date",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page size (1-500, default 50)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Analyses to skip (default 0)",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.AnalysisListResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid parameters",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal error",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/analyses/{id}": {
+            "get": {
+                "description": "Returns a recorded analysis with its result, the JSON response of the analysis endpoint. BED and GFF3\nresponses of the CpG island endpoint are rendered from it.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "analyses"
+                ],
+                "summary": "Get a past analysis",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Analysis ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.AnalysisResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Analysis not found",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal error",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/jobs": {
             "post": {
-                "description": "Upload a .fasta/.fa (or plain text FASTA) file and get counts of A/C/G/T with total.",
+                "description": "Upload a file to run one of the nucleotide analyses in the background. The analysis is named by the analysis\nparameter; all other query parameters are those of the analysis endpoint and are validated right away.\nThe upload is stored, so the job survives restarts, and any replica may run it. Poll GET /jobs/{id} for the\nprogress and, once the job has succeeded, the result, which is the JSON response of the analysis endpoint.\nA job on an upload that has been analysed with the same parameters before is served the stored result.\nInstead of polling, a client configured with a webhook secret may pass callback_url and callback_client:\nwhen the job succeeds or fails, the callback is POSTed a JSON payload with the event (job.succeeded or\njob.failed), the job and its result or error. The X-Webhook-Signature header is \"sha256=\" followed by the\nhex HMAC-SHA256, keyed with the client's secret, of the X-Webhook-Timestamp header, a dot and the body.\nThe client signs the submission the same way, with the callback_url, a newline and the hex SHA-256 of the\nfile in place of the body, and a timestamp at most 5 minutes off; the SHA-256 is declared in the\nX-Content-SHA256 header and the upload is refused unless it matches, so a captured submission cannot be\nreplayed with another file. Callbacks are only sent to public addresses.\nResponses other than 2xx are retried with exponential backoff; see GET /jobs/{id}/deliveries.",
                 "consumes": [
-                    "multipart/form-data"
+                    "multipart/form-data",
+                    "application/octet-stream"
                 ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "nucleotides"
+                    "jobs"
                 ],
-                "summary": "Count nucleotides from a FASTA file",
+                "summary": "Submit an analysis job",
                 "parameters": [
                     {
                         "type": "file",
-                        "description": "FASTA file (.fasta, .fa) or text/plain",
+                        "description": "File accepted by the analysis",
                         "name": "file",
                         "in": "formData",
                         "required": true
+                    },
+                    {
+                        "enum": [
+                            "count",
+                            "gc-profile",
+                            "kmers",
+                            "dinucleotides",
+                            "cpg-islands"
+                        ],
+                        "type": "string",
+                        "description": "Analysis to run",
+                        "name": "analysis",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "http or https URL to POST the outcome of the job to",
+                        "name": "callback_url",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Client whose secret signs the callback, required with callback_url",
+                        "name": "callback_client",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Unix time in seconds of the signature, required with callback_url",
+                        "name": "X-Webhook-Timestamp",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Signature of callback_url and the file's SHA-256 with the client's secret, required with callback_url",
+                        "name": "X-Webhook-Signature",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "SHA-256 of the file as sent, hex; required with callback_url",
+                        "name": "X-Content-SHA256",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.JobResponse"
+                        },
+                        "headers": {
+                            "Location": {
+                                "type": "string",
+                                "description": "URL of the job"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Unknown analysis, invalid parameters, invalid callback or file not matching its SHA-256",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Callback not signed with the client's secret",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.ErrorResponse"
+                        }
+                    },
+                    "413": {
+                        "description": "Upload too large",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.ErrorResponse"
+                        }
+                    },
+                    "415": {
+                        "description": "File content is not accepted by the analysis",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal error while storing the upload",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/jobs/{id}": {
+            "get": {
+                "description": "Returns the status and progress of a job and, once it has succeeded, its result. Progress counts the upload\nbytes read by the current attempt; a job that was interrupted by a restart starts again from the beginning.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "jobs"
+                ],
+                "summary": "Get an analysis job",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Job ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/controllers.NucleotideCountResponse"
+                            "$ref": "#/definitions/controllers.JobResponse"
                         }
                     },
-                    "400": {
-                        "description": "Invalid file or parameters",
+                    "404": {
+                        "description": "Job not found",
                         "schema": {
                             "$ref": "#/definitions/apierror.ErrorResponse"
                         }
                     },
                     "500": {
-                        "description": "Internal error while processing file",
+                        "description": "Internal error",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Cancels a queued or running job and deletes its upload. A running analysis stops within about a second.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "jobs"
+                ],
+                "summary": "Cancel a job",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Job ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.JobResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Job not found",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Job has already finished",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal error",
                         "schema": {
                             "$ref": "#/definitions/apierror.ErrorResponse"
                         }
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "apierror.ErrorResponse": {
-            "type": "object",
-            "properties": {
-                "error": {
-                    "type": "string"
+        },
+        "/jobs/{id}/deliveries": {
+            "get": {
+                "description": "Returns the deliveries of the job's callback with every attempt at sending them, oldest first. A job has a\ndelivery once it has succeeded or failed, and only if it was submitted with a callback_url.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "jobs"
+                ],
+                "summary": "List the webhook deliveries of a job",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Job ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/controllers.WebhookDeliveryResponse"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Job not found",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal error",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.ErrorResponse"
+                        }
+                    }
                 }
             }
         },
-        "controllers.NucleotideCountDTO": {
-            "type": "object",
-            "properties": {
-                "A": {
-                    "type": "integer"
-                },
-                "C": {
-                    "type": "integer"
-                },
-                "G": {
-                    "type": "integer"
-                },
-                "T": {
-                    "type": "integer"
+        "/jobs/{id}/events": {
+            "get": {
+                "description": "Server-Sent Events stream of a job. A \"progress\" event is sent once per second while the job is queued or\nrunning, with the upload bytes analysed, the records seen, the completed fraction and the throughput over the\nlast 10 seconds. The stream ends with one final event carrying the job: \"result\" when it succeeded (the job's\nresult field holds the analysis result), \"failed\" or \"cancelled\"; clients should close the EventSource then,\nor it reconnects. Progress restarts from zero when an interrupted job is run again. A server shutting down ends\nthe stream without a final event; the EventSource then reconnects.",
+                "produces": [
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "jobs"
+                ],
+                "summary": "Stream the progress of a job",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Job ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "progress events, then a result, failed or cancelled event with a JobResponse",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.JobProgressEvent"
+                        }
+                    },
+                    "404": {
+                        "description": "Job not found",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal error",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.ErrorResponse"
+                        }
+                    }
                 }
             }
         },
-        "controllers.NucleotideCountResponse": {
-            "type": "object",
-            "properties": {
-                "bases": {
-                    "$ref": "#/definitions/controllers.NucleotideCountDTO"
-                },
-                "total": {
-                    "type": "integer"
+        "/nucleotides/count": {
+            "post": {
+                "description": "Upload a .fasta/.fa (or plain text FASTA) file and get counts of A/C/G/T with total.\nFASTQ files (.fastq/.fq) are detected from their content and additionally report read count, length distribution,\nmean quality per position, Q20/Q30 fractions and per-read GC distribution. Phred+33 and Phred+64 are detected automatically.\nUCSC .2bit files always report per-record counts; N and soft-masked bases are taken from the block tables.\nIUPAC ambiguity codes, gaps and invalid characters are reported separately.\nDerived metrics (GC%, AT%, GC/AT skew, purine/pyrimidine ratio, N fraction) are rounded to the requested precision.\nWith records=true the response also lists the counts, length and GC% of every record.",
+                "consumes": [
+                    "multipart/form-data",
+                    "application/octet-stream"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "nucleotides"
+                ],
+                "summary": "Count nucleotides from a FASTA or FASTQ file",
+                "parameters": [
+                    {
+                        "type": "file",
+                        "description": "FASTA (.fasta, .fa), FASTQ (.fastq, .fq) or 2bit file",
+                        "name": "file",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "SHA-256 of the file as sent, hex; needed for a cached result",
+                        "name": "X-Content-SHA256",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of a result the client already has",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Include per-record counts (FASTA only)",
+                        "name": "records",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Decimal places of derived metrics (0-10, default 2)",
+                        "name": "precision",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/analyses.NucleotideCountResponse"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Strong entity tag of the result"
+                            },
+                            "X-Analysis-ID": {
+                                "type": "string",
+                                "description": "ID of the analysis in the history"
+                            },
+                            "X-Cache": {
+                                "type": "string",
+                                "description": "hit if the result was served from the cache, else miss"
+                            },
+                            "X-Content-SHA256": {
+                                "type": "string",
+                                "description": "SHA-256 of the file as sent, hex, to declare when sending it again"
+                            }
+                        }
+                    },
+                    "304": {
+                        "description": "Result unchanged, matches If-None-Match"
+                    },
+                    "400": {
+                        "description": "Invalid file or parameters",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.ErrorResponse"
+                        }
+                    },
+                    "413": {
+                        "description": "Upload or decompressed content too large",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.ErrorResponse"
+                        }
+                    },
+                    "415": {
+                        "description": "File content is not a supported sequence format",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal error while processing file",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/nucleotides/cpg-islands": {
+            "post": {
+                "description": "Upload a FASTA file and get merged CpG island intervals per record, using the Gardiner-Garden criteria\n(window length \u003e= 200, GC \u003e= 50%, CpG o/e \u003e= 0.6) unless other thresholds are given.\nThe format follows the Accept header: JSON (default), BED (text/x-bed, 0-based) or GFF3 (text/x-gff3, 1-based).\nAll three are rendered from the same result, which is recorded in the history and cached like the JSON one.",
+                "consumes": [
+                    "multipart/form-data",
+                    "application/octet-stream"
+                ],
+                "produces": [
+                    "application/json",
+                    "text/x-bed",
+                    "text/x-gff3"
+                ],
+                "tags": [
+                    "nucleotides"
+                ],
+                "summary": "Detect CpG islands",
+                "parameters": [
+                    {
+                        "type": "file",
+                        "description": "FASTA file (.fasta, .fa) or text/plain",
+                        "name": "file",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "SHA-256 of the file as sent, hex; needed for a cached result",
+                        "name": "X-Content-SHA256",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of a result the client already has",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Window length in bases (2-10000000, default 200)",
+                        "name": "min_length",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Minimum GC percentage (0-100, default 50)",
+                        "name": "min_gc",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Minimum CpG observed/expected ratio (default 0.6)",
+                        "name": "min_oe",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Decimal places of GC% and CpG o/e in JSON and GFF3 (0-10, default 2)",
+                        "name": "precision",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/analyses.CpGIslandResponse"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Strong entity tag of the result in the negotiated format"
+                            },
+                            "X-Analysis-ID": {
+                                "type": "string",
+                                "description": "ID of the analysis in the history"
+                            },
+                            "X-Cache": {
+                                "type": "string",
+                                "description": "hit if the result was served from the cache, else miss"
+                            },
+                            "X-Content-SHA256": {
+                                "type": "string",
+                                "description": "SHA-256 of the file as sent, hex, to declare when sending it again"
+                            }
+                        }
+                    },
+                    "304": {
+                        "description": "Result unchanged, matches If-None-Match"
+                    },
+                    "400": {
+                        "description": "Invalid file or parameters",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.ErrorResponse"
+                        }
+                    },
+                    "406": {
+                        "description": "Unsupported Accept header",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.ErrorResponse"
+                        }
+                    },
+                    "413": {
+                        "description": "Upload or decompressed content too large",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.ErrorResponse"
+                        }
+                    },
+                    "415": {
+                        "description": "File content is not a supported sequence format",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal error while processing file",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/nucleotides/dinucleotides": {
+            "post": {
+                "description": "Upload a FASTA file and get the counts and frequencies of all 16 dinucleotides and the CpG o/e ratio of every record.\nPairs are counted across line breaks, but never across record boundaries or N. CpG o/e = CpG * length / (C * G).",
+                "consumes": [
+                    "multipart/form-data",
+                    "application/octet-stream"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "nucleotides"
+                ],
+                "summary": "Dinucleotide frequencies and CpG observed/expected ratio",
+                "parameters": [
+                    {
+                        "type": "file",
+                        "description": "FASTA file (.fasta, .fa) or text/plain",
+                        "name": "file",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "SHA-256 of the file as sent, hex; needed for a cached result",
+                        "name": "X-Content-SHA256",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of a result the client already has",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Decimal places of frequencies and CpG o/e (0-10, default 2)",
+                        "name": "precision",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/analyses.DinucleotideResponse"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Strong entity tag of the result"
+                            },
+                            "X-Analysis-ID": {
+                                "type": "string",
+                                "description": "ID of the analysis in the history"
+                            },
+                            "X-Cache": {
+                                "type": "string",
+                                "description": "hit if the result was served from the cache, else miss"
+                            },
+                            "X-Content-SHA256": {
+                                "type": "string",
+                                "description": "SHA-256 of the file as sent, hex, to declare when sending it again"
+                            }
+                        }
+                    },
+                    "304": {
+                        "description": "Result unchanged, matches If-None-Match"
+                    },
+                    "400": {
+                        "description": "Invalid file or parameters",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.ErrorResponse"
+                        }
+                    },
+                    "413": {
+                        "description": "Upload or decompressed content too large",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.ErrorResponse"
+                        }
+                    },
+                    "415": {
+                        "description": "File content is not a supported sequence format",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal error while processing file",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/nucleotides/gc-profile": {
+            "post": {
+                "description": "Upload a FASTA file and get, for every record, the start, end, GC% and GC skew of each window.\nWindows start every step bases; the last window of a record may be shorter. Cumulative skew is the running sum of window skews.\nA request yields at most 1,000,000 windows over all records; more are refused with 400, asking for a larger step.",
+                "consumes": [
+                    "multipart/form-data",
+                    "application/octet-stream"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "nucleotides"
+                ],
+                "summary": "Sliding-window GC profile of a FASTA file",
+                "parameters": [
+                    {
+                        "type": "file",
+                        "description": "FASTA file (.fasta, .fa) or text/plain",
+                        "name": "file",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "SHA-256 of the file as sent, hex; needed for a cached result",
+                        "name": "X-Content-SHA256",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of a result the client already has",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Window size in bases (default 1000)",
+                        "name": "window",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Distance between window starts (default: window size)",
+                        "name": "step",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Decimal places of GC% and skew (0-10, default 2)",
+                        "name": "precision",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/analyses.GCProfileResponse"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Strong entity tag of the result"
+                            },
+                            "X-Analysis-ID": {
+                                "type": "string",
+                                "description": "ID of the analysis in the history"
+                            },
+                            "X-Cache": {
+                                "type": "string",
+                                "description": "hit if the result was served from the cache, else miss"
+                            },
+                            "X-Content-SHA256": {
+                                "type": "string",
+                                "description": "SHA-256 of the file as sent, hex, to declare when sending it again"
+                            }
+                        }
+                    },
+                    "304": {
+                        "description": "Result unchanged, matches If-None-Match"
+                    },
+                    "400": {
+                        "description": "Invalid file or parameters, or too many windows",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.ErrorResponse"
+                        }
+                    },
+                    "413": {
+                        "description": "Upload or decompressed content too large",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.ErrorResponse"
+                        }
+                    },
+                    "415": {
+                        "description": "File content is not a supported sequence format",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal error while processing file",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/nucleotides/kmers": {
+            "post": {
+                "description": "Upload a FASTA file and get the most frequent k-mers and the k-mer spectrum (how many distinct k-mers occur n times).\nK-mers containing N or other non-ACGT symbols are skipped and never span two records. With canonical=true each k-mer is merged with its reverse complement.",
+                "consumes": [
+                    "multipart/form-data",
+                    "application/octet-stream"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "nucleotides"
+                ],
+                "summary": "Count k-mers in a FASTA file",
+                "parameters": [
+                    {
+                        "type": "file",
+                        "description": "FASTA file (.fasta, .fa) or text/plain",
+                        "name": "file",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "SHA-256 of the file as sent, hex; needed for a cached result",
+                        "name": "X-Content-SHA256",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of a result the client already has",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "K-mer length (1-31, default 21)",
+                        "name": "k",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Merge k-mers with their reverse complement",
+                        "name": "canonical",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of most frequent k-mers to return (0-1000, default 10)",
+                        "name": "top",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/analyses.KmerResponse"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Strong entity tag of the result"
+                            },
+                            "X-Analysis-ID": {
+                                "type": "string",
+                                "description": "ID of the analysis in the history"
+                            },
+                            "X-Cache": {
+                                "type": "string",
+                                "description": "hit if the result was served from the cache, else miss"
+                            },
+                            "X-Content-SHA256": {
+                                "type": "string",
+                                "description": "SHA-256 of the file as sent, hex, to declare when sending it again"
+                            }
+                        }
+                    },
+                    "304": {
+                        "description": "Result unchanged, matches If-None-Match"
+                    },
+                    "400": {
+                        "description": "Invalid file or parameters",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.ErrorResponse"
+                        }
+                    },
+                    "413": {
+                        "description": "Upload or decompressed content too large",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.ErrorResponse"
+                        }
+                    },
+                    "415": {
+                        "description": "File content is not a supported sequence format",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Too many distinct k-mers",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal error while processing file",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/sequences": {
+            "get": {
+                "description": "Lists stored sequences by ID, without their bases and features. The page after this one is fetched by\npassing its next_cursor as cursor with the same filters; next_cursor is absent on the last page.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sequences"
+                ],
+                "summary": "List stored sequences",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Species, case-insensitive",
+                        "name": "species",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Chromosome or scaffold",
+                        "name": "chromosome",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Start of the accession, e.g. CM034",
+                        "name": "accession_prefix",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page size (1-500, default 50)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "next_cursor of the previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.SequenceListResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid parameters",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal error",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/sequences/export.2bit": {
+            "get": {
+                "description": "Streams the stored sequences as a UCSC .2bit file with one record per sequence, named by accession.\nLowercase bases are stored as soft-mask blocks and every non-ACGT symbol as N.\nWithout accession parameters every stored sequence is exported.",
+                "produces": [
+                    "application/octet-stream"
+                ],
+                "tags": [
+                    "sequences"
+                ],
+                "summary": "Export stored sequences as 2bit",
+                "parameters": [
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Accessions to export",
+                        "name": "accession",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "404": {
+                        "description": "An accession is not stored",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal error while exporting",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/sequences/import": {
+            "post": {
+                "description": "Upload a GenBank or EMBL file (optionally gzip, bzip2 or zstd compressed). Every record is stored as a sequence\nwith its accession (versioned when known), organism, chromosome (from the source feature), definition and feature table.\nThe file is imported in a single transaction: if any record fails to parse or store, nothing is stored.",
+                "consumes": [
+                    "multipart/form-data",
+                    "application/octet-stream"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sequences"
+                ],
+                "summary": "Import a GenBank or EMBL flat file",
+                "parameters": [
+                    {
+                        "type": "file",
+                        "description": "GenBank (.gb, .gbk) or EMBL (.embl) file",
+                        "name": "file",
+                        "in": "formData",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.SequenceImportResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Malformed flat file",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "A record with the same accession already exists",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.ErrorResponse"
+                        }
+                    },
+                    "413": {
+                        "description": "Upload or decompressed content too large",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.ErrorResponse"
+                        }
+                    },
+                    "415": {
+                        "description": "File content is not GenBank or EMBL",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal error while importing file",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/sequences/{ref}": {
+            "get": {
+                "description": "Returns the metadata of a sequence, by ID or accession, without its bases; see the subsequence endpoint\nfor those. With features=true its feature table is included.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sequences"
+                ],
+                "summary": "Get a stored sequence",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Sequence ID or accession",
+                        "name": "ref",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Include the feature table",
+                        "name": "features",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.SequenceDTO"
+                        }
+                    },
+                    "404": {
+                        "description": "Sequence not found",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal error",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Deletes a sequence, by ID or accession, with its features.",
+                "tags": [
+                    "sequences"
+                ],
+                "summary": "Delete a stored sequence",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Sequence ID or accession",
+                        "name": "ref",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "404": {
+                        "description": "Sequence not found",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal error",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "description": "Changes the accession, species, chromosome or description of a sequence, by ID or accession. Absent\nfields are left as they are; an empty species, chromosome or description is cleared.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sequences"
+                ],
+                "summary": "Update the metadata of a stored sequence",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Sequence ID or accession",
+                        "name": "ref",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Metadata to change",
+                        "name": "metadata",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controllers.SequenceUpdateRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.SequenceDTO"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid metadata",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Sequence not found",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Another sequence has the accession",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal error",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/sequences/{ref}/subsequence": {
+            "get": {
+                "description": "Returns the bases of a sequence, by ID or accession, from start to end, both included, counting from\nits start position (normally 1). start defaults to the start of the sequence and end to its end; at\nmost 16777216 bases are returned at once.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sequences"
+                ],
+                "summary": "Get part of a stored sequence",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Sequence ID or accession",
+                        "name": "ref",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "First position",
+                        "name": "start",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Last position",
+                        "name": "end",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.SubsequenceResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid range",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Sequence not found",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal error",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/webhooks/deliveries/{id}": {
+            "get": {
+                "description": "Returns a delivery of a job's callback, its payload and every attempt at sending it.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Get a webhook delivery",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Delivery ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.WebhookDeliveryResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Delivery not found",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal error",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/webhooks/deliveries/{id}/replay": {
+            "post": {
+                "description": "Sends a delivered or failed delivery again, with the same payload and a fresh round of retries. The\nrequest is signed anew, with the current timestamp.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Replay a webhook delivery",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Delivery ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.WebhookDeliveryResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Delivery not found",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Delivery is still pending",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal error",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "analyses.AmbiguityCountDTO": {
+            "type": "object",
+            "properties": {
+                "B": {
+                    "type": "integer"
+                },
+                "D": {
+                    "type": "integer"
+                },
+                "H": {
+                    "type": "integer"
+                },
+                "K": {
+                    "type": "integer"
+                },
+                "M": {
+                    "type": "integer"
+                },
+                "N": {
+                    "type": "integer"
+                },
+                "R": {
+                    "type": "integer"
+                },
+                "S": {
+                    "type": "integer"
+                },
+                "V": {
+                    "type": "integer"
+                },
+                "W": {
+                    "type": "integer"
+                },
+                "Y": {
+                    "type": "integer"
+                }
+            }
+        },
+        "analyses.CpGIslandResponse": {
+            "type": "object",
+            "properties": {
+                "min_gc": {
+                    "type": "number"
+                },
+                "min_length": {
+                    "type": "integer"
+                },
+                "min_obs_exp": {
+                    "type": "number"
+                },
+                "records": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/analyses.RecordIslandsDTO"
+                    }
+                }
+            }
+        },
+        "analyses.DinucleotideDTO": {
+            "type": "object",
+            "properties": {
+                "counts": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "integer"
+                    }
+                },
+                "cpg_observed_expected": {
+                    "type": "number"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "frequencies": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "number"
+                    }
+                },
+                "id": {
+                    "type": "string"
+                },
+                "length": {
+                    "type": "integer"
+                },
+                "pairs": {
+                    "type": "integer"
+                }
+            }
+        },
+        "analyses.DinucleotideResponse": {
+            "type": "object",
+            "properties": {
+                "records": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/analyses.DinucleotideDTO"
+                    }
+                },
+                "total": {
+                    "$ref": "#/definitions/analyses.DinucleotideDTO"
+                }
+            }
+        },
+        "analyses.FASTQStatsDTO": {
+            "type": "object",
+            "properties": {
+                "gc_distribution": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/analyses.GCBinDTO"
+                    }
+                },
+                "length_distribution": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/analyses.LengthBinDTO"
+                    }
+                },
+                "max_length": {
+                    "type": "integer"
+                },
+                "mean_length": {
+                    "type": "number"
+                },
+                "mean_quality_per_position": {
+                    "type": "array",
+                    "items": {
+                        "type": "number"
+                    }
+                },
+                "min_length": {
+                    "type": "integer"
+                },
+                "q20_fraction": {
+                    "type": "number"
+                },
+                "q30_fraction": {
+                    "type": "number"
+                },
+                "quality_encoding": {
+                    "type": "string"
+                },
+                "reads": {
+                    "type": "integer"
+                }
+            }
+        },
+        "analyses.GCBinDTO": {
+            "type": "object",
+            "properties": {
+                "gc_percent": {
+                    "type": "integer"
+                },
+                "reads": {
+                    "type": "integer"
+                }
+            }
+        },
+        "analyses.GCProfileResponse": {
+            "type": "object",
+            "properties": {
+                "records": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/analyses.RecordProfileDTO"
+                    }
+                },
+                "step": {
+                    "type": "integer"
+                },
+                "window": {
+                    "type": "integer"
+                }
+            }
+        },
+        "analyses.IslandDTO": {
+            "type": "object",
+            "properties": {
+                "cpg_observed_expected": {
+                    "type": "number"
+                },
+                "end": {
+                    "type": "integer"
+                },
+                "gc_percent": {
+                    "type": "number"
+                },
+                "length": {
+                    "type": "integer"
+                },
+                "start": {
+                    "type": "integer"
+                }
+            }
+        },
+        "analyses.KmerCountDTO": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer"
+                },
+                "kmer": {
+                    "type": "string"
+                }
+            }
+        },
+        "analyses.KmerResponse": {
+            "type": "object",
+            "properties": {
+                "canonical": {
+                    "type": "boolean"
+                },
+                "distinct": {
+                    "type": "integer"
+                },
+                "k": {
+                    "type": "integer"
+                },
+                "spectrum": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/analyses.SpectrumBinDTO"
+                    }
+                },
+                "top": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/analyses.KmerCountDTO"
+                    }
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "analyses.LengthBinDTO": {
+            "type": "object",
+            "properties": {
+                "length": {
+                    "type": "integer"
+                },
+                "reads": {
+                    "type": "integer"
+                }
+            }
+        },
+        "analyses.MetricsDTO": {
+            "type": "object",
+            "properties": {
+                "at_percent": {
+                    "type": "number"
+                },
+                "at_skew": {
+                    "type": "number"
+                },
+                "gc_percent": {
+                    "type": "number"
+                },
+                "gc_skew": {
+                    "type": "number"
+                },
+                "n_fraction": {
+                    "type": "number"
+                },
+                "purine_pyrimidine_ratio": {
+                    "type": "number"
+                }
+            }
+        },
+        "analyses.NucleotideCountDTO": {
+            "type": "object",
+            "properties": {
+                "A": {
+                    "type": "integer"
+                },
+                "C": {
+                    "type": "integer"
+                },
+                "G": {
+                    "type": "integer"
+                },
+                "T": {
+                    "type": "integer"
+                },
+                "U": {
+                    "type": "integer"
+                }
+            }
+        },
+        "analyses.NucleotideCountResponse": {
+            "type": "object",
+            "properties": {
+                "ambiguous": {
+                    "$ref": "#/definitions/analyses.AmbiguityCountDTO"
+                },
+                "bases": {
+                    "$ref": "#/definitions/analyses.NucleotideCountDTO"
+                },
+                "fastq": {
+                    "$ref": "#/definitions/analyses.FASTQStatsDTO"
+                },
+                "format": {
+                    "type": "string"
+                },
+                "gaps": {
+                    "type": "integer"
+                },
+                "invalid": {
+                    "type": "integer"
+                },
+                "length": {
+                    "type": "integer"
+                },
+                "masked": {
+                    "description": "soft-masked bases, 2bit only",
+                    "type": "integer"
+                },
+                "metrics": {
+                    "$ref": "#/definitions/analyses.MetricsDTO"
+                },
+                "records": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/analyses.NucleotideRecordDTO"
+                    }
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "analyses.NucleotideRecordDTO": {
+            "type": "object",
+            "properties": {
+                "ambiguous": {
+                    "$ref": "#/definitions/analyses.AmbiguityCountDTO"
+                },
+                "bases": {
+                    "$ref": "#/definitions/analyses.NucleotideCountDTO"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "gaps": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "invalid": {
+                    "type": "integer"
+                },
+                "length": {
+                    "type": "integer"
+                },
+                "masked": {
+                    "type": "integer"
+                },
+                "metrics": {
+                    "$ref": "#/definitions/analyses.MetricsDTO"
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "analyses.RecordIslandsDTO": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "islands": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/analyses.IslandDTO"
+                    }
+                },
+                "length": {
+                    "type": "integer"
+                }
+            }
+        },
+        "analyses.RecordProfileDTO": {
+            "type": "object",
+            "properties": {
+                "cumulative_skew": {
+                    "type": "array",
+                    "items": {
+                        "type": "number"
+                    }
+                },
+                "description": {
+                    "type": "string"
+                },
+                "end": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "gc": {
+                    "type": "array",
+                    "items": {
+                        "type": "number"
+                    }
+                },
+                "id": {
+                    "type": "string"
+                },
+                "length": {
+                    "type": "integer"
+                },
+                "skew": {
+                    "type": "array",
+                    "items": {
+                        "type": "number"
+                    }
+                },
+                "start": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                }
+            }
+        },
+        "analyses.SpectrumBinDTO": {
+            "type": "object",
+            "properties": {
+                "kmers": {
+                    "type": "integer"
+                },
+                "occurrences": {
+                    "type": "integer"
+                }
+            }
+        },
+        "apierror.ErrorResponse": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                }
+            }
+        },
+        "controllers.AnalysisListResponse": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/controllers.AnalysisSummaryDTO"
+                    }
+                },
+                "limit": {
+                    "type": "integer"
+                },
+                "offset": {
+                    "type": "integer"
+                },
+                "total": {
+                    "description": "analyses matching the filters",
+                    "type": "integer"
+                }
+            }
+        },
+        "controllers.AnalysisResponse": {
+            "type": "object",
+            "properties": {
+                "analysis": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "duration_ms": {
+                    "type": "integer"
+                },
+                "filename": {
+                    "type": "string"
+                },
+                "finished_at": {
+                    "type": "string"
+                },
+                "format": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "params": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        }
+                    }
+                },
+                "result": {
+                    "description": "response of the analysis endpoint",
+                    "type": "object"
+                },
+                "sha256": {
+                    "description": "of the upload, as sent",
+                    "type": "string"
+                },
+                "size": {
+                    "description": "upload bytes, as sent",
+                    "type": "integer"
+                },
+                "started_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "controllers.AnalysisSummaryDTO": {
+            "type": "object",
+            "properties": {
+                "analysis": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "duration_ms": {
+                    "type": "integer"
+                },
+                "filename": {
+                    "type": "string"
+                },
+                "finished_at": {
+                    "type": "string"
+                },
+                "format": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "params": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        }
+                    }
+                },
+                "sha256": {
+                    "description": "of the upload, as sent",
+                    "type": "string"
+                },
+                "size": {
+                    "description": "upload bytes, as sent",
+                    "type": "integer"
+                },
+                "started_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "controllers.ImportedSequenceDTO": {
+            "type": "object",
+            "properties": {
+                "accession": {
+                    "type": "string"
+                },
+                "chromosome": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "features": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "length": {
+                    "type": "integer"
+                },
+                "species": {
+                    "type": "string"
+                }
+            }
+        },
+        "controllers.JobProgressDTO": {
+            "type": "object",
+            "properties": {
+                "bytes": {
+                    "description": "upload bytes analysed so far",
+                    "type": "integer"
+                },
+                "fraction": {
+                    "description": "bytes / size",
+                    "type": "number"
+                },
+                "records": {
+                    "description": "records or reads started, FASTQ and 2bit and per-record analyses only",
+                    "type": "integer"
+                }
+            }
+        },
+        "controllers.JobProgressEvent": {
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "type": "integer"
+                },
+                "bytes": {
+                    "description": "upload bytes analysed so far",
+                    "type": "integer"
+                },
+                "fraction": {
+                    "description": "bytes / size",
+                    "type": "number"
+                },
+                "records": {
+                    "description": "records or reads started, FASTQ and 2bit and per-record analyses only",
+                    "type": "integer"
+                },
+                "size": {
+                    "type": "integer"
+                },
+                "status": {
+                    "type": "string",
+                    "enum": [
+                        "queued",
+                        "running"
+                    ]
+                },
+                "throughput": {
+                    "description": "upload bytes per second over the last 10 seconds",
+                    "type": "number"
+                }
+            }
+        },
+        "controllers.JobResponse": {
+            "type": "object",
+            "properties": {
+                "analysis": {
+                    "type": "string"
+                },
+                "attempts": {
+                    "type": "integer"
+                },
+                "callback_client": {
+                    "type": "string"
+                },
+                "callback_url": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "filename": {
+                    "type": "string"
+                },
+                "finished_at": {
+                    "type": "string"
+                },
+                "format": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "params": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        }
+                    }
+                },
+                "progress": {
+                    "$ref": "#/definitions/controllers.JobProgressDTO"
+                },
+                "result": {
+                    "description": "response of the analysis endpoint",
+                    "type": "object"
+                },
+                "sha256": {
+                    "description": "of the upload",
+                    "type": "string"
+                },
+                "size": {
+                    "type": "integer"
+                },
+                "started_at": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string",
+                    "enum": [
+                        "queued",
+                        "running",
+                        "succeeded",
+                        "failed",
+                        "cancelled"
+                    ]
+                }
+            }
+        },
+        "controllers.SequenceDTO": {
+            "type": "object",
+            "properties": {
+                "accession": {
+                    "type": "string"
+                },
+                "chromosome": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "end_position": {
+                    "type": "integer"
+                },
+                "features": {
+                    "description": "with ?features=true only",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Feature"
+                    }
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "length": {
+                    "type": "integer"
+                },
+                "species": {
+                    "type": "string"
+                },
+                "start_position": {
+                    "type": "integer"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "controllers.SequenceImportResponse": {
+            "type": "object",
+            "properties": {
+                "format": {
+                    "type": "string"
+                },
+                "imported": {
+                    "type": "integer"
+                },
+                "records": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/controllers.ImportedSequenceDTO"
+                    }
+                }
+            }
+        },
+        "controllers.SequenceListResponse": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/controllers.SequenceDTO"
+                    }
+                },
+                "limit": {
+                    "type": "integer"
+                },
+                "next_cursor": {
+                    "description": "absent on the last page",
+                    "type": "string"
+                }
+            }
+        },
+        "controllers.SequenceUpdateRequest": {
+            "type": "object",
+            "properties": {
+                "accession": {
+                    "type": "string"
+                },
+                "chromosome": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "species": {
+                    "type": "string"
+                }
+            }
+        },
+        "controllers.SubsequenceResponse": {
+            "type": "object",
+            "properties": {
+                "accession": {
+                    "type": "string"
+                },
+                "end": {
+                    "description": "included",
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "length": {
+                    "type": "integer"
+                },
+                "sequence": {
+                    "type": "string"
+                },
+                "start": {
+                    "description": "1-based, included",
+                    "type": "integer"
+                }
+            }
+        },
+        "controllers.WebhookAttemptDTO": {
+            "type": "object",
+            "properties": {
+                "attempt": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "duration_ms": {
+                    "type": "integer"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "status_code": {
+                    "description": "0 if there was no response",
+                    "type": "integer"
+                }
+            }
+        },
+        "controllers.WebhookDeliveryResponse": {
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "description": "since the delivery was created or replayed",
+                    "type": "integer"
+                },
+                "client": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "delivered_at": {
+                    "type": "string"
+                },
+                "event": {
+                    "type": "string",
+                    "enum": [
+                        "job.succeeded",
+                        "job.failed"
+                    ]
+                },
+                "history": {
+                    "description": "oldest first, including attempts before a replay",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/controllers.WebhookAttemptDTO"
+                    }
+                },
+                "id": {
+                    "type": "string"
+                },
+                "job_id": {
+                    "type": "string"
+                },
+                "next_attempt_at": {
+                    "description": "pending deliveries only",
+                    "type": "string"
+                },
+                "payload": {
+                    "description": "request body",
+                    "type": "object"
+                },
+                "status": {
+                    "type": "string",
+                    "enum": [
+                        "pending",
+                        "delivered",
+                        "failed"
+                    ]
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.Feature": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "integer"
+                },
+                "key": {
+                    "description": "e.g. source, gene, CDS",
+                    "type": "string"
+                },
+                "location": {
+                    "description": "e.g. complement(join(1..10,20..30))",
+                    "type": "string"
+                },
+                "qualifiers": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Qualifier"
+                    }
+                }
+            }
+        },
+        "models.Qualifier": {
+            "type": "object",
+            "properties": {
+                "name": {
+                    "type": "string"
+                },
+                "value": {
+                    "type": "string"
                 }
             }
         }
@@ -120,7 +2396,7 @@ var SwaggerInfo = &swag.Spec{
 	BasePath:         "/api/v1",
 	Schemes:          []string{},
 	Title:            "Swagger Example API",
-	Description:      "This is a sample server celler server.",
+	Description:      "This is a sample server celler server.\nEndpoints that take a file accept it as the \"file\" field of a multipart form or as the raw request body\n(application/octet-stream, optionally with Content-Encoding: gzip). Either way the upload is streamed, not\nbuffered, and classified by its content: files compressed with gzip, bgzip, bzip2 or zstd are decompressed\non the fly, and content of a format an endpoint does not accept is refused with 415.\nThe results of the nucleotide analyses are cached by the SHA-256 of the upload and the parameters, with\ntheir defaults filled in. The digest is only known once the upload has been read, so only a client that\ndeclares it in the X-Content-SHA256 header is served a stored result without the file being analysed;\nan undeclared upload is always analysed again. Each response carries the digest of its upload in\nX-Content-SHA256, to be declared when sending the same file again. A declared digest that does not\nmatch the upload is refused with 400. The ETag is the digest of the response body, and If-None-Match\nis honoured.",
 	InfoInstanceName: "swagger",
 	SwaggerTemplate:  docTemplate,
 	LeftDelim:        "{{",
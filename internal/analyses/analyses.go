@@ -0,0 +1,164 @@
+// Package analyses runs the nucleotide analyses of the API on a stream, with
+// the query parameters of their endpoints, and builds their response bodies.
+// It has no HTTP or storage dependencies, so that the endpoints, background
+// jobs and the nucount command run the same code and produce the same results.
+package analyses
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/Viktor2805/nucount/internal/helpers"
+	nucleotide "github.com/Viktor2805/nucount/internal/services/nucleotide"
+)
+
+// Names of the analyses, as used in routes and as the analysis of a job.
+const (
+	Count         = "count"
+	GCProfile     = "gc-profile"
+	Kmers         = "kmers"
+	Dinucleotides = "dinucleotides"
+	CpGIslands    = "cpg-islands"
+)
+
+// Defaults and bounds of query parameters.
+const (
+	defaultPrecision  = 2
+	maxPrecision      = 10
+	defaultWindowSize = 1000
+	defaultK          = 21
+	defaultKmerTop    = 10
+)
+
+// ErrUnknownAnalysis is returned by Analyze for a name it does not know.
+var ErrUnknownAnalysis = errors.New("unknown analysis")
+
+// RunFunc runs an analysis, whose parameters have been validated, on an upload
+// and returns the response body. opts are given to the analysis service, for
+// instance to report progress.
+type RunFunc func(ctx context.Context, r io.Reader, format helpers.Format, opts ...nucleotide.Option) (any, error)
+
+// Analysis is an analysis endpoint without its HTTP exchange.
+type Analysis struct {
+	Accepted []helpers.Format // upload formats, checked by sniffing the content
+	// Prepare validates the query parameters and returns the analysis to run
+	// and the parameters it runs with: every parameter of the analysis, with
+	// its default if it is missing, in canonical form, and no others. Runs
+	// with the same parameters on the same upload have the same result.
+	Prepare func(q url.Values) (RunFunc, url.Values, error)
+}
+
+// ParamError is an invalid query parameter; its text is the response message.
+type ParamError string
+
+func (e ParamError) Error() string {
+	return string(e)
+}
+
+// UnsupportedFormatError is returned for content of a format an analysis does
+// not accept; its text is the response message.
+type UnsupportedFormatError struct {
+	Format   helpers.Format
+	Accepted []helpers.Format
+}
+
+func (e UnsupportedFormatError) Error() string {
+	names := make([]string, len(e.Accepted))
+	for i, f := range e.Accepted {
+		names[i] = f.Description()
+	}
+	return fmt.Sprintf("Unsupported file content: detected %s, expected %s.", e.Format.Description(), strings.Join(names, " or "))
+}
+
+// Analyzer holds the analyses by name.
+type Analyzer struct {
+	service       nucleotide.Service
+	kmers         nucleotide.KmerService
+	dinucleotides nucleotide.DinucleotideService
+	analyses      map[string]Analysis
+}
+
+func New(
+	service nucleotide.Service,
+	kmers nucleotide.KmerService,
+	dinucleotides nucleotide.DinucleotideService,
+) *Analyzer {
+	a := &Analyzer{service: service, kmers: kmers, dinucleotides: dinucleotides}
+	fasta := []helpers.Format{helpers.FormatFASTA}
+	a.analyses = map[string]Analysis{
+		Count: {
+			Accepted: []helpers.Format{helpers.FormatFASTA, helpers.FormatFASTQ, helpers.Format2bit},
+			Prepare:  a.prepareCount,
+		},
+		GCProfile:     {Accepted: fasta, Prepare: a.prepareGCProfile},
+		Kmers:         {Accepted: fasta, Prepare: a.prepareKmers},
+		Dinucleotides: {Accepted: fasta, Prepare: a.prepareDinucleotides},
+		CpGIslands:    {Accepted: fasta, Prepare: a.prepareCpGIslands},
+	}
+	return a
+}
+
+// Lookup returns the named analysis.
+func (a *Analyzer) Lookup(name string) (Analysis, bool) {
+	an, ok := a.analyses[name]
+	return an, ok
+}
+
+// Names returns the names of the analyses in lexical order.
+func (a *Analyzer) Names() []string {
+	names := make([]string, 0, len(a.analyses))
+	for name := range a.analyses {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Analyze runs the named analysis on a file, exactly like its endpoint with
+// params as the query, and returns the response body and the format the file
+// was sniffed as.
+func (a *Analyzer) Analyze(ctx context.Context, name string, params url.Values, r io.Reader) ([]byte, helpers.Format, error) {
+	an, ok := a.analyses[name]
+	if !ok {
+		return nil, "", fmt.Errorf("%w: %q", ErrUnknownAnalysis, name)
+	}
+	run, _, err := an.Prepare(params)
+	if err != nil {
+		return nil, "", err
+	}
+
+	det, r, err := helpers.SniffReader(r)
+	if err != nil {
+		return nil, "", err
+	}
+	if !slices.Contains(an.Accepted, det.Format) {
+		return nil, det.Format, UnsupportedFormatError{Format: det.Format, Accepted: an.Accepted}
+	}
+
+	result, err := run(ctx, r, det.Format)
+	if err != nil {
+		return nil, det.Format, err
+	}
+	// Encoded like the result the history stores and the endpoints respond with.
+	data, err := json.Marshal(result)
+	if err != nil {
+		return nil, det.Format, fmt.Errorf("encode result: %w", err)
+	}
+	return data, det.Format, nil
+}
+
+// queryPrecision returns the precision parameter shared by all analyses.
+func queryPrecision(q url.Values) (int, error) {
+	precision, err := strconv.Atoi(helpers.QueryDefault(q, "precision", strconv.Itoa(defaultPrecision)))
+	if err != nil || precision < 0 || precision > maxPrecision {
+		return 0, ParamError("Invalid precision parameter. Expected an integer between 0 and 10.")
+	}
+	return precision, nil
+}
@@ -0,0 +1,159 @@
+package analyses_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/Viktor2805/nucount/internal/analyses"
+	"github.com/Viktor2805/nucount/internal/helpers"
+	nucleotide "github.com/Viktor2805/nucount/internal/services/nucleotide"
+)
+
+func newAnalyzer() *analyses.Analyzer {
+	return analyses.New(nucleotide.NewCounter(), nucleotide.NewKmerCounter(), nucleotide.NewDinucleotideAnalyzer())
+}
+
+func TestAnalyze(t *testing.T) {
+	body, format, err := newAnalyzer().Analyze(context.Background(), analyses.Count, url.Values{"precision": {"1"}}, strings.NewReader(">r\nACGTGG\n"))
+	if err != nil {
+		t.Fatalf("Analyze() error: %v", err)
+	}
+	if format != helpers.FormatFASTA {
+		t.Errorf("format = %q, want %q", format, helpers.FormatFASTA)
+	}
+
+	var resp analyses.NucleotideCountResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Bases.G != 3 || resp.Metrics.GCPercent != 66.7 {
+		t.Errorf("response = %+v", resp)
+	}
+}
+
+func TestAnalyze_Errors(t *testing.T) {
+	a := newAnalyzer()
+	ctx := context.Background()
+
+	_, _, err := a.Analyze(ctx, "gc", nil, strings.NewReader(">r\nACGT\n"))
+	if !errors.Is(err, analyses.ErrUnknownAnalysis) {
+		t.Errorf("unknown analysis: error = %v, want ErrUnknownAnalysis", err)
+	}
+
+	_, _, err = a.Analyze(ctx, analyses.Kmers, url.Values{"k": {"x"}}, strings.NewReader(">r\nACGT\n"))
+	if !errors.As(err, new(analyses.ParamError)) {
+		t.Errorf("invalid parameter: error = %v, want a ParamError", err)
+	}
+
+	_, format, err := a.Analyze(ctx, analyses.Kmers, nil, strings.NewReader("@r\nACGT\n+\nIIII\n"))
+	var unsupported analyses.UnsupportedFormatError
+	if !errors.As(err, &unsupported) || format != helpers.FormatFASTQ {
+		t.Fatalf("FASTQ to kmers: format %q, error = %v, want an UnsupportedFormatError", format, err)
+	}
+	if want := "Unsupported file content: detected FASTQ, expected FASTA."; err.Error() != want {
+		t.Errorf("message = %q, want %q", err.Error(), want)
+	}
+}
+
+func TestAnalyzer_Names(t *testing.T) {
+	a := newAnalyzer()
+	names := a.Names()
+	want := []string{"count", "cpg-islands", "dinucleotides", "gc-profile", "kmers"}
+	if !slices.Equal(names, want) {
+		t.Errorf("Names() = %v, want %v", names, want)
+	}
+	for _, name := range names {
+		if _, ok := a.Lookup(name); !ok {
+			t.Errorf("Lookup(%q) not found", name)
+		}
+	}
+}
+
+func TestCpGIslandResponse_Write(t *testing.T) {
+	params := url.Values{"min_length": {"4"}, "min_gc": {"50"}, "min_oe": {"0.6"}}
+	body, _, err := newAnalyzer().Analyze(context.Background(), analyses.CpGIslands, params,
+		strings.NewReader(">adjacent\nCGCGAAAACGCG\n>gap\nCGCGAAAAAAAACGCG\n"))
+	if err != nil {
+		t.Fatalf("Analyze() error: %v", err)
+	}
+	var resp analyses.CpGIslandResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		t.Fatal(err)
+	}
+
+	var bed bytes.Buffer
+	if err := resp.WriteBED(&bed); err != nil {
+		t.Fatalf("WriteBED() error: %v", err)
+	}
+	wantBED := "adjacent\t0\t12\tCpG_island_1\ngap\t0\t6\tCpG_island_1\ngap\t10\t16\tCpG_island_2\n"
+	if bed.String() != wantBED {
+		t.Fatalf("WriteBED() = %q, want %q", bed.String(), wantBED)
+	}
+
+	var gff bytes.Buffer
+	if err := resp.WriteGFF3(&gff); err != nil {
+		t.Fatalf("WriteGFF3() error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(gff.String()), "\n")
+	if len(lines) != 4 || lines[0] != "##gff-version 3" {
+		t.Fatalf("WriteGFF3() = %q", gff.String())
+	}
+	if want := "gap\tdna-analyzer\tCpG_island\t11\t16\t.\t.\t.\tID=gap.cpg2;gc=66.67;obs_exp=3"; lines[3] != want {
+		t.Fatalf("GFF3 line = %q, want %q", lines[3], want)
+	}
+}
+
+func TestPrepare_Params(t *testing.T) {
+	a := newAnalyzer()
+	tests := []struct {
+		name  string
+		query url.Values
+		want  url.Values
+	}{
+		{analyses.Count, nil, url.Values{"records": {"false"}, "precision": {"2"}}},
+		{analyses.Count, url.Values{"records": {"1"}, "precision": {"2"}, "other": {"x"}}, url.Values{"records": {"true"}, "precision": {"2"}}},
+		{analyses.GCProfile, url.Values{"window": {"500"}}, url.Values{"window": {"500"}, "step": {"500"}, "precision": {"2"}}},
+		{analyses.Kmers, url.Values{"canonical": {"T"}}, url.Values{"k": {"21"}, "canonical": {"true"}, "top": {"10"}}},
+		{analyses.Dinucleotides, url.Values{"precision": {"03"}}, url.Values{"precision": {"3"}}},
+		{analyses.CpGIslands, url.Values{"min_gc": {"50.0"}}, url.Values{"min_length": {"200"}, "min_gc": {"50"}, "min_oe": {"0.6"}, "precision": {"2"}}},
+	}
+	for _, tt := range tests {
+		an, _ := a.Lookup(tt.name)
+		_, got, err := an.Prepare(tt.query)
+		if err != nil {
+			t.Fatalf("%s %v: Prepare() error: %v", tt.name, tt.query, err)
+		}
+		if got.Encode() != tt.want.Encode() {
+			t.Errorf("%s %v: params = %v, want %v", tt.name, tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestIslandParams(t *testing.T) {
+	for _, v := range []string{"0", "1", "10000001", "x"} {
+		_, _, err := analyses.IslandParams(url.Values{"min_length": {v}})
+		want := "Invalid min_length parameter. Expected an integer between 2 and 10000000."
+		if !errors.As(err, new(analyses.ParamError)) || err.Error() != want {
+			t.Errorf("min_length=%s: error = %v, want %q", v, err, want)
+		}
+	}
+	for _, v := range []string{"-1", "100.5"} {
+		if _, _, err := analyses.IslandParams(url.Values{"min_gc": {v}}); !errors.As(err, new(analyses.ParamError)) {
+			t.Errorf("min_gc=%s: error = %v, want a ParamError", v, err)
+		}
+	}
+
+	// The bounds themselves run.
+	for _, v := range []string{"2", "10000000"} {
+		params := url.Values{"min_length": {v}, "min_gc": {"100"}}
+		if _, _, err := newAnalyzer().Analyze(context.Background(), analyses.CpGIslands, params, strings.NewReader(">r\nCG\n")); err != nil {
+			t.Errorf("min_length=%s: error = %v", v, err)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package analyses
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// unnamedRecord is the sequence name written for records without a header.
+const unnamedRecord = "unnamed"
+
+// WriteBED writes the islands as BED4 lines (0-based, half-open).
+func (r CpGIslandResponse) WriteBED(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	for _, rec := range r.Records {
+		name := recordName(rec.ID)
+		for i, isl := range rec.Islands {
+			fmt.Fprintf(bw, "%s\t%d\t%d\tCpG_island_%d\n", name, isl.Start, isl.End, i+1)
+		}
+	}
+	return bw.Flush()
+}
+
+// WriteGFF3 writes the islands as GFF3 CpG_island features (1-based, closed).
+// GC% and CpG o/e are written as the response holds them, rounded to the
+// precision of the request.
+func (r CpGIslandResponse) WriteGFF3(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "##gff-version 3")
+	for _, rec := range r.Records {
+		name := recordName(rec.ID)
+		for i, isl := range rec.Islands {
+			fmt.Fprintf(bw, "%s\tdna-analyzer\tCpG_island\t%d\t%d\t.\t.\t.\tID=%s.cpg%d;gc=%s;obs_exp=%s\n",
+				name, isl.Start+1, isl.End, name, i+1,
+				strconv.FormatFloat(isl.GCPercent, 'f', -1, 64), strconv.FormatFloat(isl.ObsExp, 'f', -1, 64))
+		}
+	}
+	return bw.Flush()
+}
+
+func recordName(id string) string {
+	if id == "" {
+		return unnamedRecord
+	}
+	return id
+}
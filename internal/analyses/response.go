@@ -0,0 +1,157 @@
+package analyses
+
+type NucleotideCountDTO struct {
+	A int `json:"A"`
+	C int `json:"C"`
+	G int `json:"G"`
+	T int `json:"T"`
+	U int `json:"U"`
+}
+
+type AmbiguityCountDTO struct {
+	N int `json:"N"`
+	R int `json:"R"`
+	Y int `json:"Y"`
+	S int `json:"S"`
+	W int `json:"W"`
+	K int `json:"K"`
+	M int `json:"M"`
+	B int `json:"B"`
+	D int `json:"D"`
+	H int `json:"H"`
+	V int `json:"V"`
+}
+
+type MetricsDTO struct {
+	GCPercent             float64 `json:"gc_percent"`
+	ATPercent             float64 `json:"at_percent"`
+	GCSkew                float64 `json:"gc_skew"`
+	ATSkew                float64 `json:"at_skew"`
+	PurinePyrimidineRatio float64 `json:"purine_pyrimidine_ratio"`
+	NFraction             float64 `json:"n_fraction"`
+}
+
+// CompositionDTO is the symbol breakdown shared by file totals and records.
+type CompositionDTO struct {
+	Bases     NucleotideCountDTO `json:"bases"`
+	Ambiguous AmbiguityCountDTO  `json:"ambiguous"`
+	Gaps      int                `json:"gaps"`
+	Invalid   int                `json:"invalid"`
+	Total     int                `json:"total"`
+	Length    int                `json:"length"`
+	Metrics   MetricsDTO         `json:"metrics"`
+}
+
+type NucleotideRecordDTO struct {
+	ID          string `json:"id"`
+	Description string `json:"description,omitempty"`
+	CompositionDTO
+	Masked *int `json:"masked,omitempty"`
+}
+
+type LengthBinDTO struct {
+	Length int `json:"length"`
+	Reads  int `json:"reads"`
+}
+
+type GCBinDTO struct {
+	GCPercent int `json:"gc_percent"`
+	Reads     int `json:"reads"`
+}
+
+type FASTQStatsDTO struct {
+	Reads              int            `json:"reads"`
+	QualityEncoding    string         `json:"quality_encoding"`
+	MinLength          int            `json:"min_length"`
+	MaxLength          int            `json:"max_length"`
+	MeanLength         float64        `json:"mean_length"`
+	LengthDistribution []LengthBinDTO `json:"length_distribution"`
+	MeanQuality        []float64      `json:"mean_quality_per_position"`
+	Q20                float64        `json:"q20_fraction"`
+	Q30                float64        `json:"q30_fraction"`
+	GCDistribution     []GCBinDTO     `json:"gc_distribution"`
+}
+
+type NucleotideCountResponse struct {
+	Format string `json:"format"`
+	CompositionDTO
+	Masked  *int                  `json:"masked,omitempty"` // soft-masked bases, 2bit only
+	Records []NucleotideRecordDTO `json:"records,omitempty"`
+	FASTQ   *FASTQStatsDTO        `json:"fastq,omitempty"`
+}
+
+// RecordProfileDTO lists the windows of one record as parallel arrays.
+type RecordProfileDTO struct {
+	ID             string    `json:"id"`
+	Description    string    `json:"description,omitempty"`
+	Length         int       `json:"length"`
+	Start          []int     `json:"start"`
+	End            []int     `json:"end"`
+	GC             []float64 `json:"gc"`
+	Skew           []float64 `json:"skew"`
+	CumulativeSkew []float64 `json:"cumulative_skew"`
+}
+
+type GCProfileResponse struct {
+	Window  int                `json:"window"`
+	Step    int                `json:"step"`
+	Records []RecordProfileDTO `json:"records"`
+}
+
+type KmerCountDTO struct {
+	Kmer  string `json:"kmer"`
+	Count int    `json:"count"`
+}
+
+type SpectrumBinDTO struct {
+	Occurrences int `json:"occurrences"`
+	Kmers       int `json:"kmers"`
+}
+
+type KmerResponse struct {
+	K         int              `json:"k"`
+	Canonical bool             `json:"canonical"`
+	Total     int              `json:"total"`
+	Distinct  int              `json:"distinct"`
+	Top       []KmerCountDTO   `json:"top"`
+	Spectrum  []SpectrumBinDTO `json:"spectrum"`
+}
+
+// DinucleotideDTO holds the pair counts of a record keyed by dinucleotide,
+// e.g. "CG".
+type DinucleotideDTO struct {
+	ID                  string             `json:"id,omitempty"`
+	Description         string             `json:"description,omitempty"`
+	Length              int                `json:"length"`
+	Pairs               int                `json:"pairs"`
+	Counts              map[string]int     `json:"counts"`
+	Frequencies         map[string]float64 `json:"frequencies"`
+	CpGObservedExpected float64            `json:"cpg_observed_expected"`
+}
+
+type DinucleotideResponse struct {
+	Total   DinucleotideDTO   `json:"total"`
+	Records []DinucleotideDTO `json:"records"`
+}
+
+type IslandDTO struct {
+	Start     int     `json:"start"`
+	End       int     `json:"end"`
+	Length    int     `json:"length"`
+	GCPercent float64 `json:"gc_percent"`
+	ObsExp    float64 `json:"cpg_observed_expected"`
+}
+
+type RecordIslandsDTO struct {
+	ID          string      `json:"id"`
+	Description string      `json:"description,omitempty"`
+	Length      int         `json:"length"`
+	Islands     []IslandDTO `json:"islands"`
+}
+
+type CpGIslandResponse struct {
+	MinLength int                `json:"min_length"`
+	MinGC     float64            `json:"min_gc"`
+	MinObsExp float64            `json:"min_obs_exp"`
+	Records   []RecordIslandsDTO `json:"records"`
+}
@@ -0,0 +1,368 @@
+package analyses
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+
+	"github.com/Viktor2805/nucount/internal/helpers"
+	nucleotide "github.com/Viktor2805/nucount/internal/services/nucleotide"
+	"github.com/Viktor2805/nucount/internal/utils"
+)
+
+func (a *Analyzer) prepareCount(q url.Values) (RunFunc, url.Values, error) {
+	withRecords, err := strconv.ParseBool(helpers.QueryDefault(q, "records", "false"))
+	if err != nil {
+		return nil, nil, ParamError("Invalid records parameter. Expected true or false.")
+	}
+
+	precision, err := queryPrecision(q)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return func(ctx context.Context, file io.Reader, format helpers.Format, opts ...nucleotide.Option) (any, error) {
+		switch {
+		case format == helpers.FormatFASTQ:
+			st, err := a.service.CountFASTQ(ctx, file, opts...)
+			if err != nil {
+				return nil, err
+			}
+
+			return NucleotideCountResponse{
+				Format:         string(format),
+				CompositionDTO: newCompositionDTO(st.Bases, precision),
+				FASTQ:          newFASTQStatsDTO(st, precision),
+			}, nil
+
+		case format == helpers.Format2bit:
+			res, err := a.service.Count2bit(ctx, file, opts...)
+			if err != nil {
+				return nil, err
+			}
+
+			resp := NucleotideCountResponse{
+				Format:         string(format),
+				CompositionDTO: newCompositionDTO(res.Total, precision),
+				Masked:         &res.Masked,
+				Records:        make([]NucleotideRecordDTO, 0, len(res.Records)),
+			}
+			for _, r := range res.Records {
+				resp.Records = append(resp.Records, NucleotideRecordDTO{
+					ID:             r.ID,
+					CompositionDTO: newCompositionDTO(r.Count, precision),
+					Masked:         &r.Masked,
+				})
+			}
+			return resp, nil
+
+		case !withRecords:
+			bc, err := a.service.Count(ctx, file, opts...)
+			if err != nil {
+				return nil, err
+			}
+
+			return NucleotideCountResponse{
+				Format:         string(format),
+				CompositionDTO: newCompositionDTO(bc, precision),
+			}, nil
+		}
+
+		res, err := a.service.CountRecords(ctx, file, opts...)
+		if err != nil {
+			return nil, err
+		}
+
+		resp := NucleotideCountResponse{
+			Format:         string(format),
+			CompositionDTO: newCompositionDTO(res.Total, precision),
+			Records:        make([]NucleotideRecordDTO, 0, len(res.Records)),
+		}
+		for _, r := range res.Records {
+			resp.Records = append(resp.Records, NucleotideRecordDTO{
+				ID:             r.ID,
+				Description:    r.Description,
+				CompositionDTO: newCompositionDTO(r.Count, precision),
+			})
+		}
+		return resp, nil
+	}, url.Values{
+		"records":   {strconv.FormatBool(withRecords)},
+		"precision": {strconv.Itoa(precision)},
+	}, nil
+}
+
+func newCompositionDTO(n nucleotide.NucleotideCount, precision int) CompositionDTO {
+	m := n.Composition()
+
+	return CompositionDTO{
+		Bases: NucleotideCountDTO{A: n.A, C: n.C, G: n.G, T: n.T, U: n.U},
+		Ambiguous: AmbiguityCountDTO{
+			N: n.N, R: n.R, Y: n.Y, S: n.S, W: n.W, K: n.K,
+			M: n.M, B: n.B, D: n.D, H: n.H, V: n.V,
+		},
+		Gaps:    n.Gap,
+		Invalid: n.Invalid,
+		Total:   n.Total(),
+		Length:  n.Length(),
+		Metrics: MetricsDTO{
+			GCPercent:             utils.Round(m.GCContent, precision),
+			ATPercent:             utils.Round(m.ATContent, precision),
+			GCSkew:                utils.Round(m.GCSkew, precision),
+			ATSkew:                utils.Round(m.ATSkew, precision),
+			PurinePyrimidineRatio: utils.Round(m.PurinePyrimidineRatio, precision),
+			NFraction:             utils.Round(m.NFraction, precision),
+		},
+	}
+}
+
+func newFASTQStatsDTO(st nucleotide.FASTQStats, precision int) *FASTQStatsDTO {
+	dto := &FASTQStatsDTO{
+		Reads:              st.Reads,
+		QualityEncoding:    fmt.Sprintf("phred+%d", st.PhredOffset),
+		MinLength:          st.MinLength,
+		MaxLength:          st.MaxLength,
+		MeanLength:         utils.Round(st.MeanLength(), precision),
+		LengthDistribution: make([]LengthBinDTO, 0, len(st.LengthDistribution)),
+		MeanQuality:        make([]float64, len(st.MeanQuality)),
+		Q20:                utils.Round(st.Q20, precision),
+		Q30:                utils.Round(st.Q30, precision),
+		GCDistribution:     []GCBinDTO{},
+	}
+	for _, bin := range st.LengthDistribution {
+		dto.LengthDistribution = append(dto.LengthDistribution, LengthBinDTO{Length: bin.Length, Reads: bin.Reads})
+	}
+	for i, q := range st.MeanQuality {
+		dto.MeanQuality[i] = utils.Round(q, precision)
+	}
+	for gc, reads := range st.GCDistribution {
+		if reads > 0 {
+			dto.GCDistribution = append(dto.GCDistribution, GCBinDTO{GCPercent: gc, Reads: reads})
+		}
+	}
+	return dto
+}
+
+func (a *Analyzer) prepareGCProfile(q url.Values) (RunFunc, url.Values, error) {
+	window, err := strconv.Atoi(helpers.QueryDefault(q, "window", strconv.Itoa(defaultWindowSize)))
+	if err != nil {
+		return nil, nil, ParamError("Invalid window parameter. Expected an integer.")
+	}
+
+	step, err := strconv.Atoi(helpers.QueryDefault(q, "step", "0"))
+	if err != nil {
+		return nil, nil, ParamError("Invalid step parameter. Expected an integer.")
+	}
+	if step == 0 {
+		step = window
+	}
+
+	precision, err := queryPrecision(q)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return func(ctx context.Context, file io.Reader, _ helpers.Format, opts ...nucleotide.Option) (any, error) {
+		profile, err := a.service.GCProfile(ctx, file, nucleotide.WindowOptions{Size: window, Step: step}, opts...)
+		if err != nil {
+			return nil, err
+		}
+
+		resp := GCProfileResponse{
+			Window:  profile.Options.Size,
+			Step:    profile.Options.Step,
+			Records: make([]RecordProfileDTO, 0, len(profile.Records)),
+		}
+		for _, r := range profile.Records {
+			dto := RecordProfileDTO{
+				ID:             r.ID,
+				Description:    r.Description,
+				Length:         r.Length,
+				Start:          make([]int, len(r.Windows)),
+				End:            make([]int, len(r.Windows)),
+				GC:             make([]float64, len(r.Windows)),
+				Skew:           make([]float64, len(r.Windows)),
+				CumulativeSkew: make([]float64, len(r.Windows)),
+			}
+			for i, w := range r.Windows {
+				dto.Start[i] = w.Start
+				dto.End[i] = w.End
+				dto.GC[i] = utils.Round(w.GCContent, precision)
+				dto.Skew[i] = utils.Round(w.GCSkew, precision)
+				dto.CumulativeSkew[i] = utils.Round(w.CumulativeSkew, precision)
+			}
+			resp.Records = append(resp.Records, dto)
+		}
+		return resp, nil
+	}, url.Values{
+		"window":    {strconv.Itoa(window)},
+		"step":      {strconv.Itoa(step)},
+		"precision": {strconv.Itoa(precision)},
+	}, nil
+}
+
+func (a *Analyzer) prepareKmers(q url.Values) (RunFunc, url.Values, error) {
+	k, err := strconv.Atoi(helpers.QueryDefault(q, "k", strconv.Itoa(defaultK)))
+	if err != nil {
+		return nil, nil, ParamError("Invalid k parameter. Expected an integer.")
+	}
+
+	canonical, err := strconv.ParseBool(helpers.QueryDefault(q, "canonical", "false"))
+	if err != nil {
+		return nil, nil, ParamError("Invalid canonical parameter. Expected true or false.")
+	}
+
+	top, err := strconv.Atoi(helpers.QueryDefault(q, "top", strconv.Itoa(defaultKmerTop)))
+	if err != nil {
+		return nil, nil, ParamError("Invalid top parameter. Expected an integer.")
+	}
+
+	return func(ctx context.Context, file io.Reader, _ helpers.Format, opts ...nucleotide.Option) (any, error) {
+		res, err := a.kmers.CountKmers(ctx, file, nucleotide.KmerOptions{K: k, Canonical: canonical, Top: top}, opts...)
+		if err != nil {
+			return nil, err
+		}
+
+		resp := KmerResponse{
+			K:         res.Options.K,
+			Canonical: res.Options.Canonical,
+			Total:     res.Total,
+			Distinct:  res.Distinct,
+			Top:       make([]KmerCountDTO, 0, len(res.Top)),
+			Spectrum:  make([]SpectrumBinDTO, 0, len(res.Spectrum)),
+		}
+		for _, kc := range res.Top {
+			resp.Top = append(resp.Top, KmerCountDTO{Kmer: kc.Kmer, Count: kc.Count})
+		}
+		for _, bin := range res.Spectrum {
+			resp.Spectrum = append(resp.Spectrum, SpectrumBinDTO{Occurrences: bin.Occurrences, Kmers: bin.Kmers})
+		}
+		return resp, nil
+	}, url.Values{
+		"k":         {strconv.Itoa(k)},
+		"canonical": {strconv.FormatBool(canonical)},
+		"top":       {strconv.Itoa(top)},
+	}, nil
+}
+
+func (a *Analyzer) prepareDinucleotides(q url.Values) (RunFunc, url.Values, error) {
+	precision, err := queryPrecision(q)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return func(ctx context.Context, file io.Reader, _ helpers.Format, opts ...nucleotide.Option) (any, error) {
+		res, err := a.dinucleotides.CountDinucleotides(ctx, file, opts...)
+		if err != nil {
+			return nil, err
+		}
+
+		resp := DinucleotideResponse{
+			Total:   newDinucleotideDTO(res.Total, precision),
+			Records: make([]DinucleotideDTO, 0, len(res.Records)),
+		}
+		for _, r := range res.Records {
+			resp.Records = append(resp.Records, newDinucleotideDTO(r, precision))
+		}
+		return resp, nil
+	}, url.Values{"precision": {strconv.Itoa(precision)}}, nil
+}
+
+func newDinucleotideDTO(r nucleotide.RecordDinucleotides, precision int) DinucleotideDTO {
+	dto := DinucleotideDTO{
+		ID:                  r.ID,
+		Description:         r.Description,
+		Length:              r.Bases.Length(),
+		Pairs:               r.Pairs.Total(),
+		Counts:              make(map[string]int, len(r.Pairs)),
+		Frequencies:         make(map[string]float64, len(r.Pairs)),
+		CpGObservedExpected: utils.Round(r.CpGObservedExpected(), precision),
+	}
+
+	total := r.Pairs.Total()
+	for i, name := range nucleotide.DinucleotideNames {
+		dto.Counts[name] = r.Pairs[i]
+		if total > 0 {
+			dto.Frequencies[name] = utils.Round(float64(r.Pairs[i])/float64(total), precision)
+		} else {
+			dto.Frequencies[name] = 0
+		}
+	}
+	return dto
+}
+
+// IslandParams returns the island thresholds and JSON precision of a CpG
+// island request. Missing thresholds take their default; given ones, zero
+// included, are used as is, and ones the island search refuses are rejected.
+func IslandParams(q url.Values) (nucleotide.IslandOptions, int, error) {
+	opts := nucleotide.DefaultIslandOptions()
+	var err error
+
+	if v, ok := q["min_length"]; ok && len(v) > 0 {
+		opts.MinLength, err = strconv.Atoi(v[0])
+		if err != nil || opts.MinLength < nucleotide.MinIslandLength || opts.MinLength > nucleotide.MaxWindowSize {
+			return opts, 0, ParamError(fmt.Sprintf("Invalid min_length parameter. Expected an integer between %d and %d.",
+				nucleotide.MinIslandLength, nucleotide.MaxWindowSize))
+		}
+	}
+	if v, ok := q["min_gc"]; ok && len(v) > 0 {
+		if opts.MinGC, err = strconv.ParseFloat(v[0], 64); err != nil || opts.MinGC < 0 || opts.MinGC > 100 {
+			return opts, 0, ParamError("Invalid min_gc parameter. Expected a number between 0 and 100.")
+		}
+	}
+	if v, ok := q["min_oe"]; ok && len(v) > 0 {
+		if opts.MinObsExp, err = strconv.ParseFloat(v[0], 64); err != nil || opts.MinObsExp < 0 {
+			return opts, 0, ParamError("Invalid min_oe parameter. Expected a non-negative number.")
+		}
+	}
+
+	precision, err := queryPrecision(q)
+	return opts, precision, err
+}
+
+func (a *Analyzer) prepareCpGIslands(q url.Values) (RunFunc, url.Values, error) {
+	island, precision, err := IslandParams(q)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return func(ctx context.Context, file io.Reader, _ helpers.Format, opts ...nucleotide.Option) (any, error) {
+		res, err := a.service.CpGIslands(ctx, file, island, opts...)
+		if err != nil {
+			return nil, err
+		}
+
+		resp := CpGIslandResponse{
+			MinLength: res.Options.MinLength,
+			MinGC:     res.Options.MinGC,
+			MinObsExp: res.Options.MinObsExp,
+			Records:   make([]RecordIslandsDTO, 0, len(res.Records)),
+		}
+		for _, r := range res.Records {
+			dto := RecordIslandsDTO{
+				ID:          r.ID,
+				Description: r.Description,
+				Length:      r.Length,
+				Islands:     make([]IslandDTO, 0, len(r.Islands)),
+			}
+			for _, isl := range r.Islands {
+				dto.Islands = append(dto.Islands, IslandDTO{
+					Start:     isl.Start,
+					End:       isl.End,
+					Length:    isl.End - isl.Start,
+					GCPercent: utils.Round(isl.GCContent, precision),
+					ObsExp:    utils.Round(isl.ObsExp, precision),
+				})
+			}
+			resp.Records = append(resp.Records, dto)
+		}
+		return resp, nil
+	}, url.Values{
+		"min_length": {strconv.Itoa(island.MinLength)},
+		"min_gc":     {strconv.FormatFloat(island.MinGC, 'g', -1, 64)},
+		"min_oe":     {strconv.FormatFloat(island.MinObsExp, 'g', -1, 64)},
+		"precision":  {strconv.Itoa(precision)},
+	}, nil
+}
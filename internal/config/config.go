@@ -3,6 +3,8 @@ package config
 
 import (
 	"fmt"
+	"net/netip"
+	"time"
 
 	"github.com/kelseyhightower/envconfig"
 )
@@ -11,6 +13,9 @@ type Config struct {
 	Server   ServerConfig
 	Sentry   SentryConfig
 	Database DatabaseConfig
+	Upload   UploadConfig
+	Jobs     JobConfig
+	Webhooks WebhookConfig
 }
 
 type ServerConfig struct {
@@ -21,12 +26,39 @@ type SentryConfig struct {
 	DSN string `envconfig:"SENTRY_DSN"`
 }
 
+type UploadConfig struct {
+	MaxDecompressedSize int64 `envconfig:"MAX_DECOMPRESSED_SIZE" default:"8589934592"` // 8 GB
+	CountWorkers        int   `envconfig:"COUNT_WORKERS" default:"1"`                  // 0 means one per CPU
+}
+
+type JobConfig struct {
+	Workers      int           `envconfig:"JOB_WORKERS" default:"1"`        // jobs run at a time per replica, 0 to only accept jobs
+	PollInterval time.Duration `envconfig:"JOB_POLL_INTERVAL" default:"1s"` // wait of an idle worker, period of progress events
+	Heartbeat    time.Duration `envconfig:"JOB_HEARTBEAT" default:"1s"`     // how often a running job stores its progress
+	Lease        time.Duration `envconfig:"JOB_LEASE" default:"1m"`         // a running job without heartbeat for this long is run again
+	MaxAttempts  int           `envconfig:"JOB_MAX_ATTEMPTS" default:"3"`
+}
+
+type WebhookConfig struct {
+	Secrets     map[string]string `envconfig:"WEBHOOK_SECRETS"`               // client:secret,... - clients that may register callbacks
+	Timeout     time.Duration     `envconfig:"WEBHOOK_TIMEOUT" default:"10s"` // how long a callback may take to respond
+	Backoff     time.Duration     `envconfig:"WEBHOOK_BACKOFF" default:"30s"` // wait before the first retry, doubled for every further one
+	MaxBackoff  time.Duration     `envconfig:"WEBHOOK_MAX_BACKOFF" default:"1h"`
+	MaxAttempts int               `envconfig:"WEBHOOK_MAX_ATTEMPTS" default:"10"` // attempts before a delivery is given up on
+	// AllowedNetworks are CIDRs callbacks may be sent to although they are
+	// not public, e.g. 10.0.0.0/8 for receivers in the same private network.
+	AllowedNetworks []netip.Prefix `envconfig:"WEBHOOK_ALLOWED_NETWORKS"`
+}
+
 type DatabaseConfig struct {
 	Host     string `envconfig:"DB_HOST" default:"localhost"`
 	Port     int    `envconfig:"DB_PORT" default:"5432"`
 	User     string `envconfig:"DB_USER" default:"postgres"`
 	Password string `envconfig:"DB_PASSWORD" required:"true"`
 	Name     string `envconfig:"DB_NAME" default:"app"`
+	// AutoMigrate applies pending migrations at startup, instead of leaving
+	// them to "app migrate up".
+	AutoMigrate bool `envconfig:"DB_AUTO_MIGRATE" default:"false"`
 }
 
 func Load() (*Config, error) {
@@ -1,7 +1,8 @@
 package config_test
 
 import (
-	"golang/internal/config"
+	"github.com/Viktor2805/nucount/internal/config"
+	"net/netip"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -21,4 +22,17 @@ func TestLoad_Defaults(t *testing.T) {
 func TestLoad_MissingRequired(t *testing.T) {
 	_, err := config.Load()
 	require.Error(t, err, "missing DB_PASSWORD")
-}
\ No newline at end of file
+}
+
+func TestLoad_WebhookAllowedNetworks(t *testing.T) {
+	t.Setenv("DB_PASSWORD", "secret")
+	t.Setenv("WEBHOOK_ALLOWED_NETWORKS", "10.0.0.0/8,fd00::/8")
+
+	cfg, err := config.Load()
+	require.NoError(t, err)
+	require.Equal(t, []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8"), netip.MustParsePrefix("fd00::/8")}, cfg.Webhooks.AllowedNetworks)
+
+	t.Setenv("WEBHOOK_ALLOWED_NETWORKS", "10.0.0.1")
+	_, err = config.Load()
+	require.Error(t, err)
+}
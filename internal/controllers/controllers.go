@@ -1,15 +1,30 @@
 package controllers
 
 import (
-	"golang/internal/services"
+	"github.com/Viktor2805/nucount/internal/services"
 )
 
 type Controllers struct {
 	NucleotideController *NucleotideController
+	SequenceController   *SequenceController
+	JobController        *JobController
+	WebhookController    *WebhookController
+	HistoryController    *HistoryController
 }
 
 func NewControllers(services *services.Services) *Controllers {
+	nucleotides := NewNucleotideController(
+		services.NucleotideService,
+		services.KmerService,
+		services.DinucleotideService,
+		services.AnalysisService,
+	)
+
 	return &Controllers{
-		NucleotideController: NewNucleotideController(services.NucleotideService),
+		NucleotideController: nucleotides,
+		SequenceController:   NewSequenceController(services.SequenceService),
+		JobController:        NewJobController(services.JobService, services.WebhookService, nucleotides),
+		WebhookController:    NewWebhookController(services.WebhookService),
+		HistoryController:    NewHistoryController(services.AnalysisService),
 	}
 }
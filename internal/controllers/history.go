@@ -0,0 +1,201 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/Viktor2805/nucount/internal/analyses"
+	"github.com/Viktor2805/nucount/internal/helpers"
+	"github.com/Viktor2805/nucount/internal/models"
+	repository "github.com/Viktor2805/nucount/internal/repository/analysis"
+	history "github.com/Viktor2805/nucount/internal/services/analysis"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// Bounds of the history page size.
+const (
+	defaultHistoryLimit = 50
+	maxHistoryLimit     = 500
+)
+
+type AnalysisSummaryDTO struct {
+	ID         uuid.UUID           `json:"id"`
+	Analysis   string              `json:"analysis"`
+	Params     map[string][]string `json:"params"`
+	Filename   string              `json:"filename,omitempty"`
+	Size       int64               `json:"size"`   // upload bytes, as sent
+	SHA256     string              `json:"sha256"` // of the upload, as sent
+	Format     string              `json:"format"`
+	StartedAt  time.Time           `json:"started_at"`
+	FinishedAt time.Time           `json:"finished_at"`
+	DurationMs int64               `json:"duration_ms"`
+	CreatedAt  time.Time           `json:"created_at"`
+}
+
+type AnalysisResponse struct {
+	AnalysisSummaryDTO
+	Result models.JSON `json:"result" swaggertype:"object"` // response of the analysis endpoint
+}
+
+type AnalysisListResponse struct {
+	Items  []AnalysisSummaryDTO `json:"items"`
+	Total  int64                `json:"total"` // analyses matching the filters
+	Limit  int                  `json:"limit"`
+	Offset int                  `json:"offset"`
+}
+
+type HistoryController struct {
+	service history.Service
+}
+
+func NewHistoryController(service history.Service) *HistoryController {
+	return &HistoryController{service: service}
+}
+
+// List lists past analyses.
+// @Summary List past analyses
+// @Description Every successful response of the nucleotide endpoints, CpG islands in BED or GFF3 included, and every
+// @Description succeeded job is recorded with the uploaded file's name, size and SHA-256, the detected format, the timings
+// @Description and the result. An upload analysed again with the same parameters and result is recorded once. Analyses
+// @Description are listed newest first, without their results. from and to take an RFC 3339 time or a date; a date as to
+// @Description includes that whole day (UTC).
+// @Tags analyses
+// @Produce application/json
+// @Param analysis query string false "Analysis name" Enums(count, gc-profile, kmers, dinucleotides, cpg-islands)
+// @Param filename query string false "Part of the file name, case-insensitive"
+// @Param sha256 query string false "SHA-256 of the upload, hex"
+// @Param from query string false "Recorded at or after, e.g. 2026-01-31 or 2026-01-31T12:00:00Z"
+// @Param to query string false "Recorded before, or on a date"
+// @Param limit query int false "Page size (1-500, default 50)"
+// @Param offset query int false "Analyses to skip (default 0)"
+// @Success 200 {object} controllers.AnalysisListResponse
+// @Failure 400 {object} apierror.ErrorResponse "Invalid parameters"
+// @Failure 500 {object} apierror.ErrorResponse "Internal error"
+// @Router /analyses [get]
+func (c *HistoryController) List(ctx *gin.Context) {
+	q := ctx.Request.URL.Query()
+	filter, err := historyFilter(q)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	limit, err := strconv.Atoi(helpers.QueryDefault(q, "limit", strconv.Itoa(defaultHistoryLimit)))
+	if err != nil || limit < 1 || limit > maxHistoryLimit {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit parameter. Expected an integer between 1 and 500."})
+		return
+	}
+	offset, err := strconv.Atoi(helpers.QueryDefault(q, "offset", "0"))
+	if err != nil || offset < 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid offset parameter. Expected a non-negative integer."})
+		return
+	}
+
+	analyses, total, err := c.service.List(filter, limit, offset)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	resp := AnalysisListResponse{
+		Items:  make([]AnalysisSummaryDTO, len(analyses)),
+		Total:  total,
+		Limit:  limit,
+		Offset: offset,
+	}
+	for i := range analyses {
+		resp.Items[i] = newAnalysisSummaryDTO(&analyses[i])
+	}
+	ctx.JSON(http.StatusOK, resp)
+}
+
+// Get returns a past analysis.
+// @Summary Get a past analysis
+// @Description Returns a recorded analysis with its result, the JSON response of the analysis endpoint. BED and GFF3
+// @Description responses of the CpG island endpoint are rendered from it.
+// @Tags analyses
+// @Produce application/json
+// @Param id path string true "Analysis ID"
+// @Success 200 {object} controllers.AnalysisResponse
+// @Failure 404 {object} apierror.ErrorResponse "Analysis not found"
+// @Failure 500 {object} apierror.ErrorResponse "Internal error"
+// @Router /analyses/{id} [get]
+func (c *HistoryController) Get(ctx *gin.Context) {
+	id, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": history.ErrNotFound.Error()})
+		return
+	}
+
+	a, err := c.service.Get(id)
+	switch {
+	case errors.Is(err, history.ErrNotFound):
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, AnalysisResponse{AnalysisSummaryDTO: newAnalysisSummaryDTO(a), Result: a.Result})
+}
+
+// historyFilter reads the filters of the history list.
+func historyFilter(q url.Values) (repository.Filter, error) {
+	filter := repository.Filter{
+		Analysis: q.Get("analysis"),
+		Filename: q.Get("filename"),
+		SHA256:   q.Get("sha256"),
+	}
+
+	var err error
+	if v := q.Get("from"); v != "" {
+		if filter.From, _, err = parseTimeOrDate(v); err != nil {
+			return filter, analyses.ParamError("Invalid from parameter. Expected an RFC 3339 time or a date (YYYY-MM-DD).")
+		}
+	}
+	if v := q.Get("to"); v != "" {
+		var date bool
+		if filter.To, date, err = parseTimeOrDate(v); err != nil {
+			return filter, analyses.ParamError("Invalid to parameter. Expected an RFC 3339 time or a date (YYYY-MM-DD).")
+		}
+		if date {
+			filter.To = filter.To.AddDate(0, 0, 1)
+		}
+	}
+	return filter, nil
+}
+
+// parseTimeOrDate parses an RFC 3339 time or a date, which is midnight UTC,
+// and reports whether it was a date.
+func parseTimeOrDate(v string) (time.Time, bool, error) {
+	if t, err := time.Parse(time.DateOnly, v); err == nil {
+		return t, true, nil
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	return t, false, err
+}
+
+func newAnalysisSummaryDTO(a *models.Analysis) AnalysisSummaryDTO {
+	dto := AnalysisSummaryDTO{
+		ID:         a.ID,
+		Analysis:   a.Analysis,
+		Params:     a.Params,
+		Filename:   a.Filename,
+		Size:       a.Size,
+		SHA256:     a.SHA256,
+		Format:     a.Format,
+		StartedAt:  a.StartedAt,
+		FinishedAt: a.FinishedAt,
+		DurationMs: a.DurationMs,
+		CreatedAt:  a.CreatedAt,
+	}
+	if dto.Params == nil {
+		dto.Params = map[string][]string{}
+	}
+	return dto
+}
@@ -0,0 +1,387 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Viktor2805/nucount/internal/analyses"
+	"github.com/Viktor2805/nucount/internal/models"
+	"github.com/Viktor2805/nucount/internal/services/job"
+	"github.com/Viktor2805/nucount/internal/services/webhook"
+	"github.com/Viktor2805/nucount/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type JobProgressDTO struct {
+	Bytes    int64   `json:"bytes"`    // upload bytes analysed so far
+	Records  int64   `json:"records"`  // records or reads started, FASTQ and 2bit and per-record analyses only
+	Fraction float64 `json:"fraction"` // bytes / size
+}
+
+type JobResponse struct {
+	ID         uuid.UUID           `json:"id"`
+	Analysis   string              `json:"analysis"`
+	Params     map[string][]string `json:"params"`
+	Status     string              `json:"status" enums:"queued,running,succeeded,failed,cancelled"`
+	Filename   string              `json:"filename,omitempty"`
+	Format     string              `json:"format"`
+	Size       int64               `json:"size"`
+	SHA256     string              `json:"sha256"` // of the upload
+	Attempts   int                 `json:"attempts"`
+	Progress   JobProgressDTO      `json:"progress"`
+	Result     models.JSON         `json:"result,omitempty" swaggertype:"object"` // response of the analysis endpoint
+	Error      string              `json:"error,omitempty"`
+	CreatedAt  time.Time           `json:"created_at"`
+	StartedAt  *time.Time          `json:"started_at,omitempty"`
+	FinishedAt *time.Time          `json:"finished_at,omitempty"`
+
+	CallbackURL    string `json:"callback_url,omitempty"`
+	CallbackClient string `json:"callback_client,omitempty"`
+}
+
+// JobProgressEvent is the data of a progress event of a job's event stream.
+type JobProgressEvent struct {
+	Status   string `json:"status" enums:"queued,running"`
+	Attempts int    `json:"attempts"`
+	Size     int64  `json:"size"`
+	JobProgressDTO
+	Throughput float64 `json:"throughput"` // upload bytes per second over the last 10 seconds
+}
+
+// Names of the events of a job's event stream.
+const (
+	eventProgress  = "progress"
+	eventResult    = "result"
+	eventFailed    = "failed"
+	eventCancelled = "cancelled"
+)
+
+type JobController struct {
+	service  job.Service
+	webhooks webhook.Service
+	analyzer *analyses.Analyzer
+
+	// done ends the event streams, which last as long as their jobs, when the
+	// server shuts down; see Shutdown.
+	done     context.Context
+	shutdown context.CancelFunc
+}
+
+func NewJobController(service job.Service, webhooks webhook.Service, nucleotides *NucleotideController) *JobController {
+	done, shutdown := context.WithCancel(context.Background())
+	return &JobController{
+		service:  service,
+		webhooks: webhooks,
+		analyzer: nucleotides.analyzer,
+		done:     done,
+		shutdown: shutdown,
+	}
+}
+
+// Shutdown ends the open event streams, so that they do not hold up a
+// graceful shutdown; their clients reconnect, to another replica. It is meant
+// for http.Server.RegisterOnShutdown.
+func (c *JobController) Shutdown() {
+	c.shutdown()
+}
+
+// Create queues an analysis of an uploaded file.
+// @Summary Submit an analysis job
+// @Description Upload a file to run one of the nucleotide analyses in the background. The analysis is named by the analysis
+// @Description parameter; all other query parameters are those of the analysis endpoint and are validated right away.
+// @Description The upload is stored, so the job survives restarts, and any replica may run it. Poll GET /jobs/{id} for the
+// @Description progress and, once the job has succeeded, the result, which is the JSON response of the analysis endpoint.
+// @Description A job on an upload that has been analysed with the same parameters before is served the stored result.
+// @Description Instead of polling, a client configured with a webhook secret may pass callback_url and callback_client:
+// @Description when the job succeeds or fails, the callback is POSTed a JSON payload with the event (job.succeeded or
+// @Description job.failed), the job and its result or error. The X-Webhook-Signature header is "sha256=" followed by the
+// @Description hex HMAC-SHA256, keyed with the client's secret, of the X-Webhook-Timestamp header, a dot and the body.
+// @Description The client signs the submission the same way, with the callback_url, a newline and the hex SHA-256 of the
+// @Description file in place of the body, and a timestamp at most 5 minutes off; the SHA-256 is declared in the
+// @Description X-Content-SHA256 header and the upload is refused unless it matches, so a captured submission cannot be
+// @Description replayed with another file. Callbacks are only sent to public addresses.
+// @Description Responses other than 2xx are retried with exponential backoff; see GET /jobs/{id}/deliveries.
+// @Tags jobs
+// @Accept multipart/form-data,application/octet-stream
+// @Produce application/json
+// @Param file formData file true "File accepted by the analysis"
+// @Param analysis query string true "Analysis to run" Enums(count, gc-profile, kmers, dinucleotides, cpg-islands)
+// @Param callback_url query string false "http or https URL to POST the outcome of the job to"
+// @Param callback_client query string false "Client whose secret signs the callback, required with callback_url"
+// @Param X-Webhook-Timestamp header string false "Unix time in seconds of the signature, required with callback_url"
+// @Param X-Webhook-Signature header string false "Signature of callback_url and the file's SHA-256 with the client's secret, required with callback_url"
+// @Param X-Content-SHA256 header string false "SHA-256 of the file as sent, hex; required with callback_url"
+// @Success 202 {object} controllers.JobResponse
+// @Header 202 {string} Location "URL of the job"
+// @Failure 400 {object} apierror.ErrorResponse "Unknown analysis, invalid parameters, invalid callback or file not matching its SHA-256"
+// @Failure 401 {object} apierror.ErrorResponse "Callback not signed with the client's secret"
+// @Failure 413 {object} apierror.ErrorResponse "Upload too large"
+// @Failure 415 {object} apierror.ErrorResponse "File content is not accepted by the analysis"
+// @Failure 500 {object} apierror.ErrorResponse "Internal error while storing the upload"
+// @Router /jobs [post]
+func (c *JobController) Create(ctx *gin.Context) {
+	name := ctx.Query("analysis")
+	a, ok := c.analyzer.Lookup(name)
+	if !ok {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": unknownAnalysisMessage(c.analyzer.Names())})
+		return
+	}
+
+	params := ctx.Request.URL.Query()
+	params.Del("analysis")
+	callbackURL, callbackClient := params.Get("callback_url"), params.Get("callback_client")
+	params.Del("callback_url")
+	params.Del("callback_client")
+	if _, _, err := a.Prepare(params); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	digest, err := declaredDigest(ctx.Request)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if callbackURL != "" || callbackClient != "" {
+		err := c.webhooks.Validate(
+			callbackClient,
+			callbackURL,
+			digest,
+			ctx.GetHeader(webhook.HeaderTimestamp),
+			ctx.GetHeader(webhook.HeaderSignature),
+		)
+		if err != nil {
+			status := http.StatusBadRequest
+			if errors.Is(err, webhook.ErrUnsigned) {
+				status = http.StatusUnauthorized
+			}
+			ctx.JSON(status, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	up, ok := formUpload(ctx, a.Accepted...)
+	if !ok {
+		return
+	}
+
+	j, err := c.service.Submit(ctx.Request.Context(), job.Submission{
+		Analysis: name,
+		Params:   params,
+		Format:   up.format,
+		Filename: up.filename,
+		SHA256:   digest,
+
+		CallbackURL:    callbackURL,
+		CallbackClient: callbackClient,
+	}, up)
+	if err != nil {
+		ctx.JSON(analysisErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.Header("Location", fmt.Sprintf("%s/%s", strings.TrimSuffix(ctx.Request.URL.Path, "/"), j.ID))
+	ctx.JSON(http.StatusAccepted, newJobResponse(j))
+}
+
+// Get reports the status of a job.
+// @Summary Get an analysis job
+// @Description Returns the status and progress of a job and, once it has succeeded, its result. Progress counts the upload
+// @Description bytes read by the current attempt; a job that was interrupted by a restart starts again from the beginning.
+// @Tags jobs
+// @Produce application/json
+// @Param id path string true "Job ID"
+// @Success 200 {object} controllers.JobResponse
+// @Failure 404 {object} apierror.ErrorResponse "Job not found"
+// @Failure 500 {object} apierror.ErrorResponse "Internal error"
+// @Router /jobs/{id} [get]
+func (c *JobController) Get(ctx *gin.Context) {
+	id, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": job.ErrNotFound.Error()})
+		return
+	}
+
+	j, err := c.service.Get(id)
+	if err != nil {
+		ctx.JSON(jobErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, newJobResponse(j))
+}
+
+// Events streams the progress of a job.
+// @Summary Stream the progress of a job
+// @Description Server-Sent Events stream of a job. A "progress" event is sent once per second while the job is queued or
+// @Description running, with the upload bytes analysed, the records seen, the completed fraction and the throughput over the
+// @Description last 10 seconds. The stream ends with one final event carrying the job: "result" when it succeeded (the job's
+// @Description result field holds the analysis result), "failed" or "cancelled"; clients should close the EventSource then,
+// @Description or it reconnects. Progress restarts from zero when an interrupted job is run again. A server shutting down ends
+// @Description the stream without a final event; the EventSource then reconnects.
+// @Tags jobs
+// @Produce text/event-stream
+// @Param id path string true "Job ID"
+// @Success 200 {object} controllers.JobProgressEvent "progress events, then a result, failed or cancelled event with a JobResponse"
+// @Failure 404 {object} apierror.ErrorResponse "Job not found"
+// @Failure 500 {object} apierror.ErrorResponse "Internal error"
+// @Router /jobs/{id}/events [get]
+func (c *JobController) Events(ctx *gin.Context) {
+	id, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": job.ErrNotFound.Error()})
+		return
+	}
+	if _, err := c.service.Get(id); err != nil {
+		ctx.JSON(jobErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.Header("Content-Type", "text/event-stream")
+	ctx.Header("Cache-Control", "no-cache")
+	ctx.Header("X-Accel-Buffering", "no") // keep proxies from holding events back
+	// The stream lasts as long as the job, which may be longer than the
+	// server's WriteTimeout.
+	_ = http.NewResponseController(ctx.Writer).SetWriteDeadline(time.Time{})
+	ctx.Status(http.StatusOK)
+
+	// The stream simply ends if the client goes away, the job can no longer
+	// be read or the server shuts down; an EventSource then reconnects.
+	watch, cancel := context.WithCancel(ctx.Request.Context())
+	defer cancel()
+	defer context.AfterFunc(c.done, cancel)()
+	_ = c.service.Watch(watch, id, func(u job.Update) error {
+		switch u.Status {
+		case models.JobSucceeded:
+			ctx.SSEvent(eventResult, newJobResponse(u.Job))
+		case models.JobFailed:
+			ctx.SSEvent(eventFailed, newJobResponse(u.Job))
+		case models.JobCancelled:
+			ctx.SSEvent(eventCancelled, newJobResponse(u.Job))
+		default:
+			resp := newJobResponse(u.Job)
+			ctx.SSEvent(eventProgress, JobProgressEvent{
+				Status:         resp.Status,
+				Attempts:       resp.Attempts,
+				Size:           resp.Size,
+				JobProgressDTO: resp.Progress,
+				Throughput:     utils.Round(u.Throughput, 0),
+			})
+		}
+		ctx.Writer.Flush()
+		return nil
+	})
+}
+
+// Cancel cancels a job.
+// @Summary Cancel a job
+// @Description Cancels a queued or running job and deletes its upload. A running analysis stops within about a second.
+// @Tags jobs
+// @Produce application/json
+// @Param id path string true "Job ID"
+// @Success 200 {object} controllers.JobResponse
+// @Failure 404 {object} apierror.ErrorResponse "Job not found"
+// @Failure 409 {object} apierror.ErrorResponse "Job has already finished"
+// @Failure 500 {object} apierror.ErrorResponse "Internal error"
+// @Router /jobs/{id} [delete]
+func (c *JobController) Cancel(ctx *gin.Context) {
+	id, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": job.ErrNotFound.Error()})
+		return
+	}
+
+	j, err := c.service.Cancel(id)
+	if err != nil {
+		ctx.JSON(jobErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, newJobResponse(j))
+}
+
+// Deliveries lists the callbacks of a job.
+// @Summary List the webhook deliveries of a job
+// @Description Returns the deliveries of the job's callback with every attempt at sending them, oldest first. A job has a
+// @Description delivery once it has succeeded or failed, and only if it was submitted with a callback_url.
+// @Tags jobs
+// @Produce application/json
+// @Param id path string true "Job ID"
+// @Success 200 {array} controllers.WebhookDeliveryResponse
+// @Failure 404 {object} apierror.ErrorResponse "Job not found"
+// @Failure 500 {object} apierror.ErrorResponse "Internal error"
+// @Router /jobs/{id}/deliveries [get]
+func (c *JobController) Deliveries(ctx *gin.Context) {
+	id, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": job.ErrNotFound.Error()})
+		return
+	}
+	if _, err := c.service.Get(id); err != nil {
+		ctx.JSON(jobErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	deliveries, err := c.webhooks.ListByJob(id)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	resp := make([]WebhookDeliveryResponse, len(deliveries))
+	for i := range deliveries {
+		resp[i] = newWebhookDeliveryResponse(&deliveries[i])
+	}
+	ctx.JSON(http.StatusOK, resp)
+}
+
+func newJobResponse(j *models.Job) JobResponse {
+	resp := JobResponse{
+		ID:         j.ID,
+		Analysis:   j.Analysis,
+		Params:     j.Params,
+		Status:     string(j.Status),
+		Filename:   j.Filename,
+		Format:     j.Format,
+		Size:       j.Size,
+		SHA256:     j.SHA256,
+		Attempts:   j.Attempts,
+		Progress:   JobProgressDTO{Bytes: j.BytesRead, Records: j.Records},
+		Result:     j.Result,
+		Error:      j.Error,
+		CreatedAt:  j.CreatedAt,
+		StartedAt:  j.StartedAt,
+		FinishedAt: j.FinishedAt,
+
+		CallbackURL:    j.CallbackURL,
+		CallbackClient: j.CallbackClient,
+	}
+	if resp.Params == nil {
+		resp.Params = map[string][]string{}
+	}
+	switch {
+	case j.Status == models.JobSucceeded:
+		resp.Progress.Fraction = 1
+	case j.Size > 0:
+		resp.Progress.Fraction = utils.Round(float64(j.BytesRead)/float64(j.Size), 4)
+	}
+	return resp
+}
+
+func unknownAnalysisMessage(names []string) string {
+	return fmt.Sprintf("Invalid analysis parameter. Expected one of %s.", strings.Join(names, ", "))
+}
+
+// jobErrorStatus maps a job service error to an HTTP status code.
+func jobErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, job.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, job.ErrFinished):
+		return http.StatusConflict
+	default:
+		return analysisErrorStatus(err)
+	}
+}
@@ -0,0 +1,115 @@
+package controllers_test
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Viktor2805/nucount/internal/controllers"
+	"github.com/Viktor2805/nucount/internal/models"
+	"github.com/Viktor2805/nucount/internal/services/job"
+	"github.com/Viktor2805/nucount/internal/services/webhook"
+	"github.com/Viktor2805/nucount/internal/testutil"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// runningJobs serves a job that runs forever.
+type runningJobs struct {
+	job.Service
+	job *models.Job
+}
+
+func (r runningJobs) Get(uuid.UUID) (*models.Job, error) {
+	return r.job, nil
+}
+
+func (r runningJobs) Watch(ctx context.Context, _ uuid.UUID, fn func(job.Update) error) error {
+	for {
+		if err := fn(job.Update{Job: r.job}); err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+func TestJobEvents_Shutdown(t *testing.T) {
+	jobs := runningJobs{job: &models.Job{ID: uuid.New(), Analysis: "count", Status: models.JobRunning}}
+	c := controllers.NewJobController(jobs, nil, newNucleotideController(&testutil.AnalysisRepo{}))
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/jobs/:id/events", c.Events)
+
+	srv := httptest.NewUnstartedServer(router)
+	srv.Config.RegisterOnShutdown(c.Shutdown)
+	srv.Start()
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/jobs/" + jobs.job.ID.String() + "/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	line, err := bufio.NewReader(resp.Body).ReadString('\n')
+	if err != nil || !strings.HasPrefix(line, "event:progress") {
+		t.Fatalf("first line = %q, %v; want a progress event", line, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := srv.Config.Shutdown(ctx); err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+	if _, err := io.Copy(io.Discard, resp.Body); err != nil {
+		t.Errorf("stream did not end cleanly: %v", err)
+	}
+}
+
+func TestJobCreate_Callback(t *testing.T) {
+	hooks := webhook.NewHooks(nil, map[string]string{"orchestrator": "shh"})
+	// The job service is not reached: the callback is refused first.
+	c := controllers.NewJobController(nil, hooks, newNucleotideController(&testutil.AnalysisRepo{}))
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/jobs", c.Create)
+
+	const payload = ">a\nACGT\n"
+	submit := func(callbackURL, digest string, signed bool) int {
+		q := url.Values{"analysis": {"count"}, "callback_url": {callbackURL}, "callback_client": {"orchestrator"}}
+		req := httptest.NewRequest(http.MethodPost, "/jobs?"+q.Encode(), strings.NewReader(payload))
+		if digest != "" {
+			req.Header.Set("X-Content-SHA256", digest)
+		}
+		if signed {
+			now := time.Now().Unix()
+			req.Header.Set(webhook.HeaderTimestamp, strconv.FormatInt(now, 10))
+			req.Header.Set(webhook.HeaderSignature, webhook.Sign("shh", now, webhook.Registration(callbackURL, digest)))
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	digest := digestOf(payload)
+	if code := submit("https://orchestrator.example/hooks", digest, false); code != http.StatusUnauthorized {
+		t.Errorf("unsigned callback: status %d, want 401", code)
+	}
+	if code := submit("https://orchestrator.example/hooks", "", true); code != http.StatusBadRequest {
+		t.Errorf("callback without digest: status %d, want 400", code)
+	}
+	if code := submit("http://169.254.169.254/latest/meta-data/", digest, true); code != http.StatusBadRequest {
+		t.Errorf("link-local callback: status %d, want 400", code)
+	}
+}
@@ -1,72 +1,400 @@
 package controllers
 
 import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"net/url"
+	"strings"
 
-	"golang/internal/helpers"
-	nucleotide "golang/internal/services/nucleotide"
+	"github.com/Viktor2805/nucount/internal/analyses"
+	"github.com/Viktor2805/nucount/internal/helpers"
+	"github.com/Viktor2805/nucount/internal/models"
+	history "github.com/Viktor2805/nucount/internal/services/analysis"
+	"github.com/Viktor2805/nucount/internal/services/job"
+	nucleotide "github.com/Viktor2805/nucount/internal/services/nucleotide"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 )
 
-type NucleotideCountDTO struct {
-	A int `json:"A"`
-	C int `json:"C"`
-	G int `json:"G"`
-	T int `json:"T"`
+// Media types offered by the CpG island endpoint.
+const (
+	MIMEBED  = "text/x-bed"
+	MIMEGFF3 = "text/x-gff3"
+)
+
+// statusClientClosedRequest is reported when the client went away before the
+// analysis finished (the response is only seen in logs).
+const statusClientClosedRequest = 499
+
+type NucleotideController struct {
+	analyzer *analyses.Analyzer
+	history  history.Service
 }
 
-type NucleotideCountResponse struct {
-	Bases NucleotideCountDTO `json:"bases"`
-	Total int                `json:"total"`
+func NewNucleotideController(
+	service nucleotide.Service,
+	kmers nucleotide.KmerService,
+	dinucleotides nucleotide.DinucleotideService,
+	history history.Service,
+) *NucleotideController {
+	return &NucleotideController{
+		analyzer: analyses.New(service, kmers, dinucleotides),
+		history:  history,
+	}
 }
 
-type NucleotideController struct {
-	service nucleotide.Service
+// representation is a media type the result of an analysis is sent as,
+// rendered from the stored JSON result.
+type representation struct {
+	mediaType string
+	render    func(result []byte) ([]byte, error) // nil to send the result as is
 }
 
-func NewNucleotideController(service nucleotide.Service) *NucleotideController {
-	return &NucleotideController{service: service}
+// jsonResult sends the JSON result of an analysis as it is stored.
+var jsonResult = representation{mediaType: gin.MIMEJSON}
+
+// islandsAs returns the representation of a CpG island result written by
+// write, e.g. analyses.CpGIslandResponse.WriteBED.
+func islandsAs(mediaType string, write func(analyses.CpGIslandResponse, io.Writer) error) representation {
+	return representation{mediaType: mediaType, render: func(result []byte) ([]byte, error) {
+		var resp analyses.CpGIslandResponse
+		if err := json.Unmarshal(result, &resp); err != nil {
+			return nil, err
+		}
+		var buf bytes.Buffer
+		err := write(resp, &buf)
+		return buf.Bytes(), err
+	}}
 }
 
-// CountBases uploads a FASTA file and returns A/C/G/T counts.
-// @Summary Count nucleotides from a FASTA file
-// @Description Upload a .fasta/.fa (or plain text FASTA) file and get counts of A/C/G/T with total.
-// @Tags nucleotides
-// @Accept multipart/form-data
-// @Produce application/json
-// @Param file formData file true "FASTA file (.fasta, .fa) or text/plain"
-// @Success 200 {object} controllers.NucleotideCountResponse
-// @Failure 400 {object} apierror.ErrorResponse "Invalid file or parameters"
-// @Failure 500 {object} apierror.ErrorResponse "Internal error while processing file"
-// @Router /nucleotides/count [post]
-func (c *NucleotideController) Count(ctx *gin.Context) {
-	file, header, err := ctx.Request.FormFile("file")
+// serve runs the named analysis on the upload of a synchronous request and
+// sends the result as rep. An upload with a declared digest that has been
+// analysed with the same parameters before is not read: the stored result is
+// served instead. The digest of an undeclared upload is only known once it
+// has been analysed, so it always runs; the response tells the digest to
+// declare next time.
+func (c *NucleotideController) serve(ctx *gin.Context, name string, rep representation) {
+	a, _ := c.analyzer.Lookup(name)
+	run, params, err := a.Prepare(ctx.Request.URL.Query())
 	if err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	defer file.Close()
+	digest, err := declaredDigest(ctx.Request)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if rec := c.cached(name, params, digest); rec != nil {
+		respondAnalysis(ctx, rec, true, rep)
+		return
+	}
 
-	if !helpers.IsFASTAByExtension(header.Filename) {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid file extension. Please upload a .fasta or .fa file."})
+	up, ok := formUpload(ctx, a.Accepted...)
+	if !ok {
 		return
 	}
+	up.digest = digest
 
-	if !helpers.IsValidFASTAContentType(header) {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid content type. Expected 'application/octet-stream' or 'text/plain'."})
+	rec, err := c.analyze(ctx.Request.Context(), name, params, run, up)
+	if err != nil {
+		ctx.JSON(analysisErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
+	respondAnalysis(ctx, rec, false, rep)
+}
 
-	bc, err := c.service.Count(file)
+// Run runs the analysis of a job, exactly like the synchronous endpoint of the
+// analysis with the job's query parameters. A stored result of the same
+// analysis of the same upload is returned without reading the upload.
+func (c *NucleotideController) Run(ctx context.Context, s job.Submission, r io.Reader, opts ...nucleotide.Option) (any, error) {
+	a, ok := c.analyzer.Lookup(s.Analysis)
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", job.ErrUnknownAnalysis, s.Analysis)
+	}
+	run, params, err := a.Prepare(s.Params)
+	if err != nil {
+		return nil, err
+	}
+	if rec := c.cached(s.Analysis, params, s.SHA256); rec != nil {
+		return rec.Result, nil
+	}
+	rec, err := c.analyze(ctx, s.Analysis, params, run, upload{Reader: r, format: s.Format, filename: s.Filename, digest: s.SHA256}, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return rec.Result, nil
+}
+
+// cached returns the stored analysis of an upload with the given digest, or
+// nil if there is none or the digest is unknown. Failing lookups are misses:
+// the analysis then simply runs.
+func (c *NucleotideController) cached(name string, params url.Values, digest string) *models.Analysis {
+	if digest == "" {
+		return nil
+	}
+	rec, err := c.history.Find(name, params, digest)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil
+	}
+	return rec
+}
+
+// analyze runs a prepared analysis on an upload, with opts, and records it in
+// the history; the recorded result is the response body.
+func (c *NucleotideController) analyze(ctx context.Context, name string, params url.Values, run analyses.RunFunc, up upload, opts ...nucleotide.Option) (*models.Analysis, error) {
+	return c.history.Run(ctx, history.Upload{
+		Analysis: name,
+		Params:   params,
+		Format:   up.format,
+		Filename: up.filename,
+		SHA256:   up.digest,
+	}, up, func(ctx context.Context, r io.Reader) (any, error) {
+		return run(ctx, r, up.format, opts...)
+	})
+}
+
+// respondAnalysis writes the result of an analysis as rep, with a strong
+// ETag, or 304 Not Modified if it matches the request's If-None-Match.
+func respondAnalysis(ctx *gin.Context, rec *models.Analysis, hit bool, rep representation) {
+	body := []byte(rec.Result)
+	if rep.render != nil {
+		var err error
+		if body, err = rep.render(rec.Result); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	etag := history.ETag(body)
+	ctx.Header("ETag", etag)
+	ctx.Header(headerContentSHA256, rec.SHA256)
+	if hit {
+		ctx.Header("X-Cache", "hit")
+	} else {
+		ctx.Header("X-Cache", "miss")
+	}
+	if rec.ID != uuid.Nil {
+		ctx.Header("X-Analysis-ID", rec.ID.String())
+	}
+
+	if etagMatches(ctx.GetHeader("If-None-Match"), etag) {
+		ctx.Status(http.StatusNotModified)
 		return
 	}
+	ctx.Data(http.StatusOK, rep.mediaType+"; charset=utf-8", body)
+}
+
+// etagMatches reports whether an If-None-Match header matches etag, using
+// the weak comparison that RFC 9110 prescribes for If-None-Match.
+func etagMatches(header, etag string) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// CountBases uploads a FASTA or FASTQ file and returns A/C/G/T counts.
+// @Summary Count nucleotides from a FASTA or FASTQ file
+// @Description Upload a .fasta/.fa (or plain text FASTA) file and get counts of A/C/G/T with total.
+// @Description FASTQ files (.fastq/.fq) are detected from their content and additionally report read count, length distribution,
+// @Description mean quality per position, Q20/Q30 fractions and per-read GC distribution. Phred+33 and Phred+64 are detected automatically.
+// @Description UCSC .2bit files always report per-record counts; N and soft-masked bases are taken from the block tables.
+// @Description IUPAC ambiguity codes, gaps and invalid characters are reported separately.
+// @Description Derived metrics (GC%, AT%, GC/AT skew, purine/pyrimidine ratio, N fraction) are rounded to the requested precision.
+// @Description With records=true the response also lists the counts, length and GC% of every record.
+// @Tags nucleotides
+// @Accept multipart/form-data,application/octet-stream
+// @Produce application/json
+// @Param file formData file true "FASTA (.fasta, .fa), FASTQ (.fastq, .fq) or 2bit file"
+// @Param X-Content-SHA256 header string false "SHA-256 of the file as sent, hex; needed for a cached result"
+// @Param If-None-Match header string false "ETag of a result the client already has"
+// @Param records query bool false "Include per-record counts (FASTA only)"
+// @Param precision query int false "Decimal places of derived metrics (0-10, default 2)"
+// @Success 200 {object} analyses.NucleotideCountResponse
+// @Header 200 {string} X-Analysis-ID "ID of the analysis in the history"
+// @Header 200 {string} ETag "Strong entity tag of the result"
+// @Header 200 {string} X-Content-SHA256 "SHA-256 of the file as sent, hex, to declare when sending it again"
+// @Header 200 {string} X-Cache "hit if the result was served from the cache, else miss"
+// @Success 304 "Result unchanged, matches If-None-Match"
+// @Failure 400 {object} apierror.ErrorResponse "Invalid file or parameters"
+// @Failure 413 {object} apierror.ErrorResponse "Upload or decompressed content too large"
+// @Failure 415 {object} apierror.ErrorResponse "File content is not a supported sequence format"
+// @Failure 500 {object} apierror.ErrorResponse "Internal error while processing file"
+// @Router /nucleotides/count [post]
+func (c *NucleotideController) Count(ctx *gin.Context) {
+	c.serve(ctx, analyses.Count, jsonResult)
+}
+
+// GCProfile computes GC content and GC skew in sliding windows along each record.
+// @Summary Sliding-window GC profile of a FASTA file
+// @Description Upload a FASTA file and get, for every record, the start, end, GC% and GC skew of each window.
+// @Description Windows start every step bases; the last window of a record may be shorter. Cumulative skew is the running sum of window skews.
+// @Description A request yields at most 1,000,000 windows over all records; more are refused with 400, asking for a larger step.
+// @Tags nucleotides
+// @Accept multipart/form-data,application/octet-stream
+// @Produce application/json
+// @Param file formData file true "FASTA file (.fasta, .fa) or text/plain"
+// @Param X-Content-SHA256 header string false "SHA-256 of the file as sent, hex; needed for a cached result"
+// @Param If-None-Match header string false "ETag of a result the client already has"
+// @Param window query int false "Window size in bases (default 1000)"
+// @Param step query int false "Distance between window starts (default: window size)"
+// @Param precision query int false "Decimal places of GC% and skew (0-10, default 2)"
+// @Success 200 {object} analyses.GCProfileResponse
+// @Header 200 {string} X-Analysis-ID "ID of the analysis in the history"
+// @Header 200 {string} ETag "Strong entity tag of the result"
+// @Header 200 {string} X-Content-SHA256 "SHA-256 of the file as sent, hex, to declare when sending it again"
+// @Header 200 {string} X-Cache "hit if the result was served from the cache, else miss"
+// @Success 304 "Result unchanged, matches If-None-Match"
+// @Failure 400 {object} apierror.ErrorResponse "Invalid file or parameters, or too many windows"
+// @Failure 413 {object} apierror.ErrorResponse "Upload or decompressed content too large"
+// @Failure 415 {object} apierror.ErrorResponse "File content is not a supported sequence format"
+// @Failure 500 {object} apierror.ErrorResponse "Internal error while processing file"
+// @Router /nucleotides/gc-profile [post]
+func (c *NucleotideController) GCProfile(ctx *gin.Context) {
+	c.serve(ctx, analyses.GCProfile, jsonResult)
+}
+
+// Kmers counts the k-mers of a FASTA file.
+// @Summary Count k-mers in a FASTA file
+// @Description Upload a FASTA file and get the most frequent k-mers and the k-mer spectrum (how many distinct k-mers occur n times).
+// @Description K-mers containing N or other non-ACGT symbols are skipped and never span two records. With canonical=true each k-mer is merged with its reverse complement.
+// @Tags nucleotides
+// @Accept multipart/form-data,application/octet-stream
+// @Produce application/json
+// @Param file formData file true "FASTA file (.fasta, .fa) or text/plain"
+// @Param X-Content-SHA256 header string false "SHA-256 of the file as sent, hex; needed for a cached result"
+// @Param If-None-Match header string false "ETag of a result the client already has"
+// @Param k query int false "K-mer length (1-31, default 21)"
+// @Param canonical query bool false "Merge k-mers with their reverse complement"
+// @Param top query int false "Number of most frequent k-mers to return (0-1000, default 10)"
+// @Success 200 {object} analyses.KmerResponse
+// @Header 200 {string} X-Analysis-ID "ID of the analysis in the history"
+// @Header 200 {string} ETag "Strong entity tag of the result"
+// @Header 200 {string} X-Content-SHA256 "SHA-256 of the file as sent, hex, to declare when sending it again"
+// @Header 200 {string} X-Cache "hit if the result was served from the cache, else miss"
+// @Success 304 "Result unchanged, matches If-None-Match"
+// @Failure 400 {object} apierror.ErrorResponse "Invalid file or parameters"
+// @Failure 422 {object} apierror.ErrorResponse "Too many distinct k-mers"
+// @Failure 413 {object} apierror.ErrorResponse "Upload or decompressed content too large"
+// @Failure 415 {object} apierror.ErrorResponse "File content is not a supported sequence format"
+// @Failure 500 {object} apierror.ErrorResponse "Internal error while processing file"
+// @Router /nucleotides/kmers [post]
+func (c *NucleotideController) Kmers(ctx *gin.Context) {
+	c.serve(ctx, analyses.Kmers, jsonResult)
+}
+
+// Dinucleotides counts the 16 dinucleotides of every record.
+// @Summary Dinucleotide frequencies and CpG observed/expected ratio
+// @Description Upload a FASTA file and get the counts and frequencies of all 16 dinucleotides and the CpG o/e ratio of every record.
+// @Description Pairs are counted across line breaks, but never across record boundaries or N. CpG o/e = CpG * length / (C * G).
+// @Tags nucleotides
+// @Accept multipart/form-data,application/octet-stream
+// @Produce application/json
+// @Param file formData file true "FASTA file (.fasta, .fa) or text/plain"
+// @Param X-Content-SHA256 header string false "SHA-256 of the file as sent, hex; needed for a cached result"
+// @Param If-None-Match header string false "ETag of a result the client already has"
+// @Param precision query int false "Decimal places of frequencies and CpG o/e (0-10, default 2)"
+// @Success 200 {object} analyses.DinucleotideResponse
+// @Header 200 {string} X-Analysis-ID "ID of the analysis in the history"
+// @Header 200 {string} ETag "Strong entity tag of the result"
+// @Header 200 {string} X-Content-SHA256 "SHA-256 of the file as sent, hex, to declare when sending it again"
+// @Header 200 {string} X-Cache "hit if the result was served from the cache, else miss"
+// @Success 304 "Result unchanged, matches If-None-Match"
+// @Failure 400 {object} apierror.ErrorResponse "Invalid file or parameters"
+// @Failure 413 {object} apierror.ErrorResponse "Upload or decompressed content too large"
+// @Failure 415 {object} apierror.ErrorResponse "File content is not a supported sequence format"
+// @Failure 500 {object} apierror.ErrorResponse "Internal error while processing file"
+// @Router /nucleotides/dinucleotides [post]
+func (c *NucleotideController) Dinucleotides(ctx *gin.Context) {
+	c.serve(ctx, analyses.Dinucleotides, jsonResult)
+}
+
+// CpGIslands finds CpG islands in every record of a FASTA file.
+// @Summary Detect CpG islands
+// @Description Upload a FASTA file and get merged CpG island intervals per record, using the Gardiner-Garden criteria
+// @Description (window length >= 200, GC >= 50%, CpG o/e >= 0.6) unless other thresholds are given.
+// @Description The format follows the Accept header: JSON (default), BED (text/x-bed, 0-based) or GFF3 (text/x-gff3, 1-based).
+// @Description All three are rendered from the same result, which is recorded in the history and cached like the JSON one.
+// @Tags nucleotides
+// @Accept multipart/form-data,application/octet-stream
+// @Produce application/json
+// @Produce text/x-bed
+// @Produce text/x-gff3
+// @Param file formData file true "FASTA file (.fasta, .fa) or text/plain"
+// @Param X-Content-SHA256 header string false "SHA-256 of the file as sent, hex; needed for a cached result"
+// @Param If-None-Match header string false "ETag of a result the client already has"
+// @Param min_length query int false "Window length in bases (2-10000000, default 200)"
+// @Param min_gc query number false "Minimum GC percentage (0-100, default 50)"
+// @Param min_oe query number false "Minimum CpG observed/expected ratio (default 0.6)"
+// @Param precision query int false "Decimal places of GC% and CpG o/e in JSON and GFF3 (0-10, default 2)"
+// @Success 200 {object} analyses.CpGIslandResponse
+// @Header 200 {string} X-Analysis-ID "ID of the analysis in the history"
+// @Header 200 {string} ETag "Strong entity tag of the result in the negotiated format"
+// @Header 200 {string} X-Content-SHA256 "SHA-256 of the file as sent, hex, to declare when sending it again"
+// @Header 200 {string} X-Cache "hit if the result was served from the cache, else miss"
+// @Success 304 "Result unchanged, matches If-None-Match"
+// @Failure 400 {object} apierror.ErrorResponse "Invalid file or parameters"
+// @Failure 406 {object} apierror.ErrorResponse "Unsupported Accept header"
+// @Failure 413 {object} apierror.ErrorResponse "Upload or decompressed content too large"
+// @Failure 415 {object} apierror.ErrorResponse "File content is not a supported sequence format"
+// @Failure 500 {object} apierror.ErrorResponse "Internal error while processing file"
+// @Router /nucleotides/cpg-islands [post]
+func (c *NucleotideController) CpGIslands(ctx *gin.Context) {
+	format := ctx.NegotiateFormat(gin.MIMEJSON, MIMEBED, MIMEGFF3)
+	if format == "" {
+		ctx.JSON(http.StatusNotAcceptable, gin.H{"error": "Unsupported Accept header. Expected application/json, text/x-bed or text/x-gff3."})
+		return
+	}
+	ctx.Header("Vary", "Accept")
+
+	switch format {
+	case MIMEBED:
+		c.serve(ctx, analyses.CpGIslands, islandsAs(MIMEBED, analyses.CpGIslandResponse.WriteBED))
+	case MIMEGFF3:
+		c.serve(ctx, analyses.CpGIslands, islandsAs(MIMEGFF3, analyses.CpGIslandResponse.WriteGFF3))
+	default:
+		c.serve(ctx, analyses.CpGIslands, jsonResult)
+	}
+}
 
-	resp := NucleotideCountResponse{
-		Bases: NucleotideCountDTO{A: bc.A, C: bc.C, G: bc.G, T: bc.T},
-		Total: bc.Total(),
+// analysisErrorStatus maps errors returned by the nucleotide services to HTTP
+// status codes.
+func analysisErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, context.Canceled):
+		return statusClientClosedRequest
+	case errors.As(err, new(analyses.ParamError)),
+		errors.Is(err, history.ErrDigestMismatch),
+		errors.Is(err, job.ErrDigestMismatch),
+		errors.Is(err, nucleotide.ErrInvalidWindow),
+		errors.Is(err, nucleotide.ErrTooManyWindows),
+		errors.Is(err, nucleotide.ErrInvalidKmerOptions),
+		errors.Is(err, nucleotide.ErrInvalidIslandOptions),
+		errors.Is(err, nucleotide.ErrInvalidFASTQ),
+		errors.Is(err, nucleotide.ErrInvalidFASTA),
+		errors.Is(err, nucleotide.ErrInvalid2bit),
+		errors.Is(err, gzip.ErrHeader),
+		errors.Is(err, gzip.ErrChecksum):
+		return http.StatusBadRequest
+	case errors.Is(err, helpers.ErrDecompressedTooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.As(err, new(*http.MaxBytesError)):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, nucleotide.ErrTooManyKmers):
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusInternalServerError
 	}
-	ctx.JSON(http.StatusOK, resp)
 }
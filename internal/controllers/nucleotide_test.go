@@ -0,0 +1,213 @@
+package controllers_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Viktor2805/nucount/internal/controllers"
+	history "github.com/Viktor2805/nucount/internal/services/analysis"
+	nucleotide "github.com/Viktor2805/nucount/internal/services/nucleotide"
+	"github.com/Viktor2805/nucount/internal/testutil"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	fasta               = ">r1\nACGTCGCG\n"
+	headerContentSHA256 = "X-Content-SHA256"
+)
+
+func newNucleotideController(repo *testutil.AnalysisRepo) *controllers.NucleotideController {
+	return controllers.NewNucleotideController(
+		nucleotide.NewCounter(),
+		nucleotide.NewKmerCounter(),
+		nucleotide.NewDinucleotideAnalyzer(),
+		history.NewHistory(repo),
+	)
+}
+
+func newRouter(repo *testutil.AnalysisRepo) *gin.Engine {
+	c := newNucleotideController(repo)
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/count", c.Count)
+	return router
+}
+
+// post sends body to /count with the given headers.
+func post(router *gin.Engine, body string, header map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/count?precision=3", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/octet-stream")
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func digestOf(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestCount_Cache(t *testing.T) {
+	repo := &testutil.AnalysisRepo{}
+	router := newRouter(repo)
+	digest := digestOf(fasta)
+
+	first := post(router, fasta, nil)
+	if first.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", first.Code, first.Body)
+	}
+	if got := first.Header().Get("X-Cache"); got != "miss" {
+		t.Errorf("X-Cache = %q, want miss", got)
+	}
+	if got := first.Header().Get("X-Content-SHA256"); got != digest {
+		t.Errorf("X-Content-SHA256 = %q, want %q", got, digest)
+	}
+
+	// Without the digest the upload cannot be looked up before it is read,
+	// but the analysis is not recorded twice.
+	again := post(router, fasta, nil)
+	if got := again.Header().Get("X-Cache"); got != "miss" {
+		t.Errorf("undeclared digest: X-Cache = %q, want miss", got)
+	}
+	if got, want := again.Header().Get("X-Analysis-ID"), first.Header().Get("X-Analysis-ID"); got != want {
+		t.Errorf("undeclared digest: X-Analysis-ID = %q, want %q", got, want)
+	}
+
+	// With it the stored result is served and the body is not read.
+	hit := post(router, "", map[string]string{"X-Content-SHA256": strings.ToUpper(digest)})
+	if hit.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", hit.Code, hit.Body)
+	}
+	if got := hit.Header().Get("X-Cache"); got != "hit" {
+		t.Errorf("declared digest: X-Cache = %q, want hit", got)
+	}
+	if hit.Body.String() != first.Body.String() {
+		t.Errorf("cached body = %s, want %s", hit.Body, first.Body)
+	}
+	if hit.Header().Get("ETag") != first.Header().Get("ETag") {
+		t.Errorf("cached ETag = %q, want %q", hit.Header().Get("ETag"), first.Header().Get("ETag"))
+	}
+	if len(repo.Analyses) != 1 {
+		t.Errorf("stored %d analyses, want 1", len(repo.Analyses))
+	}
+
+	// Parameters are compared with their defaults filled in.
+	req := httptest.NewRequest(http.MethodPost, "/count?records=0&precision=03&unused=1", strings.NewReader(""))
+	req.Header.Set(headerContentSHA256, digest)
+	respelled := httptest.NewRecorder()
+	router.ServeHTTP(respelled, req)
+	if got := respelled.Header().Get("X-Cache"); respelled.Code != http.StatusOK || got != "hit" {
+		t.Errorf("equivalent parameters: status %d, X-Cache = %q, want a hit", respelled.Code, got)
+	}
+
+	// A declared digest that does not match the upload is refused.
+	if rec := post(router, ">r2\nAAAA\n", map[string]string{"X-Content-SHA256": digestOf("other")}); rec.Code != http.StatusBadRequest {
+		t.Errorf("wrong digest: status = %d, want 400", rec.Code)
+	}
+}
+
+func TestCount_IfNoneMatch(t *testing.T) {
+	router := newRouter(&testutil.AnalysisRepo{})
+	first := post(router, fasta, nil)
+	etag := first.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("no ETag")
+	}
+
+	tests := []struct {
+		name   string
+		header string
+		want   int
+	}{
+		{"exact", etag, http.StatusNotModified},
+		{"weak", "W/" + etag, http.StatusNotModified},
+		{"list", `"other", ` + etag, http.StatusNotModified},
+		{"any", "*", http.StatusNotModified},
+		{"other", `"other"`, http.StatusOK},
+		{"unquoted", strings.Trim(etag, `"`), http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, cached := range []bool{false, true} {
+				header := map[string]string{"If-None-Match": tt.header}
+				if cached {
+					header["X-Content-SHA256"] = digestOf(fasta)
+				}
+				rec := post(router, fasta, header)
+				if rec.Code != tt.want {
+					t.Fatalf("cached %v: status = %d, want %d", cached, rec.Code, tt.want)
+				}
+				if rec.Header().Get("ETag") != etag {
+					t.Errorf("cached %v: ETag = %q, want %q", cached, rec.Header().Get("ETag"), etag)
+				}
+				if tt.want == http.StatusNotModified && rec.Body.Len() != 0 {
+					t.Errorf("cached %v: 304 with body %s", cached, rec.Body)
+				}
+			}
+		})
+	}
+}
+
+func TestCpGIslands_Formats(t *testing.T) {
+	repo := &testutil.AnalysisRepo{}
+	c := newNucleotideController(repo)
+	router := gin.New()
+	router.POST("/cpg-islands", c.CpGIslands)
+	body := ">r1\n" + strings.Repeat("CG", 10) + "\n"
+	digest := digestOf(body)
+
+	request := func(accept, upload string, header map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/cpg-islands?min_length=4", strings.NewReader(upload))
+		req.Header.Set("Accept", accept)
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	bed := request(controllers.MIMEBED, body, nil)
+	if bed.Code != http.StatusOK || bed.Body.String() != "r1\t0\t20\tCpG_island_1\n" {
+		t.Fatalf("BED: status %d, body %q", bed.Code, bed.Body)
+	}
+	if got := bed.Header().Get("Content-Type"); got != controllers.MIMEBED+"; charset=utf-8" {
+		t.Errorf("BED: Content-Type = %q", got)
+	}
+	if bed.Header().Get("X-Analysis-ID") == "" || bed.Header().Get("X-Content-SHA256") != digest || bed.Header().Get("X-Cache") != "miss" {
+		t.Errorf("BED: headers = %v", bed.Header())
+	}
+	if len(repo.Analyses) != 1 {
+		t.Fatalf("stored %d analyses, want 1", len(repo.Analyses))
+	}
+
+	// The other formats are rendered from the stored result, with their own
+	// entity tags.
+	declared := map[string]string{headerContentSHA256: digest}
+	gff := request(controllers.MIMEGFF3, "", declared)
+	if gff.Code != http.StatusOK || gff.Header().Get("X-Cache") != "hit" ||
+		!strings.HasPrefix(gff.Body.String(), "##gff-version 3\nr1\tdna-analyzer\tCpG_island\t1\t20\t") {
+		t.Errorf("GFF3: status %d, X-Cache %q, body %q", gff.Code, gff.Header().Get("X-Cache"), gff.Body)
+	}
+	jsonResp := request(gin.MIMEJSON, "", declared)
+	if jsonResp.Code != http.StatusOK || jsonResp.Body.String() != string(repo.Analyses[0].Result) {
+		t.Errorf("JSON: status %d, body %s", jsonResp.Code, jsonResp.Body)
+	}
+	etags := map[string]bool{bed.Header().Get("ETag"): true, gff.Header().Get("ETag"): true, jsonResp.Header().Get("ETag"): true}
+	if len(etags) != 3 {
+		t.Errorf("ETags of the formats are not distinct: %v", etags)
+	}
+
+	notModified := request(controllers.MIMEBED, "", map[string]string{headerContentSHA256: digest, "If-None-Match": bed.Header().Get("ETag")})
+	if notModified.Code != http.StatusNotModified {
+		t.Errorf("If-None-Match: status %d, want 304", notModified.Code)
+	}
+}
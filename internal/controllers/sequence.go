@@ -0,0 +1,353 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Viktor2805/nucount/internal/helpers"
+	"github.com/Viktor2805/nucount/internal/models"
+	repository "github.com/Viktor2805/nucount/internal/repository/sequence"
+	"github.com/Viktor2805/nucount/internal/services/sequence"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type ImportedSequenceDTO struct {
+	ID          uint    `json:"id"`
+	Accession   string  `json:"accession"`
+	Species     *string `json:"species,omitempty"`
+	Chromosome  *string `json:"chromosome,omitempty"`
+	Description *string `json:"description,omitempty"`
+	Length      int     `json:"length"`
+	Features    int     `json:"features"`
+}
+
+type SequenceImportResponse struct {
+	Format   string                `json:"format"`
+	Imported int                   `json:"imported"`
+	Records  []ImportedSequenceDTO `json:"records"`
+}
+
+type SequenceDTO struct {
+	ID            uint             `json:"id"`
+	Accession     string           `json:"accession"`
+	Species       *string          `json:"species,omitempty"`
+	Chromosome    *string          `json:"chromosome,omitempty"`
+	Description   *string          `json:"description,omitempty"`
+	StartPosition int              `json:"start_position"`
+	EndPosition   int              `json:"end_position"`
+	Length        int              `json:"length"`
+	CreatedAt     time.Time        `json:"created_at"`
+	UpdatedAt     time.Time        `json:"updated_at"`
+	Features      []models.Feature `json:"features,omitempty"` // with ?features=true only
+}
+
+type SequenceListResponse struct {
+	Items      []SequenceDTO `json:"items"`
+	Limit      int           `json:"limit"`
+	NextCursor string        `json:"next_cursor,omitempty"` // absent on the last page
+}
+
+// SequenceUpdateRequest changes the metadata of a sequence. Absent fields are
+// left as they are; an empty species, chromosome or description is cleared.
+type SequenceUpdateRequest struct {
+	Accession   *string `json:"accession"`
+	Species     *string `json:"species"`
+	Chromosome  *string `json:"chromosome"`
+	Description *string `json:"description"`
+}
+
+type SubsequenceResponse struct {
+	ID        uint   `json:"id"`
+	Accession string `json:"accession"`
+	Start     int    `json:"start"` // 1-based, included
+	End       int    `json:"end"`   // included
+	Length    int    `json:"length"`
+	Sequence  string `json:"sequence"`
+}
+
+// Bounds of the sequence list page size.
+const (
+	defaultSequenceLimit = 50
+	maxSequenceLimit     = 500
+)
+
+// MIME2bit is the media type of UCSC 2bit downloads.
+const MIME2bit = "application/x-2bit"
+
+type SequenceController struct {
+	service sequence.Service
+}
+
+func NewSequenceController(service sequence.Service) *SequenceController {
+	return &SequenceController{service: service}
+}
+
+// Import stores the records of a GenBank or EMBL file.
+// @Summary Import a GenBank or EMBL flat file
+// @Description Upload a GenBank or EMBL file (optionally gzip, bzip2 or zstd compressed). Every record is stored as a sequence
+// @Description with its accession (versioned when known), organism, chromosome (from the source feature), definition and feature table.
+// @Description The file is imported in a single transaction: if any record fails to parse or store, nothing is stored.
+// @Tags sequences
+// @Accept multipart/form-data,application/octet-stream
+// @Produce application/json
+// @Param file formData file true "GenBank (.gb, .gbk) or EMBL (.embl) file"
+// @Success 201 {object} controllers.SequenceImportResponse
+// @Failure 400 {object} apierror.ErrorResponse "Malformed flat file"
+// @Failure 409 {object} apierror.ErrorResponse "A record with the same accession already exists"
+// @Failure 413 {object} apierror.ErrorResponse "Upload or decompressed content too large"
+// @Failure 415 {object} apierror.ErrorResponse "File content is not GenBank or EMBL"
+// @Failure 500 {object} apierror.ErrorResponse "Internal error while importing file"
+// @Router /sequences/import [post]
+func (c *SequenceController) Import(ctx *gin.Context) {
+	up, ok := formUpload(ctx, helpers.FormatGenBank, helpers.FormatEMBL)
+	if !ok {
+		return
+	}
+
+	imported, err := c.service.Import(ctx.Request.Context(), up, up.format)
+	if err != nil {
+		ctx.JSON(sequenceErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	resp := SequenceImportResponse{
+		Format:   string(up.format),
+		Imported: len(imported),
+		Records:  make([]ImportedSequenceDTO, len(imported)),
+	}
+	for i, r := range imported {
+		resp.Records[i] = ImportedSequenceDTO(r)
+	}
+	ctx.JSON(http.StatusCreated, resp)
+}
+
+// Export2bit downloads stored sequences as a UCSC 2bit file.
+// @Summary Export stored sequences as 2bit
+// @Description Streams the stored sequences as a UCSC .2bit file with one record per sequence, named by accession.
+// @Description Lowercase bases are stored as soft-mask blocks and every non-ACGT symbol as N.
+// @Description Without accession parameters every stored sequence is exported.
+// @Tags sequences
+// @Produce application/octet-stream
+// @Param accession query []string false "Accessions to export" collectionFormat(multi)
+// @Success 200 {file} binary
+// @Failure 404 {object} apierror.ErrorResponse "An accession is not stored"
+// @Failure 500 {object} apierror.ErrorResponse "Internal error while exporting"
+// @Router /sequences/export.2bit [get]
+func (c *SequenceController) Export2bit(ctx *gin.Context) {
+	ctx.Header("Content-Type", MIME2bit)
+	ctx.Header("Content-Disposition", `attachment; filename="sequences.2bit"`)
+
+	err := c.service.Export2bit(ctx.Request.Context(), ctx.Writer, ctx.QueryArray("accession"))
+	if err == nil {
+		return
+	}
+	if ctx.Writer.Written() {
+		// The body has started; all that is left is to cut the download short.
+		ctx.Abort()
+		return
+	}
+	ctx.Header("Content-Disposition", "")
+	ctx.JSON(sequenceErrorStatus(err), gin.H{"error": err.Error()})
+}
+
+// List lists stored sequences.
+// @Summary List stored sequences
+// @Description Lists stored sequences by ID, without their bases and features. The page after this one is fetched by
+// @Description passing its next_cursor as cursor with the same filters; next_cursor is absent on the last page.
+// @Tags sequences
+// @Produce application/json
+// @Param species query string false "Species, case-insensitive"
+// @Param chromosome query string false "Chromosome or scaffold"
+// @Param accession_prefix query string false "Start of the accession, e.g. CM034"
+// @Param limit query int false "Page size (1-500, default 50)"
+// @Param cursor query string false "next_cursor of the previous page"
+// @Success 200 {object} controllers.SequenceListResponse
+// @Failure 400 {object} apierror.ErrorResponse "Invalid parameters"
+// @Failure 500 {object} apierror.ErrorResponse "Internal error"
+// @Router /sequences [get]
+func (c *SequenceController) List(ctx *gin.Context) {
+	q := ctx.Request.URL.Query()
+	limit, err := strconv.Atoi(helpers.QueryDefault(q, "limit", strconv.Itoa(defaultSequenceLimit)))
+	if err != nil || limit < 1 || limit > maxSequenceLimit {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit parameter. Expected an integer between 1 and 500."})
+		return
+	}
+
+	filter := repository.Filter{
+		Species:         q.Get("species"),
+		Chromosome:      q.Get("chromosome"),
+		AccessionPrefix: q.Get("accession_prefix"),
+	}
+	sequences, next, err := c.service.List(filter, limit, q.Get("cursor"))
+	if err != nil {
+		ctx.JSON(sequenceErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	resp := SequenceListResponse{
+		Items:      make([]SequenceDTO, len(sequences)),
+		Limit:      limit,
+		NextCursor: next,
+	}
+	for i := range sequences {
+		resp.Items[i] = newSequenceDTO(&sequences[i])
+	}
+	ctx.JSON(http.StatusOK, resp)
+}
+
+// Get returns a stored sequence.
+// @Summary Get a stored sequence
+// @Description Returns the metadata of a sequence, by ID or accession, without its bases; see the subsequence endpoint
+// @Description for those. With features=true its feature table is included.
+// @Tags sequences
+// @Produce application/json
+// @Param ref path string true "Sequence ID or accession"
+// @Param features query bool false "Include the feature table"
+// @Success 200 {object} controllers.SequenceDTO
+// @Failure 404 {object} apierror.ErrorResponse "Sequence not found"
+// @Failure 500 {object} apierror.ErrorResponse "Internal error"
+// @Router /sequences/{ref} [get]
+func (c *SequenceController) Get(ctx *gin.Context) {
+	withFeatures, _ := strconv.ParseBool(ctx.Query("features"))
+	seq, err := c.service.Get(ctx.Param("ref"), withFeatures)
+	if err != nil {
+		ctx.JSON(sequenceErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, newSequenceDTO(seq))
+}
+
+// Update changes the metadata of a stored sequence.
+// @Summary Update the metadata of a stored sequence
+// @Description Changes the accession, species, chromosome or description of a sequence, by ID or accession. Absent
+// @Description fields are left as they are; an empty species, chromosome or description is cleared.
+// @Tags sequences
+// @Accept application/json
+// @Produce application/json
+// @Param ref path string true "Sequence ID or accession"
+// @Param metadata body controllers.SequenceUpdateRequest true "Metadata to change"
+// @Success 200 {object} controllers.SequenceDTO
+// @Failure 400 {object} apierror.ErrorResponse "Invalid metadata"
+// @Failure 404 {object} apierror.ErrorResponse "Sequence not found"
+// @Failure 409 {object} apierror.ErrorResponse "Another sequence has the accession"
+// @Failure 500 {object} apierror.ErrorResponse "Internal error"
+// @Router /sequences/{ref} [patch]
+func (c *SequenceController) Update(ctx *gin.Context) {
+	var req SequenceUpdateRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body. Expected a JSON object."})
+		return
+	}
+
+	seq, err := c.service.Update(ctx.Param("ref"), sequence.Metadata(req))
+	if err != nil {
+		ctx.JSON(sequenceErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, newSequenceDTO(seq))
+}
+
+// Delete deletes a stored sequence.
+// @Summary Delete a stored sequence
+// @Description Deletes a sequence, by ID or accession, with its features.
+// @Tags sequences
+// @Param ref path string true "Sequence ID or accession"
+// @Success 204
+// @Failure 404 {object} apierror.ErrorResponse "Sequence not found"
+// @Failure 500 {object} apierror.ErrorResponse "Internal error"
+// @Router /sequences/{ref} [delete]
+func (c *SequenceController) Delete(ctx *gin.Context) {
+	if err := c.service.Delete(ctx.Param("ref")); err != nil {
+		ctx.JSON(sequenceErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
+
+// Subsequence returns part of a stored sequence.
+// @Summary Get part of a stored sequence
+// @Description Returns the bases of a sequence, by ID or accession, from start to end, both included, counting from
+// @Description its start position (normally 1). start defaults to the start of the sequence and end to its end; at
+// @Description most 16777216 bases are returned at once.
+// @Tags sequences
+// @Produce application/json
+// @Param ref path string true "Sequence ID or accession"
+// @Param start query int false "First position"
+// @Param end query int false "Last position"
+// @Success 200 {object} controllers.SubsequenceResponse
+// @Failure 400 {object} apierror.ErrorResponse "Invalid range"
+// @Failure 404 {object} apierror.ErrorResponse "Sequence not found"
+// @Failure 500 {object} apierror.ErrorResponse "Internal error"
+// @Router /sequences/{ref}/subsequence [get]
+func (c *SequenceController) Subsequence(ctx *gin.Context) {
+	start, end, ok := 0, 0, true
+	if v := ctx.Query("start"); v != "" {
+		start, ok = parsePosition(v)
+	}
+	if v := ctx.Query("end"); ok && v != "" {
+		end, ok = parsePosition(v)
+	}
+	if !ok {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid start or end parameter. Expected a positive integer."})
+		return
+	}
+
+	seq, bases, err := c.service.Subsequence(ctx.Param("ref"), start, end)
+	if err != nil {
+		ctx.JSON(sequenceErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	if start == 0 {
+		start = seq.StartPos
+	}
+	ctx.JSON(http.StatusOK, SubsequenceResponse{
+		ID:        seq.ID,
+		Accession: seq.Accession,
+		Start:     start,
+		End:       start + len(bases) - 1,
+		Length:    len(bases),
+		Sequence:  bases,
+	})
+}
+
+func parsePosition(v string) (int, bool) {
+	n, err := strconv.Atoi(v)
+	return n, err == nil && n > 0
+}
+
+func newSequenceDTO(s *models.Sequence) SequenceDTO {
+	return SequenceDTO{
+		ID:            s.ID,
+		Accession:     s.Accession,
+		Species:       s.Species,
+		Chromosome:    s.Chromosome,
+		Description:   s.Description,
+		StartPosition: s.StartPos,
+		EndPosition:   s.EndPos,
+		Length:        s.Length,
+		CreatedAt:     s.CreatedAt,
+		UpdatedAt:     s.UpdatedAt,
+		Features:      s.Features,
+	}
+}
+
+// sequenceErrorStatus maps a sequence service error to an HTTP status code.
+func sequenceErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, sequence.ErrInvalidFlatFile),
+		errors.Is(err, sequence.ErrInvalidCursor),
+		errors.Is(err, sequence.ErrInvalidRange),
+		errors.Is(err, sequence.ErrInvalidMetadata):
+		return http.StatusBadRequest
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		return http.StatusConflict
+	case errors.Is(err, sequence.ErrNotFound):
+		return http.StatusNotFound
+	default:
+		return analysisErrorStatus(err)
+	}
+}
@@ -0,0 +1,126 @@
+package controllers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/Viktor2805/nucount/internal/analyses"
+	"github.com/Viktor2805/nucount/internal/helpers"
+
+	"github.com/gin-gonic/gin"
+)
+
+// uploadField is the multipart form field holding the uploaded file.
+const uploadField = "file"
+
+// headerContentSHA256 declares the hex SHA-256 of the uploaded file as sent:
+// the multipart file field or the raw body, before any Content-Encoding is
+// removed.
+const headerContentSHA256 = "X-Content-SHA256"
+
+var (
+	errMissingUpload       = errors.New(`missing "file" form field`)
+	errUnsupportedEncoding = errors.New("unsupported Content-Encoding, expected gzip or identity")
+	errInvalidDigest       = analyses.ParamError("Invalid X-Content-SHA256 header. Expected 64 hexadecimal digits.")
+)
+
+// upload is an uploaded file, streamed from the request body.
+type upload struct {
+	io.Reader
+	format   helpers.Format // sniffed from the content
+	filename string         // as sent by the client, may be empty
+	digest   string         // hex SHA-256 declared by the client, may be empty
+}
+
+// formUpload streams the uploaded file and classifies it by content; the file
+// name and declared content type are ignored. Content of a format not in
+// accepted is rejected with 415 Unsupported Media Type. On failure the error
+// response has already been written.
+func formUpload(ctx *gin.Context, accepted ...helpers.Format) (upload, bool) {
+	body, filename, err := uploadBody(ctx.Request)
+	if err != nil {
+		ctx.JSON(uploadErrorStatus(err), gin.H{"error": err.Error()})
+		return upload{}, false
+	}
+
+	det, r, err := helpers.SniffReader(body)
+	if err != nil {
+		ctx.JSON(uploadErrorStatus(err), gin.H{"error": err.Error()})
+		return upload{}, false
+	}
+	if !slices.Contains(accepted, det.Format) {
+		err := analyses.UnsupportedFormatError{Format: det.Format, Accepted: accepted}
+		ctx.JSON(http.StatusUnsupportedMediaType, gin.H{"error": err.Error()})
+		return upload{}, false
+	}
+
+	return upload{Reader: r, format: det.Format, filename: filename}, true
+}
+
+// declaredDigest returns the digest of the upload declared by the
+// X-Content-SHA256 header, in lower case, or "" if there is none.
+func declaredDigest(req *http.Request) (string, error) {
+	v := strings.TrimSpace(req.Header.Get(headerContentSHA256))
+	if v == "" {
+		return "", nil
+	}
+	if b, err := hex.DecodeString(v); err != nil || len(b) != sha256.Size {
+		return "", errInvalidDigest
+	}
+	return strings.ToLower(v), nil
+}
+
+// uploadBody returns the uploaded file as a stream, without spooling it to
+// memory or disk, and its name if the client sent one. A multipart/form-data
+// request is read part by part up to the "file" field; any other request body
+// is the file itself, named by a Content-Disposition header. A gzip
+// Content-Encoding is left in place: the analyses decompress gzip on the fly,
+// within the configured decompressed size limit.
+func uploadBody(req *http.Request) (io.Reader, string, error) {
+	switch strings.ToLower(req.Header.Get("Content-Encoding")) {
+	case "", "identity", "gzip", "x-gzip":
+	default:
+		return nil, "", errUnsupportedEncoding
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	if mediaType != "multipart/form-data" {
+		_, params, _ := mime.ParseMediaType(req.Header.Get("Content-Disposition"))
+		return req.Body, params["filename"], nil
+	}
+
+	mr, err := req.MultipartReader()
+	if err != nil {
+		return nil, "", err
+	}
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			return nil, "", errMissingUpload
+		}
+		if err != nil {
+			return nil, "", err
+		}
+		if part.FormName() == uploadField {
+			return part, part.FileName(), nil
+		}
+	}
+}
+
+// uploadErrorStatus maps an error reading the request body to an HTTP status code.
+func uploadErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, errUnsupportedEncoding):
+		return http.StatusUnsupportedMediaType
+	case errors.Is(err, errMissingUpload):
+		return http.StatusBadRequest
+	default:
+		return analysisErrorStatus(err)
+	}
+}
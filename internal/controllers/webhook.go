@@ -0,0 +1,137 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/Viktor2805/nucount/internal/models"
+	"github.com/Viktor2805/nucount/internal/services/webhook"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type WebhookAttemptDTO struct {
+	Attempt    int       `json:"attempt"`
+	StatusCode int       `json:"status_code"` // 0 if there was no response
+	Error      string    `json:"error,omitempty"`
+	DurationMs int64     `json:"duration_ms"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type WebhookDeliveryResponse struct {
+	ID            uuid.UUID           `json:"id"`
+	JobID         uuid.UUID           `json:"job_id"`
+	Client        string              `json:"client"`
+	URL           string              `json:"url"`
+	Event         string              `json:"event" enums:"job.succeeded,job.failed"`
+	Payload       models.JSON         `json:"payload" swaggertype:"object"` // request body
+	Status        string              `json:"status" enums:"pending,delivered,failed"`
+	Attempts      int                 `json:"attempts"`                  // since the delivery was created or replayed
+	NextAttemptAt *time.Time          `json:"next_attempt_at,omitempty"` // pending deliveries only
+	CreatedAt     time.Time           `json:"created_at"`
+	DeliveredAt   *time.Time          `json:"delivered_at,omitempty"`
+	History       []WebhookAttemptDTO `json:"history"` // oldest first, including attempts before a replay
+}
+
+type WebhookController struct {
+	service webhook.Service
+}
+
+func NewWebhookController(service webhook.Service) *WebhookController {
+	return &WebhookController{service: service}
+}
+
+// Get reports the state of a webhook delivery.
+// @Summary Get a webhook delivery
+// @Description Returns a delivery of a job's callback, its payload and every attempt at sending it.
+// @Tags webhooks
+// @Produce application/json
+// @Param id path string true "Delivery ID"
+// @Success 200 {object} controllers.WebhookDeliveryResponse
+// @Failure 404 {object} apierror.ErrorResponse "Delivery not found"
+// @Failure 500 {object} apierror.ErrorResponse "Internal error"
+// @Router /webhooks/deliveries/{id} [get]
+func (c *WebhookController) Get(ctx *gin.Context) {
+	id, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": webhook.ErrNotFound.Error()})
+		return
+	}
+
+	d, err := c.service.Get(id)
+	if err != nil {
+		ctx.JSON(webhookErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, newWebhookDeliveryResponse(d))
+}
+
+// Replay sends a webhook delivery again.
+// @Summary Replay a webhook delivery
+// @Description Sends a delivered or failed delivery again, with the same payload and a fresh round of retries. The
+// @Description request is signed anew, with the current timestamp.
+// @Tags webhooks
+// @Produce application/json
+// @Param id path string true "Delivery ID"
+// @Success 202 {object} controllers.WebhookDeliveryResponse
+// @Failure 404 {object} apierror.ErrorResponse "Delivery not found"
+// @Failure 409 {object} apierror.ErrorResponse "Delivery is still pending"
+// @Failure 500 {object} apierror.ErrorResponse "Internal error"
+// @Router /webhooks/deliveries/{id}/replay [post]
+func (c *WebhookController) Replay(ctx *gin.Context) {
+	id, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": webhook.ErrNotFound.Error()})
+		return
+	}
+
+	d, err := c.service.Replay(id)
+	if err != nil {
+		ctx.JSON(webhookErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusAccepted, newWebhookDeliveryResponse(d))
+}
+
+func newWebhookDeliveryResponse(d *models.WebhookDelivery) WebhookDeliveryResponse {
+	resp := WebhookDeliveryResponse{
+		ID:          d.ID,
+		JobID:       d.JobID,
+		Client:      d.Client,
+		URL:         d.URL,
+		Event:       d.Event,
+		Payload:     d.Payload,
+		Status:      string(d.Status),
+		Attempts:    d.Attempts,
+		CreatedAt:   d.CreatedAt,
+		DeliveredAt: d.DeliveredAt,
+		History:     make([]WebhookAttemptDTO, len(d.History)),
+	}
+	if d.Status == models.DeliveryPending {
+		resp.NextAttemptAt = &d.NextAttemptAt
+	}
+	for i, a := range d.History {
+		resp.History[i] = WebhookAttemptDTO{
+			Attempt:    a.Attempt,
+			StatusCode: a.StatusCode,
+			Error:      a.Error,
+			DurationMs: a.DurationMs,
+			CreatedAt:  a.CreatedAt,
+		}
+	}
+	return resp
+}
+
+// webhookErrorStatus maps a webhook service error to an HTTP status code.
+func webhookErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, webhook.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, webhook.ErrPending):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -3,7 +3,7 @@ package db
 import (
 	"context"
 	"fmt"
-	"golang/internal/config"
+	"github.com/Viktor2805/nucount/internal/config"
 	"time"
 
 	"go.uber.org/zap"
@@ -28,7 +28,9 @@ func New(cfg *config.DatabaseConfig, logger *zap.Logger) (*Db, error) {
 
 	db, err := gorm.Open(postgres.New(postgres.Config{
 		DSN: databaseURL,
-	}), &gorm.Config{})
+	}), &gorm.Config{
+		TranslateError: true, // report unique violations as gorm.ErrDuplicatedKey
+	})
 
 	if err != nil {
 		return nil, fmt.Errorf("gorm open: %w", err)
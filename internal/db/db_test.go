@@ -5,8 +5,8 @@ import (
 	"testing"
 	"time"
 
-	"golang/internal/config"
-	pkgdb "golang/internal/db"
+	"github.com/Viktor2805/nucount/internal/config"
+	pkgdb "github.com/Viktor2805/nucount/internal/db"
 
 	"github.com/docker/go-connections/nat"
 	"github.com/stretchr/testify/require"
@@ -0,0 +1,109 @@
+package helpers
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compression is a compression format recognized from magic bytes.
+type Compression string
+
+const (
+	CompressionNone  Compression = "none"
+	CompressionGzip  Compression = "gzip" // also covers multi-member bgzip
+	CompressionBzip2 Compression = "bzip2"
+	CompressionZstd  Compression = "zstd"
+)
+
+// ErrDecompressedTooLarge is returned when a compressed upload expands past
+// the configured limit.
+var ErrDecompressedTooLarge = errors.New("decompressed size exceeds the maximum allowed limit")
+
+var (
+	magicGzip  = []byte{0x1f, 0x8b}
+	magicBzip2 = []byte("BZh")
+	magicZstd  = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// DetectCompression classifies the leading bytes of a stream.
+func DetectCompression(head []byte) Compression {
+	switch {
+	case bytes.HasPrefix(head, magicGzip):
+		return CompressionGzip
+	case bytes.HasPrefix(head, magicBzip2):
+		return CompressionBzip2
+	case bytes.HasPrefix(head, magicZstd):
+		return CompressionZstd
+	default:
+		return CompressionNone
+	}
+}
+
+// Decompress detects the compression of r from its magic bytes and returns a
+// reader of the decompressed stream. Uncompressed input is passed through.
+// Reading more than limit decompressed bytes fails with ErrDecompressedTooLarge;
+// a limit <= 0 disables the check.
+func Decompress(r io.Reader, limit int64) (io.ReadCloser, Compression, error) {
+	br := bufio.NewReaderSize(r, 64<<10)
+
+	head, err := br.Peek(len(magicZstd))
+	if err != nil && err != io.EOF {
+		return nil, CompressionNone, err
+	}
+
+	c := DetectCompression(head)
+
+	var dec io.ReadCloser
+	switch c {
+	case CompressionGzip:
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, c, fmt.Errorf("gzip: %w", err)
+		}
+		dec = zr
+	case CompressionBzip2:
+		dec = io.NopCloser(bzip2.NewReader(br))
+	case CompressionZstd:
+		zr, err := zstd.NewReader(br, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, c, fmt.Errorf("zstd: %w", err)
+		}
+		dec = zr.IOReadCloser()
+	default:
+		return io.NopCloser(br), c, nil
+	}
+
+	if limit > 0 {
+		dec = &capReader{ReadCloser: dec, left: limit}
+	}
+	return dec, c, nil
+}
+
+// capReader fails, instead of truncating like io.LimitReader, once more than
+// left bytes have been read.
+type capReader struct {
+	io.ReadCloser
+	left int64
+}
+
+func (r *capReader) Read(p []byte) (int, error) {
+	if r.left < 0 {
+		return 0, ErrDecompressedTooLarge
+	}
+	if int64(len(p)) > r.left+1 {
+		p = p[:r.left+1]
+	}
+	n, err := r.ReadCloser.Read(p)
+	r.left -= int64(n)
+	if r.left < 0 {
+		return n, ErrDecompressedTooLarge
+	}
+	return n, err
+}
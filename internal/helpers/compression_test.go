@@ -0,0 +1,89 @@
+package helpers
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/require"
+)
+
+func gzipMembers(t *testing.T, members ...string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	for _, m := range members {
+		zw := gzip.NewWriter(&buf)
+		_, err := zw.Write([]byte(m))
+		require.NoError(t, err)
+		require.NoError(t, zw.Close())
+	}
+	return buf.Bytes()
+}
+
+func zstdFrame(t *testing.T, s string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	zw, err := zstd.NewWriter(&buf)
+	require.NoError(t, err)
+	_, err = zw.Write([]byte(s))
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+// bzip2Hello is "hello\n" compressed with bzip2 -9.
+var bzip2Hello = []byte{
+	0x42, 0x5a, 0x68, 0x39, 0x31, 0x41, 0x59, 0x26, 0x53, 0x59, 0xc1, 0xc0, 0x80, 0xe2, 0x00, 0x00,
+	0x01, 0x41, 0x00, 0x00, 0x10, 0x02, 0x44, 0xa0, 0x00, 0x30, 0xcd, 0x00, 0xc3, 0x46, 0x29, 0x97,
+	0x17, 0x72, 0x45, 0x38, 0x50, 0x90, 0xc1, 0xc0, 0x80, 0xe2,
+}
+
+func TestDecompress(t *testing.T) {
+	tests := []struct {
+		name  string
+		input []byte
+		want  string
+		kind  Compression
+	}{
+		{"plain", []byte(">r\nACGT\n"), ">r\nACGT\n", CompressionNone},
+		{"empty", nil, "", CompressionNone},
+		{"gzip", gzipMembers(t, ">r\nACGT\n"), ">r\nACGT\n", CompressionGzip},
+		{"bgzip members", gzipMembers(t, ">r\nAC", "GT\n", ""), ">r\nACGT\n", CompressionGzip},
+		{"bzip2", bzip2Hello, "hello\n", CompressionBzip2},
+		{"zstd", zstdFrame(t, ">r\nACGT\n"), ">r\nACGT\n", CompressionZstd},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, kind, err := Decompress(bytes.NewReader(tt.input), 1<<20)
+			require.NoError(t, err)
+			defer r.Close()
+
+			got, err := io.ReadAll(r)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, string(got))
+			require.Equal(t, tt.kind, kind)
+		})
+	}
+}
+
+func TestDecompress_Limit(t *testing.T) {
+	data := strings.Repeat("A", 1000)
+
+	r, _, err := Decompress(bytes.NewReader(gzipMembers(t, data)), 999)
+	require.NoError(t, err)
+	_, err = io.ReadAll(r)
+	require.True(t, errors.Is(err, ErrDecompressedTooLarge), "got %v", err)
+
+	r, _, err = Decompress(bytes.NewReader(gzipMembers(t, data)), 1000)
+	require.NoError(t, err)
+	got, err := io.ReadAll(r)
+	require.NoError(t, err)
+	require.Len(t, got, 1000)
+}
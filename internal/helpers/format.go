@@ -0,0 +1,190 @@
+package helpers
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+)
+
+// Format is the content class of an upload.
+type Format string
+
+const (
+	FormatFASTA      Format = "fasta"
+	FormatFASTQ      Format = "fastq"
+	FormatGenBank    Format = "genbank"
+	FormatEMBL       Format = "embl"
+	Format2bit       Format = "2bit"
+	FormatCompressed Format = "compressed" // compressed with an unsupported or corrupt codec
+	FormatBinary     Format = "binary"
+	FormatUnknown    Format = "unknown" // text that is not a known sequence format
+)
+
+// Description returns a human readable name of the format for error messages.
+func (f Format) Description() string {
+	switch f {
+	case FormatFASTA:
+		return "FASTA"
+	case FormatFASTQ:
+		return "FASTQ"
+	case FormatGenBank:
+		return "GenBank flat file"
+	case FormatEMBL:
+		return "EMBL flat file"
+	case Format2bit:
+		return "UCSC 2bit"
+	case FormatCompressed:
+		return "unsupported or corrupt compressed data"
+	case FormatBinary:
+		return "binary data"
+	default:
+		return "unrecognized text"
+	}
+}
+
+// Detection is the result of sniffing an upload.
+type Detection struct {
+	Format      Format
+	Compression Compression // codec the content is wrapped in, CompressionNone if plain
+}
+
+// sniffSize is the number of leading (decompressed) bytes that are classified.
+const sniffSize = 4 << 10
+
+// peekSize is the number of raw bytes read for sniffing. Compressed content is
+// classified by what these bytes decompress to; it is large enough to hold a
+// whole bzip2 block of typical sequence data.
+const peekSize = 1 << 20
+
+// Magic numbers of formats that are recognized but not decoded.
+var (
+	magic2bitLE = []byte{0x43, 0x27, 0x41, 0x1a}
+	magic2bitBE = []byte{0x1a, 0x41, 0x27, 0x43}
+
+	unsupportedArchives = [][]byte{
+		{0xfd, '7', 'z', 'X', 'Z', 0x00}, // xz
+		{'P', 'K', 0x03, 0x04},           // zip
+		{'7', 'z', 0xbc, 0xaf, 0x27, 0x1c},
+		{0x04, 0x22, 0x4d, 0x18}, // lz4
+	}
+)
+
+// SniffReader classifies the content of r from its first bytes. Data
+// compressed with a supported codec is classified by its decompressed content.
+// The returned reader yields the whole stream, sniffed bytes included.
+func SniffReader(r io.Reader) (Detection, io.Reader, error) {
+	br := bufio.NewReaderSize(r, peekSize)
+	head, err := br.Peek(peekSize)
+	if err != nil && err != io.EOF {
+		return Detection{}, nil, err
+	}
+	return sniffHead(head), br, nil
+}
+
+func sniffHead(head []byte) Detection {
+	det := Detection{Compression: DetectCompression(head)}
+	if det.Compression == CompressionNone {
+		det.Format = classify(head[:min(len(head), sniffSize)])
+		return det
+	}
+
+	// head may end in the middle of the compressed stream, so a decoding error
+	// only matters if nothing could be decompressed before it.
+	var inner []byte
+	dec, _, err := Decompress(bytes.NewReader(head), 0)
+	if err == nil {
+		inner, err = readHead(dec, sniffSize)
+		dec.Close()
+	}
+	if len(inner) == 0 && err != nil {
+		det.Format = FormatCompressed
+		return det
+	}
+	det.Format = classify(inner)
+	return det
+}
+
+// readHead reads up to n bytes. A short stream is not an error; the bytes read
+// before any other error are returned with it.
+func readHead(r io.Reader, n int) ([]byte, error) {
+	buf := make([]byte, n)
+	m, err := io.ReadFull(r, buf)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		err = nil
+	}
+	return buf[:m], err
+}
+
+// classify inspects decompressed leading bytes.
+func classify(head []byte) Format {
+	if bytes.HasPrefix(head, magic2bitLE) || bytes.HasPrefix(head, magic2bitBE) {
+		return Format2bit
+	}
+	for _, m := range unsupportedArchives {
+		if bytes.HasPrefix(head, m) {
+			return FormatCompressed
+		}
+	}
+	if DetectCompression(head) != CompressionNone {
+		// compressed twice, e.g. a .gz inside a .gz
+		return FormatCompressed
+	}
+	if isBinary(head) {
+		return FormatBinary
+	}
+
+	text := bytes.TrimLeft(head, " \t\r\n")
+	switch {
+	case len(text) == 0, text[0] == '>':
+		return FormatFASTA
+	case text[0] == '@':
+		if looksLikeFASTQ(text) {
+			return FormatFASTQ
+		}
+		return FormatUnknown
+	case bytes.HasPrefix(text, []byte("LOCUS ")):
+		return FormatGenBank
+	case bytes.HasPrefix(text, []byte("ID   ")):
+		return FormatEMBL
+	case bytes.IndexFunc(text, func(r rune) bool { return !isSequenceChar(r) }) < 0:
+		// headerless raw sequence
+		return FormatFASTA
+	default:
+		return FormatUnknown
+	}
+}
+
+// looksLikeFASTQ checks that the third line of the first record, when it is
+// within the sniffed bytes, is a '+' separator.
+func looksLikeFASTQ(text []byte) bool {
+	lines := bytes.SplitN(text, []byte{'\n'}, 4)
+	if len(lines) < 3 {
+		return true
+	}
+	return bytes.HasPrefix(lines[2], []byte{'+'})
+}
+
+// isBinary reports whether head contains NUL bytes or mostly non-text bytes.
+func isBinary(head []byte) bool {
+	if bytes.IndexByte(head, 0) >= 0 {
+		return true
+	}
+
+	control := 0
+	for _, b := range head {
+		if b < 0x20 && b != '\n' && b != '\r' && b != '\t' && b != '\f' {
+			control++
+		}
+	}
+	return control*10 > len(head)
+}
+
+func isSequenceChar(r rune) bool {
+	switch {
+	case r >= 'A' && r <= 'Z', r >= 'a' && r <= 'z':
+		return true
+	case r == '-', r == '.', r == '*', r == '\n', r == '\r', r == ' ', r == '\t':
+		return true
+	}
+	return false
+}
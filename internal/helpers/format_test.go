@@ -0,0 +1,78 @@
+package helpers
+
+import (
+	"bytes"
+	"io"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSniffReader_Formats(t *testing.T) {
+	tests := []struct {
+		name    string
+		content []byte
+		format  Format
+	}{
+		{"fasta", []byte(">seq1 test\nACGT\n"), FormatFASTA},
+		{"fasta leading blank lines", []byte("\n\n>seq1\nACGT\n"), FormatFASTA},
+		{"raw sequence", []byte("ACGTNNacgt\nACGT\n"), FormatFASTA},
+		{"empty", nil, FormatFASTA},
+		{"fastq", []byte("@r1\nACGT\n+\nIIII\n"), FormatFASTQ},
+		{"at sign without separator", []byte("@user hello\nsome text\nmore text\n"), FormatUnknown},
+		{"genbank", []byte("LOCUS       NC_000913 4641652 bp    DNA     circular BCT 09-MAR-2022\n"), FormatGenBank},
+		{"embl", []byte("ID   X56734; SV 1; linear; mRNA; STD; PLN; 1859 BP.\nXX\n"), FormatEMBL},
+		{"2bit little endian", []byte{0x43, 0x27, 0x41, 0x1a, 0, 0, 0, 0}, Format2bit},
+		{"2bit big endian", []byte{0x1a, 0x41, 0x27, 0x43, 0, 0, 0, 0}, Format2bit},
+		{"zip", []byte("PK\x03\x04\x14\x00rest"), FormatCompressed},
+		{"xz", []byte{0xfd, '7', 'z', 'X', 'Z', 0x00, 1, 2}, FormatCompressed},
+		{"corrupt gzip", []byte{0x1f, 0x8b, 0xff, 0xff}, FormatCompressed},
+		{"binary", []byte{0x89, 'P', 'N', 'G', '\r', '\n', 0x1a, '\n', 0, 0, 0, 0x0d}, FormatBinary},
+		{"text", []byte("hello, world: 42\n"), FormatUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			det, _, err := SniffReader(bytes.NewReader(tt.content))
+			require.NoError(t, err)
+			require.Equal(t, tt.format, det.Format)
+		})
+	}
+}
+
+func TestSniffReader_CompressedGenBank(t *testing.T) {
+	det, _, err := SniffReader(bytes.NewReader(zstdFrame(t, "LOCUS       X 10 bp DNA\n")))
+	require.NoError(t, err)
+	require.Equal(t, Detection{Format: FormatGenBank, Compression: CompressionZstd}, det)
+}
+
+func TestSniffReader(t *testing.T) {
+	// Random bases barely compress below 2 bits each, so the gzip stream is
+	// longer than the peeked head and is cut off mid-block.
+	rng := rand.New(rand.NewSource(1))
+	seq := make([]byte, 6<<20)
+	for i := range seq {
+		seq[i] = "ACGT"[rng.Intn(4)]
+	}
+	data := gzipMembers(t, ">chr1\n"+string(seq)+"\n")
+	require.Greater(t, len(data), peekSize)
+
+	det, r, err := SniffReader(bytes.NewReader(data))
+	require.NoError(t, err)
+	require.Equal(t, Detection{Format: FormatFASTA, Compression: CompressionGzip}, det)
+
+	got, err := io.ReadAll(r)
+	require.NoError(t, err)
+	require.Equal(t, data, got, "SniffReader must replay the sniffed bytes")
+}
+
+func TestSniffReader_Short(t *testing.T) {
+	det, r, err := SniffReader(bytes.NewReader([]byte("@r\nACGT\n+\nIIII\n")))
+	require.NoError(t, err)
+	require.Equal(t, FormatFASTQ, det.Format)
+
+	got, err := io.ReadAll(r)
+	require.NoError(t, err)
+	require.Equal(t, "@r\nACGT\n+\nIIII\n", string(got))
+}
@@ -0,0 +1,12 @@
+package helpers
+
+import "net/url"
+
+// QueryDefault returns the first value of key, or def if the key is absent,
+// like gin.Context.DefaultQuery.
+func QueryDefault(q url.Values, key, def string) string {
+	if v, ok := q[key]; ok && len(v) > 0 {
+		return v[0]
+	}
+	return def
+}
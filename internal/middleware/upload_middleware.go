@@ -6,17 +6,20 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-// MaxUploadSizeMiddleware restricts the maximum size of the request body.
+// MaxUploadSizeMiddleware restricts the maximum size of the request body. The
+// body is not read here: handlers stream it and get an *http.MaxBytesError once
+// more than maxSize bytes arrive. Requests that declare a larger Content-Length
+// are rejected up front.
 func MaxUploadSizeMiddleware(maxSize int64) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
-		ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxSize)
-
-		if err := ctx.Request.ParseMultipartForm(maxSize); err != nil {
+		if ctx.Request.ContentLength > maxSize {
 			ctx.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "File size exceeds the maximum allowed limit."})
 			ctx.Abort()
 			return
 		}
 
+		ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxSize)
+
 		ctx.Next()
 	}
 }
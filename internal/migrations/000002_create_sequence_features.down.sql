@@ -0,0 +1 @@
+DROP TABLE IF EXISTS sequence_features;
\ No newline at end of file
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS sequence_features (
+  id SERIAL PRIMARY KEY,
+  sequence_id INT NOT NULL REFERENCES sequences(id) ON DELETE CASCADE,
+  key TEXT NOT NULL,                -- Feature key (source, gene, CDS, ...)
+  location TEXT NOT NULL,           -- INSDC location, e.g. complement(12..2189)
+  qualifiers JSONB                  -- [{"name": "gene", "value": "recA"}, ...]
+);
+
+CREATE INDEX IF NOT EXISTS idx_sequence_features_sequence_id ON sequence_features (sequence_id);
@@ -0,0 +1,28 @@
+CREATE TABLE IF NOT EXISTS jobs (
+  id UUID PRIMARY KEY,
+  analysis TEXT NOT NULL,                  -- count, gc-profile, kmers, dinucleotides, cpg-islands
+  params JSONB NOT NULL DEFAULT '{}',      -- query parameters, {"precision": ["3"]}
+  filename TEXT NOT NULL DEFAULT '',
+  format TEXT NOT NULL,                    -- sniffed upload format (fasta, fastq, 2bit)
+  size BIGINT NOT NULL DEFAULT 0,          -- upload size in bytes
+  status TEXT NOT NULL DEFAULT 'queued',   -- queued, running, succeeded, failed, cancelled
+  attempts INT NOT NULL DEFAULT 0,         -- times claimed by a worker; fences stale workers
+  bytes_read BIGINT NOT NULL DEFAULT 0,
+  records BIGINT NOT NULL DEFAULT 0,
+  result JSONB,
+  error TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  started_at TIMESTAMPTZ,
+  finished_at TIMESTAMPTZ,
+  heartbeat_at TIMESTAMPTZ                 -- a running job whose heartbeat is too old is claimed again
+);
+
+-- Workers look for the oldest job that is queued or running.
+CREATE INDEX IF NOT EXISTS idx_jobs_pending ON jobs (created_at) WHERE status IN ('queued', 'running');
+
+CREATE TABLE IF NOT EXISTS job_chunks (
+  job_id UUID NOT NULL REFERENCES jobs(id) ON DELETE CASCADE,
+  seq INT NOT NULL,                        -- position of the chunk in the upload, from 0
+  data BYTEA NOT NULL,
+  PRIMARY KEY (job_id, seq)
+);
@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS webhook_attempts;
+DROP TABLE IF EXISTS webhook_deliveries;
+
+ALTER TABLE jobs
+  DROP COLUMN IF EXISTS callback_client,
+  DROP COLUMN IF EXISTS callback_url;
@@ -0,0 +1,34 @@
+ALTER TABLE jobs
+  ADD COLUMN IF NOT EXISTS callback_url TEXT NOT NULL DEFAULT '',     -- POSTed to when the job finishes
+  ADD COLUMN IF NOT EXISTS callback_client TEXT NOT NULL DEFAULT '';  -- whose secret signs the callback
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+  id UUID PRIMARY KEY,
+  job_id UUID NOT NULL REFERENCES jobs(id) ON DELETE CASCADE,
+  client TEXT NOT NULL,
+  url TEXT NOT NULL,
+  event TEXT NOT NULL,                     -- job.succeeded, job.failed
+  payload JSONB NOT NULL,                  -- request body, signed when sent
+  status TEXT NOT NULL DEFAULT 'pending',  -- pending, delivered, failed
+  attempts INT NOT NULL DEFAULT 0,         -- since created or replayed; fences stale dispatchers
+  next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  delivered_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_job_id ON webhook_deliveries (job_id);
+-- Dispatchers look for the pending delivery that has been due longest.
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
+
+CREATE TABLE IF NOT EXISTS webhook_attempts (
+  id SERIAL PRIMARY KEY,
+  delivery_id UUID NOT NULL REFERENCES webhook_deliveries(id) ON DELETE CASCADE,
+  attempt INT NOT NULL,
+  status_code INT NOT NULL DEFAULT 0,      -- 0 if there was no response
+  error TEXT NOT NULL DEFAULT '',
+  response TEXT NOT NULL DEFAULT '',       -- start of the response body
+  duration_ms BIGINT NOT NULL DEFAULT 0,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_attempts_delivery_id ON webhook_attempts (delivery_id);
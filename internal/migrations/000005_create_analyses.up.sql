@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS analyses (
+  id UUID PRIMARY KEY,
+  analysis TEXT NOT NULL,                  -- count, gc-profile, kmers, dinucleotides, cpg-islands
+  params JSONB NOT NULL DEFAULT '{}',      -- query parameters, {"precision": ["3"]}
+  filename TEXT NOT NULL DEFAULT '',
+  size BIGINT NOT NULL,                    -- upload size in bytes, as sent (compressed or not)
+  sha256 TEXT NOT NULL,                    -- hex digest of the upload, as sent
+  format TEXT NOT NULL,                    -- sniffed upload format (fasta, fastq, 2bit)
+  started_at TIMESTAMPTZ NOT NULL,
+  finished_at TIMESTAMPTZ NOT NULL,
+  duration_ms BIGINT NOT NULL,
+  result JSONB NOT NULL,                   -- response body of the analysis
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- History is listed newest first, optionally by file.
+CREATE INDEX IF NOT EXISTS idx_analyses_created_at ON analyses (created_at);
+CREATE INDEX IF NOT EXISTS idx_analyses_sha256 ON analyses (sha256);
+CREATE INDEX IF NOT EXISTS idx_analyses_filename ON analyses (filename);
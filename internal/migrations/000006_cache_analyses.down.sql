@@ -0,0 +1,3 @@
+ALTER TABLE jobs DROP COLUMN IF EXISTS sha256;
+
+ALTER TABLE analyses ALTER COLUMN result TYPE JSONB USING result::jsonb;
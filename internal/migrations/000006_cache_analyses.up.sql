@@ -0,0 +1,6 @@
+-- Cached results are served as stored: json keeps them byte for byte, so that
+-- a cached response is identical to the fresh one and its ETag can be strong.
+ALTER TABLE analyses ALTER COLUMN result TYPE JSON USING result::json;
+
+-- Digest of a job's upload, so that its analysis can be looked up in the cache.
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS sha256 TEXT NOT NULL DEFAULT '';
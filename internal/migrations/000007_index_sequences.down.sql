@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_sequences_accession_prefix;
+DROP INDEX IF EXISTS idx_sequences_chromosome;
+DROP INDEX IF EXISTS idx_sequences_species;
@@ -0,0 +1,4 @@
+CREATE INDEX IF NOT EXISTS idx_sequences_species ON sequences (LOWER(species));
+CREATE INDEX IF NOT EXISTS idx_sequences_chromosome ON sequences (chromosome);
+-- Serves accession prefix searches (LIKE 'CM034%') whatever the collation.
+CREATE INDEX IF NOT EXISTS idx_sequences_accession_prefix ON sequences (accession text_pattern_ops);
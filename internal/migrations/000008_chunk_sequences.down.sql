@@ -0,0 +1,15 @@
+-- Chunks cannot be decompressed in SQL: refuse to lose the bases of chunked
+-- sequences.
+DO $$
+BEGIN
+  IF EXISTS (SELECT 1 FROM sequences WHERE chunk_size > 0) THEN
+    RAISE EXCEPTION 'sequences are stored in chunks; export them before migrating down';
+  END IF;
+END
+$$;
+
+DROP TABLE IF EXISTS sequence_chunks;
+
+ALTER TABLE sequences
+  DROP COLUMN IF EXISTS chunk_size,
+  DROP COLUMN IF EXISTS length;
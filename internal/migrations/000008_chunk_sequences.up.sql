@@ -0,0 +1,18 @@
+-- Bases are stored in zstd-compressed chunks of chunk_size bases, so that a
+-- range is read without loading the whole sequence. Rows imported before keep
+-- theirs in the sequence column, with chunk_size 0.
+ALTER TABLE sequences
+  ADD COLUMN IF NOT EXISTS length INT NOT NULL DEFAULT 0,      -- number of bases
+  ADD COLUMN IF NOT EXISTS chunk_size INT NOT NULL DEFAULT 0;  -- bases per chunk, 0 if not chunked
+
+UPDATE sequences SET length = LENGTH(sequence);
+
+CREATE TABLE IF NOT EXISTS sequence_chunks (
+  sequence_id INT NOT NULL REFERENCES sequences(id) ON DELETE CASCADE,
+  seq INT NOT NULL,                 -- chunk n holds bases [n * chunk_size, (n + 1) * chunk_size)
+  data BYTEA NOT NULL,              -- zstd-compressed bases
+  PRIMARY KEY (sequence_id, seq)
+);
+
+-- The chunks are compressed already.
+ALTER TABLE sequence_chunks ALTER COLUMN data SET STORAGE EXTERNAL;
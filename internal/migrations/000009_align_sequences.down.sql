@@ -0,0 +1,12 @@
+ALTER TABLE sequences
+  DROP COLUMN IF EXISTS updated_at,
+  ALTER COLUMN created_at DROP NOT NULL,
+  ALTER COLUMN created_at TYPE TIMESTAMP USING created_at::timestamp;
+
+DROP INDEX IF EXISTS idx_sequences_accession;
+
+ALTER TABLE sequences ALTER COLUMN start_position DROP NOT NULL;
+
+ALTER TABLE sequences ADD COLUMN IF NOT EXISTS end_position INT;
+UPDATE sequences SET end_position = length;
+ALTER TABLE sequences ALTER COLUMN end_position SET NOT NULL;
@@ -0,0 +1,38 @@
+-- Align sequences with models.Sequence.
+
+-- The end position is start_position + length - 1; nothing ever stored it.
+ALTER TABLE sequences DROP COLUMN IF EXISTS end_position;
+
+UPDATE sequences SET start_position = 1 WHERE start_position IS NULL;
+ALTER TABLE sequences ALTER COLUMN start_position SET NOT NULL;
+
+-- Accessions are unique: importing a stored accession is a conflict. Rows
+-- imported twice before cannot be told apart safely: list them, to be deleted
+-- or renamed by hand, rather than fail on the index.
+DO $$
+DECLARE
+  dups TEXT;
+  n INT;
+BEGIN
+  SELECT string_agg(format('%s (%s rows)', accession, copies), ', ' ORDER BY accession), COUNT(*)
+    INTO dups, n
+    FROM (
+      SELECT accession, COUNT(*) AS copies FROM sequences GROUP BY accession HAVING COUNT(*) > 1
+      ORDER BY accession LIMIT 100
+    ) d;
+  IF n > 0 THEN
+    RAISE EXCEPTION 'duplicate sequence accessions: %', dups
+      USING HINT = 'keep one row per accession, then migrate again; at most 100 accessions are listed';
+  END IF;
+END
+$$;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_sequences_accession ON sequences (accession);
+
+-- Timestamps are TIMESTAMPTZ like those of the other tables. created_at was
+-- only ever set by its default, NOW() in the session time zone.
+UPDATE sequences SET created_at = NOW() WHERE created_at IS NULL;
+ALTER TABLE sequences
+  ALTER COLUMN created_at TYPE TIMESTAMPTZ USING created_at::timestamptz,
+  ALTER COLUMN created_at SET NOT NULL,
+  ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
+UPDATE sequences SET updated_at = created_at;
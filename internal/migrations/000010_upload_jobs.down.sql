@@ -0,0 +1,5 @@
+-- Releases before this one know nothing of uploading jobs: drop them with
+-- their chunks.
+DELETE FROM jobs WHERE status = 'uploading';
+
+DROP INDEX IF EXISTS idx_jobs_uploading;
@@ -0,0 +1,4 @@
+-- Uploads are committed chunk by chunk while their job is 'uploading', which
+-- workers do not claim. Uploads left behind by a stopped replica are found by
+-- their age.
+CREATE INDEX IF NOT EXISTS idx_jobs_uploading ON jobs (created_at) WHERE status = 'uploading';
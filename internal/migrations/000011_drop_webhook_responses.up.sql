@@ -0,0 +1,4 @@
+-- Callbacks may be sent to hosts that the client registering them cannot
+-- reach itself; their responses are not kept, so that the API does not relay
+-- them.
+ALTER TABLE webhook_attempts DROP COLUMN IF EXISTS response;
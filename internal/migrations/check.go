@@ -0,0 +1,190 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/Viktor2805/nucount/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// ErrSchemaDrift is returned by Check when the schema is not the one the
+// models expect.
+var ErrSchemaDrift = errors.New("database schema does not match the models")
+
+// ErrNotBackfilled is returned by Check when sequences imported before
+// sequences were stored in chunks still keep their bases in the sequence
+// column, which nothing reads any more.
+var ErrNotBackfilled = errors.New("sequences are not stored in chunks")
+
+// Models are the models stored in the database, which Check compares the
+// schema with.
+var Models = []any{
+	&models.Sequence{},
+	&models.Feature{},
+	&models.SequenceChunk{},
+	&models.Job{},
+	&models.JobChunk{},
+	&models.WebhookDelivery{},
+	&models.WebhookAttempt{},
+	&models.Analysis{},
+}
+
+// columnTypes are the column types a field of each data type is read from and
+// written to. Fields with a type tag, e.g. uuid or jsonb, expect that type.
+var columnTypes = map[schema.DataType][]string{
+	schema.Bool:   {"boolean"},
+	schema.Int:    {"smallint", "integer", "bigint"},
+	schema.Uint:   {"smallint", "integer", "bigint"},
+	schema.Float:  {"real", "double precision", "numeric"},
+	schema.String: {"text", "character varying", "character"},
+	schema.Time:   {"timestamp with time zone"},
+	schema.Bytes:  {"bytea"},
+}
+
+// column is a row of information_schema.columns.
+type column struct {
+	Name     string  `gorm:"column:column_name"`
+	DataType string  `gorm:"column:data_type"`
+	Nullable string  `gorm:"column:is_nullable"`
+	Default  *string `gorm:"column:column_default"`
+	Identity string  `gorm:"column:is_identity"`
+}
+
+// Check compares the schema with the migrations and Models. Every migration
+// must have been applied; every column of a model must exist with a type its
+// field can be read from and written to; every other column must be nullable
+// or have a default, or inserts would fail; and every unique index of a model
+// must exist. The error lists every difference. A schema that matches is
+// then checked for sequences waiting to be backfilled, see ErrNotBackfilled.
+func (m *Migrator) Check(ctx context.Context) error {
+	db := m.db.WithContext(ctx)
+
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return err
+	}
+	var problems []string
+	for _, s := range statuses {
+		if s.AppliedAt == nil {
+			problems = append(problems, fmt.Sprintf("migration %d_%s is not applied", s.Version, s.Name))
+		}
+	}
+
+	for _, model := range Models {
+		found, err := checkModel(db, model)
+		if err != nil {
+			return err
+		}
+		problems = append(problems, found...)
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %s", ErrSchemaDrift, strings.Join(problems, "; "))
+	}
+
+	var legacy int64
+	if err := db.Model(&models.Sequence{}).Where("chunk_size = 0").Count(&legacy).Error; err != nil {
+		return err
+	}
+	if legacy > 0 {
+		return fmt.Errorf("%w: %d sequences, see app migrate backfill", ErrNotBackfilled, legacy)
+	}
+	return nil
+}
+
+func checkModel(db *gorm.DB, model any) ([]string, error) {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(model); err != nil {
+		return nil, err
+	}
+	sch := stmt.Schema
+
+	var columns []column
+	err := db.Raw(`SELECT column_name, data_type, is_nullable, column_default, is_identity
+		FROM information_schema.columns
+		WHERE table_schema = current_schema() AND table_name = ?`, sch.Table).
+		Scan(&columns).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(columns) == 0 {
+		return []string{fmt.Sprintf("table %s does not exist", sch.Table)}, nil
+	}
+
+	var problems []string
+	byName := make(map[string]column, len(columns))
+	for _, c := range columns {
+		byName[c.Name] = c
+	}
+	for _, f := range sch.Fields {
+		if f.DBName == "" {
+			continue
+		}
+		c, ok := byName[f.DBName]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("column %s.%s does not exist", sch.Table, f.DBName))
+			continue
+		}
+		if !compatible(f.DataType, c.DataType) {
+			problems = append(problems, fmt.Sprintf("column %s.%s is %s, the model expects %s", sch.Table, f.DBName, c.DataType, f.DataType))
+		}
+	}
+	for _, c := range columns {
+		if _, ok := sch.FieldsByDBName[c.Name]; ok {
+			continue
+		}
+		if c.Nullable == "NO" && c.Default == nil && c.Identity != "YES" {
+			problems = append(problems, fmt.Sprintf("column %s.%s is NOT NULL without a default but not in the model", sch.Table, c.Name))
+		}
+	}
+
+	unique, err := uniqueIndexes(db, sch.Table)
+	if err != nil {
+		return nil, err
+	}
+	for _, idx := range sch.ParseIndexes() {
+		if idx.Class != "UNIQUE" {
+			continue
+		}
+		names := make([]string, len(idx.Fields))
+		for i, f := range idx.Fields {
+			names[i] = f.DBName
+		}
+		if !slices.Contains(unique, strings.Join(names, ",")) {
+			problems = append(problems, fmt.Sprintf("unique index %s on %s (%s) does not exist", idx.Name, sch.Table, strings.Join(names, ", ")))
+		}
+	}
+	return problems, nil
+}
+
+// compatible reports whether a field of data type field can be stored in a
+// column of type column.
+func compatible(field schema.DataType, column string) bool {
+	if types, ok := columnTypes[field]; ok {
+		return slices.Contains(types, column)
+	}
+	return strings.EqualFold(string(field), column)
+}
+
+// uniqueIndexes returns the columns of each unique index of a table that is
+// neither partial nor on expressions, comma-separated.
+func uniqueIndexes(db *gorm.DB, table string) ([]string, error) {
+	var indexes []string
+	err := db.Raw(`SELECT string_agg(a.attname, ',' ORDER BY k.ord)
+		FROM pg_index i
+		JOIN pg_class t ON t.oid = i.indrelid
+		JOIN pg_namespace n ON n.oid = t.relnamespace
+		CROSS JOIN LATERAL unnest(i.indkey) WITH ORDINALITY AS k(attnum, ord)
+		JOIN pg_attribute a ON a.attrelid = t.oid AND a.attnum = k.attnum
+		WHERE n.nspname = current_schema() AND t.relname = ? AND i.indisunique
+			AND i.indpred IS NULL AND i.indexprs IS NULL
+		GROUP BY i.indexrelid`, table).
+		Scan(&indexes).Error
+	return indexes, err
+}
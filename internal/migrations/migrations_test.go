@@ -0,0 +1,69 @@
+package migrations
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad(t *testing.T) {
+	migrations, err := Load()
+	require.NoError(t, err)
+	require.NotEmpty(t, migrations)
+
+	for i, m := range migrations {
+		require.Equal(t, i+1, m.Version, "versions are numbered from 1 without gaps")
+		require.NotEmpty(t, m.Up, "%d_%s up", m.Version, m.Name)
+		require.NotEmpty(t, m.Down, "%d_%s down", m.Version, m.Name)
+	}
+	require.Equal(t, "create_sequences", migrations[0].Name)
+}
+
+func TestLoad_Errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		files fstest.MapFS
+		err   string
+	}{
+		{
+			name:  "missing down",
+			files: fstest.MapFS{"000001_a.up.sql": {Data: []byte("SELECT 1;")}},
+			err:   "missing its up or down file",
+		},
+		{
+			name:  "bad name",
+			files: fstest.MapFS{"1-a.up.sql": {Data: []byte("SELECT 1;")}},
+			err:   "name is not",
+		},
+		{
+			name: "two names",
+			files: fstest.MapFS{
+				"000001_a.up.sql":   {Data: []byte("SELECT 1;")},
+				"000001_b.down.sql": {Data: []byte("SELECT 1;")},
+			},
+			err: "named both",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := load(tt.files)
+			require.Error(t, err)
+			require.True(t, strings.Contains(err.Error(), tt.err), err.Error())
+		})
+	}
+}
+
+func TestLoad_Order(t *testing.T) {
+	migrations, err := load(fstest.MapFS{
+		"000010_c.up.sql":   {Data: []byte("SELECT 10;")},
+		"000010_c.down.sql": {},
+		"000002_b.up.sql":   {Data: []byte("SELECT 2;")},
+		"000002_b.down.sql": {Data: []byte("SELECT -2;")},
+	})
+	require.NoError(t, err)
+	require.Len(t, migrations, 2)
+	require.Equal(t, Migration{Version: 2, Name: "b", Up: "SELECT 2;", Down: "SELECT -2;"}, migrations[0])
+	require.Equal(t, 10, migrations[1].Version)
+}
@@ -0,0 +1,224 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// LockID is the key of the Postgres advisory lock held while migrating, so
+// that replicas starting together do not apply the same migration twice.
+const LockID int64 = 0x6d6967726174 // "migrat"
+
+// ErrUnknownVersion is returned when migrating a database down from a version
+// this binary has no migration for.
+var ErrUnknownVersion = errors.New("applied version has no migration")
+
+// Status is a migration and when it was applied, if it was.
+type Status struct {
+	Migration
+	AppliedAt *time.Time
+	// Unknown is set for an applied version this binary has no migration
+	// for, e.g. one applied by a newer release.
+	Unknown bool
+}
+
+// appliedVersion is a row of schema_versions.
+type appliedVersion struct {
+	Version   int       `gorm:"primaryKey"`
+	Name      string    `gorm:"not null"`
+	AppliedAt time.Time `gorm:"not null"`
+}
+
+func (appliedVersion) TableName() string {
+	return "schema_versions"
+}
+
+const createVersionTable = `CREATE TABLE IF NOT EXISTS schema_versions (
+  version INT PRIMARY KEY,
+  name TEXT NOT NULL,
+  applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+)`
+
+// Migrator applies migrations to a database.
+type Migrator struct {
+	db         *gorm.DB
+	migrations []Migration
+	logger     *zap.Logger
+}
+
+// Option configures a Migrator.
+type Option func(*Migrator)
+
+// WithLogger sets the logger of applied and reverted migrations.
+func WithLogger(l *zap.Logger) Option {
+	return func(m *Migrator) {
+		m.logger = l
+	}
+}
+
+// WithMigrations replaces the embedded migrations.
+func WithMigrations(migrations []Migration) Option {
+	return func(m *Migrator) {
+		m.migrations = migrations
+	}
+}
+
+// New returns a Migrator of the embedded migrations.
+func New(db *gorm.DB, opts ...Option) (*Migrator, error) {
+	migrations, err := Load()
+	if err != nil {
+		return nil, err
+	}
+	m := &Migrator{db: db, migrations: migrations, logger: zap.NewNop()}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m, nil
+}
+
+// Up applies the migrations that have not been applied, in order, and returns
+// them. It stops at the first that fails, which is rolled back.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var done []Migration
+	err := m.locked(ctx, func(db *gorm.DB, applied map[int]appliedVersion) error {
+		for _, mig := range m.migrations {
+			if _, ok := applied[mig.Version]; ok {
+				continue
+			}
+			err := db.Transaction(func(tx *gorm.DB) error {
+				if err := tx.Exec(mig.Up).Error; err != nil {
+					return err
+				}
+				return tx.Create(&appliedVersion{Version: mig.Version, Name: mig.Name, AppliedAt: time.Now()}).Error
+			})
+			if err != nil {
+				return fmt.Errorf("migration %d_%s up: %w", mig.Version, mig.Name, err)
+			}
+			m.logger.Info("migration applied", zap.Int("version", mig.Version), zap.String("name", mig.Name))
+			done = append(done, mig)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Down reverts the latest steps applied migrations, latest first, and returns
+// them. It stops at the first that fails, which is rolled back.
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	byVersion := make(map[int]Migration, len(m.migrations))
+	for _, mig := range m.migrations {
+		byVersion[mig.Version] = mig
+	}
+
+	var done []Migration
+	err := m.locked(ctx, func(db *gorm.DB, _ map[int]appliedVersion) error {
+		var versions []appliedVersion
+		if err := db.Order("version DESC").Limit(steps).Find(&versions).Error; err != nil {
+			return err
+		}
+		for _, v := range versions {
+			mig, ok := byVersion[v.Version]
+			if !ok {
+				return fmt.Errorf("%w: %d_%s", ErrUnknownVersion, v.Version, v.Name)
+			}
+			err := db.Transaction(func(tx *gorm.DB) error {
+				if err := tx.Exec(mig.Down).Error; err != nil {
+					return err
+				}
+				return tx.Delete(&appliedVersion{}, mig.Version).Error
+			})
+			if err != nil {
+				return fmt.Errorf("migration %d_%s down: %w", mig.Version, mig.Name, err)
+			}
+			m.logger.Info("migration reverted", zap.Int("version", mig.Version), zap.String("name", mig.Name))
+			done = append(done, mig)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Status returns the migrations and when they were applied, followed by
+// applied versions this binary has no migration for.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	applied, err := m.applied(m.db.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, 0, len(m.migrations))
+	for _, mig := range m.migrations {
+		s := Status{Migration: mig}
+		if v, ok := applied[mig.Version]; ok {
+			s.AppliedAt = &v.AppliedAt
+			delete(applied, mig.Version)
+		}
+		statuses = append(statuses, s)
+	}
+	for _, v := range applied {
+		statuses = append(statuses, Status{Migration: Migration{Version: v.Version, Name: v.Name}, AppliedAt: &v.AppliedAt, Unknown: true})
+	}
+	unknown := statuses[len(m.migrations):]
+	slices.SortFunc(unknown, func(a, b Status) int { return a.Version - b.Version })
+	return statuses, nil
+}
+
+// applied returns the applied versions.
+func (m *Migrator) applied(db *gorm.DB) (map[int]appliedVersion, error) {
+	applied := map[int]appliedVersion{}
+	if !db.Migrator().HasTable(&appliedVersion{}) {
+		return applied, nil
+	}
+	var versions []appliedVersion
+	if err := db.Order("version").Find(&versions).Error; err != nil {
+		return nil, err
+	}
+	for _, v := range versions {
+		applied[v.Version] = v
+	}
+	return applied, nil
+}
+
+// locked runs fn on a connection holding the migration lock, with the
+// versions applied once the lock was taken.
+func (m *Migrator) locked(ctx context.Context, fn func(db *gorm.DB, applied map[int]appliedVersion) error) error {
+	return m.db.WithContext(ctx).Connection(func(db *gorm.DB) (err error) {
+		// Each query on db starts afresh, as on m.db.
+		db = db.Session(&gorm.Session{})
+		if err := db.Exec("SELECT pg_advisory_lock(?)", LockID).Error; err != nil {
+			return fmt.Errorf("migration lock: %w", err)
+		}
+		defer func() {
+			// The lock belongs to the session, which outlives the connection's
+			// return to the pool: unlock even if ctx is done, or else close
+			// the connection.
+			uerr := db.WithContext(context.Background()).Exec("SELECT pg_advisory_unlock(?)", LockID).Error
+			if uerr == nil {
+				return
+			}
+			if conn, ok := db.Statement.ConnPool.(*sql.Conn); ok {
+				_ = conn.Raw(func(any) error { return driver.ErrBadConn })
+			}
+			if err == nil {
+				err = fmt.Errorf("migration unlock: %w", uerr)
+			}
+		}()
+
+		if err := db.Exec(createVersionTable).Error; err != nil {
+			return err
+		}
+		applied, err := m.applied(db)
+		if err != nil {
+			return err
+		}
+		return fn(db, applied)
+	})
+}
@@ -0,0 +1,122 @@
+package migrations_test
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/Viktor2805/nucount/internal/migrations"
+	"github.com/Viktor2805/nucount/internal/testutil"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrator(t *testing.T) {
+	db := testutil.Postgres(t)
+	ctx := context.Background()
+	m, err := migrations.New(db)
+	require.NoError(t, err)
+	all, err := migrations.Load()
+	require.NoError(t, err)
+
+	require.ErrorIs(t, m.Check(ctx), migrations.ErrSchemaDrift, "nothing applied")
+
+	// Replicas starting together apply each migration once.
+	var wg sync.WaitGroup
+	applied := make([][]migrations.Migration, 3)
+	errs := make([]error, len(applied))
+	for i := range applied {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			applied[i], errs[i] = m.Up(ctx)
+		}()
+	}
+	wg.Wait()
+	for _, err := range errs {
+		require.NoError(t, err)
+	}
+	require.Equal(t, len(all), len(applied[0])+len(applied[1])+len(applied[2]))
+
+	require.NoError(t, m.Check(ctx))
+
+	statuses, err := m.Status(ctx)
+	require.NoError(t, err)
+	require.Len(t, statuses, len(all))
+	for _, s := range statuses {
+		require.NotNil(t, s.AppliedAt, "%d_%s", s.Version, s.Name)
+	}
+
+	reverted, err := m.Down(ctx, 2)
+	require.NoError(t, err)
+	require.Len(t, reverted, 2)
+	require.Equal(t, all[len(all)-1].Version, reverted[0].Version, "latest first")
+	require.ErrorIs(t, m.Check(ctx), migrations.ErrSchemaDrift)
+
+	reverted, err = m.Down(ctx, len(all))
+	require.NoError(t, err)
+	require.Len(t, reverted, len(all)-2)
+
+	_, err = m.Up(ctx)
+	require.NoError(t, err)
+	require.NoError(t, m.Check(ctx))
+}
+
+func TestMigrator_CheckDrift(t *testing.T) {
+	db := testutil.Postgres(t)
+	ctx := context.Background()
+	m, err := migrations.New(db)
+	require.NoError(t, err)
+	_, err = m.Up(ctx)
+	require.NoError(t, err)
+
+	for _, stmt := range []string{
+		"ALTER TABLE sequences ADD COLUMN end_position INT NOT NULL",
+		"DROP INDEX idx_sequences_accession",
+		"ALTER TABLE sequences ALTER COLUMN created_at TYPE TIMESTAMP",
+		"ALTER TABLE jobs DROP COLUMN sha256",
+	} {
+		require.NoError(t, db.Exec(stmt).Error, stmt)
+	}
+
+	err = m.Check(ctx)
+	require.ErrorIs(t, err, migrations.ErrSchemaDrift)
+	for _, want := range []string{
+		"sequences.end_position is NOT NULL without a default",
+		"unique index idx_sequences_accession",
+		"sequences.created_at is timestamp without time zone",
+		"jobs.sha256 does not exist",
+	} {
+		require.Contains(t, err.Error(), want)
+	}
+}
+
+func TestMigrator_DuplicateAccessions(t *testing.T) {
+	db := testutil.Postgres(t)
+	ctx := context.Background()
+	all, err := migrations.Load()
+	require.NoError(t, err)
+	i := slices.IndexFunc(all, func(m migrations.Migration) bool { return m.Name == "align_sequences" })
+	require.GreaterOrEqual(t, i, 0)
+
+	before, err := migrations.New(db, migrations.WithMigrations(all[:i]))
+	require.NoError(t, err)
+	_, err = before.Up(ctx)
+	require.NoError(t, err)
+	for _, accession := range []string{"B1", "A1", "B1", "C1", "A1", "B1"} {
+		require.NoError(t, db.Exec("INSERT INTO sequences (accession, sequence, end_position) VALUES (?, 'ACGT', 4)", accession).Error)
+	}
+
+	m, err := migrations.New(db)
+	require.NoError(t, err)
+	_, err = m.Up(ctx)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "duplicate sequence accessions: A1 (2 rows), B1 (3 rows)")
+	require.NotContains(t, err.Error(), "C1")
+
+	require.NoError(t, db.Exec("DELETE FROM sequences WHERE accession IN ('A1', 'B1')").Error)
+	_, err = m.Up(ctx)
+	require.NoError(t, err)
+	require.NoError(t, m.Check(ctx))
+}
@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Analysis is a finished analysis of an uploaded file, kept as history.
+type Analysis struct {
+	ID         uuid.UUID `gorm:"type:uuid;primaryKey" json:"id"`
+	Analysis   string    `gorm:"not null" json:"analysis"`             // e.g. count, kmers
+	Params     JobParams `gorm:"type:jsonb;not null" json:"params"`    // query parameters of the analysis
+	Filename   string    `gorm:"not null;default:''" json:"filename"`  // name of the uploaded file, if known
+	Size       int64     `gorm:"not null" json:"size"`                 // upload size in bytes, as sent
+	SHA256     string    `gorm:"column:sha256;not null" json:"sha256"` // hex digest of the upload, as sent
+	Format     string    `gorm:"not null" json:"format"`               // sniffed upload format
+	StartedAt  time.Time `gorm:"not null" json:"started_at"`
+	FinishedAt time.Time `gorm:"not null" json:"finished_at"`
+	DurationMs int64     `gorm:"not null" json:"duration_ms"`
+	Result     JSON      `gorm:"type:json;not null" json:"result,omitempty"` // response body of the analysis, byte for byte
+	CreatedAt  time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
@@ -0,0 +1,64 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// Feature is one entry of the feature table of a GenBank or EMBL record.
+type Feature struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	SequenceID uint       `gorm:"not null;index" json:"-"`
+	Key        string     `gorm:"not null" json:"key"`      // e.g. source, gene, CDS
+	Location   string     `gorm:"not null" json:"location"` // e.g. complement(join(1..10,20..30))
+	Qualifiers Qualifiers `gorm:"type:jsonb" json:"qualifiers,omitempty"`
+}
+
+func (Feature) TableName() string {
+	return "sequence_features"
+}
+
+// Qualifier is a /name=value pair of a feature. Value is empty for qualifiers
+// without a value such as /pseudo.
+type Qualifier struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// Qualifiers keeps the qualifiers of a feature in file order; a name may occur
+// more than once (e.g. /db_xref).
+type Qualifiers []Qualifier
+
+// Get returns the value of the first qualifier called name.
+func (q Qualifiers) Get(name string) (string, bool) {
+	for _, v := range q {
+		if v.Name == name {
+			return v.Value, true
+		}
+	}
+	return "", false
+}
+
+// Value stores the qualifiers as a JSON array.
+func (q Qualifiers) Value() (driver.Value, error) {
+	if q == nil {
+		return nil, nil
+	}
+	return json.Marshal(q)
+}
+
+// Scan reads qualifiers stored by Value.
+func (q *Qualifiers) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		*q = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(v, q)
+	case string:
+		return json.Unmarshal([]byte(v), q)
+	default:
+		return fmt.Errorf("qualifiers: unsupported type %T", src)
+	}
+}
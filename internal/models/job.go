@@ -0,0 +1,127 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// JobStatus is the state of an analysis job.
+type JobStatus string
+
+const (
+	// JobUploading is the status of a job while its upload is stored; it is
+	// queued once the upload is complete.
+	JobUploading JobStatus = "uploading"
+	JobQueued    JobStatus = "queued"
+	JobRunning   JobStatus = "running"
+	JobSucceeded JobStatus = "succeeded"
+	JobFailed    JobStatus = "failed"
+	JobCancelled JobStatus = "cancelled"
+)
+
+// Job is an analysis of an uploaded file that runs in the background. The
+// upload is kept in job_chunks until the job has finished.
+type Job struct {
+	ID          uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	Analysis    string     `gorm:"not null" json:"analysis"`                        // e.g. count, kmers
+	Params      JobParams  `gorm:"type:jsonb;not null" json:"params"`               // query parameters of the analysis
+	Filename    string     `gorm:"not null;default:''" json:"filename"`             // name of the uploaded file, if known
+	Format      string     `gorm:"not null" json:"format"`                          // sniffed upload format
+	Size        int64      `gorm:"not null" json:"size"`                            // upload size in bytes
+	SHA256      string     `gorm:"column:sha256;not null;default:''" json:"sha256"` // hex digest of the upload
+	Status      JobStatus  `gorm:"not null;default:queued" json:"status"`           // uploading, queued, running, succeeded, failed or cancelled
+	Attempts    int        `gorm:"not null;default:0" json:"attempts"`              // claims, not counting released ones
+	BytesRead   int64      `gorm:"not null;default:0" json:"bytes_read"`            // progress of the current attempt
+	Records     int64      `gorm:"not null;default:0" json:"records"`               // progress of the current attempt
+	Result      JSON       `gorm:"type:jsonb" json:"result,omitempty"`              // response body of the analysis
+	Error       string     `gorm:"not null;default:''" json:"error,omitempty"`      // why the job failed
+	CreatedAt   time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	StartedAt   *time.Time `json:"started_at,omitempty"`  // start of the current attempt
+	FinishedAt  *time.Time `json:"finished_at,omitempty"` // when the job succeeded, failed or was cancelled
+	HeartbeatAt *time.Time `json:"-"`                     // last sign of life of the worker running the job
+
+	CallbackURL    string `gorm:"not null;default:''" json:"callback_url,omitempty"`    // POSTed to when the job finishes
+	CallbackClient string `gorm:"not null;default:''" json:"callback_client,omitempty"` // whose secret signs the callback
+}
+
+// Finished reports whether the job has reached a final status.
+func (j *Job) Finished() bool {
+	return j.Status == JobSucceeded || j.Status == JobFailed || j.Status == JobCancelled
+}
+
+// JobChunk is a piece of the upload of a job. Chunks are numbered from 0.
+type JobChunk struct {
+	JobID uuid.UUID `gorm:"type:uuid;primaryKey"`
+	Seq   int       `gorm:"primaryKey"`
+	Data  []byte    `gorm:"not null"`
+}
+
+// JobParams are the query parameters of a job's analysis, as in url.Values.
+type JobParams map[string][]string
+
+// Value stores the parameters as a JSON object.
+func (p JobParams) Value() (driver.Value, error) {
+	if p == nil {
+		return []byte("{}"), nil
+	}
+	return json.Marshal(p)
+}
+
+// Scan reads parameters stored by Value.
+func (p *JobParams) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		*p = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(v, p)
+	case string:
+		return json.Unmarshal([]byte(v), p)
+	default:
+		return fmt.Errorf("job params: unsupported type %T", src)
+	}
+}
+
+// JSON is an encoded JSON document stored in a jsonb column.
+type JSON json.RawMessage
+
+// Value stores the document as is; an empty document is NULL.
+func (j JSON) Value() (driver.Value, error) {
+	if len(j) == 0 {
+		return nil, nil
+	}
+	return []byte(j), nil
+}
+
+// Scan reads a jsonb column.
+func (j *JSON) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		*j = nil
+	case []byte:
+		*j = append(JSON(nil), v...)
+	case string:
+		*j = JSON(v)
+	default:
+		return fmt.Errorf("json: unsupported type %T", src)
+	}
+	return nil
+}
+
+// MarshalJSON returns the document itself, or null if it is empty.
+func (j JSON) MarshalJSON() ([]byte, error) {
+	if len(j) == 0 {
+		return []byte("null"), nil
+	}
+	return j, nil
+}
+
+// UnmarshalJSON keeps a copy of the document.
+func (j *JSON) UnmarshalJSON(data []byte) error {
+	*j = append((*j)[:0], data...)
+	return nil
+}
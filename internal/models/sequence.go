@@ -1,15 +1,29 @@
 package models
 
+import "time"
+
 // Sequence represents a genomic sequence stored in the database.
 type Sequence struct {
-	ID          uint    `gorm:"primaryKey" json:"id"`
-	Accession   string  `gorm:"not null;uniqueIndex" json:"accession"`     // Unique GenBank ID
-	Species     *string `gorm:"default:null" json:"species,omitempty"`     // Nullable if unknown
-	Chromosome  *string `gorm:"default:null" json:"chromosome,omitempty"`  // Nullable scaffold/chromosome
-	Description *string `gorm:"default:null" json:"description,omitempty"` // Metadata, optional
-	Sequence    string  `gorm:"not null" json:"sequence"`                  // Raw DNA sequence
-	StartPos    int     `gorm:"default:1" json:"start_position"`           // Always starts at 1
-	EndPos      int     `gorm:"-" json:"end_position"`                     // Will be computed dynamically
-	CreatedAt   int64   `gorm:"autoCreateTime" json:"created_at"`
-	UpdatedAt   int64   `gorm:"autoUpdateTime" json:"updated_at"`
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	Accession   string    `gorm:"not null;uniqueIndex" json:"accession"`                          // Unique GenBank ID
+	Species     *string   `gorm:"default:null" json:"species,omitempty"`                          // Nullable if unknown
+	Chromosome  *string   `gorm:"default:null" json:"chromosome,omitempty"`                       // Nullable scaffold/chromosome
+	Description *string   `gorm:"default:null" json:"description,omitempty"`                      // Metadata, optional
+	Sequence    string    `gorm:"not null" json:"sequence"`                                       // Raw DNA sequence, stored in chunks; only rows not yet backfilled keep it here
+	Length      int       `gorm:"not null;default:0" json:"length"`                               // Number of bases
+	ChunkSize   int       `gorm:"not null;default:0" json:"-"`                                    // Bases per SequenceChunk; 0 if kept in the sequence column, see app migrate backfill
+	StartPos    int       `gorm:"column:start_position;not null;default:1" json:"start_position"` // Always starts at 1
+	EndPos      int       `gorm:"-" json:"end_position"`                                          // StartPos + Length - 1, set when loaded
+	CreatedAt   time.Time `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt   time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+
+	Features []Feature `gorm:"foreignKey:SequenceID;constraint:OnDelete:CASCADE" json:"features,omitempty"`
+}
+
+// SequenceChunk holds bases [Seq*ChunkSize, (Seq+1)*ChunkSize) of a sequence,
+// compressed. Chunks are numbered from 0.
+type SequenceChunk struct {
+	SequenceID uint   `gorm:"primaryKey"`
+	Seq        int    `gorm:"primaryKey"`
+	Data       []byte `gorm:"not null"`
 }
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DeliveryStatus is the state of a webhook delivery.
+type DeliveryStatus string
+
+const (
+	DeliveryPending   DeliveryStatus = "pending"
+	DeliveryDelivered DeliveryStatus = "delivered"
+	DeliveryFailed    DeliveryStatus = "failed" // gave up after the last attempt
+)
+
+// WebhookDelivery is a callback POSTed to a client when a job finishes,
+// together with the state of its delivery.
+type WebhookDelivery struct {
+	ID            uuid.UUID        `gorm:"type:uuid;primaryKey" json:"id"`
+	JobID         uuid.UUID        `gorm:"type:uuid;not null;index" json:"job_id"`
+	Client        string           `gorm:"not null" json:"client"`                 // whose secret signs the payload
+	URL           string           `gorm:"not null" json:"url"`                    // callback URL
+	Event         string           `gorm:"not null" json:"event"`                  // e.g. job.succeeded
+	Payload       JSON             `gorm:"type:jsonb;not null" json:"payload"`     // request body
+	Status        DeliveryStatus   `gorm:"not null;default:pending" json:"status"` // pending, delivered or failed
+	Attempts      int              `gorm:"not null;default:0" json:"attempts"`     // attempts since the delivery was created or replayed
+	NextAttemptAt time.Time        `gorm:"not null" json:"next_attempt_at"`        // when a pending delivery is due
+	CreatedAt     time.Time        `gorm:"autoCreateTime" json:"created_at"`
+	DeliveredAt   *time.Time       `json:"delivered_at,omitempty"`
+	History       []WebhookAttempt `gorm:"foreignKey:DeliveryID" json:"history,omitempty"`
+}
+
+// WebhookAttempt is one try at sending a delivery.
+type WebhookAttempt struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	DeliveryID uuid.UUID `gorm:"type:uuid;not null;index" json:"-"`
+	Attempt    int       `gorm:"not null" json:"attempt"`         // number of the attempt since the delivery was created or replayed
+	StatusCode int       `gorm:"not null" json:"status_code"`     // 0 if there was no response
+	Error      string    `gorm:"not null" json:"error,omitempty"` // why the attempt failed
+	DurationMs int64     `gorm:"not null" json:"duration_ms"`
+	CreatedAt  time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
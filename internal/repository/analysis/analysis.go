@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"strings"
+	"time"
+
+	"github.com/Viktor2805/nucount/internal/models"
+	scope "github.com/Viktor2805/nucount/internal/repository/scope"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Filter selects analyses. Zero fields match every analysis.
+type Filter struct {
+	Analysis string
+	Filename string // substring of the file name, case-insensitive
+	SHA256   string
+	From     time.Time // created at or after
+	To       time.Time // created before
+}
+
+type AnalysisRepositoryI interface {
+	Create(analysis *models.Analysis) error
+	FindByID(id uuid.UUID) (*models.Analysis, error)
+	// FindLatest returns the latest analysis of the named analysis with params
+	// on an upload with the digest sha256.
+	FindLatest(analysis string, params models.JobParams, sha256 string) (*models.Analysis, error)
+	// List returns the analyses matching filter, newest first and without
+	// their results, and how many there are in total. scopes paginate the
+	// page, see scope.Paginate.
+	List(filter Filter, scopes ...func(*gorm.DB) *gorm.DB) ([]models.Analysis, int64, error)
+}
+
+type AnalysisRepository struct {
+	db *gorm.DB
+}
+
+func NewAnalysisRepository(db *gorm.DB) *AnalysisRepository {
+	return &AnalysisRepository{
+		db: db,
+	}
+}
+
+func (r *AnalysisRepository) Create(analysis *models.Analysis) error {
+	return r.db.Create(analysis).Error
+}
+
+func (r *AnalysisRepository) FindByID(id uuid.UUID) (*models.Analysis, error) {
+	var analysis models.Analysis
+	if err := r.db.Take(&analysis, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &analysis, nil
+}
+
+func (r *AnalysisRepository) FindLatest(name string, params models.JobParams, sha256 string) (*models.Analysis, error) {
+	value, err := params.Value()
+	if err != nil {
+		return nil, err
+	}
+
+	var analysis models.Analysis
+	err = r.db.Where("sha256 = ? AND analysis = ? AND params = ?::jsonb", sha256, name, string(value.([]byte))).
+		Order("created_at DESC").
+		Take(&analysis).Error
+	if err != nil {
+		return nil, err
+	}
+	return &analysis, nil
+}
+
+func (r *AnalysisRepository) List(filter Filter, scopes ...func(*gorm.DB) *gorm.DB) ([]models.Analysis, int64, error) {
+	q := r.db.Model(&models.Analysis{})
+	if filter.Analysis != "" {
+		q = q.Where("analysis = ?", filter.Analysis)
+	}
+	if filter.Filename != "" {
+		q = q.Where("filename ILIKE ?", "%"+scope.EscapeLike(filter.Filename)+"%")
+	}
+	if filter.SHA256 != "" {
+		q = q.Where("sha256 = ?", strings.ToLower(filter.SHA256))
+	}
+	if !filter.From.IsZero() {
+		q = q.Where("created_at >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		q = q.Where("created_at < ?", filter.To)
+	}
+	// The count and the page each build on the filtered query.
+	q = q.Session(&gorm.Session{})
+
+	var total int64
+	if err := q.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var analyses []models.Analysis
+	err := q.Omit("result").Scopes(scopes...).Order("created_at DESC, id").Find(&analyses).Error
+	return analyses, total, err
+}
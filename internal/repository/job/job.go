@@ -0,0 +1,275 @@
+package repository
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/Viktor2805/nucount/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ChunkSize is the size of the pieces an upload is stored in.
+const ChunkSize = 4 << 20
+
+// ErrIncompletePayload is returned when reading an upload whose chunks do not
+// add up to its size, e.g. because the job was cancelled meanwhile.
+var ErrIncompletePayload = errors.New("upload is incomplete")
+
+// JobRepositoryI stores jobs and their uploads. A claimed job is identified
+// by its ID and attempt: the updates of a worker whose claim has expired and
+// been taken over by another worker are ignored.
+type JobRepositoryI interface {
+	// Create stores the job and its upload, read from payload, and sets
+	// job.Size and job.SHA256. The upload is committed chunk by chunk while
+	// the job is uploading; it is queued, and can be claimed, once the last
+	// chunk has been stored. A failed upload is deleted.
+	Create(job *models.Job, payload io.Reader) error
+	FindByID(id uuid.UUID) (*models.Job, error)
+	// Claim marks the oldest job that is queued, or running with a heartbeat
+	// older than lease, as running and returns it. It returns nil if there is no
+	// such job. Workers claiming concurrently never get the same job.
+	Claim(lease time.Duration) (*models.Job, error)
+	// Payload returns the upload of the job, fetched one chunk at a time.
+	// Reading it fails with ErrIncompletePayload if a chunk is missing.
+	Payload(job *models.Job) io.Reader
+	// Heartbeat stores the progress of a claimed job and renews its claim. It
+	// reports false if the claim has been lost.
+	Heartbeat(job *models.Job) (bool, error)
+	// Finish stores the outcome of a claimed job and deletes its upload, and
+	// creates the webhook delivery announcing it, if any, in the same
+	// transaction. It reports false if the claim has been lost.
+	Finish(job *models.Job, delivery *models.WebhookDelivery) (bool, error)
+	// Release puts a claimed job back in the queue without using up an attempt.
+	Release(job *models.Job) error
+	// Cancel marks a queued or running job as cancelled and deletes its upload;
+	// the worker running it loses its claim. It reports false if the job has
+	// already finished or does not exist.
+	Cancel(id uuid.UUID) (bool, error)
+	// DeleteUploads deletes the jobs that have been uploading for longer than
+	// age, left behind by a replica that stopped in the middle of an upload,
+	// and returns how many there were.
+	DeleteUploads(age time.Duration) (int64, error)
+}
+
+type JobRepository struct {
+	db *gorm.DB
+}
+
+func NewJobRepository(db *gorm.DB) *JobRepository {
+	return &JobRepository{
+		db: db,
+	}
+}
+
+func (r *JobRepository) Create(job *models.Job, payload io.Reader) error {
+	// Uploads may be large: each chunk is committed on its own rather than
+	// all in a transaction, and the job only queued once they all are.
+	job.Status = models.JobUploading
+	if err := r.db.Create(job).Error; err != nil {
+		return err
+	}
+	if err := r.upload(job, payload); err != nil {
+		// The chunks go with the job. Were this to fail as well, DeleteUploads
+		// would remove them later.
+		r.db.Delete(&models.Job{}, "id = ?", job.ID)
+		return err
+	}
+
+	job.Status = models.JobQueued
+	return r.db.Model(job).Updates(map[string]any{
+		"status": job.Status,
+		"size":   job.Size,
+		"sha256": job.SHA256,
+	}).Error
+}
+
+// upload stores payload in the chunks of job and sets its size and digest.
+func (r *JobRepository) upload(job *models.Job, payload io.Reader) error {
+	digest := sha256.New()
+	buf := make([]byte, ChunkSize)
+	for seq := 0; ; seq++ {
+		n, err := io.ReadFull(payload, buf)
+		if n > 0 {
+			digest.Write(buf[:n])
+			if err := r.db.Create(&models.JobChunk{JobID: job.ID, Seq: seq, Data: buf[:n]}).Error; err != nil {
+				return err
+			}
+			job.Size += int64(n)
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	job.SHA256 = hex.EncodeToString(digest.Sum(nil))
+	return nil
+}
+
+func (r *JobRepository) FindByID(id uuid.UUID) (*models.Job, error) {
+	var job models.Job
+	if err := r.db.Take(&job, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (r *JobRepository) Claim(lease time.Duration) (*models.Job, error) {
+	var job models.Job
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		// The database clock decides whether a heartbeat is too old, so that
+		// clock skew between replicas does not matter.
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ?", models.JobQueued).
+			Or("status = ? AND heartbeat_at < NOW() - ? * INTERVAL '1 second'", models.JobRunning, lease.Seconds()).
+			Order("created_at").
+			Take(&job).Error
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		job.Status = models.JobRunning
+		job.Attempts++
+		job.BytesRead, job.Records = 0, 0
+		job.StartedAt = &now
+		return tx.Model(&job).Updates(map[string]any{
+			"status":       job.Status,
+			"attempts":     job.Attempts,
+			"bytes_read":   0,
+			"records":      0,
+			"started_at":   now,
+			"heartbeat_at": gorm.Expr("NOW()"),
+		}).Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (r *JobRepository) Payload(job *models.Job) io.Reader {
+	return &payloadReader{db: r.db, id: job.ID, size: job.Size}
+}
+
+func (r *JobRepository) Heartbeat(job *models.Job) (bool, error) {
+	res := r.claimed(r.db, job).Updates(map[string]any{
+		"bytes_read":   job.BytesRead,
+		"records":      job.Records,
+		"heartbeat_at": gorm.Expr("NOW()"),
+	})
+	return res.RowsAffected == 1, res.Error
+}
+
+func (r *JobRepository) Finish(job *models.Job, delivery *models.WebhookDelivery) (bool, error) {
+	var ok bool
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		res := r.claimed(tx, job).Updates(map[string]any{
+			"status":       job.Status,
+			"bytes_read":   job.BytesRead,
+			"records":      job.Records,
+			"result":       job.Result,
+			"error":        job.Error,
+			"finished_at":  job.FinishedAt,
+			"heartbeat_at": nil,
+		})
+		if res.Error != nil || res.RowsAffected != 1 {
+			return res.Error
+		}
+		ok = true
+		if delivery != nil {
+			if err := tx.Create(delivery).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Where("job_id = ?", job.ID).Delete(&models.JobChunk{}).Error
+	})
+	return ok && err == nil, err
+}
+
+func (r *JobRepository) Release(job *models.Job) error {
+	return r.claimed(r.db, job).Updates(map[string]any{
+		"status":       models.JobQueued,
+		"attempts":     gorm.Expr("attempts - 1"),
+		"heartbeat_at": nil,
+	}).Error
+}
+
+func (r *JobRepository) Cancel(id uuid.UUID) (bool, error) {
+	var ok bool
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&models.Job{}).
+			Where("id = ? AND status IN ?", id, []models.JobStatus{models.JobQueued, models.JobRunning}).
+			Updates(map[string]any{
+				"status":       models.JobCancelled,
+				"finished_at":  time.Now(),
+				"heartbeat_at": nil,
+			})
+		if res.Error != nil || res.RowsAffected != 1 {
+			return res.Error
+		}
+		ok = true
+		return tx.Where("job_id = ?", id).Delete(&models.JobChunk{}).Error
+	})
+	return ok && err == nil, err
+}
+
+func (r *JobRepository) DeleteUploads(age time.Duration) (int64, error) {
+	res := r.db.Where("status = ? AND created_at < NOW() - ? * INTERVAL '1 second'", models.JobUploading, age.Seconds()).
+		Delete(&models.Job{})
+	return res.RowsAffected, res.Error
+}
+
+// claimed selects the job if it is still running under the same claim.
+func (r *JobRepository) claimed(db *gorm.DB, job *models.Job) *gorm.DB {
+	return db.Model(&models.Job{}).
+		Where("id = ? AND attempts = ? AND status = ?", job.ID, job.Attempts, models.JobRunning)
+}
+
+// payloadReader reads the chunks of an upload in order, until they add up to
+// its size.
+type payloadReader struct {
+	db      *gorm.DB
+	id      uuid.UUID
+	size    int64
+	fetched int64 // bytes of the chunks fetched so far
+	seq     int
+	buf     []byte
+}
+
+func (p *payloadReader) Read(b []byte) (int, error) {
+	for len(p.buf) == 0 {
+		if p.fetched == p.size {
+			return 0, io.EOF
+		}
+
+		var chunk models.JobChunk
+		err := p.db.Take(&chunk, "job_id = ? AND seq = ?", p.id, p.seq).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, fmt.Errorf("%w: chunk %d is missing after %d of %d bytes", ErrIncompletePayload, p.seq, p.fetched, p.size)
+		}
+		if err != nil {
+			return 0, err
+		}
+		if p.fetched += int64(len(chunk.Data)); p.fetched > p.size {
+			return 0, fmt.Errorf("%w: chunks exceed the size of %d bytes", ErrIncompletePayload, p.size)
+		}
+		p.buf = chunk.Data
+		p.seq++
+	}
+
+	n := copy(b, p.buf)
+	p.buf = p.buf[n:]
+	return n, nil
+}
@@ -0,0 +1,286 @@
+package repository_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Viktor2805/nucount/internal/migrations"
+	"github.com/Viktor2805/nucount/internal/models"
+	repository "github.com/Viktor2805/nucount/internal/repository/job"
+	"github.com/Viktor2805/nucount/internal/testutil"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// newRepo returns a repository on a migrated database in a container, or
+// skips the test without Docker.
+func newRepo(t *testing.T) (*repository.JobRepository, *gorm.DB) {
+	t.Helper()
+	db := testutil.Postgres(t)
+	m, err := migrations.New(db)
+	require.NoError(t, err)
+	_, err = m.Up(context.Background())
+	require.NoError(t, err)
+	return repository.NewJobRepository(db), db
+}
+
+func create(t *testing.T, repo *repository.JobRepository, payload []byte) *models.Job {
+	t.Helper()
+	job := &models.Job{ID: uuid.New(), Analysis: "count", Format: "fasta", Status: models.JobQueued}
+	require.NoError(t, repo.Create(job, bytes.NewReader(payload)))
+	return job
+}
+
+func TestJobRepository_Create(t *testing.T) {
+	repo, _ := newRepo(t)
+	payload := make([]byte, 2*repository.ChunkSize+10)
+	_, _ = rand.Read(payload)
+
+	job := create(t, repo, payload)
+	sum := sha256.Sum256(payload)
+	require.Equal(t, models.JobQueued, job.Status)
+	require.Equal(t, int64(len(payload)), job.Size)
+	require.Equal(t, hex.EncodeToString(sum[:]), job.SHA256)
+
+	stored, err := repo.FindByID(job.ID)
+	require.NoError(t, err)
+	require.Equal(t, models.JobQueued, stored.Status)
+	require.Equal(t, job.Size, stored.Size)
+
+	got, err := io.ReadAll(repo.Payload(stored))
+	require.NoError(t, err)
+	require.True(t, bytes.Equal(payload, got), "payload read back differs")
+}
+
+func TestJobRepository_CreateNotClaimableUntilStored(t *testing.T) {
+	repo, _ := newRepo(t)
+
+	// The first chunk is stored, then the upload stalls until released.
+	release := make(chan struct{})
+	r := io.MultiReader(
+		bytes.NewReader(make([]byte, repository.ChunkSize)),
+		readerFunc(func(p []byte) (int, error) {
+			<-release
+			return 0, io.EOF
+		}),
+	)
+	job := &models.Job{ID: uuid.New(), Analysis: "count", Format: "fasta", Status: models.JobQueued}
+	done := make(chan error, 1)
+	go func() { done <- repo.Create(job, r) }()
+
+	uploading := testutil.RunUntil(t, func(context.Context) {}, func() (*models.Job, error) {
+		j, err := repo.FindByID(job.ID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return j, err
+	}, func(j *models.Job) bool { return j != nil })
+	require.Equal(t, models.JobUploading, uploading.Status)
+	claimed, err := repo.Claim(time.Minute)
+	require.NoError(t, err)
+	require.Nil(t, claimed, "an uploading job was claimed")
+
+	close(release)
+	require.NoError(t, <-done)
+	claimed, err = repo.Claim(time.Minute)
+	require.NoError(t, err)
+	require.NotNil(t, claimed)
+	require.Equal(t, job.ID, claimed.ID)
+}
+
+func TestJobRepository_CreateFailed(t *testing.T) {
+	repo, db := newRepo(t)
+	job := &models.Job{ID: uuid.New(), Analysis: "count", Format: "fasta", Status: models.JobQueued}
+	r := io.MultiReader(bytes.NewReader(make([]byte, repository.ChunkSize+1)), readerFunc(func([]byte) (int, error) {
+		return 0, io.ErrClosedPipe
+	}))
+	require.ErrorIs(t, repo.Create(job, r), io.ErrClosedPipe)
+
+	_, err := repo.FindByID(job.ID)
+	require.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	var chunks int64
+	require.NoError(t, db.Model(&models.JobChunk{}).Where("job_id = ?", job.ID).Count(&chunks).Error)
+	require.Zero(t, chunks)
+}
+
+func TestJobRepository_PayloadGap(t *testing.T) {
+	repo, db := newRepo(t)
+	job := create(t, repo, make([]byte, 2*repository.ChunkSize+10))
+	require.NoError(t, db.Where("job_id = ? AND seq = 1", job.ID).Delete(&models.JobChunk{}).Error)
+
+	_, err := io.ReadAll(repo.Payload(job))
+	require.ErrorIs(t, err, repository.ErrIncompletePayload)
+
+	// The last chunk missing is a gap too, not the end of the upload.
+	job = create(t, repo, make([]byte, repository.ChunkSize+10))
+	require.NoError(t, db.Where("job_id = ? AND seq = 1", job.ID).Delete(&models.JobChunk{}).Error)
+	_, err = io.ReadAll(repo.Payload(job))
+	require.ErrorIs(t, err, repository.ErrIncompletePayload)
+}
+
+func TestJobRepository_ClaimSkipsLocked(t *testing.T) {
+	repo, db := newRepo(t)
+	first := create(t, repo, []byte(">a\nACGT\n"))
+	second := create(t, repo, []byte(">b\nACGT\n"))
+
+	// Another worker is in the middle of claiming the oldest job.
+	tx := db.Begin()
+	t.Cleanup(func() { tx.Rollback() })
+	require.NoError(t, tx.Exec("SELECT 1 FROM jobs WHERE id = ? FOR UPDATE", first.ID).Error)
+
+	claimed, err := repo.Claim(time.Minute)
+	require.NoError(t, err)
+	require.NotNil(t, claimed)
+	require.Equal(t, second.ID, claimed.ID)
+
+	claimed, err = repo.Claim(time.Minute)
+	require.NoError(t, err)
+	require.Nil(t, claimed, "the locked job was claimed")
+}
+
+func TestJobRepository_ClaimConcurrently(t *testing.T) {
+	repo, _ := newRepo(t)
+	const n = 8
+	for range n {
+		create(t, repo, []byte(">a\nACGT\n"))
+	}
+
+	var mu sync.Mutex
+	seen := map[uuid.UUID]int{}
+	var wg sync.WaitGroup
+	for range 2 * n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			job, err := repo.Claim(time.Minute)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if job != nil {
+				mu.Lock()
+				seen[job.ID]++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	require.Len(t, seen, n)
+	for id, claims := range seen {
+		require.Equal(t, 1, claims, "job %s claimed more than once", id)
+	}
+}
+
+func TestJobRepository_Fencing(t *testing.T) {
+	repo, db := newRepo(t)
+	job := create(t, repo, []byte(">a\nACGT\n"))
+
+	stale, err := repo.Claim(time.Minute)
+	require.NoError(t, err)
+	require.Equal(t, 1, stale.Attempts)
+
+	// Not expired yet: nobody else gets it.
+	again, err := repo.Claim(time.Minute)
+	require.NoError(t, err)
+	require.Nil(t, again)
+
+	// The first worker stops sending heartbeats and its lease expires.
+	require.NoError(t, db.Exec("UPDATE jobs SET heartbeat_at = NOW() - INTERVAL '1 hour' WHERE id = ?", job.ID).Error)
+	current, err := repo.Claim(time.Minute)
+	require.NoError(t, err)
+	require.NotNil(t, current)
+	require.Equal(t, 2, current.Attempts)
+
+	stale.BytesRead = 100
+	ok, err := repo.Heartbeat(stale)
+	require.NoError(t, err)
+	require.False(t, ok, "heartbeat of a stale claim")
+
+	stale.Status = models.JobFailed
+	ok, err = repo.Finish(stale, nil)
+	require.NoError(t, err)
+	require.False(t, ok, "finish of a stale claim")
+
+	require.NoError(t, repo.Release(stale))
+	stored, err := repo.FindByID(job.ID)
+	require.NoError(t, err)
+	require.Equal(t, models.JobRunning, stored.Status, "release of a stale claim")
+	require.Equal(t, 2, stored.Attempts)
+	require.Zero(t, stored.BytesRead)
+
+	current.Status = models.JobSucceeded
+	current.Result = models.JSON(`{"A":1}`)
+	ok, err = repo.Finish(current, nil)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	var chunks int64
+	require.NoError(t, db.Model(&models.JobChunk{}).Where("job_id = ?", job.ID).Count(&chunks).Error)
+	require.Zero(t, chunks, "the upload of a finished job is kept")
+}
+
+func TestJobRepository_Release(t *testing.T) {
+	repo, _ := newRepo(t)
+	job := create(t, repo, []byte(">a\nACGT\n"))
+
+	claimed, err := repo.Claim(time.Minute)
+	require.NoError(t, err)
+	require.Equal(t, 1, claimed.Attempts)
+	require.NoError(t, repo.Release(claimed))
+
+	stored, err := repo.FindByID(job.ID)
+	require.NoError(t, err)
+	require.Equal(t, models.JobQueued, stored.Status)
+	require.Equal(t, 0, stored.Attempts, "a release uses up no attempt")
+	require.Nil(t, stored.HeartbeatAt)
+
+	claimed, err = repo.Claim(time.Minute)
+	require.NoError(t, err)
+	require.Equal(t, job.ID, claimed.ID)
+	require.Equal(t, 1, claimed.Attempts)
+}
+
+func TestJobRepository_DeleteUploads(t *testing.T) {
+	repo, db := newRepo(t)
+	abandoned := &models.Job{ID: uuid.New(), Analysis: "count", Format: "fasta", Status: models.JobUploading}
+	recent := &models.Job{ID: uuid.New(), Analysis: "count", Format: "fasta", Status: models.JobUploading}
+	for _, j := range []*models.Job{abandoned, recent} {
+		require.NoError(t, db.Create(j).Error)
+		require.NoError(t, db.Create(&models.JobChunk{JobID: j.ID, Seq: 0, Data: []byte(">a\n")}).Error)
+	}
+	queued := create(t, repo, []byte(">a\nACGT\n"))
+	require.NoError(t, db.Exec("UPDATE jobs SET created_at = NOW() - INTERVAL '2 hours' WHERE id IN ?",
+		[]uuid.UUID{abandoned.ID, queued.ID}).Error)
+
+	n, err := repo.DeleteUploads(time.Hour)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), n)
+
+	_, err = repo.FindByID(abandoned.ID)
+	require.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	var chunks int64
+	require.NoError(t, db.Model(&models.JobChunk{}).Where("job_id = ?", abandoned.ID).Count(&chunks).Error)
+	require.Zero(t, chunks)
+	for _, id := range []uuid.UUID{recent.ID, queued.ID} {
+		_, err = repo.FindByID(id)
+		require.NoError(t, err)
+	}
+}
+
+// readerFunc adapts a function to io.Reader.
+type readerFunc func(p []byte) (int, error)
+
+func (f readerFunc) Read(p []byte) (int, error) {
+	return f(p)
+}
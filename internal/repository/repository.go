@@ -1,29 +1,26 @@
 package repository
 
 import (
-	sequence "golang/internal/repository/sequence"
+	analysis "github.com/Viktor2805/nucount/internal/repository/analysis"
+	job "github.com/Viktor2805/nucount/internal/repository/job"
+	sequence "github.com/Viktor2805/nucount/internal/repository/sequence"
+	webhook "github.com/Viktor2805/nucount/internal/repository/webhook"
 
 	"gorm.io/gorm"
 )
 
 type Repositories struct {
 	SequenceRepo sequence.SequenceRepositoryI
-}
-
-func Paginate(limit, offset int) func(db *gorm.DB) *gorm.DB {
-	return func(db *gorm.DB) *gorm.DB {
-		if limit > 0 {
-			db = db.Limit(limit)
-		}
-		if offset >= 0 {
-			db = db.Offset(offset)
-		}
-		return db
-	}
+	JobRepo      job.JobRepositoryI
+	WebhookRepo  webhook.WebhookRepositoryI
+	AnalysisRepo analysis.AnalysisRepositoryI
 }
 
 func NewRepositories(db *gorm.DB) *Repositories {
 	return &Repositories{
 		SequenceRepo: sequence.NewSequenceRepository(db),
+		JobRepo:      job.NewJobRepository(db),
+		WebhookRepo:  webhook.NewWebhookRepository(db),
+		AnalysisRepo: analysis.NewAnalysisRepository(db),
 	}
 }
@@ -0,0 +1,151 @@
+package repository
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/Viktor2805/nucount/internal/models"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// ChunkSize is the number of bases in each stored chunk of a sequence but the
+// last. A range read decompresses at most ChunkSize bases it does not need at
+// either end.
+const ChunkSize = 1 << 20
+
+// The zstd encoder and decoder are safe for concurrent use with EncodeAll and
+// DecodeAll.
+var (
+	chunkEncoder, _ = zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1))
+	chunkDecoder, _ = zstd.NewReader(nil, zstd.WithDecoderConcurrency(0))
+)
+
+// chunkSource yields the rows of sequence_chunks of a sequence for CopyFrom,
+// reading and compressing one chunk of bases at a time.
+type chunkSource struct {
+	sequenceID uint
+	bases      io.Reader
+	buf        []byte
+	seq        int
+	length     int // bases read so far
+	data       []byte
+	done       bool
+	err        error
+}
+
+func newChunkSource(sequenceID uint, bases io.Reader, size int) *chunkSource {
+	return &chunkSource{sequenceID: sequenceID, bases: bases, buf: make([]byte, size), seq: -1}
+}
+
+func (s *chunkSource) Next() bool {
+	if s.done || s.err != nil {
+		return false
+	}
+	n, err := io.ReadFull(s.bases, s.buf)
+	switch err {
+	case nil:
+	case io.EOF, io.ErrUnexpectedEOF:
+		s.done = true
+	default:
+		s.err = err
+		return false
+	}
+	if n == 0 {
+		return false
+	}
+	s.seq++
+	s.length += n
+	s.data = chunkEncoder.EncodeAll(s.buf[:n], nil)
+	return true
+}
+
+func (s *chunkSource) Values() ([]any, error) {
+	return []any{int64(s.sequenceID), int32(s.seq), s.data}, nil
+}
+
+func (s *chunkSource) Err() error {
+	return s.err
+}
+
+// chunkRange returns the first and last chunk holding bases [start, end), for
+// chunks of size bases. end must be greater than start.
+func chunkRange(start, end, size int) (first, last int) {
+	return start / size, (end - 1) / size
+}
+
+// assembleChunks returns bases [start, end) of a sequence from its chunks of
+// size bases, in order and starting with the first of chunkRange.
+func assembleChunks(chunks []models.SequenceChunk, start, end, size int) (string, error) {
+	first, last := chunkRange(start, end, size)
+	if len(chunks) != last-first+1 {
+		return "", fmt.Errorf("sequence chunks %d-%d: found %d", first, last, len(chunks))
+	}
+
+	buf := make([]byte, 0, len(chunks)*size)
+	for i, c := range chunks {
+		if c.Seq != first+i {
+			return "", fmt.Errorf("sequence chunk %d: found chunk %d", first+i, c.Seq)
+		}
+		var err error
+		if buf, err = chunkDecoder.DecodeAll(c.Data, buf); err != nil {
+			return "", fmt.Errorf("sequence chunk %d: %w", c.Seq, err)
+		}
+	}
+
+	offset := first * size
+	if end-offset > len(buf) {
+		return "", fmt.Errorf("sequence chunks %d-%d: %d bases short", first, last, end-offset-len(buf))
+	}
+	return string(buf[start-offset : end-offset]), nil
+}
+
+// chunkReader reads the bases of a sequence of length bases from its chunks of
+// size bases, fetching and decompressing one chunk at a time.
+type chunkReader struct {
+	fetch   func(seq int) ([]byte, error) // compressed data of chunk seq
+	length  int
+	size    int
+	seq     int
+	decoded int // bases of the chunks fetched so far
+	buf     []byte
+	next    []byte // buf's backing array, reused
+}
+
+func newChunkReader(length, size int, fetch func(seq int) ([]byte, error)) *chunkReader {
+	return &chunkReader{fetch: fetch, length: length, size: size}
+}
+
+func (r *chunkReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		if r.decoded == r.length {
+			return 0, io.EOF
+		}
+		data, err := r.fetch(r.seq)
+		if err != nil {
+			return 0, fmt.Errorf("sequence chunk %d: %w", r.seq, err)
+		}
+		if r.next, err = chunkDecoder.DecodeAll(data, r.next[:0]); err != nil {
+			return 0, fmt.Errorf("sequence chunk %d: %w", r.seq, err)
+		}
+		if want := min(r.size, r.length-r.decoded); len(r.next) != want {
+			return 0, fmt.Errorf("sequence chunk %d: %d bases, expected %d", r.seq, len(r.next), want)
+		}
+		r.buf = r.next
+		r.decoded += len(r.next)
+		r.seq++
+	}
+
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+// errReader fails every read with err.
+type errReader struct {
+	err error
+}
+
+func (r errReader) Read([]byte) (int, error) {
+	return 0, r.err
+}
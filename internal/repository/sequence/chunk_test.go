@@ -0,0 +1,128 @@
+package repository
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/Viktor2805/nucount/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// storeChunks returns the chunks a COPY of bases would store.
+func storeChunks(t *testing.T, bases string, size int) []models.SequenceChunk {
+	t.Helper()
+	var chunks []models.SequenceChunk
+	src := newChunkSource(7, strings.NewReader(bases), size)
+	for src.Next() {
+		values, err := src.Values()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if values[0] != int64(7) {
+			t.Fatalf("sequence_id = %v", values[0])
+		}
+		chunks = append(chunks, models.SequenceChunk{SequenceID: 7, Seq: int(values[1].(int32)), Data: values[2].([]byte)})
+	}
+	if err := src.Err(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if src.length != len(bases) {
+		t.Fatalf("length = %d, want %d", src.length, len(bases))
+	}
+	return chunks
+}
+
+func TestChunks_RoundTrip(t *testing.T) {
+	const bases = "ACGTNNacgtRYKM-*ACG"
+	const size = 4
+	chunks := storeChunks(t, bases, size)
+	if len(chunks) != 5 {
+		t.Fatalf("expected 5 chunks, got %d", len(chunks))
+	}
+
+	for start := 0; start < len(bases); start++ {
+		for end := start + 1; end <= len(bases); end++ {
+			first, last := chunkRange(start, end, size)
+			got, err := assembleChunks(chunks[first:last+1], start, end, size)
+			if err != nil || got != bases[start:end] {
+				t.Fatalf("[%d, %d) = %q, %v; want %q", start, end, got, err, bases[start:end])
+			}
+		}
+	}
+}
+
+func TestChunks_Compressed(t *testing.T) {
+	bases := strings.Repeat("ACGTTGCA", ChunkSize/8) + "ACG"
+	chunks := storeChunks(t, bases, ChunkSize)
+	if len(chunks) != 2 {
+		t.Fatalf("expected 2 chunks, got %d", len(chunks))
+	}
+	if n := len(chunks[0].Data); n >= ChunkSize/4 {
+		t.Errorf("chunk of %d bases compressed to %d bytes", ChunkSize, n)
+	}
+
+	got, err := assembleChunks(chunks, ChunkSize-2, ChunkSize+3, ChunkSize)
+	if err != nil || got != "CAACG" {
+		t.Errorf("across chunks = %q, %v", got, err)
+	}
+}
+
+func TestChunks_Missing(t *testing.T) {
+	chunks := storeChunks(t, "ACGTACGTAC", 4)
+	if _, err := assembleChunks(chunks[:1], 2, 6, 4); err == nil {
+		t.Error("expected an error for a missing chunk")
+	}
+	if _, err := assembleChunks(chunks[1:], 0, 6, 4); err == nil {
+		t.Error("expected an error for chunks out of range")
+	}
+	if storeChunks(t, "", 4) != nil {
+		t.Error("expected no chunks for an empty sequence")
+	}
+}
+
+func TestChunkSource_ReadError(t *testing.T) {
+	errRead := errors.New("read failed")
+	src := newChunkSource(7, io.MultiReader(strings.NewReader("ACGTACGTAC"), iotest.ErrReader(errRead)), 4)
+	n := 0
+	for src.Next() {
+		n++
+	}
+	if n != 2 || !errors.Is(src.Err(), errRead) {
+		t.Errorf("%d chunks, err = %v; want 2 chunks and the read error", n, src.Err())
+	}
+}
+
+func TestChunkReader(t *testing.T) {
+	const bases = "ACGTNNacgtRYKM-*ACG"
+	chunks := storeChunks(t, bases, 4)
+	fetch := func(chunks []models.SequenceChunk) func(int) ([]byte, error) {
+		return func(seq int) ([]byte, error) {
+			if seq >= len(chunks) {
+				return nil, gorm.ErrRecordNotFound
+			}
+			return chunks[seq].Data, nil
+		}
+	}
+
+	got, err := io.ReadAll(iotest.OneByteReader(newChunkReader(len(bases), 4, fetch(chunks))))
+	if err != nil || string(got) != bases {
+		t.Errorf("read %q, %v; want %q", got, err, bases)
+	}
+	if got, err := io.ReadAll(newChunkReader(0, 4, fetch(nil))); err != nil || len(got) != 0 {
+		t.Errorf("empty sequence: read %q, %v", got, err)
+	}
+
+	// A missing last chunk is not the end of the sequence.
+	if _, err := io.ReadAll(newChunkReader(len(bases), 4, fetch(chunks[:4]))); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("missing chunk: err = %v", err)
+	}
+	// Nor is a short one.
+	short := append(append([]models.SequenceChunk(nil), chunks[:2]...), chunks[4])
+	if _, err := io.ReadAll(newChunkReader(len(bases), 4, fetch(short))); err == nil {
+		t.Error("expected an error for a short chunk")
+	}
+}
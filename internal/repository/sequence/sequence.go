@@ -1,17 +1,72 @@
 package repository
 
 import (
-	"golang/internal/models"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
 
+	"github.com/Viktor2805/nucount/internal/models"
+	scope "github.com/Viktor2805/nucount/internal/repository/scope"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// Filter selects sequences. Zero fields match every sequence.
+type Filter struct {
+	Species         string // case-insensitive
+	Chromosome      string
+	AccessionPrefix string
+	AfterID         uint // only sequences with a greater ID, the cursor of a page
+}
+
 type SequenceRepositoryI interface {
-	Create(sequence *models.Sequence) error
+	// Create inserts the sequence together with its features, and its bases,
+	// read from bases a chunk of ChunkSize at a time, and sets
+	// sequence.Length and EndPos.
+	Create(sequence *models.Sequence, bases io.Reader) error
+	// FindByAccessions returns the sequences with the given accessions, or all
+	// sequences when none are given, ordered by ID and without their bases and
+	// features; see Bases.
+	FindByAccessions(accessions []string) ([]models.Sequence, error)
+	// List returns the sequences matching filter ordered by ID, without their
+	// bases and features. scopes limit the page, see scope.CursorPage.
+	List(filter Filter, scopes ...func(*gorm.DB) *gorm.DB) ([]models.Sequence, error)
+	// FindByID and FindByAccession return a sequence without its bases and
+	// features.
+	FindByID(id uint) (*models.Sequence, error)
+	FindByAccession(accession string) (*models.Sequence, error)
+	// Features returns the features of a sequence ordered by ID.
+	Features(sequenceID uint) ([]models.Feature, error)
+	// Update stores the accession, species, chromosome and description of a
+	// sequence.
+	Update(sequence *models.Sequence) error
+	// Delete deletes a sequence and its features. It reports whether the
+	// sequence existed.
+	Delete(id uint) (bool, error)
+	// Subsequence returns bases [start, end) of a sequence found by FindByID
+	// or FindByAccession, counting from 0. Only the chunks holding the range
+	// are read.
+	Subsequence(sequence *models.Sequence, start, end int) (string, error)
+	// Bases returns the bases of a sequence found by FindByID, FindByAccession
+	// or FindByAccessions, reading its chunks in order, one at a time, with
+	// ctx.
+	Bases(ctx context.Context, sequence *models.Sequence) io.Reader
+	// Transaction runs fn with a repository bound to a single database
+	// transaction, which is rolled back if fn returns an error.
+	Transaction(fn func(repo SequenceRepositoryI) error) error
 }
 
 type SequenceRepository struct {
 	db *gorm.DB
+	// conn is the connection of the transaction db is bound to, if any, which
+	// chunks are copied in over.
+	conn *sql.Conn
 }
 
 func NewSequenceRepository(db *gorm.DB) *SequenceRepository {
@@ -20,6 +75,226 @@ func NewSequenceRepository(db *gorm.DB) *SequenceRepository {
 	}
 }
 
-func (r *SequenceRepository) Create(sequence *models.Sequence) error {
-	return r.db.Create(sequence).Error
+func (r *SequenceRepository) Create(sequence *models.Sequence, bases io.Reader) error {
+	return r.transaction(func(tx *SequenceRepository) error {
+		// The length is only known once the bases are stored.
+		sequence.Sequence, sequence.Length, sequence.ChunkSize = "", 0, ChunkSize
+		if err := tx.db.Create(sequence).Error; err != nil {
+			return err
+		}
+		n, err := tx.copyChunks(sequence.ID, bases)
+		if err != nil {
+			return err
+		}
+		sequence.Length = n
+		setEndPosition(sequence)
+		return tx.db.Model(sequence).UpdateColumn("length", n).Error
+	})
+}
+
+// copyChunks stores the bases of a sequence with COPY, reading and compressing
+// a chunk at a time, and returns their number. It must run in a transaction.
+func (r *SequenceRepository) copyChunks(sequenceID uint, bases io.Reader) (int, error) {
+	src := newChunkSource(sequenceID, bases, ChunkSize)
+	err := r.conn.Raw(func(driverConn any) error {
+		conn, ok := driverConn.(*stdlib.Conn)
+		if !ok {
+			return fmt.Errorf("copy sequence chunks: unsupported driver connection %T", driverConn)
+		}
+		_, err := conn.Conn().CopyFrom(
+			r.db.Statement.Context,
+			pgx.Identifier{"sequence_chunks"},
+			[]string{"sequence_id", "seq", "data"},
+			src,
+		)
+		return err
+	})
+	return src.length, err
+}
+
+func (r *SequenceRepository) FindByAccessions(accessions []string) ([]models.Sequence, error) {
+	var sequences []models.Sequence
+	q := r.db.Omit("sequence").Order("id")
+	if len(accessions) > 0 {
+		q = q.Where("accession IN ?", accessions)
+	}
+	if err := q.Find(&sequences).Error; err != nil {
+		return nil, err
+	}
+	for i := range sequences {
+		setEndPosition(&sequences[i])
+	}
+	return sequences, nil
+}
+
+func (r *SequenceRepository) List(filter Filter, scopes ...func(*gorm.DB) *gorm.DB) ([]models.Sequence, error) {
+	q := r.db.Model(&models.Sequence{})
+	if filter.Species != "" {
+		q = q.Where("LOWER(species) = LOWER(?)", filter.Species)
+	}
+	if filter.Chromosome != "" {
+		q = q.Where("chromosome = ?", filter.Chromosome)
+	}
+	if filter.AccessionPrefix != "" {
+		q = q.Where("accession LIKE ?", scope.EscapeLike(filter.AccessionPrefix)+"%")
+	}
+	if filter.AfterID > 0 {
+		q = q.Where("id > ?", filter.AfterID)
+	}
+
+	var sequences []models.Sequence
+	if err := q.Omit("sequence").Scopes(scopes...).Order("id").Find(&sequences).Error; err != nil {
+		return nil, err
+	}
+	for i := range sequences {
+		setEndPosition(&sequences[i])
+	}
+	return sequences, nil
+}
+
+func (r *SequenceRepository) FindByID(id uint) (*models.Sequence, error) {
+	return r.take("id = ?", id)
+}
+
+func (r *SequenceRepository) FindByAccession(accession string) (*models.Sequence, error) {
+	return r.take("accession = ?", accession)
+}
+
+// take returns the first sequence matching the condition, without its bases.
+func (r *SequenceRepository) take(query string, args ...any) (*models.Sequence, error) {
+	var sequence models.Sequence
+	if err := r.db.Omit("sequence").Where(query, args...).Order("id").Take(&sequence).Error; err != nil {
+		return nil, err
+	}
+	setEndPosition(&sequence)
+	return &sequence, nil
+}
+
+func setEndPosition(s *models.Sequence) {
+	s.EndPos = s.StartPos + s.Length - 1
+}
+
+func (r *SequenceRepository) Features(sequenceID uint) ([]models.Feature, error) {
+	var features []models.Feature
+	return features, r.db.Where("sequence_id = ?", sequenceID).Order("id").Find(&features).Error
+}
+
+func (r *SequenceRepository) Update(sequence *models.Sequence) error {
+	return r.db.Model(sequence).
+		Select("accession", "species", "chromosome", "description", "updated_at").
+		Updates(sequence).Error
+}
+
+func (r *SequenceRepository) Delete(id uint) (bool, error) {
+	res := r.db.Delete(&models.Sequence{}, id)
+	return res.RowsAffected > 0, res.Error
+}
+
+func (r *SequenceRepository) Subsequence(sequence *models.Sequence, start, end int) (string, error) {
+	if start >= end {
+		return "", nil
+	}
+	if sequence.ChunkSize == 0 {
+		return "", notChunked(sequence)
+	}
+
+	first, last := chunkRange(start, end, sequence.ChunkSize)
+	var chunks []models.SequenceChunk
+	err := r.db.Where("sequence_id = ? AND seq BETWEEN ? AND ?", sequence.ID, first, last).
+		Order("seq").
+		Find(&chunks).Error
+	if err != nil {
+		return "", err
+	}
+	if len(chunks) == 0 {
+		return "", gorm.ErrRecordNotFound
+	}
+	return assembleChunks(chunks, start, end, sequence.ChunkSize)
+}
+
+func (r *SequenceRepository) Bases(ctx context.Context, sequence *models.Sequence) io.Reader {
+	if sequence.ChunkSize == 0 && sequence.Length > 0 {
+		return errReader{notChunked(sequence)}
+	}
+	db := r.db.WithContext(ctx)
+	return newChunkReader(sequence.Length, sequence.ChunkSize, func(seq int) ([]byte, error) {
+		var chunk models.SequenceChunk
+		err := db.Select("data").Take(&chunk, "sequence_id = ? AND seq = ?", sequence.ID, seq).Error
+		return chunk.Data, err
+	})
+}
+
+// notChunked is the error of reading a sequence imported before sequences were
+// stored in chunks and not moved into chunks since.
+func notChunked(sequence *models.Sequence) error {
+	return fmt.Errorf("sequence %s is not stored in chunks: run app migrate backfill", sequence.Accession)
+}
+
+// Backfill moves the bases of the sequences imported before sequences were
+// stored in chunks from their sequence column into chunks, a sequence per
+// transaction, and calls done with each. It returns how many it moved.
+func (r *SequenceRepository) Backfill(ctx context.Context, done func(*models.Sequence)) (int, error) {
+	moved := 0
+	for {
+		var sequence models.Sequence
+		err := r.withContext(ctx).transaction(func(tx *SequenceRepository) error {
+			err := tx.db.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+				Where("chunk_size = 0").
+				Order("id").
+				Take(&sequence).Error
+			if err != nil {
+				return err
+			}
+			if _, err := tx.copyChunks(sequence.ID, strings.NewReader(sequence.Sequence)); err != nil {
+				return err
+			}
+			sequence.Length = len(sequence.Sequence)
+			return tx.db.Model(&sequence).UpdateColumns(map[string]any{
+				"sequence":   "",
+				"length":     sequence.Length,
+				"chunk_size": ChunkSize,
+			}).Error
+		})
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return moved, nil
+		}
+		if err != nil {
+			return moved, fmt.Errorf("backfill %s: %w", sequence.Accession, err)
+		}
+		moved++
+		if done != nil {
+			done(&sequence)
+		}
+	}
+}
+
+// withContext returns a repository whose queries run with ctx.
+func (r *SequenceRepository) withContext(ctx context.Context) *SequenceRepository {
+	return &SequenceRepository{db: r.db.WithContext(ctx), conn: r.conn}
+}
+
+func (r *SequenceRepository) Transaction(fn func(repo SequenceRepositoryI) error) error {
+	return r.transaction(func(tx *SequenceRepository) error {
+		return fn(tx)
+	})
+}
+
+// transaction runs fn in a transaction, or in a savepoint of the transaction
+// r is bound to. The transaction is begun on a connection of its own, which
+// database/sql hands out for COPY.
+func (r *SequenceRepository) transaction(fn func(tx *SequenceRepository) error) error {
+	if r.conn != nil {
+		return r.db.Transaction(func(tx *gorm.DB) error {
+			return fn(&SequenceRepository{db: tx, conn: r.conn})
+		})
+	}
+	return r.db.Connection(func(db *gorm.DB) error {
+		conn, ok := db.Statement.ConnPool.(*sql.Conn)
+		if !ok {
+			return fmt.Errorf("sequence transaction: unexpected connection %T", db.Statement.ConnPool)
+		}
+		return db.Transaction(func(tx *gorm.DB) error {
+			return fn(&SequenceRepository{db: tx, conn: conn})
+		})
+	})
 }
@@ -0,0 +1,64 @@
+package repository_test
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/Viktor2805/nucount/internal/migrations"
+	"github.com/Viktor2805/nucount/internal/models"
+	repository "github.com/Viktor2805/nucount/internal/repository/sequence"
+	"github.com/Viktor2805/nucount/internal/testutil"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSequenceRepository_Backfill(t *testing.T) {
+	db := testutil.Postgres(t)
+	m, err := migrations.New(db)
+	require.NoError(t, err)
+	_, err = m.Up(context.Background())
+	require.NoError(t, err)
+	repo := repository.NewSequenceRepository(db)
+
+	// Rows as imported before sequences were stored in chunks.
+	bases := map[string]string{
+		"A1": strings.Repeat("ACGTN", repository.ChunkSize/2),
+		"B1": "acgt",
+	}
+	for accession, seq := range bases {
+		require.NoError(t, db.Exec(
+			"INSERT INTO sequences (accession, sequence, length, created_at, updated_at) VALUES (?, ?, ?, now(), now())",
+			accession, seq, len(seq),
+		).Error)
+	}
+	chunked := &models.Sequence{Accession: "C1"}
+	require.NoError(t, repo.Create(chunked, strings.NewReader("GATTACA")))
+	require.Equal(t, 7, chunked.Length)
+	require.Equal(t, 7, chunked.EndPos)
+	require.ErrorIs(t, m.Check(context.Background()), migrations.ErrNotBackfilled)
+
+	var moved []string
+	n, err := repo.Backfill(context.Background(), func(s *models.Sequence) {
+		moved = append(moved, s.Accession)
+	})
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+	require.ElementsMatch(t, []string{"A1", "B1"}, moved)
+	require.NoError(t, m.Check(context.Background()))
+
+	for accession, want := range bases {
+		s, err := repo.FindByAccession(accession)
+		require.NoError(t, err)
+		require.Equal(t, repository.ChunkSize, s.ChunkSize)
+		require.Equal(t, len(want), s.Length)
+		got, err := io.ReadAll(repo.Bases(context.Background(), s))
+		require.NoError(t, err)
+		require.Equal(t, want, string(got))
+	}
+
+	n, err = repo.Backfill(context.Background(), nil)
+	require.NoError(t, err)
+	require.Zero(t, n)
+}
@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/Viktor2805/nucount/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// WebhookRepositoryI stores webhook deliveries and their attempts. Deliveries
+// are created together with the outcome of their job, see
+// JobRepositoryI.Finish. A claimed delivery is identified by its ID and
+// attempt, like a claimed job.
+type WebhookRepositoryI interface {
+	// FindByID returns the delivery with its attempts, oldest first.
+	FindByID(id uuid.UUID) (*models.WebhookDelivery, error)
+	// FindByJob returns the deliveries of a job with their attempts, oldest
+	// first.
+	FindByJob(jobID uuid.UUID) ([]models.WebhookDelivery, error)
+	// Claim takes the pending delivery that has been due longest, counts the
+	// attempt and postpones the delivery by lease, so that no other dispatcher
+	// sends it meanwhile. It returns nil if no delivery is due.
+	Claim(lease time.Duration) (*models.WebhookDelivery, error)
+	// Record stores an attempt at sending a claimed delivery together with the
+	// new status and next_attempt_at of the delivery. It reports false if the
+	// delivery has been claimed again or replayed since.
+	Record(delivery *models.WebhookDelivery, attempt *models.WebhookAttempt) (bool, error)
+	// Replay makes a delivered or failed delivery pending and due now, with a
+	// fresh count of attempts. It reports false if the delivery does not exist
+	// or is still pending.
+	Replay(id uuid.UUID) (bool, error)
+}
+
+type WebhookRepository struct {
+	db *gorm.DB
+}
+
+func NewWebhookRepository(db *gorm.DB) *WebhookRepository {
+	return &WebhookRepository{
+		db: db,
+	}
+}
+
+func (r *WebhookRepository) FindByID(id uuid.UUID) (*models.WebhookDelivery, error) {
+	var delivery models.WebhookDelivery
+	if err := r.withHistory().Take(&delivery, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+func (r *WebhookRepository) FindByJob(jobID uuid.UUID) ([]models.WebhookDelivery, error) {
+	var deliveries []models.WebhookDelivery
+	if err := r.withHistory().Where("job_id = ?", jobID).Order("created_at").Find(&deliveries).Error; err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+func (r *WebhookRepository) Claim(lease time.Duration) (*models.WebhookDelivery, error) {
+	var delivery models.WebhookDelivery
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_attempt_at <= NOW()", models.DeliveryPending).
+			Order("next_attempt_at").
+			Take(&delivery).Error
+		if err != nil {
+			return err
+		}
+
+		delivery.Attempts++
+		return tx.Model(&delivery).Updates(map[string]any{
+			"attempts":        delivery.Attempts,
+			"next_attempt_at": gorm.Expr("NOW() + ? * INTERVAL '1 second'", lease.Seconds()),
+		}).Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+func (r *WebhookRepository) Record(delivery *models.WebhookDelivery, attempt *models.WebhookAttempt) (bool, error) {
+	var ok bool
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&models.WebhookDelivery{}).
+			Where("id = ? AND attempts = ? AND status = ?", delivery.ID, delivery.Attempts, models.DeliveryPending).
+			Updates(map[string]any{
+				"status":          delivery.Status,
+				"next_attempt_at": delivery.NextAttemptAt,
+				"delivered_at":    delivery.DeliveredAt,
+			})
+		if res.Error != nil || res.RowsAffected != 1 {
+			return res.Error
+		}
+		ok = true
+		attempt.DeliveryID = delivery.ID
+		return tx.Create(attempt).Error
+	})
+	return ok && err == nil, err
+}
+
+func (r *WebhookRepository) Replay(id uuid.UUID) (bool, error) {
+	res := r.db.Model(&models.WebhookDelivery{}).Where("id = ? AND status <> ?", id, models.DeliveryPending).Updates(map[string]any{
+		"status":          models.DeliveryPending,
+		"attempts":        0,
+		"next_attempt_at": gorm.Expr("NOW()"),
+		"delivered_at":    nil,
+	})
+	return res.RowsAffected == 1, res.Error
+}
+
+func (r *WebhookRepository) withHistory() *gorm.DB {
+	return r.db.Preload("History", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id")
+	})
+}
@@ -0,0 +1,15 @@
+package routes
+
+import (
+	"github.com/Viktor2805/nucount/internal/controllers"
+
+	"github.com/gin-gonic/gin"
+)
+
+func SetupHistoryRoutes(router *gin.RouterGroup, controllers *controllers.Controllers) {
+	analyses := router.Group("/analyses")
+	{
+		analyses.GET("", controllers.HistoryController.List)
+		analyses.GET("/:id", controllers.HistoryController.Get)
+	}
+}
@@ -2,7 +2,7 @@
 package routes
 
 import (
-	"golang/internal/controllers"
+	"github.com/Viktor2805/nucount/internal/controllers"
 
 	"github.com/gin-gonic/gin"
 	swaggerFiles "github.com/swaggo/files"
@@ -13,6 +13,10 @@ import (
 // It sets up the route groups and binds the handlers for each route.
 func SetupRoutes(routerGroup *gin.RouterGroup, controllers *controllers.Controllers) {
 	SetupNucleotideRoutes(routerGroup, controllers)
+	SetupSequenceRoutes(routerGroup, controllers)
+	SetupJobRoutes(routerGroup, controllers)
+	SetupWebhookRoutes(routerGroup, controllers)
+	SetupHistoryRoutes(routerGroup, controllers)
 
 	routerGroup.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 }
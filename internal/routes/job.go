@@ -0,0 +1,24 @@
+package routes
+
+import (
+	"github.com/Viktor2805/nucount/internal/controllers"
+	"github.com/Viktor2805/nucount/internal/middleware"
+	nucleotide "github.com/Viktor2805/nucount/internal/services/nucleotide"
+
+	"github.com/gin-gonic/gin"
+)
+
+func SetupJobRoutes(router *gin.RouterGroup, controllers *controllers.Controllers) {
+	jobs := router.Group("/jobs")
+	{
+		jobs.POST(
+			"",
+			middleware.MaxUploadSizeMiddleware(nucleotide.MaxUploadSizeFASTA),
+			controllers.JobController.Create,
+		)
+		jobs.GET("/:id", controllers.JobController.Get)
+		jobs.DELETE("/:id", controllers.JobController.Cancel)
+		jobs.GET("/:id/events", controllers.JobController.Events)
+		jobs.GET("/:id/deliveries", controllers.JobController.Deliveries)
+	}
+}
@@ -1,9 +1,9 @@
 package routes
 
 import (
-	"golang/internal/controllers"
-	"golang/internal/middleware"
-	nucleotide "golang/internal/services/nucleotide"
+	"github.com/Viktor2805/nucount/internal/controllers"
+	"github.com/Viktor2805/nucount/internal/middleware"
+	nucleotide "github.com/Viktor2805/nucount/internal/services/nucleotide"
 
 	"github.com/gin-gonic/gin"
 )
@@ -16,5 +16,25 @@ func SetupNucleotideRoutes(router *gin.RouterGroup, controllers *controllers.Con
 			middleware.MaxUploadSizeMiddleware(nucleotide.MaxUploadSizeFASTA),
 			controllers.NucleotideController.Count,
 		)
+		dna.POST(
+			"/gc-profile",
+			middleware.MaxUploadSizeMiddleware(nucleotide.MaxUploadSizeFASTA),
+			controllers.NucleotideController.GCProfile,
+		)
+		dna.POST(
+			"/kmers",
+			middleware.MaxUploadSizeMiddleware(nucleotide.MaxUploadSizeFASTA),
+			controllers.NucleotideController.Kmers,
+		)
+		dna.POST(
+			"/dinucleotides",
+			middleware.MaxUploadSizeMiddleware(nucleotide.MaxUploadSizeFASTA),
+			controllers.NucleotideController.Dinucleotides,
+		)
+		dna.POST(
+			"/cpg-islands",
+			middleware.MaxUploadSizeMiddleware(nucleotide.MaxUploadSizeFASTA),
+			controllers.NucleotideController.CpGIslands,
+		)
 	}
 }
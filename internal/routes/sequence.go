@@ -0,0 +1,26 @@
+package routes
+
+import (
+	"github.com/Viktor2805/nucount/internal/controllers"
+	"github.com/Viktor2805/nucount/internal/middleware"
+	"github.com/Viktor2805/nucount/internal/services/sequence"
+
+	"github.com/gin-gonic/gin"
+)
+
+func SetupSequenceRoutes(router *gin.RouterGroup, controllers *controllers.Controllers) {
+	sequences := router.Group("/sequences")
+	{
+		sequences.POST(
+			"/import",
+			middleware.MaxUploadSizeMiddleware(sequence.MaxUploadSize),
+			controllers.SequenceController.Import,
+		)
+		sequences.GET("/export.2bit", controllers.SequenceController.Export2bit)
+		sequences.GET("", controllers.SequenceController.List)
+		sequences.GET("/:ref", controllers.SequenceController.Get)
+		sequences.PATCH("/:ref", controllers.SequenceController.Update)
+		sequences.DELETE("/:ref", controllers.SequenceController.Delete)
+		sequences.GET("/:ref/subsequence", controllers.SequenceController.Subsequence)
+	}
+}
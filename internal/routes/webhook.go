@@ -0,0 +1,15 @@
+package routes
+
+import (
+	"github.com/Viktor2805/nucount/internal/controllers"
+
+	"github.com/gin-gonic/gin"
+)
+
+func SetupWebhookRoutes(router *gin.RouterGroup, controllers *controllers.Controllers) {
+	deliveries := router.Group("/webhooks/deliveries")
+	{
+		deliveries.GET("/:id", controllers.WebhookController.Get)
+		deliveries.POST("/:id/replay", controllers.WebhookController.Replay)
+	}
+}
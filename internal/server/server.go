@@ -3,8 +3,8 @@ package server
 import (
 	"context"
 	"fmt"
-	"golang/internal/controllers"
-	"golang/internal/routes"
+	"github.com/Viktor2805/nucount/internal/controllers"
+	"github.com/Viktor2805/nucount/internal/routes"
 	"log"
 	"net/http"
 	_ "net/http/pprof"
@@ -88,6 +88,8 @@ func NewServer(options ...Option) *Server {
 		ReadTimeout:  10 * time.Minute,
 		WriteTimeout: 10 * time.Minute,
 	}
+	// Event streams last as long as their jobs: end them rather than wait.
+	server.httpServer.RegisterOnShutdown(server.controllers.JobController.Shutdown)
 
 	return server
 }
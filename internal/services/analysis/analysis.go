@@ -0,0 +1,177 @@
+// Package analysis keeps the history of analyses of uploaded files: what was
+// uploaded, how long the analysis took and its result.
+package analysis
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Viktor2805/nucount/internal/helpers"
+	"github.com/Viktor2805/nucount/internal/models"
+	repository "github.com/Viktor2805/nucount/internal/repository/analysis"
+	scope "github.com/Viktor2805/nucount/internal/repository/scope"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrNotFound is returned when a requested analysis does not exist.
+	ErrNotFound = errors.New("analysis not found")
+	// ErrDigestMismatch is returned when an upload does not have the digest
+	// it was declared with.
+	ErrDigestMismatch = errors.New("upload does not match its declared SHA-256")
+)
+
+// Upload describes an analysis to run and the file it runs on.
+type Upload struct {
+	Analysis string
+	// Params are the parameters the analysis runs with, in the canonical
+	// form that analyses.Analysis.Prepare returns them in, which Find looks
+	// analyses up by.
+	Params   url.Values
+	Format   helpers.Format
+	Filename string
+	// SHA256 is the hex digest of the upload, if known beforehand. It is
+	// checked once the upload has been read.
+	SHA256 string
+}
+
+// RunFunc runs an analysis on the upload read from r.
+type RunFunc func(ctx context.Context, r io.Reader) (any, error)
+
+type Service interface {
+	// Run runs fn on the upload read from r and records the analysis with its
+	// JSON-encoded result. The upload is hashed as it streams and read to the
+	// end. Nothing is recorded if fn fails; if the analysis cannot be stored,
+	// the error is logged and the returned analysis has a zero ID. An upload
+	// that has been analysed with the same result before is not recorded
+	// again: the earlier analysis is returned.
+	Run(ctx context.Context, u Upload, r io.Reader, fn RunFunc) (*models.Analysis, error)
+	Get(id uuid.UUID) (*models.Analysis, error)
+	// Find returns the latest analysis of the named analysis with params on
+	// an upload with the hex digest sha256, whose result can be served in
+	// place of running the analysis again.
+	Find(analysis string, params url.Values, sha256 string) (*models.Analysis, error)
+	// List returns a page of the analyses matching filter, newest first and
+	// without their results, and how many there are in total.
+	List(filter repository.Filter, limit, offset int) ([]models.Analysis, int64, error)
+}
+
+// History records analyses in the analysis repository.
+type History struct {
+	repo repository.AnalysisRepositoryI
+	opts options
+}
+
+func NewHistory(repo repository.AnalysisRepositoryI, opts ...Option) Service {
+	return &History{repo: repo, opts: newOptions(opts)}
+}
+
+func (h *History) Run(ctx context.Context, u Upload, r io.Reader, fn RunFunc) (*models.Analysis, error) {
+	d := newDigestReader(r)
+	start := time.Now()
+
+	result, err := fn(ctx, d)
+	if err != nil {
+		return nil, err
+	}
+	// The digest covers the whole upload, also when the analysis did not need
+	// all of it.
+	if _, err := io.Copy(io.Discard, d); err != nil {
+		return nil, err
+	}
+	if u.SHA256 != "" && !strings.EqualFold(u.SHA256, d.Sum()) {
+		return nil, ErrDigestMismatch
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		return nil, fmt.Errorf("encode result: %w", err)
+	}
+	end := time.Now()
+
+	if prev, err := h.Find(u.Analysis, u.Params, d.Sum()); err == nil && bytes.Equal(prev.Result, data) {
+		return prev, nil
+	}
+
+	analysis := &models.Analysis{
+		ID:         uuid.New(),
+		Analysis:   u.Analysis,
+		Params:     models.JobParams(u.Params),
+		Filename:   u.Filename,
+		Size:       d.n,
+		SHA256:     d.Sum(),
+		Format:     string(u.Format),
+		StartedAt:  start,
+		FinishedAt: end,
+		DurationMs: end.Sub(start).Milliseconds(),
+		Result:     data,
+	}
+	if err := h.repo.Create(analysis); err != nil {
+		h.opts.logger.Error("store analysis failed", zap.String("analysis", u.Analysis), zap.Error(err))
+		analysis.ID = uuid.Nil
+	}
+	return analysis, nil
+}
+
+func (h *History) Get(id uuid.UUID) (*models.Analysis, error) {
+	analysis, err := h.repo.FindByID(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	return analysis, err
+}
+
+func (h *History) Find(name string, params url.Values, sha256 string) (*models.Analysis, error) {
+	analysis, err := h.repo.FindLatest(name, models.JobParams(params), strings.ToLower(sha256))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	return analysis, err
+}
+
+func (h *History) List(filter repository.Filter, limit, offset int) ([]models.Analysis, int64, error) {
+	return h.repo.List(filter, scope.Paginate(limit, offset))
+}
+
+// ETag returns the strong entity tag of a representation of an analysis
+// result, e.g. its JSON result: the digest of its bytes, so that a result that
+// changes, say with another release, changes its entity tag as well.
+func ETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// digestReader hashes and counts the bytes read through it.
+type digestReader struct {
+	r    io.Reader
+	hash hash.Hash
+	n    int64
+}
+
+func newDigestReader(r io.Reader) *digestReader {
+	return &digestReader{r: r, hash: sha256.New()}
+}
+
+func (d *digestReader) Read(p []byte) (int, error) {
+	n, err := d.r.Read(p)
+	d.hash.Write(p[:n])
+	d.n += int64(n)
+	return n, err
+}
+
+// Sum returns the hex SHA-256 of the bytes read so far.
+func (d *digestReader) Sum() string {
+	return hex.EncodeToString(d.hash.Sum(nil))
+}
@@ -0,0 +1,185 @@
+package analysis_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/Viktor2805/nucount/internal/helpers"
+	"github.com/Viktor2805/nucount/internal/models"
+	"github.com/Viktor2805/nucount/internal/services/analysis"
+	"github.com/Viktor2805/nucount/internal/testutil"
+
+	"github.com/google/uuid"
+)
+
+var upload = analysis.Upload{
+	Analysis: "count",
+	Params:   url.Values{"precision": {"3"}},
+	Format:   helpers.FormatFASTA,
+	Filename: "a.fa",
+}
+
+func TestHistory_Run(t *testing.T) {
+	repo := &testutil.AnalysisRepo{}
+	h := analysis.NewHistory(repo)
+	payload := ">a\nACGT\n>b\nGG\n"
+
+	a, err := h.Run(context.Background(), upload, strings.NewReader(payload), func(_ context.Context, r io.Reader) (any, error) {
+		// Analyses may stop before the end of the upload.
+		buf := make([]byte, 3)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		return map[string]string{"head": string(buf)}, nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	sum := sha256.Sum256([]byte(payload))
+	if a.SHA256 != hex.EncodeToString(sum[:]) || a.Size != int64(len(payload)) {
+		t.Errorf("digest = %s, size = %d, want the whole upload's", a.SHA256, a.Size)
+	}
+	if string(a.Result) != `{"head":"\u003ea\n"}` {
+		t.Errorf("result = %s", a.Result)
+	}
+	if a.ID == uuid.Nil || a.Analysis != "count" || a.Filename != "a.fa" || a.Format != "fasta" || a.Params["precision"][0] != "3" {
+		t.Errorf("analysis = %+v", a)
+	}
+	if a.FinishedAt.Before(a.StartedAt) || a.DurationMs < 0 {
+		t.Errorf("timings = %v - %v (%d ms)", a.StartedAt, a.FinishedAt, a.DurationMs)
+	}
+
+	got, err := h.Get(a.ID)
+	if err != nil || got != a {
+		t.Errorf("Get = %v, %v, want the recorded analysis", got, err)
+	}
+}
+
+func TestHistory_RunFailed(t *testing.T) {
+	repo := &testutil.AnalysisRepo{}
+	h := analysis.NewHistory(repo)
+	want := errors.New("too many distinct k-mers")
+
+	_, err := h.Run(context.Background(), upload, strings.NewReader(">a\nACGT\n"), func(context.Context, io.Reader) (any, error) {
+		return nil, want
+	})
+	if !errors.Is(err, want) {
+		t.Errorf("err = %v, want %v", err, want)
+	}
+	if len(repo.Analyses) != 0 {
+		t.Errorf("failed analysis recorded: %+v", repo.Analyses)
+	}
+}
+
+func TestHistory_RunNotStored(t *testing.T) {
+	h := analysis.NewHistory(&testutil.AnalysisRepo{Err: errors.New("connection refused")})
+
+	a, err := h.Run(context.Background(), upload, strings.NewReader(">a\nACGT\n"), func(context.Context, io.Reader) (any, error) {
+		return []int{1, 2}, nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if a.ID != uuid.Nil || string(a.Result) != "[1,2]" {
+		t.Errorf("analysis = %+v, want the result without an ID", a)
+	}
+}
+
+func TestHistory_RunDigestMismatch(t *testing.T) {
+	repo := &testutil.AnalysisRepo{}
+	h := analysis.NewHistory(repo)
+
+	declared := upload
+	declared.SHA256 = strings.Repeat("ab", 32)
+	_, err := h.Run(context.Background(), declared, strings.NewReader(">a\nACGT\n"), func(context.Context, io.Reader) (any, error) {
+		return 1, nil
+	})
+	if !errors.Is(err, analysis.ErrDigestMismatch) {
+		t.Errorf("err = %v, want ErrDigestMismatch", err)
+	}
+	if len(repo.Analyses) != 0 {
+		t.Errorf("mismatched upload recorded: %+v", repo.Analyses)
+	}
+}
+
+func TestHistory_Find(t *testing.T) {
+	h := analysis.NewHistory(&testutil.AnalysisRepo{})
+	payload := ">a\nACGT\n"
+	sum := sha256.Sum256([]byte(payload))
+	digest := hex.EncodeToString(sum[:])
+
+	declared := upload
+	declared.SHA256 = strings.ToUpper(digest)
+	a, err := h.Run(context.Background(), declared, strings.NewReader(payload), func(context.Context, io.Reader) (any, error) {
+		return 1, nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got, err := h.Find("count", url.Values{"precision": {"3"}}, strings.ToUpper(digest))
+	if err != nil || got.ID != a.ID {
+		t.Errorf("Find = %v, %v, want the recorded analysis", got, err)
+	}
+	if _, err := h.Find("count", url.Values{"precision": {"2"}}, digest); !errors.Is(err, analysis.ErrNotFound) {
+		t.Errorf("Find with other parameters = %v, want ErrNotFound", err)
+	}
+	if _, err := h.Find("kmers", url.Values{"precision": {"3"}}, digest); !errors.Is(err, analysis.ErrNotFound) {
+		t.Errorf("Find of another analysis = %v, want ErrNotFound", err)
+	}
+}
+
+func TestHistory_RunDuplicate(t *testing.T) {
+	repo := &testutil.AnalysisRepo{}
+	h := analysis.NewHistory(repo)
+	result := 1
+	run := func() *models.Analysis {
+		t.Helper()
+		a, err := h.Run(context.Background(), upload, strings.NewReader(">a\nACGT\n"), func(context.Context, io.Reader) (any, error) {
+			return result, nil
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return a
+	}
+
+	first := run()
+	// The same upload without a declared digest is analysed again, but not
+	// recorded twice.
+	if again := run(); again.ID != first.ID || len(repo.Analyses) != 1 {
+		t.Errorf("repeated analysis = %+v, %d recorded; want the first", again, len(repo.Analyses))
+	}
+	// A different result is recorded and found from then on.
+	result = 2
+	if changed := run(); changed.ID == first.ID || len(repo.Analyses) != 2 {
+		t.Errorf("changed result = %+v, %d recorded; want a new analysis", changed, len(repo.Analyses))
+	}
+}
+
+func TestETag(t *testing.T) {
+	etag := analysis.ETag([]byte(`{"A":1}`))
+	if !strings.HasPrefix(etag, `"`) || !strings.HasSuffix(etag, `"`) || strings.HasPrefix(etag, "W/") {
+		t.Errorf("ETag = %s, want a strong entity tag", etag)
+	}
+	if analysis.ETag([]byte(`{"A":1}`)) != etag {
+		t.Errorf("ETag differs for the same result")
+	}
+	if analysis.ETag([]byte(`{"A":2}`)) == etag {
+		t.Errorf("ETag equal for different results")
+	}
+}
+
+func TestHistory_GetNotFound(t *testing.T) {
+	h := analysis.NewHistory(&testutil.AnalysisRepo{})
+	if _, err := h.Get(uuid.New()); !errors.Is(err, analysis.ErrNotFound) {
+		t.Errorf("err = %v, want ErrNotFound", err)
+	}
+}
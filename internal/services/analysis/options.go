@@ -0,0 +1,25 @@
+package analysis
+
+import "go.uber.org/zap"
+
+// Option configures the History.
+type Option func(*options)
+
+type options struct {
+	logger *zap.Logger
+}
+
+func newOptions(opts []Option) options {
+	o := options{logger: zap.NewNop()}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// WithLogger sets the logger of analyses that could not be stored.
+func WithLogger(l *zap.Logger) Option {
+	return func(o *options) {
+		o.logger = l
+	}
+}
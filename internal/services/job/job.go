@@ -0,0 +1,238 @@
+// Package job runs analyses of uploaded files in the background. Jobs and their
+// uploads are stored in Postgres, so any replica can run a job, and a job
+// outlives the replica that accepted it as well as the one running it.
+package job
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Viktor2805/nucount/internal/helpers"
+	"github.com/Viktor2805/nucount/internal/models"
+	repository "github.com/Viktor2805/nucount/internal/repository/job"
+	nucleotide "github.com/Viktor2805/nucount/internal/services/nucleotide"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrNotFound is returned when a requested job does not exist.
+	ErrNotFound = errors.New("job not found")
+	// ErrUnknownAnalysis is returned by a Runner asked for an analysis it
+	// does not provide.
+	ErrUnknownAnalysis = errors.New("unknown analysis")
+	// ErrFinished is returned when cancelling a job that has already finished.
+	ErrFinished = errors.New("job has already finished")
+	// ErrDigestMismatch is returned when an upload does not have the digest
+	// it was submitted with.
+	ErrDigestMismatch = errors.New("upload does not match its declared SHA-256")
+)
+
+// ThroughputWindow is the period over which Watch averages the throughput.
+const ThroughputWindow = 10 * time.Second
+
+// Runner runs the analysis of a job, as submitted, on its upload, with opts
+// given to the analysis, which report its progress. The result is stored as
+// JSON.
+type Runner interface {
+	Run(ctx context.Context, s Submission, r io.Reader, opts ...nucleotide.Option) (any, error)
+}
+
+// Submission describes a job to queue.
+type Submission struct {
+	Analysis string
+	Params   url.Values
+	Format   helpers.Format
+	Filename string
+	// SHA256 is the hex digest of the upload. It is set by the Worker. When
+	// submitting, it is the digest the client declared, if any: the upload is
+	// refused with ErrDigestMismatch unless it matches.
+	SHA256 string
+	// CallbackURL, if set, is POSTed the outcome of the job, signed with the
+	// secret of CallbackClient; see package webhook.
+	CallbackURL    string
+	CallbackClient string
+}
+
+type Service interface {
+	// Submit stores the upload read from r and queues the job. Nothing is
+	// stored if reading r fails or ctx is cancelled.
+	Submit(ctx context.Context, s Submission, r io.Reader) (*models.Job, error)
+	Get(id uuid.UUID) (*models.Job, error)
+	// Cancel cancels a queued or running job. A running job stops at its
+	// next heartbeat.
+	Cancel(id uuid.UUID) (*models.Job, error)
+	// Watch calls fn with the state of the job once per poll interval until
+	// the job has finished, fn fails or ctx is done. The last call of a job
+	// that finished is with its final state.
+	Watch(ctx context.Context, id uuid.UUID, fn func(Update) error) error
+}
+
+// Update is the state of a job seen by Watch.
+type Update struct {
+	*models.Job
+	// Throughput is the rate at which the current attempt has read the
+	// upload over the last ThroughputWindow, in bytes per second.
+	Throughput float64
+}
+
+// Queue submits jobs and looks them up; a Worker runs them.
+type Queue struct {
+	repo repository.JobRepositoryI
+	opts options
+}
+
+// NewQueue returns the job service. Of the options only WithPollInterval
+// applies, to Watch.
+func NewQueue(repo repository.JobRepositoryI, opts ...Option) Service {
+	return &Queue{repo: repo, opts: newOptions(opts)}
+}
+
+func (q *Queue) Submit(ctx context.Context, s Submission, r io.Reader) (*models.Job, error) {
+	job := &models.Job{
+		ID:       uuid.New(),
+		Analysis: s.Analysis,
+		Params:   models.JobParams(s.Params),
+		Filename: s.Filename,
+		Format:   string(s.Format),
+		Status:   models.JobQueued,
+
+		CallbackURL:    s.CallbackURL,
+		CallbackClient: s.CallbackClient,
+	}
+	r = contextReader{ctx: ctx, r: r}
+	if s.SHA256 != "" {
+		r = &digestReader{r: r, want: s.SHA256, hash: sha256.New()}
+	}
+	if err := q.repo.Create(job, r); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+func (q *Queue) Get(id uuid.UUID) (*models.Job, error) {
+	job, err := q.repo.FindByID(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	return job, err
+}
+
+func (q *Queue) Cancel(id uuid.UUID) (*models.Job, error) {
+	ok, err := q.repo.Cancel(id)
+	if err != nil {
+		return nil, err
+	}
+	job, err := q.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return job, ErrFinished
+	}
+	return job, nil
+}
+
+func (q *Queue) Watch(ctx context.Context, id uuid.UUID, fn func(Update) error) error {
+	var rate throughput
+	for {
+		job, err := q.Get(id)
+		if err != nil {
+			return err
+		}
+
+		if err := fn(Update{Job: job, Throughput: rate.add(job, time.Now())}); err != nil {
+			return err
+		}
+		if job.Finished() {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(q.opts.pollInterval):
+		}
+	}
+}
+
+// throughput is a rolling average of the read rate of a job.
+type throughput struct {
+	attempt int
+	samples []sample
+}
+
+type sample struct {
+	at    time.Time
+	bytes int64
+}
+
+// add records the progress of job at time now and returns the rate over the
+// samples in the window.
+func (t *throughput) add(job *models.Job, now time.Time) float64 {
+	if job.Attempts != t.attempt || job.Status != models.JobRunning {
+		// A new attempt reads the upload from the start.
+		t.attempt, t.samples = job.Attempts, t.samples[:0]
+	}
+	if job.Status != models.JobRunning {
+		return 0
+	}
+
+	t.samples = append(t.samples, sample{at: now, bytes: job.BytesRead})
+	i := 0
+	for i < len(t.samples)-1 && now.Sub(t.samples[i].at) > ThroughputWindow {
+		i++
+	}
+	t.samples = t.samples[i:]
+
+	first, last := t.samples[0], t.samples[len(t.samples)-1]
+	if !last.at.After(first.at) {
+		return 0
+	}
+	return float64(last.bytes-first.bytes) / last.at.Sub(first.at).Seconds()
+}
+
+// contextReader fails reads once ctx is done.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (c contextReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.r.Read(p)
+}
+
+// digestReader fails with ErrDigestMismatch at the end of r unless r has the
+// hex SHA-256 want. The error is sticky, so a failed upload is not queued.
+type digestReader struct {
+	r    io.Reader
+	want string
+	hash hash.Hash
+	err  error
+}
+
+func (d *digestReader) Read(p []byte) (int, error) {
+	if d.err != nil {
+		return 0, d.err
+	}
+	n, err := d.r.Read(p)
+	d.hash.Write(p[:n])
+	if err == io.EOF && !strings.EqualFold(hex.EncodeToString(d.hash.Sum(nil)), d.want) {
+		err = ErrDigestMismatch
+	}
+	if err != nil {
+		d.err = err
+	}
+	return n, err
+}
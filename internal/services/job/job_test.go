@@ -0,0 +1,122 @@
+package job_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Viktor2805/nucount/internal/models"
+	"github.com/Viktor2805/nucount/internal/services/job"
+
+	"github.com/google/uuid"
+)
+
+func TestQueue_GetNotFound(t *testing.T) {
+	_, err := job.NewQueue(newMemoryRepo()).Get(uuid.New())
+	if !errors.Is(err, job.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestQueue_SubmitDigest(t *testing.T) {
+	repo := newMemoryRepo()
+	q := job.NewQueue(repo)
+	const payload = ">a\nACGT\n"
+	sum := sha256.Sum256([]byte(payload))
+	digest := hex.EncodeToString(sum[:])
+
+	j, err := q.Submit(context.Background(), job.Submission{Analysis: "count", SHA256: strings.ToUpper(digest)}, strings.NewReader(payload))
+	if err != nil || j.SHA256 != digest {
+		t.Fatalf("Submit = %+v, %v; want a job with digest %s", j, err, digest)
+	}
+
+	_, err = q.Submit(context.Background(), job.Submission{Analysis: "count", SHA256: digest}, strings.NewReader(payload+">b\nA\n"))
+	if !errors.Is(err, job.ErrDigestMismatch) {
+		t.Errorf("other upload: err = %v, want ErrDigestMismatch", err)
+	}
+	if len(repo.jobs) != 1 {
+		t.Errorf("%d jobs stored, want 1", len(repo.jobs))
+	}
+}
+
+func TestQueue_Watch(t *testing.T) {
+	repo := newMemoryRepo()
+	submitted := submit(t, repo, "count", ">a\nACGT\n")
+	q := job.NewQueue(repo, job.WithPollInterval(10*time.Millisecond))
+
+	var updates []job.Update
+	err := q.Watch(context.Background(), submitted.ID, func(u job.Update) error {
+		updates = append(updates, job.Update{Job: u.Job, Throughput: u.Throughput})
+		// Play the worker: one poll queued, then reading 1000 bytes per poll.
+		switch n := len(updates); {
+		case n < 4:
+			repo.setProgress(submitted.ID, models.JobRunning, int64(n)*1000)
+		default:
+			repo.setProgress(submitted.ID, models.JobSucceeded, 8)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(updates) != 5 {
+		t.Fatalf("got %d updates, want 5", len(updates))
+	}
+	if updates[0].Status != models.JobQueued || updates[0].Throughput != 0 {
+		t.Errorf("first update = %+v", updates[0])
+	}
+	if updates[1].Throughput != 0 {
+		t.Errorf("throughput from a single sample = %v, want 0", updates[1].Throughput)
+	}
+	// 1000 bytes per 10ms poll; timer slack only makes it slower.
+	if tp := updates[3].Throughput; tp <= 0 || tp > 100_000 {
+		t.Errorf("throughput = %v, want in (0, 100000]", tp)
+	}
+	if last := updates[4]; last.Status != models.JobSucceeded {
+		t.Errorf("last update = %+v, want the finished job", last)
+	}
+}
+
+func TestQueue_WatchStopsWithContext(t *testing.T) {
+	repo := newMemoryRepo()
+	submitted := submit(t, repo, "count", ">a\nACGT\n")
+	q := job.NewQueue(repo, job.WithPollInterval(time.Hour))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	err := q.Watch(ctx, submitted.ID, func(job.Update) error {
+		cancel()
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestQueue_Cancel(t *testing.T) {
+	repo := newMemoryRepo()
+	submitted := submit(t, repo, "count", ">a\nACGT\n")
+	q := job.NewQueue(repo)
+
+	j, err := q.Cancel(submitted.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if j.Status != models.JobCancelled {
+		t.Errorf("status = %s, want cancelled", j.Status)
+	}
+	if _, ok := repo.payloads[submitted.ID]; ok {
+		t.Error("payload was not deleted")
+	}
+
+	if _, err := q.Cancel(submitted.ID); !errors.Is(err, job.ErrFinished) {
+		t.Errorf("cancelling twice: expected ErrFinished, got %v", err)
+	}
+	if _, err := q.Cancel(uuid.New()); !errors.Is(err, job.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,110 @@
+package job
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Defaults of the Worker options.
+const (
+	DefaultPollInterval = time.Second
+	DefaultHeartbeat    = time.Second
+	DefaultLease        = time.Minute
+	DefaultMaxAttempts  = 3
+
+	// UploadTimeout is how long a job may be uploading before it is
+	// considered abandoned by its replica and deleted; longer than any upload
+	// takes, the server's read timeout being 10 minutes.
+	UploadTimeout = time.Hour
+)
+
+// Option configures a Worker or the Queue.
+type Option func(*options)
+
+type options struct {
+	workers      int
+	pollInterval time.Duration
+	heartbeat    time.Duration
+	lease        time.Duration
+	maxAttempts  int
+	logger       *zap.Logger
+}
+
+func newOptions(opts []Option) options {
+	o := options{
+		workers:      1,
+		pollInterval: DefaultPollInterval,
+		heartbeat:    DefaultHeartbeat,
+		lease:        DefaultLease,
+		maxAttempts:  DefaultMaxAttempts,
+		logger:       zap.NewNop(),
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// heartbeatInterval is how often a running job's progress is stored and its
+// claim renewed: at least often enough that a few missed heartbeats do not let
+// the lease expire.
+func (o options) heartbeatInterval() time.Duration {
+	return min(o.heartbeat, o.lease/4)
+}
+
+// WithWorkers sets the number of jobs a Worker runs at the same time. A value
+// <= 0 disables the Worker, for replicas that only accept jobs.
+func WithWorkers(n int) Option {
+	return func(o *options) {
+		o.workers = n
+	}
+}
+
+// WithPollInterval sets how long an idle worker waits before looking for a
+// job again, and how often Watch looks at a job.
+func WithPollInterval(d time.Duration) Option {
+	return func(o *options) {
+		if d > 0 {
+			o.pollInterval = d
+		}
+	}
+}
+
+// WithHeartbeat sets how often a worker stores the progress of a running job,
+// which is also how often it notices that the job was cancelled.
+func WithHeartbeat(d time.Duration) Option {
+	return func(o *options) {
+		if d > 0 {
+			o.heartbeat = d
+		}
+	}
+}
+
+// WithLease sets how long a running job may go without a heartbeat before it
+// is considered abandoned, e.g. because its replica was killed, and claimed
+// again.
+func WithLease(d time.Duration) Option {
+	return func(o *options) {
+		if d > 0 {
+			o.lease = d
+		}
+	}
+}
+
+// WithMaxAttempts sets how many times a job is claimed before it is failed
+// instead, so that a job that kills its replica is not retried forever.
+func WithMaxAttempts(n int) Option {
+	return func(o *options) {
+		if n > 0 {
+			o.maxAttempts = n
+		}
+	}
+}
+
+// WithLogger sets the logger of job outcomes and database errors.
+func WithLogger(l *zap.Logger) Option {
+	return func(o *options) {
+		o.logger = l
+	}
+}
@@ -0,0 +1,200 @@
+package job
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Viktor2805/nucount/internal/helpers"
+	"github.com/Viktor2805/nucount/internal/models"
+	repository "github.com/Viktor2805/nucount/internal/repository/job"
+	"github.com/Viktor2805/nucount/internal/services/nucleotide"
+	"github.com/Viktor2805/nucount/internal/services/webhook"
+
+	"go.uber.org/zap"
+)
+
+// Worker claims queued jobs and runs them. Every replica runs a Worker; the
+// repository makes sure that each job is run by one of them at a time.
+type Worker struct {
+	repo   repository.JobRepositoryI
+	runner Runner
+	opts   options
+}
+
+func NewWorker(repo repository.JobRepositoryI, runner Runner, opts ...Option) *Worker {
+	return &Worker{repo: repo, runner: runner, opts: newOptions(opts)}
+}
+
+// Run runs jobs until ctx is done, then waits for the running jobs to stop.
+// A job interrupted this way is put back in the queue for another replica.
+// Meanwhile it deletes the uploads that have been abandoned, once per lease.
+func (w *Worker) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for i := 0; i < w.opts.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.loop(ctx)
+		}()
+	}
+	if w.opts.workers > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.sweep(ctx)
+		}()
+	}
+	wg.Wait()
+}
+
+// sweep deletes the abandoned uploads once per lease until ctx is done.
+func (w *Worker) sweep(ctx context.Context) {
+	t := time.NewTicker(w.opts.lease)
+	defer t.Stop()
+	for {
+		n, err := w.repo.DeleteUploads(UploadTimeout)
+		if err != nil {
+			w.opts.logger.Error("delete abandoned uploads failed", zap.Error(err))
+		} else if n > 0 {
+			w.opts.logger.Info("abandoned uploads deleted", zap.Int64("jobs", n))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+func (w *Worker) loop(ctx context.Context) {
+	for ctx.Err() == nil {
+		job, err := w.repo.Claim(w.opts.lease)
+		if err != nil {
+			w.opts.logger.Error("claim job failed", zap.Error(err))
+		}
+		if job != nil {
+			w.run(ctx, job)
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(w.opts.pollInterval):
+		}
+	}
+}
+
+// run runs a claimed job and stores its outcome.
+func (w *Worker) run(ctx context.Context, job *models.Job) {
+	log := w.opts.logger.With(zap.Stringer("job", job.ID), zap.Int("attempt", job.Attempts))
+
+	if job.Attempts > w.opts.maxAttempts {
+		w.finish(log, job, nil, fmt.Errorf("abandoned after %d attempts", w.opts.maxAttempts))
+		return
+	}
+
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var bytesRead, records atomic.Int64
+	var lost atomic.Bool
+	progress := nucleotide.WithProgress(func(p nucleotide.Progress) {
+		bytesRead.Store(p.Bytes)
+		records.Store(p.Records)
+	})
+
+	// Heartbeats run beside the analysis; they also stop it when the job has
+	// been cancelled or taken over by another replica.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		t := time.NewTicker(w.opts.heartbeatInterval())
+		defer t.Stop()
+		for {
+			select {
+			case <-runCtx.Done():
+				return
+			case <-t.C:
+			}
+
+			hb := *job
+			hb.BytesRead, hb.Records = bytesRead.Load(), records.Load()
+			ok, err := w.repo.Heartbeat(&hb)
+			if err != nil {
+				log.Warn("job heartbeat failed", zap.Error(err))
+				continue
+			}
+			if !ok {
+				lost.Store(true)
+				cancel()
+				return
+			}
+		}
+	}()
+
+	result, err := w.runner.Run(runCtx, Submission{
+		Analysis: job.Analysis,
+		Params:   url.Values(job.Params),
+		Format:   helpers.Format(job.Format),
+		Filename: job.Filename,
+		SHA256:   job.SHA256,
+	}, w.repo.Payload(job), progress)
+	cancel()
+	<-done
+
+	job.BytesRead, job.Records = bytesRead.Load(), records.Load()
+	switch {
+	case lost.Load():
+		log.Warn("job cancelled or claimed by another worker")
+	case err != nil && ctx.Err() != nil:
+		if err := w.repo.Release(job); err != nil {
+			log.Error("release job failed", zap.Error(err))
+			return
+		}
+		log.Info("job released")
+	default:
+		w.finish(log, job, result, err)
+	}
+}
+
+// finish stores the result of job, or runErr if it failed, and queues the
+// delivery of its callback.
+func (w *Worker) finish(log *zap.Logger, job *models.Job, result any, runErr error) {
+	if runErr == nil {
+		data, err := json.Marshal(result)
+		if err != nil {
+			runErr = fmt.Errorf("encode result: %w", err)
+		}
+		job.Result = data
+	}
+
+	now := time.Now()
+	job.FinishedAt = &now
+	job.Status = models.JobSucceeded
+	if runErr != nil {
+		job.Status = models.JobFailed
+		job.Result = nil
+		job.Error = runErr.Error()
+	}
+
+	delivery, err := webhook.NewDelivery(job)
+	if err != nil {
+		log.Error("create webhook delivery failed", zap.Error(err))
+	}
+
+	ok, err := w.repo.Finish(job, delivery)
+	switch {
+	case err != nil:
+		log.Error("store job outcome failed", zap.Error(err))
+	case !ok:
+		log.Warn("job cancelled or claimed by another worker")
+	default:
+		log.Info("job finished", zap.String("status", string(job.Status)), zap.String("error", job.Error))
+	}
+}
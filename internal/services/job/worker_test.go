@@ -0,0 +1,414 @@
+package job_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Viktor2805/nucount/internal/helpers"
+	"github.com/Viktor2805/nucount/internal/models"
+	"github.com/Viktor2805/nucount/internal/services/job"
+	nucleotide "github.com/Viktor2805/nucount/internal/services/nucleotide"
+	"github.com/Viktor2805/nucount/internal/testutil"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// memoryRepo keeps jobs in memory. Claims ignore leases: a running job is
+// only claimed again if the test marks it expired.
+type memoryRepo struct {
+	mu       sync.Mutex
+	jobs     []*models.Job
+	payloads map[uuid.UUID][]byte
+	expired  map[uuid.UUID]bool
+	lost     bool // fail heartbeats as if another worker took over
+	sweeps   int  // calls of DeleteUploads
+
+	deliveries []*models.WebhookDelivery
+}
+
+func newMemoryRepo() *memoryRepo {
+	return &memoryRepo{payloads: map[uuid.UUID][]byte{}, expired: map[uuid.UUID]bool{}}
+}
+
+func (m *memoryRepo) Create(j *models.Job, payload io.Reader) error {
+	data, err := io.ReadAll(payload)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	j.Size = int64(len(data))
+	sum := sha256.Sum256(data)
+	j.SHA256 = hex.EncodeToString(sum[:])
+	m.jobs = append(m.jobs, j)
+	m.payloads[j.ID] = data
+	return nil
+}
+
+func (m *memoryRepo) FindByID(id uuid.UUID) (*models.Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, j := range m.jobs {
+		if j.ID == id {
+			c := *j
+			return &c, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (m *memoryRepo) Claim(time.Duration) (*models.Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, j := range m.jobs {
+		if j.Status == models.JobQueued || j.Status == models.JobRunning && m.expired[j.ID] {
+			delete(m.expired, j.ID)
+			j.Status = models.JobRunning
+			j.Attempts++
+			c := *j
+			return &c, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *memoryRepo) Payload(j *models.Job) io.Reader {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return bytes.NewReader(m.payloads[j.ID])
+}
+
+func (m *memoryRepo) Heartbeat(j *models.Job) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stored := m.claimed(j)
+	if stored == nil || m.lost {
+		return false, nil
+	}
+	stored.BytesRead, stored.Records = j.BytesRead, j.Records
+	return true, nil
+}
+
+func (m *memoryRepo) Finish(j *models.Job, delivery *models.WebhookDelivery) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stored := m.claimed(j)
+	if stored == nil {
+		return false, nil
+	}
+	*stored = *j
+	delete(m.payloads, j.ID)
+	if delivery != nil {
+		m.deliveries = append(m.deliveries, delivery)
+	}
+	return true, nil
+}
+
+func (m *memoryRepo) Release(j *models.Job) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if stored := m.claimed(j); stored != nil {
+		stored.Status = models.JobQueued
+		stored.Attempts--
+	}
+	return nil
+}
+
+func (m *memoryRepo) Cancel(id uuid.UUID) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, j := range m.jobs {
+		if j.ID == id && !j.Finished() {
+			j.Status = models.JobCancelled
+			delete(m.payloads, id)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *memoryRepo) DeleteUploads(time.Duration) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sweeps++
+	return 0, nil
+}
+
+// setProgress updates the stored job as a worker would.
+func (m *memoryRepo) setProgress(id uuid.UUID, status models.JobStatus, bytesRead int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, j := range m.jobs {
+		if j.ID == id {
+			j.Status, j.BytesRead = status, bytesRead
+		}
+	}
+}
+
+func (m *memoryRepo) claimed(j *models.Job) *models.Job {
+	for _, s := range m.jobs {
+		if s.ID == j.ID && s.Attempts == j.Attempts && s.Status == models.JobRunning {
+			return s
+		}
+	}
+	return nil
+}
+
+// runnerFunc adapts a function to job.Runner.
+type runnerFunc func(ctx context.Context, s job.Submission, r io.Reader) (any, error)
+
+func (f runnerFunc) Run(ctx context.Context, s job.Submission, r io.Reader, _ ...nucleotide.Option) (any, error) {
+	return f(ctx, s, r)
+}
+
+// runUntil runs w until cond holds for the stored job or the test times out.
+func runUntil(t *testing.T, w *job.Worker, repo *memoryRepo, id uuid.UUID, cond func(*models.Job) bool) *models.Job {
+	t.Helper()
+	return testutil.RunUntil(t, w.Run, func() (*models.Job, error) { return repo.FindByID(id) }, cond)
+}
+
+func submit(t *testing.T, repo *memoryRepo, analysis, payload string) *models.Job {
+	t.Helper()
+
+	j, err := job.NewQueue(repo).Submit(context.Background(), job.Submission{
+		Analysis: analysis,
+		Params:   url.Values{"precision": {"3"}},
+		Format:   helpers.FormatFASTA,
+		Filename: "a.fa",
+	}, strings.NewReader(payload))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return j
+}
+
+func finished(j *models.Job) bool {
+	return j.Status == models.JobSucceeded || j.Status == models.JobFailed
+}
+
+func TestWorker_Run(t *testing.T) {
+	repo := newMemoryRepo()
+	submitted := submit(t, repo, "count", ">a\nACGT\n")
+	if submitted.Status != models.JobQueued || submitted.Size != 8 {
+		t.Fatalf("submitted job = %+v", submitted)
+	}
+
+	runner := runnerFunc(func(_ context.Context, s job.Submission, r io.Reader) (any, error) {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{
+			"analysis":  s.Analysis,
+			"precision": s.Params.Get("precision"),
+			"format":    s.Format,
+			"filename":  s.Filename,
+			"sha256":    s.SHA256,
+			"payload":   string(data),
+		}, nil
+	})
+	w := job.NewWorker(repo, runner, job.WithPollInterval(time.Millisecond))
+
+	j := runUntil(t, w, repo, submitted.ID, finished)
+	if j.Status != models.JobSucceeded || j.Error != "" || j.FinishedAt == nil {
+		t.Fatalf("job = %+v", j)
+	}
+	want := `{"analysis":"count","filename":"a.fa","format":"fasta","payload":"\u003ea\nACGT\n","precision":"3",` +
+		`"sha256":"ec93753459551ffadb17f616d5e6bd45de641c045083fac84c4fa223987b232b"}`
+	if string(j.Result) != want {
+		t.Errorf("result = %s, want %s", j.Result, want)
+	}
+	if _, ok := repo.payloads[j.ID]; ok {
+		t.Error("payload was not deleted")
+	}
+	if len(repo.deliveries) != 0 {
+		t.Errorf("got %d deliveries for a job without callback", len(repo.deliveries))
+	}
+}
+
+func TestWorker_Callback(t *testing.T) {
+	repo := newMemoryRepo()
+	submitted, err := job.NewQueue(repo).Submit(context.Background(), job.Submission{
+		Analysis:       "count",
+		Format:         helpers.FormatFASTA,
+		CallbackURL:    "https://orchestrator.example/hooks",
+		CallbackClient: "orchestrator",
+	}, strings.NewReader(">a\nACGT\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	runner := runnerFunc(func(context.Context, job.Submission, io.Reader) (any, error) {
+		return map[string]int{"A": 1}, nil
+	})
+	w := job.NewWorker(repo, runner, job.WithPollInterval(time.Millisecond))
+	runUntil(t, w, repo, submitted.ID, finished)
+
+	if len(repo.deliveries) != 1 {
+		t.Fatalf("got %d deliveries, want 1", len(repo.deliveries))
+	}
+	d := repo.deliveries[0]
+	if d.JobID != submitted.ID || d.URL != "https://orchestrator.example/hooks" || d.Client != "orchestrator" ||
+		d.Event != "job.succeeded" || d.Status != models.DeliveryPending {
+		t.Errorf("delivery = %+v", d)
+	}
+	if !strings.Contains(string(d.Payload), `"result":{"A":1}`) {
+		t.Errorf("payload = %s, want the result", d.Payload)
+	}
+}
+
+func TestWorker_RunFailed(t *testing.T) {
+	repo := newMemoryRepo()
+	submitted := submit(t, repo, "kmers", ">a\nACGT\n")
+
+	runner := runnerFunc(func(context.Context, job.Submission, io.Reader) (any, error) {
+		return nil, errors.New("too many distinct k-mers")
+	})
+	w := job.NewWorker(repo, runner, job.WithPollInterval(time.Millisecond))
+
+	j := runUntil(t, w, repo, submitted.ID, finished)
+	if j.Status != models.JobFailed || j.Error != "too many distinct k-mers" || j.Result != nil {
+		t.Errorf("job = %+v", j)
+	}
+}
+
+func TestWorker_ReleasesOnShutdown(t *testing.T) {
+	repo := newMemoryRepo()
+	submitted := submit(t, repo, "count", ">a\nACGT\n")
+
+	started := make(chan struct{})
+	runner := runnerFunc(func(ctx context.Context, _ job.Submission, _ io.Reader) (any, error) {
+		close(started)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	w := job.NewWorker(repo, runner, job.WithPollInterval(time.Millisecond))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		w.Run(ctx)
+	}()
+	<-started
+	cancel()
+	<-done
+
+	j, _ := repo.FindByID(submitted.ID)
+	if j.Status != models.JobQueued || j.Attempts != 0 {
+		t.Errorf("released job = %+v, want queued without a used attempt", j)
+	}
+}
+
+func TestWorker_MaxAttempts(t *testing.T) {
+	repo := newMemoryRepo()
+	submitted := submit(t, repo, "count", ">a\nACGT\n")
+	// The job has been claimed twice by replicas that died while running it.
+	repo.jobs[0].Status = models.JobRunning
+	repo.jobs[0].Attempts = 2
+	repo.expired[submitted.ID] = true
+
+	runner := runnerFunc(func(context.Context, job.Submission, io.Reader) (any, error) {
+		t.Error("job run after its last attempt")
+		return nil, nil
+	})
+	w := job.NewWorker(repo, runner, job.WithPollInterval(time.Millisecond), job.WithMaxAttempts(2))
+
+	j := runUntil(t, w, repo, submitted.ID, finished)
+	if j.Status != models.JobFailed || j.Error != "abandoned after 2 attempts" {
+		t.Errorf("job = %+v", j)
+	}
+}
+
+func TestWorker_LostClaim(t *testing.T) {
+	repo := newMemoryRepo()
+	repo.lost = true
+	submitted := submit(t, repo, "count", ">a\nACGT\n")
+
+	stopped := make(chan struct{})
+	runner := runnerFunc(func(ctx context.Context, _ job.Submission, _ io.Reader) (any, error) {
+		<-ctx.Done()
+		close(stopped)
+		return nil, ctx.Err()
+	})
+	w := job.NewWorker(repo, runner, job.WithPollInterval(time.Hour), job.WithLease(20*time.Millisecond))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go w.Run(ctx)
+
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("analysis not cancelled after the claim was lost")
+	}
+
+	// The job belongs to whoever took it over; this worker must not touch it.
+	time.Sleep(20 * time.Millisecond)
+	j, _ := repo.FindByID(submitted.ID)
+	if j.Status != models.JobRunning || j.Attempts != 1 {
+		t.Errorf("job = %+v", j)
+	}
+}
+
+func TestWorker_Cancelled(t *testing.T) {
+	repo := newMemoryRepo()
+	submitted := submit(t, repo, "count", ">a\nACGT\n")
+
+	started := make(chan struct{})
+	stopped := make(chan struct{})
+	runner := runnerFunc(func(ctx context.Context, _ job.Submission, _ io.Reader) (any, error) {
+		close(started)
+		<-ctx.Done()
+		close(stopped)
+		return nil, ctx.Err()
+	})
+	w := job.NewWorker(repo, runner, job.WithPollInterval(time.Hour), job.WithHeartbeat(5*time.Millisecond))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go w.Run(ctx)
+
+	<-started
+	if _, err := job.NewQueue(repo).Cancel(submitted.ID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("analysis not stopped after the job was cancelled")
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	j, _ := repo.FindByID(submitted.ID)
+	if j.Status != models.JobCancelled || j.Error != "" {
+		t.Errorf("job = %+v, want it left cancelled", j)
+	}
+}
+
+func TestWorker_DeletesAbandonedUploads(t *testing.T) {
+	repo := newMemoryRepo()
+	w := job.NewWorker(repo, runnerFunc(nil), job.WithLease(10*time.Millisecond))
+	testutil.RunUntil(t, w.Run, func() (int, error) {
+		repo.mu.Lock()
+		defer repo.mu.Unlock()
+		return repo.sweeps, nil
+	}, func(n int) bool { return n >= 3 })
+
+	// A disabled worker leaves uploads to the replicas that run jobs.
+	repo = newMemoryRepo()
+	job.NewWorker(repo, runnerFunc(nil), job.WithWorkers(0)).Run(context.Background())
+	if repo.sweeps != 0 {
+		t.Errorf("disabled worker swept %d times", repo.sweeps)
+	}
+}
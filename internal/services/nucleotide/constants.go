@@ -1,3 +1,10 @@
 package nucleotide
 
 const MaxUploadSizeFASTA = 2 << 30 // 2 GB limit
+
+// bufSize is the size of the read buffer used when streaming uploads.
+const bufSize = 4 << 20
+
+// DefaultMaxDecompressedSize is the default limit on the decompressed size of
+// compressed uploads.
+const DefaultMaxDecompressedSize = 8 << 30 // 8 GB
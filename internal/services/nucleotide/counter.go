@@ -2,103 +2,74 @@
 package nucleotide
 
 import (
+	"context"
 	"io"
-	"mime/multipart"
 	_ "net/http/pprof"
+
+	"github.com/Viktor2805/nucount/pkg/nucount"
 )
 
+// Service runs the analyses of FASTA, FASTQ and 2bit streams. Options given to
+// an analysis apply to that call only, on top of those of the constructor.
 type Service interface {
-	Count(file multipart.File) (NucleotideCount, error)
-}
-
-// NucleotideCount represents raw counts of A, C, G, and T
-type NucleotideCount struct {
-	A int
-	C int
-	G int
-	T int
+	Count(ctx context.Context, r io.Reader, with ...Option) (NucleotideCount, error)
+	CountRecords(ctx context.Context, r io.Reader, with ...Option) (CountResult, error)
+	GCProfile(ctx context.Context, r io.Reader, opts WindowOptions, with ...Option) (GCProfile, error)
+	CpGIslands(ctx context.Context, r io.Reader, opts IslandOptions, with ...Option) (IslandResult, error)
+	CountFASTQ(ctx context.Context, r io.Reader, with ...Option) (FASTQStats, error)
+	Count2bit(ctx context.Context, r io.Reader, with ...Option) (TwoBitResult, error)
 }
 
-// Total returns the total number of nucleotides counted.
-func (n NucleotideCount) Total() int {
-	return n.A + n.C + n.G + n.T
-}
+// The counts and their composition metrics are those of package nucount; the
+// analyses of this package add decompression, cancellation and progress
+// reporting around it.
+type (
+	NucleotideCount = nucount.Count
+	RecordCount     = nucount.RecordCount
+	CountResult     = nucount.CountResult
+)
 
 // NucleotideCounter is used to count nucleotides in a DNA sequence.
-type Counter struct{}
-
-func NewCounter() Service {
-	return &Counter{}
+type Counter struct {
+	opts options
 }
 
-// CountNucleotides counts the nucleotides in the uploaded FASTA file.
-func (s *Counter) Count(file multipart.File) (NucleotideCount, error) {
-	defer file.Close()
-
-	const bufSize = 4 << 20
-	buf := make([]byte, bufSize)
-
-	var lutA, lutC, lutG, lutT [256]uint8
-	lutA['A'], lutA['a'] = 1, 1
-	lutC['C'], lutC['c'] = 1, 1
-	lutG['G'], lutG['g'] = 1, 1
-	lutT['T'], lutT['t'] = 1, 1
-
-	var count NucleotideCount
-	inHeader := false
-
-	for {
-		n, err := file.Read(buf)
-		if n > 0 {
-			p := buf[:n]
-			i := 0
+func NewCounter(opts ...Option) Service {
+	return &Counter{opts: newOptions(opts)}
+}
 
-			for i < len(p) {
-				if inHeader {
-					j := i
-					for j < len(p) && p[j] != '\n' {
-						j++
-					}
-					if j < len(p) && p[j] == '\n' {
-						inHeader = false
-						i = j + 1
-						continue
-					}
-					break
-				}
+// CountNucleotides counts the nucleotides of the FASTA stream r. With
+// WithWorkers the file is counted in parallel chunks.
+func (s *Counter) Count(ctx context.Context, r io.Reader, with ...Option) (NucleotideCount, error) {
+	o := s.opts.apply(with)
+	in, err := o.open(ctx, r)
+	if err != nil {
+		return NucleotideCount{}, err
+	}
+	defer in.Close()
 
-				if p[i] == '>' && (i == 0 || p[i-1] == '\n') {
-					inHeader = true
-					i++
-					continue
-				}
+	if o.workers > 1 {
+		return nucount.CountFASTAParallel(in, o.workers)
+	}
 
-				j := i
-				for j < len(p) && p[j] != '\n' {
-					j++
-				}
-				segment := p[i:j]
+	var c nucount.Counter
+	err = nucount.ScanFASTA(in, in.records(&c))
 
-				for _, b := range segment {
-					count.A += int(lutA[b])
-					count.C += int(lutC[b])
-					count.G += int(lutG[b])
-					count.T += int(lutT[b])
-				}
+	return c.Count(), err
+}
 
-				if j < len(p) && p[j] == '\n' {
-					j++
-				}
-				i = j
-			}
-		}
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			return count, err
-		}
+// CountRecords counts the nucleotides of every record of the FASTA stream r
+// separately. Sequence data found before the first header is reported as a
+// record with an empty ID.
+func (s *Counter) CountRecords(ctx context.Context, r io.Reader, with ...Option) (CountResult, error) {
+	in, err := s.opts.apply(with).open(ctx, r)
+	if err != nil {
+		return CountResult{}, err
 	}
+	defer in.Close()
+
+	var c nucount.RecordCounter
+	err = nucount.ScanFASTA(in, in.records(&c))
 
-	return count, nil
+	return c.Result(), err
 }
@@ -1,7 +1,10 @@
 package nucleotide_test
 
 import (
-	nucleotide "golang/internal/services/nucleotide"
+	"context"
+	"fmt"
+	nucleotide "github.com/Viktor2805/nucount/internal/services/nucleotide"
+	"runtime"
 	"strings"
 	"testing"
 )
@@ -13,7 +16,7 @@ func BenchmarkCountNucleotides_Small(b *testing.B) {
 	b.ResetTimer()
 
 	for i := 0; i < b.N; i++ {
-		_, err := counter.Count(asMultipartFile(input))
+		_, err := counter.Count(context.Background(), asMultipartFile(input))
 		if err != nil {
 			b.Fatal(err)
 		}
@@ -27,7 +30,7 @@ func BenchmarkCountNucleotides_1MB(b *testing.B) {
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		_, err := counter.Count(asMultipartFile(input))
+		_, err := counter.Count(context.Background(), asMultipartFile(input))
 		if err != nil {
 			b.Fatal(err)
 		}
@@ -40,7 +43,80 @@ func BenchmarkCountNucleotides_10MB(b *testing.B) {
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		_, err := counter.Count(asMultipartFile(input))
+		_, err := counter.Count(context.Background(), asMultipartFile(input))
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkCountNucleotides_Parallel compares sequential counting (workers=1)
+// with the parallel mode on a multi-record 128 MB file.
+func BenchmarkCountNucleotides_Parallel(b *testing.B) {
+	record := ">chr some description\n" + strings.Repeat(strings.Repeat("ACGTNacgtn", 6)+"\n", (1<<20)/61)
+	input := strings.Repeat(record, 128)
+
+	counts := []int{1, 2, 4}
+	if n := runtime.NumCPU(); n > 4 {
+		counts = append(counts, n)
+	}
+	for _, workers := range counts {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			counter := nucleotide.NewCounter(nucleotide.WithWorkers(workers))
+			b.SetBytes(int64(len(input)))
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				_, err := counter.Count(context.Background(), asMultipartFile(input))
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkGCProfile_10MB(b *testing.B) {
+	counter := nucleotide.NewCounter()
+	input := ">chr\n" + strings.Repeat("ACGT", (10<<20)/4)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, err := counter.GCProfile(context.Background(), asMultipartFile(input), nucleotide.WindowOptions{Size: 1000, Step: 500})
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkCountKmers_10MB(b *testing.B) {
+	counter := nucleotide.NewKmerCounter()
+	input := ">chr\n" + strings.Repeat("ACGTTGCA", (10<<20)/8)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, err := counter.CountKmers(context.Background(), asMultipartFile(input), nucleotide.KmerOptions{K: 21, Canonical: true, Top: 10})
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkCount2bit_10Mbp(b *testing.B) {
+	counter := nucleotide.NewCounter()
+	// Assembly-like layout: long runs of bases with gaps and masked repeats.
+	unit := strings.Repeat("ACGT", 1<<14) + strings.Repeat("N", 1000) + strings.Repeat("acgt", 1<<12)
+	seq := strings.Repeat(unit, (10<<20)/len(unit))
+
+	var buf strings.Builder
+	if err := nucleotide.WriteTwoBit(&buf, []nucleotide.TwoBitSequence{{Name: "chr", Sequence: seq}}); err != nil {
+		b.Fatal(err)
+	}
+	input := buf.String()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, err := counter.Count2bit(context.Background(), asMultipartFile(input))
 		if err != nil {
 			b.Fatal(err)
 		}
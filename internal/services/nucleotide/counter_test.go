@@ -2,12 +2,15 @@ package nucleotide_test
 
 import (
 	"bytes"
+	"compress/gzip"
+	"context"
 	"errors"
 	"mime/multipart"
 	"strings"
 	"testing"
 
-	nucleotide "golang/internal/services/nucleotide"
+	"github.com/Viktor2805/nucount/internal/helpers"
+	nucleotide "github.com/Viktor2805/nucount/internal/services/nucleotide"
 )
 
 type errAfterFile struct {
@@ -78,7 +81,7 @@ func TestCountBases(t *testing.T) {
 			t.Parallel()
 
 			file := asMultipartFile(tt.input)
-			got, err := counter.Count(file)
+			got, err := counter.Count(context.Background(), file)
 			if (err != nil) != tt.wantErr {
 				t.Fatalf("CountBases() error = %v, wantErr %v", err, tt.wantErr)
 			}
@@ -95,7 +98,7 @@ func TestCountBases_MultipleLinesPerSeq(t *testing.T) {
 	input := ">seq1\nACGTAC\nGT\n>seq2 description\nCC\nTTAA\n"
 	want := nucleotide.NucleotideCount{A: 4, C: 4, G: 2, T: 4}
 
-	got, err := counter.Count(asMultipartFile(input))
+	got, err := counter.Count(context.Background(), asMultipartFile(input))
 	if err != nil {
 		t.Fatalf("CountBases() error: %v", err)
 	}
@@ -107,7 +110,7 @@ func TestCountBases_MultipleLinesPerSeq(t *testing.T) {
 func TestCountBases_ErrorFile(t *testing.T) {
 	counter := nucleotide.NewCounter()
 
-	_, err := counter.Count(errAfterFile{})
+	_, err := counter.Count(context.Background(), errAfterFile{})
 	if err == nil {
 		t.Fatal("expected error, got nil")
 	}
@@ -123,7 +126,7 @@ func TestCountBases_HeaderBreak(t *testing.T) {
 		limit:  1,
 	}
 
-	got, err := counter.Count(f)
+	got, err := counter.Count(context.Background(), f)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -132,3 +135,190 @@ func TestCountBases_HeaderBreak(t *testing.T) {
 		t.Fatalf("CountBases() = %+v, want %+v", got, want)
 	}
 }
+
+func TestCountRecords(t *testing.T) {
+	counter := nucleotide.NewCounter()
+
+	input := ">chr1 Homo sapiens chromosome 1\nACGTAC\nGTNN\n>chr2\r\nGGCC\r\n>empty\n"
+
+	got, err := counter.CountRecords(context.Background(), asMultipartFile(input))
+	if err != nil {
+		t.Fatalf("CountRecords() error: %v", err)
+	}
+
+	want := []nucleotide.RecordCount{
+		{
+			ID:          "chr1",
+			Description: "Homo sapiens chromosome 1",
+			Count:       nucleotide.NucleotideCount{A: 2, C: 2, G: 2, T: 2, N: 2},
+		},
+		{
+			ID:    "chr2",
+			Count: nucleotide.NucleotideCount{C: 2, G: 2},
+		},
+		{ID: "empty"},
+	}
+
+	if len(got.Records) != len(want) {
+		t.Fatalf("CountRecords() returned %d records, want %d", len(got.Records), len(want))
+	}
+	for i := range want {
+		if got.Records[i] != want[i] {
+			t.Errorf("record %d = %+v, want %+v", i, got.Records[i], want[i])
+		}
+	}
+
+	wantTotal := nucleotide.NucleotideCount{A: 2, C: 4, G: 4, T: 2, N: 2}
+	if got.Total != wantTotal || got.Total.Length() != 14 {
+		t.Fatalf("CountRecords() total = %+v, want %+v", got.Total, wantTotal)
+	}
+}
+
+func TestCountRecords_NoHeader(t *testing.T) {
+	counter := nucleotide.NewCounter()
+
+	got, err := counter.CountRecords(context.Background(), asMultipartFile("ACGT\nACGT\n"))
+	if err != nil {
+		t.Fatalf("CountRecords() error: %v", err)
+	}
+	if len(got.Records) != 1 || got.Records[0].ID != "" || got.Records[0].Length() != 8 {
+		t.Fatalf("CountRecords() = %+v, want one anonymous record of length 8", got.Records)
+	}
+}
+
+func TestCountRecords_HeaderBreak(t *testing.T) {
+	counter := nucleotide.NewCounter()
+
+	input := ">seq1 " + strings.Repeat("d", 100) + "\nACGT\n>seq2\nGG\n"
+
+	f := &limitedFile{
+		Reader: bytes.NewReader([]byte(input)),
+		limit:  3,
+	}
+
+	got, err := counter.CountRecords(context.Background(), f)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got.Records) != 2 {
+		t.Fatalf("CountRecords() returned %d records, want 2", len(got.Records))
+	}
+	if got.Records[0].ID != "seq1" || got.Records[0].Description != strings.Repeat("d", 100) {
+		t.Fatalf("unexpected first header: %+v", got.Records[0])
+	}
+	if got.Records[1].ID != "seq2" || got.Records[1].G != 2 {
+		t.Fatalf("unexpected second record: %+v", got.Records[1])
+	}
+}
+
+func TestCountBases_IUPAC(t *testing.T) {
+	counter := nucleotide.NewCounter()
+
+	input := ">aln\nACGTU\nnrysw kmbdhv\r\nAC--GT..\nXZ*1\n"
+	want := nucleotide.NucleotideCount{
+		A: 2, C: 2, G: 2, T: 2, U: 1,
+		N: 1, R: 1, Y: 1, S: 1, W: 1, K: 1, M: 1, B: 1, D: 1, H: 1, V: 1,
+		Gap: 4, Invalid: 4,
+	}
+
+	got, err := counter.Count(context.Background(), asMultipartFile(input))
+	if err != nil {
+		t.Fatalf("CountBases() error: %v", err)
+	}
+	if got != want {
+		t.Fatalf("CountBases() = %+v, want %+v", got, want)
+	}
+	if got.Total() != 8 || got.Ambiguous() != 11 || got.Length() != 28 {
+		t.Fatalf("Total/Ambiguous/Length = %d/%d/%d, want 8/11/28", got.Total(), got.Ambiguous(), got.Length())
+	}
+}
+
+func gzipped(t *testing.T, s string) string {
+	t.Helper()
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write([]byte(s)); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestCountBases_Gzip(t *testing.T) {
+	input := gzipped(t, ">seq1\nACGTAC\nGT\n") + gzipped(t, ">seq2\nCC\nTTAA\n")
+
+	got, err := nucleotide.NewCounter().Count(context.Background(), asMultipartFile(input))
+	if err != nil {
+		t.Fatalf("CountBases() error: %v", err)
+	}
+	want := nucleotide.NucleotideCount{A: 4, C: 4, G: 2, T: 4}
+	if got != want {
+		t.Fatalf("CountBases() = %+v, want %+v", got, want)
+	}
+
+	counter := nucleotide.NewCounter(nucleotide.WithMaxDecompressedSize(10))
+	_, err = counter.Count(context.Background(), asMultipartFile(input))
+	if !errors.Is(err, helpers.ErrDecompressedTooLarge) {
+		t.Fatalf("CountBases() error = %v, want ErrDecompressedTooLarge", err)
+	}
+}
+
+func TestNucleotideCount_GCContent(t *testing.T) {
+	if got := (nucleotide.NucleotideCount{A: 1, C: 1, G: 2, T: 0}).GCContent(); got != 75 {
+		t.Fatalf("GCContent() = %v, want 75", got)
+	}
+	if got := (nucleotide.NucleotideCount{}).GCContent(); got != 0 {
+		t.Fatalf("GCContent() of empty count = %v, want 0", got)
+	}
+}
+
+func TestCount_Parallel(t *testing.T) {
+	t.Parallel()
+
+	// Parallel counting reads 4 MiB chunks; place headers, newlines and CRLF
+	// pairs across the chunk boundaries.
+	const chunk = 4 << 20
+
+	body := func(n int) string { return strings.Repeat("ACGTN", n/5+1)[:n] }
+	inputs := map[string]string{
+		"header across boundary":   body(chunk-3) + "\n>rec2 a long description\n" + body(chunk),
+		"newline at boundary":      body(chunk-1) + "\n>x\n" + body(100),
+		"header at chunk start":    body(chunk-1) + "\n" + ">hdr\n" + body(chunk+17),
+		"crlf across boundary":     strings.ReplaceAll(body(chunk-1)+"\n>y\n"+body(50), "\n", "\r\n"),
+		"header spans full chunk":  "\n>" + strings.Repeat("h", chunk+10) + "\nACGT\n",
+		"no trailing newline":      ">a\n" + body(2*chunk+5),
+		"small":                    ">a\nACGT\n>b\nNNRY\n",
+		"gt inside sequence lines": body(chunk-2) + ">" + body(10),
+	}
+
+	sequential := nucleotide.NewCounter()
+	parallel := nucleotide.NewCounter(nucleotide.WithWorkers(4))
+
+	for name, input := range inputs {
+		t.Run(name, func(t *testing.T) {
+			want, err := sequential.Count(context.Background(), asMultipartFile(input))
+			if err != nil {
+				t.Fatalf("sequential: %v", err)
+			}
+			got, err := parallel.Count(context.Background(), asMultipartFile(input))
+			if err != nil {
+				t.Fatalf("parallel: %v", err)
+			}
+			if got != want {
+				t.Errorf("parallel = %+v, sequential = %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestCount_ParallelReadError(t *testing.T) {
+	t.Parallel()
+
+	_, err := nucleotide.NewCounter(nucleotide.WithWorkers(2)).Count(context.Background(), errAfterFile{bytes.NewReader(nil)})
+	if err == nil {
+		t.Fatal("expected read error")
+	}
+}
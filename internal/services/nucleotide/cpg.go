@@ -0,0 +1,239 @@
+package nucleotide
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/Viktor2805/nucount/pkg/nucount"
+)
+
+// Gardiner-Garden & Frommer (1987) CpG island criteria.
+const (
+	DefaultIslandMinLength = 200
+	DefaultIslandMinGC     = 50.0
+	DefaultIslandMinObsExp = 0.6
+)
+
+// MinIslandLength is the smallest window of a CpG island search; windows are
+// at most MaxWindowSize bases.
+const MinIslandLength = 2
+
+// ErrInvalidIslandOptions is returned for unusable CpG island thresholds.
+var ErrInvalidIslandOptions = errors.New("invalid CpG island options")
+
+// IslandOptions holds the CpG island thresholds. A zero threshold is a
+// threshold like any other; DefaultIslandOptions returns the Gardiner-Garden
+// criteria.
+type IslandOptions struct {
+	MinLength int     // window length in bases
+	MinGC     float64 // minimum GC percentage of a window
+	MinObsExp float64 // minimum CpG observed/expected ratio of a window
+}
+
+// DefaultIslandOptions returns the Gardiner-Garden & Frommer thresholds.
+func DefaultIslandOptions() IslandOptions {
+	return IslandOptions{
+		MinLength: DefaultIslandMinLength,
+		MinGC:     DefaultIslandMinGC,
+		MinObsExp: DefaultIslandMinObsExp,
+	}
+}
+
+func (o IslandOptions) validate() (IslandOptions, error) {
+	if o.MinLength < MinIslandLength || o.MinLength > MaxWindowSize {
+		return o, fmt.Errorf("%w: minimum length must be between %d and %d", ErrInvalidIslandOptions, MinIslandLength, MaxWindowSize)
+	}
+	if o.MinGC < 0 || o.MinGC > 100 {
+		return o, fmt.Errorf("%w: minimum GC must be between 0 and 100", ErrInvalidIslandOptions)
+	}
+	if o.MinObsExp < 0 {
+		return o, fmt.Errorf("%w: minimum CpG o/e must not be negative", ErrInvalidIslandOptions)
+	}
+	return o, nil
+}
+
+// Island is a merged CpG island interval. Coordinates are 0-based and
+// half-open; GC and CpG o/e are computed over the whole interval.
+type Island struct {
+	Start     int
+	End       int
+	GCContent float64
+	ObsExp    float64
+}
+
+// RecordIslands holds the CpG islands found in one FASTA record.
+type RecordIslands struct {
+	ID          string
+	Description string
+	Length      int
+	Islands     []Island
+}
+
+// IslandResult is the result of a CpG island search.
+type IslandResult struct {
+	Options IslandOptions
+	Records []RecordIslands
+}
+
+// CpGIslands slides a window of MinLength bases along every record, one base
+// at a time, and merges the overlapping or adjacent windows that satisfy the GC
+// and CpG o/e thresholds into islands.
+func (s *Counter) CpGIslands(ctx context.Context, r io.Reader, opts IslandOptions, with ...Option) (IslandResult, error) {
+	opts, err := opts.validate()
+	if err != nil {
+		return IslandResult{}, err
+	}
+
+	in, err := s.opts.apply(with).open(ctx, r)
+	if err != nil {
+		return IslandResult{}, err
+	}
+	defer in.Close()
+
+	f := newIslandFinder(opts)
+	err = nucount.ScanFASTA(in, in.records(f))
+	f.flush()
+
+	return IslandResult{Options: opts, Records: f.records}, err
+}
+
+// islandStats accumulates the counts needed to evaluate an interval.
+type islandStats struct {
+	length int
+	c      int
+	g      int
+	cpg    int
+}
+
+func (st islandStats) gc() float64 {
+	return ratio(st.c+st.g, st.length) * 100
+}
+
+func (st islandStats) obsExp() float64 {
+	return cpgObservedExpected(st.cpg, st.c, st.g, st.length)
+}
+
+// islandFinder is a nucount.FASTAHandler that keeps the last MinLength base
+// classes in a ring, the counts of the current window and the counts of the
+// island being extended.
+type islandFinder struct {
+	opts    IslandOptions
+	records []RecordIslands
+
+	cur    RecordIslands
+	open   bool
+	ring   []uint8
+	pos    int
+	window islandStats
+
+	inIsland bool
+	island   islandStats
+	start    int
+	end      int
+	endClass uint8 // class of the last island base, which may have left the ring
+}
+
+func newIslandFinder(opts IslandOptions) *islandFinder {
+	return &islandFinder{opts: opts, ring: make([]uint8, opts.MinLength)}
+}
+
+func (f *islandFinder) Header(text []byte) error {
+	f.flush()
+	f.cur.ID, f.cur.Description = nucount.ParseHeader(text)
+	f.open = true
+	return nil
+}
+
+func (f *islandFinder) Sequence(seg []byte) error {
+	f.open = true
+	size := f.opts.MinLength
+
+	for _, b := range seg {
+		sym := nucount.SymbolOf(b)
+		if sym == nucount.SymbolSpace {
+			continue
+		}
+
+		slot := f.pos % size
+		if f.pos >= size {
+			old := f.ring[slot]
+			f.window.c -= boolInt(old == classC)
+			f.window.g -= boolInt(old == classG)
+			f.window.cpg -= boolInt(old == classC && f.ring[(f.pos+1)%size] == classG)
+		} else {
+			f.window.length++
+		}
+
+		cls := classLUT[sym]
+		f.window.c += boolInt(cls == classC)
+		f.window.g += boolInt(cls == classG)
+		f.window.cpg += boolInt(cls == classG && f.pos > 0 && f.ring[(f.pos-1)%size] == classC)
+		f.ring[slot] = cls
+		f.pos++
+
+		if f.pos >= size && f.window.gc() >= f.opts.MinGC && f.window.obsExp() >= f.opts.MinObsExp {
+			f.extend(f.pos-size, f.pos)
+		}
+	}
+	return nil
+}
+
+// extend merges the qualifying window [start, end) into the current island or
+// starts a new one. The bases added to the island are still in the ring.
+func (f *islandFinder) extend(start, end int) {
+	if f.inIsland && start > f.end {
+		f.closeIsland()
+	}
+	if !f.inIsland {
+		f.inIsland = true
+		f.start, f.end = start, start
+		f.island = islandStats{}
+	}
+
+	size := f.opts.MinLength
+	for p := f.end; p < end; p++ {
+		cls := f.ring[p%size]
+		f.island.c += boolInt(cls == classC)
+		f.island.g += boolInt(cls == classG)
+		f.island.cpg += boolInt(cls == classG && f.island.length > 0 && f.endClass == classC)
+		f.island.length++
+		f.endClass = cls
+	}
+	f.end = end
+}
+
+func (f *islandFinder) closeIsland() {
+	f.cur.Islands = append(f.cur.Islands, Island{
+		Start:     f.start,
+		End:       f.end,
+		GCContent: f.island.gc(),
+		ObsExp:    f.island.obsExp(),
+	})
+	f.inIsland = false
+}
+
+func (f *islandFinder) flush() {
+	if !f.open {
+		return
+	}
+	if f.inIsland {
+		f.closeIsland()
+	}
+
+	f.cur.Length = f.pos
+	f.records = append(f.records, f.cur)
+
+	f.cur = RecordIslands{}
+	f.open = false
+	f.pos = 0
+	f.window = islandStats{}
+}
+
+func boolInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
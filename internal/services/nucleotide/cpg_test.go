@@ -0,0 +1,102 @@
+package nucleotide_test
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+
+	nucleotide "github.com/Viktor2805/nucount/internal/services/nucleotide"
+)
+
+func TestCpGIslands(t *testing.T) {
+	counter := nucleotide.NewCounter()
+
+	at := strings.Repeat("AT", 150)
+	input := ">chr1 test\n" + at + "\n" + strings.Repeat("CG", 150) + "\n" + at + "\n>chr2\n" + at + "\n"
+
+	got, err := counter.CpGIslands(context.Background(), asMultipartFile(input), nucleotide.DefaultIslandOptions())
+	if err != nil {
+		t.Fatalf("CpGIslands() error: %v", err)
+	}
+	if got.Options != nucleotide.DefaultIslandOptions() {
+		t.Fatalf("Options = %+v, want the defaults", got.Options)
+	}
+	if len(got.Records) != 2 || len(got.Records[1].Islands) != 0 {
+		t.Fatalf("unexpected records: %+v", got.Records)
+	}
+
+	// Windows need at least 100 bases of the CG block to reach 50% GC.
+	assertIslands(t, got.Records[0].Islands, []nucleotide.Island{
+		{Start: 200, End: 700, GCContent: 60, ObsExp: 150 * 500 / (150.0 * 150.0)},
+	})
+}
+
+func TestCpGIslands_Merge(t *testing.T) {
+	counter := nucleotide.NewCounter()
+	opts := nucleotide.IslandOptions{MinLength: 4, MinGC: 50, MinObsExp: 0.6}
+
+	got, err := counter.CpGIslands(context.Background(), asMultipartFile(">adjacent\nCGCGAAAACGCG\n>gap\nCGCGAAAAAAAACGCG\n"), opts)
+	if err != nil {
+		t.Fatalf("CpGIslands() error: %v", err)
+	}
+
+	// [2,6) and [6,10) touch, so the whole record becomes one island.
+	assertIslands(t, got.Records[0].Islands, []nucleotide.Island{
+		{Start: 0, End: 12, GCContent: 8.0 / 12 * 100, ObsExp: 3},
+	})
+	assertIslands(t, got.Records[1].Islands, []nucleotide.Island{
+		{Start: 0, End: 6, GCContent: 4.0 / 6 * 100, ObsExp: 3},
+		{Start: 10, End: 16, GCContent: 4.0 / 6 * 100, ObsExp: 3},
+	})
+
+}
+
+func TestCpGIslands_ZeroThresholds(t *testing.T) {
+	counter := nucleotide.NewCounter()
+	opts := nucleotide.IslandOptions{MinLength: 4, MinGC: 0, MinObsExp: 0}
+
+	got, err := counter.CpGIslands(context.Background(), asMultipartFile(">r\nAAAAAAAA\n"), opts)
+	if err != nil {
+		t.Fatalf("CpGIslands() error: %v", err)
+	}
+	if got.Options != opts {
+		t.Fatalf("Options = %+v, want %+v", got.Options, opts)
+	}
+	// Every window passes zero thresholds, even without a C or a G.
+	assertIslands(t, got.Records[0].Islands, []nucleotide.Island{{Start: 0, End: 8}})
+}
+
+func TestCpGIslands_InvalidOptions(t *testing.T) {
+	counter := nucleotide.NewCounter()
+
+	for _, change := range []func(*nucleotide.IslandOptions){
+		func(o *nucleotide.IslandOptions) { o.MinLength = 1 },
+		func(o *nucleotide.IslandOptions) { o.MinGC = 101 },
+		func(o *nucleotide.IslandOptions) { o.MinGC = -1 },
+		func(o *nucleotide.IslandOptions) { o.MinObsExp = -1 },
+	} {
+		opts := nucleotide.DefaultIslandOptions()
+		change(&opts)
+		_, err := counter.CpGIslands(context.Background(), asMultipartFile(">r\nCG\n"), opts)
+		if !errors.Is(err, nucleotide.ErrInvalidIslandOptions) {
+			t.Errorf("CpGIslands(%+v) error = %v, want ErrInvalidIslandOptions", opts, err)
+		}
+	}
+}
+
+func assertIslands(t *testing.T, got, want []nucleotide.Island) {
+	t.Helper()
+
+	if len(got) != len(want) {
+		t.Fatalf("got %d islands %+v, want %d", len(got), got, len(want))
+	}
+	for i := range want {
+		g, w := got[i], want[i]
+		if g.Start != w.Start || g.End != w.End ||
+			math.Abs(g.GCContent-w.GCContent) > 1e-9 || math.Abs(g.ObsExp-w.ObsExp) > 1e-9 {
+			t.Errorf("island %d = %+v, want %+v", i, g, w)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package nucleotide
+
+import (
+	"context"
+	"io"
+
+	"github.com/Viktor2805/nucount/pkg/nucount"
+)
+
+type DinucleotideService interface {
+	CountDinucleotides(ctx context.Context, r io.Reader, with ...Option) (DinucleotideResult, error)
+}
+
+// DinucleotideNames lists the 16 dinucleotides in the order used by
+// Dinucleotides.
+var DinucleotideNames = [16]string{
+	"AA", "AC", "AG", "AT",
+	"CA", "CC", "CG", "CT",
+	"GA", "GC", "GG", "GT",
+	"TA", "TC", "TG", "TT",
+}
+
+// Dinucleotides holds the counts of adjacent base pairs, indexed like
+// DinucleotideNames.
+type Dinucleotides [16]int
+
+// CpG index in Dinucleotides.
+const cpgIndex = 1<<2 | 2
+
+// Total returns the number of counted pairs.
+func (d Dinucleotides) Total() int {
+	n := 0
+	for _, v := range d {
+		n += v
+	}
+	return n
+}
+
+// Add returns the element-wise sum of two pair counts.
+func (d Dinucleotides) Add(o Dinucleotides) Dinucleotides {
+	for i := range d {
+		d[i] += o[i]
+	}
+	return d
+}
+
+// RecordDinucleotides holds the dinucleotide counts of one FASTA record.
+type RecordDinucleotides struct {
+	ID          string
+	Description string
+	Bases       NucleotideCount
+	Pairs       Dinucleotides
+}
+
+// CpGObservedExpected returns the CpG observed/expected ratio
+// CpG * L / (C * G), where L is the number of A/C/G/T bases.
+func (r RecordDinucleotides) CpGObservedExpected() float64 {
+	return cpgObservedExpected(r.Pairs[cpgIndex], r.Bases.C, r.Bases.G, r.Bases.Total())
+}
+
+// DinucleotideResult holds the per-record and file-wide dinucleotide counts.
+type DinucleotideResult struct {
+	Total   RecordDinucleotides
+	Records []RecordDinucleotides
+}
+
+// DinucleotideAnalyzer counts the 16 dinucleotides of every record. Pairs are
+// counted across line breaks but never across record boundaries or non-ACGT
+// symbols.
+type DinucleotideAnalyzer struct {
+	opts options
+}
+
+func NewDinucleotideAnalyzer(opts ...Option) DinucleotideService {
+	return &DinucleotideAnalyzer{opts: newOptions(opts)}
+}
+
+// CountDinucleotides counts the dinucleotides of the FASTA stream r.
+func (s *DinucleotideAnalyzer) CountDinucleotides(ctx context.Context, r io.Reader, with ...Option) (DinucleotideResult, error) {
+	in, err := s.opts.apply(with).open(ctx, r)
+	if err != nil {
+		return DinucleotideResult{}, err
+	}
+	defer in.Close()
+
+	var c dinucleotideCounter
+	c.prev = -1
+	err = nucount.ScanFASTA(in, in.records(&c))
+	c.flush()
+
+	return c.result, err
+}
+
+func cpgObservedExpected(cpg, c, g, length int) float64 {
+	if c == 0 || g == 0 {
+		return 0
+	}
+	return float64(cpg) * float64(length) / (float64(c) * float64(g))
+}
+
+// dinucleotideCounter is a nucount.FASTAHandler that remembers the code of
+// the previous base so pairs can span sequence lines.
+type dinucleotideCounter struct {
+	result  DinucleotideResult
+	cur     RecordDinucleotides
+	counter nucount.Counter
+	open    bool
+	prev    int8
+}
+
+func (c *dinucleotideCounter) Header(text []byte) error {
+	c.flush()
+	c.cur.ID, c.cur.Description = nucount.ParseHeader(text)
+	c.open = true
+	return nil
+}
+
+func (c *dinucleotideCounter) Sequence(seg []byte) error {
+	c.open = true
+
+	for _, b := range seg {
+		sym := nucount.SymbolOf(b)
+		c.counter.Add(sym)
+		if sym == nucount.SymbolSpace {
+			continue
+		}
+
+		code := baseCode[sym]
+		if code >= 0 && c.prev >= 0 {
+			c.cur.Pairs[c.prev<<2|code]++
+		}
+		c.prev = code
+	}
+	return nil
+}
+
+func (c *dinucleotideCounter) flush() {
+	c.prev = -1
+	if !c.open {
+		return
+	}
+
+	c.cur.Bases = c.counter.Count()
+
+	c.result.Records = append(c.result.Records, c.cur)
+	c.result.Total.Bases = c.result.Total.Bases.Add(c.cur.Bases)
+	c.result.Total.Pairs = c.result.Total.Pairs.Add(c.cur.Pairs)
+
+	c.cur = RecordDinucleotides{}
+	c.counter.Reset()
+	c.open = false
+}
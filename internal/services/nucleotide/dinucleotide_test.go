@@ -0,0 +1,70 @@
+package nucleotide_test
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	nucleotide "github.com/Viktor2805/nucount/internal/services/nucleotide"
+)
+
+func pairs(counts map[string]int) nucleotide.Dinucleotides {
+	var d nucleotide.Dinucleotides
+	for i, name := range nucleotide.DinucleotideNames {
+		d[i] = counts[name]
+	}
+	return d
+}
+
+func TestCountDinucleotides(t *testing.T) {
+	analyzer := nucleotide.NewDinucleotideAnalyzer()
+
+	// The CG pair of r1 spans a line break, NC is skipped and no pair is formed
+	// between the last base of r1 and the first base of r2.
+	input := ">r1 first\nAC\nGNC\r\nG\n>r2\nCGCG\n"
+
+	got, err := analyzer.CountDinucleotides(context.Background(), asMultipartFile(input))
+	if err != nil {
+		t.Fatalf("CountDinucleotides() error: %v", err)
+	}
+	if len(got.Records) != 2 {
+		t.Fatalf("got %d records, want 2", len(got.Records))
+	}
+
+	r1 := got.Records[0]
+	if r1.ID != "r1" || r1.Description != "first" {
+		t.Fatalf("unexpected header: %+v", r1)
+	}
+	if want := pairs(map[string]int{"AC": 1, "CG": 2}); r1.Pairs != want {
+		t.Fatalf("r1 pairs = %v, want %v", r1.Pairs, want)
+	}
+	if r1.Bases.Total() != 5 || r1.Bases.N != 1 {
+		t.Fatalf("r1 bases = %+v", r1.Bases)
+	}
+
+	r2 := got.Records[1]
+	if want := pairs(map[string]int{"CG": 2, "GC": 1}); r2.Pairs != want {
+		t.Fatalf("r2 pairs = %v, want %v", r2.Pairs, want)
+	}
+
+	// 2 CpG * 4 bases / (2 C * 2 G)
+	if oe := r2.CpGObservedExpected(); math.Abs(oe-2) > 1e-9 {
+		t.Fatalf("r2 CpG o/e = %v, want 2", oe)
+	}
+
+	if got.Total.Pairs.Total() != 6 || got.Total.Bases.Total() != 9 {
+		t.Fatalf("unexpected totals: %+v", got.Total)
+	}
+}
+
+func TestCountDinucleotides_NoCpG(t *testing.T) {
+	analyzer := nucleotide.NewDinucleotideAnalyzer()
+
+	got, err := analyzer.CountDinucleotides(context.Background(), asMultipartFile("AAAATTTT\n"))
+	if err != nil {
+		t.Fatalf("CountDinucleotides() error: %v", err)
+	}
+	if oe := got.Records[0].CpGObservedExpected(); oe != 0 {
+		t.Fatalf("CpG o/e without C or G = %v, want 0", oe)
+	}
+}
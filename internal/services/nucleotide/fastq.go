@@ -0,0 +1,51 @@
+package nucleotide
+
+import (
+	"context"
+	"io"
+
+	"github.com/Viktor2805/nucount/pkg/nucount"
+)
+
+// MaxFASTQLineLength bounds the memory used for a single FASTQ line.
+const MaxFASTQLineLength = nucount.MaxFASTQLineLength
+
+// ErrInvalidFASTQ is returned for input that does not follow the 4-line FASTQ
+// layout.
+var ErrInvalidFASTQ = nucount.ErrInvalidFASTQ
+
+// ErrInvalidFASTA is returned for FASTA input that cannot be scanned, such as
+// a header line longer than nucount.MaxFASTAHeaderLength.
+var ErrInvalidFASTA = nucount.ErrInvalidFASTA
+
+// Phred quality offsets.
+const (
+	PhredOffset33 = nucount.PhredOffset33
+	PhredOffset64 = nucount.PhredOffset64
+)
+
+type (
+	LengthBin  = nucount.LengthBin
+	FASTQStats = nucount.FASTQStats
+)
+
+// CountFASTQ counts the bases of the FASTQ stream r and collects per-read
+// and per-position quality statistics.
+func (s *Counter) CountFASTQ(ctx context.Context, r io.Reader, with ...Option) (FASTQStats, error) {
+	in, err := s.opts.apply(with).open(ctx, r)
+	if err != nil {
+		return FASTQStats{}, err
+	}
+	defer in.Close()
+
+	var c nucount.FASTQCounter
+	for rec, err := range nucount.FASTQRecords(in) {
+		if err != nil {
+			return FASTQStats{}, err
+		}
+		in.meter.record()
+		c.Add(rec)
+	}
+
+	return c.Stats(), nil
+}
@@ -0,0 +1,104 @@
+package nucleotide_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	nucleotide "github.com/Viktor2805/nucount/internal/services/nucleotide"
+)
+
+func TestCountFASTQ(t *testing.T) {
+	counter := nucleotide.NewCounter()
+
+	// Qualities: '5' = Q20, '?' = Q30, '+' = Q10 in Phred+33.
+	input := "@read1 lane 1\nACGT\n+\n??5+\n" +
+		"@read2\r\nGGCCNA\r\n+read2\r\n?????5\r\n" +
+		"\n"
+
+	got, err := counter.CountFASTQ(context.Background(), asMultipartFile(input))
+	if err != nil {
+		t.Fatalf("CountFASTQ() error: %v", err)
+	}
+
+	wantBases := nucleotide.NucleotideCount{A: 2, C: 3, G: 3, T: 1, N: 1}
+	if got.Bases != wantBases {
+		t.Fatalf("Bases = %+v, want %+v", got.Bases, wantBases)
+	}
+	if got.Reads != 2 || got.PhredOffset != nucleotide.PhredOffset33 {
+		t.Fatalf("Reads/PhredOffset = %d/%d, want 2/33", got.Reads, got.PhredOffset)
+	}
+	if got.MinLength != 4 || got.MaxLength != 6 || got.MeanLength() != 5 {
+		t.Fatalf("lengths = %d..%d mean %v", got.MinLength, got.MaxLength, got.MeanLength())
+	}
+
+	wantLengths := []nucleotide.LengthBin{{Length: 4, Reads: 1}, {Length: 6, Reads: 1}}
+	if !reflect.DeepEqual(got.LengthDistribution, wantLengths) {
+		t.Fatalf("LengthDistribution = %+v, want %+v", got.LengthDistribution, wantLengths)
+	}
+
+	wantMean := []float64{30, 30, 25, 20, 30, 20}
+	if !reflect.DeepEqual(got.MeanQuality, wantMean) {
+		t.Fatalf("MeanQuality = %v, want %v", got.MeanQuality, wantMean)
+	}
+
+	if math.Abs(got.Q20-0.9) > 1e-9 || math.Abs(got.Q30-0.7) > 1e-9 {
+		t.Fatalf("Q20/Q30 = %v/%v, want 0.9/0.7", got.Q20, got.Q30)
+	}
+
+	// read1 is 50% GC, read2 has 4 G/C among 5 ACGT bases.
+	if got.GCDistribution[50] != 1 || got.GCDistribution[80] != 1 {
+		t.Fatalf("GCDistribution = %v", got.GCDistribution)
+	}
+}
+
+func TestCountFASTQ_Phred64(t *testing.T) {
+	counter := nucleotide.NewCounter()
+
+	got, err := counter.CountFASTQ(context.Background(), asMultipartFile("@r\nACGT\n+\nhh^T"))
+	if err != nil {
+		t.Fatalf("CountFASTQ() error: %v", err)
+	}
+	if got.PhredOffset != nucleotide.PhredOffset64 {
+		t.Fatalf("PhredOffset = %d, want 64", got.PhredOffset)
+	}
+	if want := []float64{40, 40, 30, 20}; !reflect.DeepEqual(got.MeanQuality, want) {
+		t.Fatalf("MeanQuality = %v, want %v", got.MeanQuality, want)
+	}
+}
+
+func TestCountFASTQ_LongLines(t *testing.T) {
+	counter := nucleotide.NewCounter()
+
+	seq := strings.Repeat("A", 5<<20)
+	input := "@long\n" + seq + "\n+\n" + strings.Repeat("I", len(seq)) + "\n"
+
+	f := &limitedFile{Reader: bytes.NewReader([]byte(input)), limit: 1 << 20}
+	got, err := counter.CountFASTQ(context.Background(), f)
+	if err != nil {
+		t.Fatalf("CountFASTQ() error: %v", err)
+	}
+	if got.Bases.A != len(seq) || len(got.MeanQuality) != len(seq) {
+		t.Fatalf("unexpected stats for long read: A=%d positions=%d", got.Bases.A, len(got.MeanQuality))
+	}
+}
+
+func TestCountFASTQ_Invalid(t *testing.T) {
+	counter := nucleotide.NewCounter()
+
+	for name, input := range map[string]string{
+		"missing @":        ">r\nACGT\n+\nIIII\n",
+		"missing +":        "@r\nACGT\n-\nIIII\n",
+		"length mismatch":  "@r\nACGT\n+\nIII\n",
+		"truncated record": "@r\nACGT\n",
+	} {
+		_, err := counter.CountFASTQ(context.Background(), asMultipartFile(input))
+		if !errors.Is(err, nucleotide.ErrInvalidFASTQ) {
+			t.Errorf("%s: error = %v, want ErrInvalidFASTQ", name, err)
+		}
+	}
+}
@@ -0,0 +1,297 @@
+package nucleotide
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/Viktor2805/nucount/pkg/nucount"
+)
+
+// Limits of the k-mer counter.
+const (
+	MaxK                    = 31
+	MaxKmerTop              = 1000
+	DefaultMaxDistinctKmers = 1 << 24
+
+	// denseMaxK is the largest k counted in a flat array of 4^k counters.
+	denseMaxK = 10
+)
+
+var (
+	// ErrInvalidKmerOptions is returned for unusable k-mer options.
+	ErrInvalidKmerOptions = errors.New("invalid k-mer options")
+	// ErrTooManyKmers is returned when the input holds more distinct k-mers than
+	// KmerOptions.MaxDistinct allows.
+	ErrTooManyKmers = errors.New("too many distinct k-mers")
+)
+
+type KmerService interface {
+	CountKmers(ctx context.Context, r io.Reader, opts KmerOptions, with ...Option) (KmerResult, error)
+}
+
+// KmerOptions configures a k-mer count.
+type KmerOptions struct {
+	K           int
+	Canonical   bool // merge each k-mer with its reverse complement
+	Top         int  // number of most frequent k-mers to return
+	MaxDistinct int  // bound on distinct k-mers kept in memory, 0 means DefaultMaxDistinctKmers
+}
+
+func (o KmerOptions) validate() (KmerOptions, error) {
+	if o.K < 1 || o.K > MaxK {
+		return o, fmt.Errorf("%w: k must be between 1 and %d", ErrInvalidKmerOptions, MaxK)
+	}
+	if o.Top < 0 || o.Top > MaxKmerTop {
+		return o, fmt.Errorf("%w: top must be between 0 and %d", ErrInvalidKmerOptions, MaxKmerTop)
+	}
+	if o.MaxDistinct <= 0 {
+		o.MaxDistinct = DefaultMaxDistinctKmers
+	}
+	return o, nil
+}
+
+// KmerCount is the number of occurrences of one k-mer.
+type KmerCount struct {
+	Kmer  string
+	Count int
+}
+
+// SpectrumBin reports how many distinct k-mers occur exactly Occurrences times.
+type SpectrumBin struct {
+	Occurrences int
+	Kmers       int
+}
+
+// KmerResult is the result of a k-mer count.
+type KmerResult struct {
+	Options  KmerOptions
+	Total    int // k-mers counted
+	Distinct int // distinct k-mers seen
+	Top      []KmerCount
+	Spectrum []SpectrumBin // sorted by Occurrences
+}
+
+// KmerCounter counts k-mers of A/C/G/T bases. K-mers never span a record
+// boundary or a non-ACGT symbol.
+type KmerCounter struct {
+	opts options
+}
+
+func NewKmerCounter(opts ...Option) KmerService {
+	return &KmerCounter{opts: newOptions(opts)}
+}
+
+// CountKmers counts the k-mers of the FASTA stream r.
+func (s *KmerCounter) CountKmers(ctx context.Context, r io.Reader, opts KmerOptions, with ...Option) (KmerResult, error) {
+	opts, err := opts.validate()
+	if err != nil {
+		return KmerResult{}, err
+	}
+
+	in, err := s.opts.apply(with).open(ctx, r)
+	if err != nil {
+		return KmerResult{}, err
+	}
+	defer in.Close()
+
+	c := newKmerScanner(opts)
+	if err := nucount.ScanFASTA(in, in.records(c)); err != nil {
+		return KmerResult{}, err
+	}
+
+	return c.result(), nil
+}
+
+// baseCode maps A/C/G/T symbols to their 2-bit code, -1 otherwise.
+var baseCode = func() (lut [nucount.NumSymbols]int8) {
+	for sym := range lut {
+		lut[sym] = -1
+	}
+	lut[nucount.SymbolA], lut[nucount.SymbolC], lut[nucount.SymbolG], lut[nucount.SymbolT] = 0, 1, 2, 3
+	return lut
+}()
+
+// kmerTable stores k-mer occurrence counts keyed by their 2-bit encoding.
+type kmerTable interface {
+	add(kmer uint64) error
+	each(fn func(kmer uint64, count int))
+}
+
+// denseTable counts small k in a flat array indexed by the k-mer code.
+type denseTable []uint32
+
+func (t denseTable) add(kmer uint64) error {
+	t[kmer]++
+	return nil
+}
+
+func (t denseTable) each(fn func(kmer uint64, count int)) {
+	for kmer, count := range t {
+		if count > 0 {
+			fn(uint64(kmer), int(count))
+		}
+	}
+}
+
+// mapTable counts large k in a map that refuses to grow past max entries.
+type mapTable struct {
+	counts map[uint64]uint32
+	max    int
+}
+
+func (t *mapTable) add(kmer uint64) error {
+	if _, ok := t.counts[kmer]; !ok && len(t.counts) >= t.max {
+		return fmt.Errorf("%w: limit is %d", ErrTooManyKmers, t.max)
+	}
+	t.counts[kmer]++
+	return nil
+}
+
+func (t *mapTable) each(fn func(kmer uint64, count int)) {
+	for kmer, count := range t.counts {
+		fn(kmer, int(count))
+	}
+}
+
+// kmerScanner is a nucount.FASTAHandler that rolls the forward and reverse
+// complement encodings of the last k bases.
+type kmerScanner struct {
+	opts  KmerOptions
+	table kmerTable
+	total int
+
+	mask  uint64
+	shift uint
+	fwd   uint64
+	rev   uint64
+	valid int
+}
+
+func newKmerScanner(opts KmerOptions) *kmerScanner {
+	c := &kmerScanner{
+		opts:  opts,
+		mask:  1<<(2*uint(opts.K)) - 1,
+		shift: 2 * uint(opts.K-1),
+	}
+	if opts.K <= denseMaxK {
+		c.table = make(denseTable, 1<<(2*uint(opts.K)))
+	} else {
+		c.table = &mapTable{counts: make(map[uint64]uint32), max: opts.MaxDistinct}
+	}
+	return c
+}
+
+func (c *kmerScanner) Header([]byte) error {
+	c.valid = 0
+	return nil
+}
+
+func (c *kmerScanner) Sequence(seg []byte) error {
+	for _, b := range seg {
+		sym := nucount.SymbolOf(b)
+		if sym == nucount.SymbolSpace {
+			continue
+		}
+
+		code := baseCode[sym]
+		if code < 0 {
+			c.valid = 0
+			continue
+		}
+
+		c.fwd = (c.fwd<<2 | uint64(code)) & c.mask
+		c.rev = c.rev>>2 | uint64(3-code)<<c.shift
+		c.valid++
+		if c.valid < c.opts.K {
+			continue
+		}
+
+		kmer := c.fwd
+		if c.opts.Canonical && c.rev < kmer {
+			kmer = c.rev
+		}
+		if err := c.table.add(kmer); err != nil {
+			return err
+		}
+		c.total++
+	}
+	return nil
+}
+
+func (c *kmerScanner) result() KmerResult {
+	res := KmerResult{Options: c.opts, Total: c.total}
+
+	top := &kmerHeap{}
+	spectrum := make(map[int]int)
+
+	c.table.each(func(kmer uint64, count int) {
+		res.Distinct++
+		spectrum[count]++
+
+		if c.opts.Top == 0 {
+			return
+		}
+		entry := kmerEntry{kmer: kmer, count: count}
+		if top.Len() < c.opts.Top {
+			heap.Push(top, entry)
+		} else if top.less(top.entries[0], entry) {
+			top.entries[0] = entry
+			heap.Fix(top, 0)
+		}
+	})
+
+	sort.Slice(top.entries, func(i, j int) bool { return top.less(top.entries[j], top.entries[i]) })
+	for _, e := range top.entries {
+		res.Top = append(res.Top, KmerCount{Kmer: decodeKmer(e.kmer, c.opts.K), Count: e.count})
+	}
+
+	for occ, n := range spectrum {
+		res.Spectrum = append(res.Spectrum, SpectrumBin{Occurrences: occ, Kmers: n})
+	}
+	sort.Slice(res.Spectrum, func(i, j int) bool { return res.Spectrum[i].Occurrences < res.Spectrum[j].Occurrences })
+
+	return res
+}
+
+func decodeKmer(kmer uint64, k int) string {
+	const letters = "ACGT"
+
+	b := make([]byte, k)
+	for i := k - 1; i >= 0; i-- {
+		b[i] = letters[kmer&3]
+		kmer >>= 2
+	}
+	return string(b)
+}
+
+type kmerEntry struct {
+	kmer  uint64
+	count int
+}
+
+// kmerHeap is a min-heap keeping the most frequent k-mers seen so far. Ties are
+// broken by k-mer code so the result does not depend on map iteration order.
+type kmerHeap struct {
+	entries []kmerEntry
+}
+
+func (h *kmerHeap) less(a, b kmerEntry) bool {
+	if a.count != b.count {
+		return a.count < b.count
+	}
+	return a.kmer > b.kmer
+}
+
+func (h *kmerHeap) Len() int           { return len(h.entries) }
+func (h *kmerHeap) Less(i, j int) bool { return h.less(h.entries[i], h.entries[j]) }
+func (h *kmerHeap) Swap(i, j int)      { h.entries[i], h.entries[j] = h.entries[j], h.entries[i] }
+func (h *kmerHeap) Push(x any)         { h.entries = append(h.entries, x.(kmerEntry)) }
+func (h *kmerHeap) Pop() any {
+	e := h.entries[len(h.entries)-1]
+	h.entries = h.entries[:len(h.entries)-1]
+	return e
+}
@@ -0,0 +1,100 @@
+package nucleotide_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	nucleotide "github.com/Viktor2805/nucount/internal/services/nucleotide"
+)
+
+func TestCountKmers(t *testing.T) {
+	counter := nucleotide.NewKmerCounter()
+
+	// "ACGTTNAC" breaks at N, "G\nT" spans a line break, and nothing spans the
+	// boundary between the two records.
+	input := ">r1\nACGTTNAC\nG\n>r2\nAC\nGT\n"
+
+	got, err := counter.CountKmers(context.Background(), asMultipartFile(input), nucleotide.KmerOptions{K: 3, Top: 2})
+	if err != nil {
+		t.Fatalf("CountKmers() error: %v", err)
+	}
+
+	// r1: ACG CGT GTT | ACG ; r2: ACG CGT
+	if got.Total != 6 || got.Distinct != 3 {
+		t.Fatalf("Total/Distinct = %d/%d, want 6/3", got.Total, got.Distinct)
+	}
+
+	wantTop := []nucleotide.KmerCount{{Kmer: "ACG", Count: 3}, {Kmer: "CGT", Count: 2}}
+	if !reflect.DeepEqual(got.Top, wantTop) {
+		t.Fatalf("Top = %+v, want %+v", got.Top, wantTop)
+	}
+
+	wantSpectrum := []nucleotide.SpectrumBin{
+		{Occurrences: 1, Kmers: 1},
+		{Occurrences: 2, Kmers: 1},
+		{Occurrences: 3, Kmers: 1},
+	}
+	if !reflect.DeepEqual(got.Spectrum, wantSpectrum) {
+		t.Fatalf("Spectrum = %+v, want %+v", got.Spectrum, wantSpectrum)
+	}
+}
+
+func TestCountKmers_Canonical(t *testing.T) {
+	counter := nucleotide.NewKmerCounter()
+
+	// AAC and its reverse complement GTT collapse into AAC.
+	for _, k := range []int{3, 12} {
+		seq := strings.Repeat("A", k-1)
+		input := ">f\n" + seq + "C\n>r\nG" + strings.Repeat("T", k-1) + "\n"
+
+		got, err := counter.CountKmers(context.Background(), asMultipartFile(input), nucleotide.KmerOptions{K: k, Canonical: true, Top: 5})
+		if err != nil {
+			t.Fatalf("CountKmers(k=%d) error: %v", k, err)
+		}
+
+		want := []nucleotide.KmerCount{{Kmer: seq + "C", Count: 2}}
+		if got.Distinct != 1 || !reflect.DeepEqual(got.Top, want) {
+			t.Fatalf("CountKmers(k=%d) = %+v, want top %+v", k, got, want)
+		}
+	}
+}
+
+func TestCountKmers_TieOrder(t *testing.T) {
+	counter := nucleotide.NewKmerCounter()
+
+	got, err := counter.CountKmers(context.Background(), asMultipartFile("TTGGCCAA\n"), nucleotide.KmerOptions{K: 21, Top: 1})
+	if err != nil {
+		t.Fatalf("CountKmers() error: %v", err)
+	}
+	if got.Total != 0 || len(got.Top) != 0 {
+		t.Fatalf("sequence shorter than k should yield no k-mers, got %+v", got)
+	}
+
+	got, err = counter.CountKmers(context.Background(), asMultipartFile("TTGGCCAA\n"), nucleotide.KmerOptions{K: 2, Top: 3})
+	if err != nil {
+		t.Fatalf("CountKmers() error: %v", err)
+	}
+	want := []nucleotide.KmerCount{{Kmer: "AA", Count: 1}, {Kmer: "CA", Count: 1}, {Kmer: "CC", Count: 1}}
+	if !reflect.DeepEqual(got.Top, want) {
+		t.Fatalf("Top = %+v, want %+v", got.Top, want)
+	}
+}
+
+func TestCountKmers_Limits(t *testing.T) {
+	counter := nucleotide.NewKmerCounter()
+
+	for _, opts := range []nucleotide.KmerOptions{{K: 0}, {K: 32}, {K: 5, Top: -1}, {K: 5, Top: nucleotide.MaxKmerTop + 1}} {
+		_, err := counter.CountKmers(context.Background(), asMultipartFile("ACGT\n"), opts)
+		if !errors.Is(err, nucleotide.ErrInvalidKmerOptions) {
+			t.Errorf("CountKmers(%+v) error = %v, want ErrInvalidKmerOptions", opts, err)
+		}
+	}
+
+	_, err := counter.CountKmers(context.Background(), asMultipartFile("ACGTACGTACGTAAAAAAAAAAAAA\n"), nucleotide.KmerOptions{K: 12, MaxDistinct: 3})
+	if !errors.Is(err, nucleotide.ErrTooManyKmers) {
+		t.Fatalf("CountKmers() error = %v, want ErrTooManyKmers", err)
+	}
+}
@@ -0,0 +1,13 @@
+package nucleotide
+
+import "github.com/Viktor2805/nucount/pkg/nucount"
+
+// Composition holds the metrics derived from a NucleotideCount.
+type Composition = nucount.Composition
+
+func ratio(num, den int) float64 {
+	if den == 0 {
+		return 0
+	}
+	return float64(num) / float64(den)
+}
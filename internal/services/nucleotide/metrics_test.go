@@ -0,0 +1,80 @@
+package nucleotide_test
+
+import (
+	"math"
+	"testing"
+
+	nucleotide "github.com/Viktor2805/nucount/internal/services/nucleotide"
+)
+
+func TestComposition(t *testing.T) {
+	tests := []struct {
+		name  string
+		count nucleotide.NucleotideCount
+		want  nucleotide.Composition
+	}{
+		{
+			name:  "empty count",
+			count: nucleotide.NucleotideCount{},
+			want:  nucleotide.Composition{},
+		},
+		{
+			name:  "balanced",
+			count: nucleotide.NucleotideCount{A: 25, C: 25, G: 25, T: 25},
+			want: nucleotide.Composition{
+				GCContent:             50,
+				ATContent:             50,
+				PurinePyrimidineRatio: 1,
+			},
+		},
+		{
+			name:  "skewed with Ns",
+			count: nucleotide.NucleotideCount{A: 3, C: 1, G: 3, T: 1, N: 2},
+			want: nucleotide.Composition{
+				GCContent:             50,
+				ATContent:             50,
+				GCSkew:                0.5,
+				ATSkew:                0.5,
+				PurinePyrimidineRatio: 3,
+				NFraction:             0.2,
+			},
+		},
+		{
+			name:  "no pyrimidines",
+			count: nucleotide.NucleotideCount{A: 4, G: 4},
+			want: nucleotide.Composition{
+				GCContent: 50,
+				ATContent: 50,
+				GCSkew:    1,
+				ATSkew:    1,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.count.Composition()
+			if !almostEqual(got, tt.want) {
+				t.Fatalf("Composition() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func almostEqual(a, b nucleotide.Composition) bool {
+	const eps = 1e-9
+	pairs := [][2]float64{
+		{a.GCContent, b.GCContent},
+		{a.ATContent, b.ATContent},
+		{a.GCSkew, b.GCSkew},
+		{a.ATSkew, b.ATSkew},
+		{a.PurinePyrimidineRatio, b.PurinePyrimidineRatio},
+		{a.NFraction, b.NFraction},
+	}
+	for _, p := range pairs {
+		if math.Abs(p[0]-p[1]) > eps {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,70 @@
+package nucleotide
+
+import (
+	"context"
+	"io"
+	"runtime"
+
+	"github.com/Viktor2805/nucount/internal/helpers"
+)
+
+// Option configures the analyzers of this package.
+type Option func(*options)
+
+type options struct {
+	maxDecompressedSize int64
+	workers             int
+	progress            ProgressFunc
+}
+
+func newOptions(opts []Option) options {
+	return options{maxDecompressedSize: DefaultMaxDecompressedSize, workers: 1}.apply(opts)
+}
+
+// apply returns a copy of o changed by opts.
+func (o options) apply(opts []Option) options {
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// WithMaxDecompressedSize sets the number of bytes a compressed upload may
+// expand to. A value <= 0 disables the limit.
+func WithMaxDecompressedSize(n int64) Option {
+	return func(o *options) {
+		o.maxDecompressedSize = n
+	}
+}
+
+// WithWorkers sets the number of goroutines Counter.Count spreads the input
+// over. One worker, the default, counts on the calling goroutine; n <= 0 uses
+// one worker per available CPU.
+func WithWorkers(n int) Option {
+	return func(o *options) {
+		if n <= 0 {
+			n = runtime.GOMAXPROCS(0)
+		}
+		o.workers = n
+	}
+}
+
+// WithProgress makes an analysis report its progress to fn: about every MiB of
+// input and once more when the analysis stops. It is meant to be given to a
+// single analysis, whose progress it then reports.
+func WithProgress(fn ProgressFunc) Option {
+	return func(o *options) {
+		o.progress = fn
+	}
+}
+
+// open returns a reader of the decompressed content of r that stops when ctx is
+// done and reports progress to the progress function.
+func (o options) open(ctx context.Context, r io.Reader) (*input, error) {
+	m := &meter{ctx: ctx, r: r, fn: o.progress}
+	dec, _, err := helpers.Decompress(m, o.maxDecompressedSize)
+	if err != nil {
+		return nil, err
+	}
+	return &input{Reader: dec, dec: dec, meter: m}, nil
+}
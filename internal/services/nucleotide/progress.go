@@ -0,0 +1,84 @@
+package nucleotide
+
+import (
+	"context"
+	"io"
+
+	"github.com/Viktor2805/nucount/pkg/nucount"
+)
+
+// progressInterval is the number of input bytes between progress reports.
+const progressInterval = 1 << 20
+
+// Progress describes how far an analysis has got.
+type Progress struct {
+	Bytes   int64 // input bytes consumed, before decompression
+	Records int64 // records or reads started; not tracked by parallel counting
+}
+
+// ProgressFunc receives progress reports. It is called on the goroutine
+// running the analysis and should return quickly.
+type ProgressFunc func(Progress)
+
+// meter wraps the raw input of an analysis. Reads fail with the context error
+// once ctx is done, so every analysis stops within one buffer of input after
+// cancellation.
+type meter struct {
+	ctx      context.Context
+	r        io.Reader
+	fn       ProgressFunc
+	progress Progress
+	reported int64
+}
+
+func (m *meter) Read(p []byte) (int, error) {
+	if err := m.ctx.Err(); err != nil {
+		return 0, err
+	}
+	n, err := m.r.Read(p)
+	m.progress.Bytes += int64(n)
+	if m.fn != nil && m.progress.Bytes-m.reported >= progressInterval {
+		m.report()
+	}
+	return n, err
+}
+
+func (m *meter) record() {
+	m.progress.Records++
+}
+
+func (m *meter) report() {
+	if m.fn != nil {
+		m.reported = m.progress.Bytes
+		m.fn(m.progress)
+	}
+}
+
+// input is the decompressed stream of an analysis.
+type input struct {
+	io.Reader
+	dec   io.Closer
+	meter *meter
+}
+
+// Close sends the final progress report and releases the decompressor. It does
+// not close the caller's reader.
+func (in *input) Close() error {
+	in.meter.report()
+	return in.dec.Close()
+}
+
+// records wraps h so that every FASTA header counts as a record.
+func (in *input) records(h nucount.FASTAHandler) nucount.FASTAHandler {
+	return recordMeter{FASTAHandler: h, meter: in.meter}
+}
+
+type recordMeter struct {
+	nucount.FASTAHandler
+	meter *meter
+}
+
+func (r recordMeter) Header(text []byte) error {
+	r.meter.record()
+	return r.FASTAHandler.Header(text)
+}
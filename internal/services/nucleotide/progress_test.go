@@ -0,0 +1,121 @@
+package nucleotide_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	nucleotide "github.com/Viktor2805/nucount/internal/services/nucleotide"
+)
+
+// cancelAfterReader cancels its context once more than limit bytes were read.
+type cancelAfterReader struct {
+	r      io.Reader
+	read   int
+	limit  int
+	cancel context.CancelFunc
+}
+
+func (c *cancelAfterReader) Read(p []byte) (int, error) {
+	if len(p) > 64<<10 {
+		p = p[:64<<10]
+	}
+	n, err := c.r.Read(p)
+	c.read += n
+	if c.read > c.limit {
+		c.cancel()
+	}
+	return n, err
+}
+
+func TestCount_Cancelled(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := nucleotide.NewCounter().Count(ctx, strings.NewReader(">a\nACGT\n"))
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestCount_CancelledMidStream(t *testing.T) {
+	t.Parallel()
+
+	input := ">chr\n" + strings.Repeat("ACGT", 16<<20/4)
+
+	for _, workers := range []int{1, 4} {
+		ctx, cancel := context.WithCancel(context.Background())
+		r := &cancelAfterReader{r: strings.NewReader(input), limit: 1 << 20, cancel: cancel}
+
+		_, err := nucleotide.NewCounter(nucleotide.WithWorkers(workers)).Count(ctx, r)
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("workers=%d: expected context.Canceled, got %v", workers, err)
+		}
+		if r.read >= len(input) {
+			t.Errorf("workers=%d: read the whole input after cancellation", workers)
+		}
+	}
+}
+
+func TestCountKmers_CancelledMidStream(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	input := ">chr\n" + strings.Repeat("ACGTTGCA", 8<<20/8)
+	r := &cancelAfterReader{r: strings.NewReader(input), limit: 1 << 20, cancel: cancel}
+
+	_, err := nucleotide.NewKmerCounter().CountKmers(ctx, r, nucleotide.KmerOptions{K: 5})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestProgress(t *testing.T) {
+	t.Parallel()
+
+	record := ">r\n" + strings.Repeat("ACGT", 1<<16) + "\n"
+	input := strings.Repeat(record, 12) // ~3 MiB
+
+	var reports []nucleotide.Progress
+	progress := nucleotide.WithProgress(func(p nucleotide.Progress) {
+		reports = append(reports, p)
+	})
+
+	_, err := nucleotide.NewCounter().CountRecords(context.Background(), &limitedFile{Reader: bytes.NewReader([]byte(input)), limit: 64 << 10}, progress)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(reports) < 3 {
+		t.Fatalf("expected intermediate and final reports, got %d", len(reports))
+	}
+	for i := 1; i < len(reports); i++ {
+		if reports[i].Bytes < reports[i-1].Bytes || reports[i].Records < reports[i-1].Records {
+			t.Errorf("progress went backwards: %+v after %+v", reports[i], reports[i-1])
+		}
+	}
+	last := reports[len(reports)-1]
+	if last.Bytes != int64(len(input)) || last.Records != 12 {
+		t.Errorf("final report = %+v, want %d bytes and 12 records", last, len(input))
+	}
+}
+
+func TestProgress_FASTQReads(t *testing.T) {
+	t.Parallel()
+
+	var last nucleotide.Progress
+	progress := nucleotide.WithProgress(func(p nucleotide.Progress) { last = p })
+
+	input := strings.Repeat("@r\nACGT\n+\nIIII\n", 5)
+	if _, err := nucleotide.NewCounter().CountFASTQ(context.Background(), strings.NewReader(input), progress); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if last.Records != 5 || last.Bytes != int64(len(input)) {
+		t.Errorf("final report = %+v", last)
+	}
+}
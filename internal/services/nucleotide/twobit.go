@@ -0,0 +1,643 @@
+package nucleotide
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/Viktor2805/nucount/pkg/nucount"
+)
+
+// ErrInvalid2bit is returned for input that does not follow the UCSC 2bit
+// layout.
+var ErrInvalid2bit = errors.New("invalid 2bit file")
+
+// ErrPartlyRead is returned by TwoBitReader.Sequence for a record that has
+// already been read from.
+var ErrPartlyRead = errors.New("2bit record has been partly read")
+
+const (
+	twoBitSignature = 0x1A412743
+	// twoBitVersion64 files store 64-bit record offsets in the index.
+	twoBitVersion64 = 1
+)
+
+// twoBitLetters maps the 2-bit codes of packed DNA to bases.
+const twoBitLetters = "TCAG"
+
+// Block is a run of Size bases starting at the 0-based position Start.
+type Block struct {
+	Start int
+	Size  int
+}
+
+func (b Block) end() int { return b.Start + b.Size }
+
+// TwoBitRecord is the header of one 2bit record.
+type TwoBitRecord struct {
+	Name       string
+	Length     int
+	NBlocks    []Block // runs of N
+	MaskBlocks []Block // soft-masked (lowercase) runs
+}
+
+// Masked returns the number of soft-masked bases.
+func (r TwoBitRecord) Masked() int {
+	return blockBases(r.MaskBlocks)
+}
+
+func blockBases(blocks []Block) int {
+	n := 0
+	for _, b := range blocks {
+		n += b.Size
+	}
+	return n
+}
+
+type twoBitEntry struct {
+	name   string
+	offset uint64
+}
+
+// TwoBitReader streams the records of a UCSC 2bit file. Records are read in
+// file order, so the input does not need to be seekable.
+type TwoBitReader struct {
+	r     *bufio.Reader
+	order binary.ByteOrder
+	index []twoBitEntry
+	next  int
+	pos   uint64 // bytes consumed from the input
+	left  int64  // packed DNA bytes of the current record not read yet
+	cur   TwoBitRecord
+}
+
+// NewTwoBitReader reads the file header and sequence index from r. Either byte
+// order and both the 32-bit (version 0) and 64-bit (version 1) index layouts
+// are accepted.
+func NewTwoBitReader(r io.Reader) (*TwoBitReader, error) {
+	t := &TwoBitReader{r: bufio.NewReaderSize(r, bufSize)}
+
+	var hdr [16]byte
+	if err := t.readFull(hdr[:]); err != nil {
+		return nil, err
+	}
+	switch {
+	case binary.LittleEndian.Uint32(hdr[0:]) == twoBitSignature:
+		t.order = binary.LittleEndian
+	case binary.BigEndian.Uint32(hdr[0:]) == twoBitSignature:
+		t.order = binary.BigEndian
+	default:
+		return nil, fmt.Errorf("%w: bad signature", ErrInvalid2bit)
+	}
+	version := t.order.Uint32(hdr[4:])
+	if version > twoBitVersion64 {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrInvalid2bit, version)
+	}
+	count := t.order.Uint32(hdr[8:])
+
+	// The index is grown while reading so a bogus count cannot force a huge
+	// allocation up front.
+	for i := uint32(0); i < count; i++ {
+		size, err := t.readByte()
+		if err != nil {
+			return nil, err
+		}
+		name := make([]byte, size)
+		if err := t.readFull(name); err != nil {
+			return nil, err
+		}
+
+		var offset uint64
+		if version == twoBitVersion64 {
+			offset, err = t.readUint64()
+		} else {
+			var o uint32
+			o, err = t.readUint32()
+			offset = uint64(o)
+		}
+		if err != nil {
+			return nil, err
+		}
+		t.index = append(t.index, twoBitEntry{name: string(name), offset: offset})
+	}
+
+	sort.SliceStable(t.index, func(i, j int) bool { return t.index[i].offset < t.index[j].offset })
+	return t, nil
+}
+
+// Next reads the header and block tables of the following record, skipping
+// whatever is left of the current record's packed DNA. It returns io.EOF after
+// the last record.
+func (t *TwoBitReader) Next() (TwoBitRecord, error) {
+	if err := t.skip(uint64(t.left)); err != nil {
+		return TwoBitRecord{}, err
+	}
+	t.left = 0
+
+	if t.next == len(t.index) {
+		return TwoBitRecord{}, io.EOF
+	}
+	e := t.index[t.next]
+	t.next++
+
+	if e.offset < t.pos {
+		return TwoBitRecord{}, fmt.Errorf("%w: record %q overlaps the previous one", ErrInvalid2bit, e.name)
+	}
+	if err := t.skip(e.offset - t.pos); err != nil {
+		return TwoBitRecord{}, err
+	}
+
+	size, err := t.readUint32()
+	if err != nil {
+		return TwoBitRecord{}, err
+	}
+	rec := TwoBitRecord{Name: e.name, Length: int(size)}
+	if rec.NBlocks, err = t.readBlocks(rec); err != nil {
+		return TwoBitRecord{}, err
+	}
+	if rec.MaskBlocks, err = t.readBlocks(rec); err != nil {
+		return TwoBitRecord{}, err
+	}
+	if _, err := t.readUint32(); err != nil { // reserved
+		return TwoBitRecord{}, err
+	}
+
+	t.left = (int64(size) + 3) / 4
+	t.cur = rec
+	return rec, nil
+}
+
+// Read reads packed DNA of the current record: four bases per byte, most
+// significant bits first, coded as in twoBitLetters. N and masked runs are
+// only described by the block tables. Read returns io.EOF at the end of the
+// record.
+func (t *TwoBitReader) Read(p []byte) (int, error) {
+	if t.left == 0 {
+		return 0, io.EOF
+	}
+	if int64(len(p)) > t.left {
+		p = p[:t.left]
+	}
+	n, err := t.r.Read(p)
+	t.left -= int64(n)
+	t.pos += uint64(n)
+	if err == io.EOF && t.left > 0 {
+		err = t.truncated()
+	}
+	return n, err
+}
+
+// Sequence decodes the current record, applying N blocks as 'N' and mask
+// blocks as lowercase. It returns ErrPartlyRead after a Read of the record.
+func (t *TwoBitReader) Sequence() ([]byte, error) {
+	rec := t.cur
+	if t.left != (int64(rec.Length)+3)/4 {
+		return nil, fmt.Errorf("%w: %q", ErrPartlyRead, rec.Name)
+	}
+	packed, err := io.ReadAll(t)
+	if err != nil {
+		return nil, err
+	}
+
+	seq := make([]byte, rec.Length)
+	for i := range seq {
+		seq[i] = twoBitLetters[packed[i/4]>>(6-2*(i%4))&3]
+	}
+	for _, b := range rec.NBlocks {
+		for i := b.Start; i < b.end(); i++ {
+			seq[i] = 'N'
+		}
+	}
+	for _, b := range rec.MaskBlocks {
+		for i := b.Start; i < b.end(); i++ {
+			seq[i] |= 0x20
+		}
+	}
+	return seq, nil
+}
+
+// readBlocks reads a block count followed by the starts and sizes arrays.
+func (t *TwoBitReader) readBlocks(rec TwoBitRecord) ([]Block, error) {
+	count, err := t.readUint32()
+	if err != nil {
+		return nil, err
+	}
+	if int64(count) > int64(rec.Length) {
+		return nil, fmt.Errorf("%w: record %q has %d blocks for %d bases", ErrInvalid2bit, rec.Name, count, rec.Length)
+	}
+
+	blocks := make([]Block, 0, min(count, 1<<16))
+	err = t.readUint32s(int(count), func(_ int, start uint32) {
+		blocks = append(blocks, Block{Start: int(start)})
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = t.readUint32s(int(count), func(i int, size uint32) {
+		blocks[i].Size = int(size)
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, b := range blocks {
+		if b.end() > rec.Length {
+			return nil, fmt.Errorf("%w: record %q has a block past its end", ErrInvalid2bit, rec.Name)
+		}
+	}
+	sort.Slice(blocks, func(i, j int) bool { return blocks[i].Start < blocks[j].Start })
+	return blocks, nil
+}
+
+func (t *TwoBitReader) readFull(p []byte) error {
+	n, err := io.ReadFull(t.r, p)
+	t.pos += uint64(n)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return t.truncated()
+	}
+	return err
+}
+
+func (t *TwoBitReader) readByte() (byte, error) {
+	var b [1]byte
+	err := t.readFull(b[:])
+	return b[0], err
+}
+
+func (t *TwoBitReader) readUint32() (uint32, error) {
+	var b [4]byte
+	err := t.readFull(b[:])
+	return t.order.Uint32(b[:]), err
+}
+
+// readUint32s reads n integers in batches, passing each with its index to fn.
+func (t *TwoBitReader) readUint32s(n int, fn func(i int, v uint32)) error {
+	var buf [16 << 10]byte
+	for i := 0; i < n; {
+		batch := min(n-i, len(buf)/4)
+		if err := t.readFull(buf[:batch*4]); err != nil {
+			return err
+		}
+		for j := 0; j < batch; j++ {
+			fn(i+j, t.order.Uint32(buf[j*4:]))
+		}
+		i += batch
+	}
+	return nil
+}
+
+func (t *TwoBitReader) readUint64() (uint64, error) {
+	var b [8]byte
+	err := t.readFull(b[:])
+	return t.order.Uint64(b[:]), err
+}
+
+func (t *TwoBitReader) skip(n uint64) error {
+	if n == 0 {
+		return nil
+	}
+	if n > math.MaxInt64 {
+		return fmt.Errorf("%w: offset out of range", ErrInvalid2bit)
+	}
+	skipped, err := t.r.Discard(int(n))
+	t.pos += uint64(skipped)
+	if err == io.EOF {
+		return t.truncated()
+	}
+	return err
+}
+
+func (t *TwoBitReader) truncated() error {
+	return fmt.Errorf("%w: unexpected end of file at byte %d", ErrInvalid2bit, t.pos)
+}
+
+// TwoBitRecordCount holds the counts of one 2bit record.
+type TwoBitRecordCount struct {
+	RecordCount
+	Masked int // soft-masked bases
+}
+
+// TwoBitResult holds the per-record and file-wide counts of a 2bit file.
+type TwoBitResult struct {
+	Total   NucleotideCount
+	Masked  int
+	Records []TwoBitRecordCount
+}
+
+// Count2bit counts the bases of every record of the 2bit stream r. N and
+// soft-masked bases are counted from the block tables; only the packed DNA
+// outside N blocks is decoded.
+func (s *Counter) Count2bit(ctx context.Context, r io.Reader, with ...Option) (TwoBitResult, error) {
+	in, err := s.opts.apply(with).open(ctx, r)
+	if err != nil {
+		return TwoBitResult{}, err
+	}
+	defer in.Close()
+
+	tb, err := NewTwoBitReader(in)
+	if err != nil {
+		return TwoBitResult{}, err
+	}
+
+	var res TwoBitResult
+	buf := make([]byte, bufSize)
+	for {
+		rec, err := tb.Next()
+		if err == io.EOF {
+			return res, nil
+		}
+		if err != nil {
+			return TwoBitResult{}, err
+		}
+
+		in.meter.record()
+		n, err := countPacked(tb, rec, buf)
+		if err != nil {
+			return TwoBitResult{}, err
+		}
+
+		rc := TwoBitRecordCount{
+			RecordCount: RecordCount{ID: rec.Name, Count: n},
+			Masked:      rec.Masked(),
+		}
+		res.Records = append(res.Records, rc)
+		res.Total = res.Total.Add(n)
+		res.Masked += rc.Masked
+	}
+}
+
+// packedCounts holds the number of T, C, A and G bases in each packed byte.
+var packedCounts = func() (lut [256][4]uint8) {
+	for b := range lut {
+		for i := 0; i < 4; i++ {
+			lut[b][b>>(2*i)&3]++
+		}
+	}
+	return lut
+}()
+
+// countPacked counts the packed DNA of rec read from r. Whole bytes that do
+// not touch an N block are counted through packedCounts; bytes overlapping an
+// N block are counted base by base, skipping the N positions.
+func countPacked(r io.Reader, rec TwoBitRecord, buf []byte) (NucleotideCount, error) {
+	var (
+		codes [4]int
+		pos   int // first base of the next byte
+		nb    int // index of the first N block that ends after pos
+	)
+
+	for pos < rec.Length {
+		n, err := r.Read(buf)
+		for _, b := range buf[:n] {
+			for nb < len(rec.NBlocks) && rec.NBlocks[nb].end() <= pos {
+				nb++
+			}
+
+			if pos+4 <= rec.Length && (nb == len(rec.NBlocks) || rec.NBlocks[nb].Start >= pos+4) {
+				c := &packedCounts[b]
+				codes[0] += int(c[0])
+				codes[1] += int(c[1])
+				codes[2] += int(c[2])
+				codes[3] += int(c[3])
+			} else {
+				for i := 0; i < 4 && pos+i < rec.Length; i++ {
+					p := pos + i
+					for nb < len(rec.NBlocks) && rec.NBlocks[nb].end() <= p {
+						nb++
+					}
+					if nb < len(rec.NBlocks) && rec.NBlocks[nb].Start <= p {
+						continue
+					}
+					codes[b>>(6-2*i)&3]++
+				}
+			}
+			pos += 4
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return NucleotideCount{}, err
+		}
+	}
+
+	return NucleotideCount{
+		T: codes[0],
+		C: codes[1],
+		A: codes[2],
+		G: codes[3],
+		N: blockBases(rec.NBlocks),
+	}, nil
+}
+
+// TwoBitSequence is a named sequence to be written as 2bit.
+type TwoBitSequence struct {
+	Name     string
+	Sequence string
+}
+
+// TwoBitSource is a named sequence to be written as 2bit whose bases are read
+// from the readers Open returns. Open is called twice, as the N and mask
+// blocks of every sequence precede the bases in the file, and must return the
+// same bases each time. An error from Open stops the write.
+type TwoBitSource struct {
+	Name string
+	Open func() (io.Reader, error)
+}
+
+// WriteTwoBit writes seqs as a little-endian UCSC 2bit file. Lowercase bases
+// become mask blocks and every symbol other than A/C/G/T becomes part of an N
+// block. The 64-bit index layout is used only when the file exceeds 4 GiB.
+func WriteTwoBit(w io.Writer, seqs []TwoBitSequence) error {
+	srcs := make([]TwoBitSource, len(seqs))
+	for i, s := range seqs {
+		srcs[i] = TwoBitSource{Name: s.Name, Open: func() (io.Reader, error) { return strings.NewReader(s.Sequence), nil }}
+	}
+	return WriteTwoBitFrom(w, srcs)
+}
+
+// WriteTwoBitFrom writes seqs as WriteTwoBit does, reading their bases as they
+// are written: only their blocks are kept in memory.
+func WriteTwoBitFrom(w io.Writer, seqs []TwoBitSource) error {
+	recs := make([]TwoBitRecord, len(seqs))
+	for i, s := range seqs {
+		if len(s.Name) > math.MaxUint8 {
+			return fmt.Errorf("%w: name of %q is longer than 255 bytes", ErrInvalid2bit, s.Name[:32])
+		}
+		r, err := s.Open()
+		if err != nil {
+			return fmt.Errorf("read %s: %w", s.Name, err)
+		}
+		var sc blockScanner
+		if _, err := io.Copy(&sc, r); err != nil {
+			return fmt.Errorf("read %s: %w", s.Name, err)
+		}
+		if sc.pos > math.MaxUint32 {
+			return fmt.Errorf("%w: %q is longer than 4 Gbp", ErrInvalid2bit, s.Name)
+		}
+		recs[i] = TwoBitRecord{Name: s.Name, Length: sc.pos, NBlocks: sc.nBlocks(), MaskBlocks: sc.maskBlocks()}
+	}
+
+	var version uint32
+	offsetSize := uint64(4)
+	if twoBitSize(recs, offsetSize) > math.MaxUint32 {
+		version, offsetSize = twoBitVersion64, 8
+	}
+
+	// Write errors are sticky in bufio.Writer and reported by Flush.
+	bw := bufio.NewWriterSize(w, 64<<10)
+	le := binary.LittleEndian
+	var scratch []byte
+
+	scratch = le.AppendUint32(scratch[:0], twoBitSignature)
+	scratch = le.AppendUint32(scratch, version)
+	scratch = le.AppendUint32(scratch, uint32(len(recs)))
+	scratch = le.AppendUint32(scratch, 0)
+	bw.Write(scratch)
+
+	offset := twoBitHeaderSize(recs, offsetSize)
+	for _, rec := range recs {
+		scratch = append(scratch[:0], byte(len(rec.Name)))
+		scratch = append(scratch, rec.Name...)
+		if offsetSize == 8 {
+			scratch = le.AppendUint64(scratch, offset)
+		} else {
+			scratch = le.AppendUint32(scratch, uint32(offset))
+		}
+		bw.Write(scratch)
+		offset += twoBitRecordSize(rec)
+	}
+
+	buf := make([]byte, 64<<10)
+	for i, rec := range recs {
+		scratch = le.AppendUint32(scratch[:0], uint32(rec.Length))
+		scratch = appendBlocks(scratch, rec.NBlocks)
+		scratch = appendBlocks(scratch, rec.MaskBlocks)
+		scratch = le.AppendUint32(scratch, 0)
+		bw.Write(scratch)
+
+		// Four bases to a byte; the last byte is padded with T (0).
+		r, err := seqs[i].Open()
+		if err != nil {
+			return fmt.Errorf("read %s: %w", rec.Name, err)
+		}
+		var packed byte
+		n := 0
+		scratch = scratch[:0]
+		for {
+			m, err := r.Read(buf)
+			for _, c := range buf[:m] {
+				packed = packed<<2 | packedCode[c]
+				if n++; n%4 == 0 {
+					scratch = append(scratch, packed)
+				}
+			}
+			if len(scratch) >= 64<<10 {
+				bw.Write(scratch)
+				scratch = scratch[:0]
+			}
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return fmt.Errorf("read %s: %w", rec.Name, err)
+			}
+		}
+		if n != rec.Length {
+			return fmt.Errorf("read %s: %d bases, %d the first time", rec.Name, n, rec.Length)
+		}
+		if n%4 != 0 {
+			scratch = append(scratch, packed<<(2*(4-n%4)))
+		}
+		bw.Write(scratch)
+	}
+
+	return bw.Flush()
+}
+
+// packedCode maps bytes to their 2bit code; anything that is not A/C/G/T is
+// stored as T (0) and covered by an N block.
+var packedCode = func() (lut [256]byte) {
+	for i, c := range twoBitLetters {
+		lut[c] = byte(i)
+		lut[c|0x20] = byte(i)
+	}
+	return lut
+}()
+
+// blockScanner finds the N and mask blocks of the bases written to it.
+type blockScanner struct {
+	pos               int // bases written so far
+	nStart, maskStart int // start+1 of the open blocks, 0 if none
+	nRuns, maskRuns   []Block
+}
+
+func (b *blockScanner) Write(p []byte) (int, error) {
+	for _, c := range p {
+		b.nStart = extendRun(&b.nRuns, b.nStart, b.pos, baseCode[nucount.SymbolOf(c)] < 0)
+		b.maskStart = extendRun(&b.maskRuns, b.maskStart, b.pos, c >= 'a' && c <= 'z')
+		b.pos++
+	}
+	return len(p), nil
+}
+
+// extendRun updates the open run starting at start-1 with the base at pos,
+// which is in a run if in is true, and returns the new start. A run that ends
+// is appended to blocks.
+func extendRun(blocks *[]Block, start, pos int, in bool) int {
+	switch {
+	case in && start == 0:
+		return pos + 1
+	case !in && start > 0:
+		*blocks = append(*blocks, Block{Start: start - 1, Size: pos - start + 1})
+		return 0
+	}
+	return start
+}
+
+func (b *blockScanner) nBlocks() []Block {
+	b.nStart = extendRun(&b.nRuns, b.nStart, b.pos, false)
+	return b.nRuns
+}
+
+func (b *blockScanner) maskBlocks() []Block {
+	b.maskStart = extendRun(&b.maskRuns, b.maskStart, b.pos, false)
+	return b.maskRuns
+}
+
+func appendBlocks(b []byte, blocks []Block) []byte {
+	le := binary.LittleEndian
+	b = le.AppendUint32(b, uint32(len(blocks)))
+	for _, bl := range blocks {
+		b = le.AppendUint32(b, uint32(bl.Start))
+	}
+	for _, bl := range blocks {
+		b = le.AppendUint32(b, uint32(bl.Size))
+	}
+	return b
+}
+
+func twoBitHeaderSize(recs []TwoBitRecord, offsetSize uint64) uint64 {
+	size := uint64(16)
+	for _, r := range recs {
+		size += 1 + uint64(len(r.Name)) + offsetSize
+	}
+	return size
+}
+
+func twoBitRecordSize(r TwoBitRecord) uint64 {
+	return 4 + 4 + 8*uint64(len(r.NBlocks)) + 4 + 8*uint64(len(r.MaskBlocks)) + 4 + (uint64(r.Length)+3)/4
+}
+
+func twoBitSize(recs []TwoBitRecord, offsetSize uint64) uint64 {
+	size := twoBitHeaderSize(recs, offsetSize)
+	for _, r := range recs {
+		size += twoBitRecordSize(r)
+	}
+	return size
+}
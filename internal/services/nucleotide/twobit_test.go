@@ -0,0 +1,252 @@
+package nucleotide_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	nucleotide "github.com/Viktor2805/nucount/internal/services/nucleotide"
+)
+
+func writeTwoBit(t *testing.T, seqs ...nucleotide.TwoBitSequence) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := nucleotide.WriteTwoBit(&buf, seqs); err != nil {
+		t.Fatalf("WriteTwoBit: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestTwoBit_RoundTrip(t *testing.T) {
+	t.Parallel()
+
+	seqs := []nucleotide.TwoBitSequence{
+		{Name: "chr1", Sequence: "ACGTacgtNNNNnnACGTA"},
+		{Name: "empty", Sequence: ""},
+		{Name: "chrM", Sequence: "NNNNNGATTACARYacgt"},
+	}
+	data := writeTwoBit(t, seqs...)
+
+	r, err := nucleotide.NewTwoBitReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Ambiguity codes other than N are stored as N.
+	want := []string{"ACGTacgtNNNNnnACGTA", "", "NNNNNGATTACANNacgt"}
+	for i, w := range want {
+		rec, err := r.Next()
+		if err != nil {
+			t.Fatalf("record %d: %v", i, err)
+		}
+		if rec.Name != seqs[i].Name || rec.Length != len(w) {
+			t.Errorf("record %d = %+v", i, rec)
+		}
+		got, err := r.Sequence()
+		if err != nil {
+			t.Fatalf("record %d: %v", i, err)
+		}
+		if string(got) != w {
+			t.Errorf("record %d sequence = %q, want %q", i, got, w)
+		}
+	}
+	if _, err := r.Next(); err != io.EOF {
+		t.Errorf("expected io.EOF, got %v", err)
+	}
+}
+
+func TestTwoBit_Blocks(t *testing.T) {
+	t.Parallel()
+
+	r, err := nucleotide.NewTwoBitReader(bytes.NewReader(writeTwoBit(t,
+		nucleotide.TwoBitSequence{Name: "s", Sequence: "NNacGTNNNtt"},
+	)))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	rec, err := r.Next()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	wantN := []nucleotide.Block{{Start: 0, Size: 2}, {Start: 6, Size: 3}}
+	wantMask := []nucleotide.Block{{Start: 2, Size: 2}, {Start: 9, Size: 2}}
+	if !equalBlocks(rec.NBlocks, wantN) || !equalBlocks(rec.MaskBlocks, wantMask) {
+		t.Errorf("blocks = %v / %v", rec.NBlocks, rec.MaskBlocks)
+	}
+	if rec.Masked() != 4 {
+		t.Errorf("masked = %d, want 4", rec.Masked())
+	}
+}
+
+func TestTwoBit_SequenceAfterRead(t *testing.T) {
+	t.Parallel()
+
+	r, err := nucleotide.NewTwoBitReader(bytes.NewReader(writeTwoBit(t,
+		nucleotide.TwoBitSequence{Name: "s", Sequence: "ACGTACGTA"},
+	)))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := r.Next(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := r.Read(make([]byte, 1)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := r.Sequence(); !errors.Is(err, nucleotide.ErrPartlyRead) {
+		t.Errorf("Sequence after Read = %v, want ErrPartlyRead", err)
+	}
+}
+
+func equalBlocks(a, b []nucleotide.Block) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestWriteTwoBitFrom(t *testing.T) {
+	t.Parallel()
+
+	seqs := []nucleotide.TwoBitSequence{
+		{Name: "chr1", Sequence: strings.Repeat("ACGTacgtNNNNnnACGTA", 5000)},
+		{Name: "empty", Sequence: ""},
+		{Name: "odd", Sequence: "nGATTACARY"},
+	}
+	want := writeTwoBit(t, seqs...)
+
+	// Bases read a few at a time give the same file.
+	srcs := make([]nucleotide.TwoBitSource, len(seqs))
+	for i, s := range seqs {
+		srcs[i] = nucleotide.TwoBitSource{Name: s.Name, Open: func() (io.Reader, error) {
+			return iotest.HalfReader(strings.NewReader(s.Sequence)), nil
+		}}
+	}
+	var buf bytes.Buffer
+	if err := nucleotide.WriteTwoBitFrom(&buf, srcs); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !bytes.Equal(buf.Bytes(), want) {
+		t.Error("file differs from WriteTwoBit's")
+	}
+
+	// Bases that change between the reads are an error, not a corrupt file.
+	opened := 0
+	changing := nucleotide.TwoBitSource{Name: "chr1", Open: func() (io.Reader, error) {
+		opened++
+		return strings.NewReader(strings.Repeat("A", opened)), nil
+	}}
+	if err := nucleotide.WriteTwoBitFrom(io.Discard, []nucleotide.TwoBitSource{changing}); err == nil {
+		t.Error("expected an error for changed bases")
+	}
+
+	failing := nucleotide.TwoBitSource{Name: "chr1", Open: func() (io.Reader, error) {
+		return iotest.ErrReader(io.ErrUnexpectedEOF), nil
+	}}
+	if err := nucleotide.WriteTwoBitFrom(io.Discard, []nucleotide.TwoBitSource{failing}); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("err = %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+
+	unopened := nucleotide.TwoBitSource{Name: "chr1", Open: func() (io.Reader, error) {
+		return nil, context.Canceled
+	}}
+	if err := nucleotide.WriteTwoBitFrom(io.Discard, []nucleotide.TwoBitSource{unopened}); !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestCount2bit(t *testing.T) {
+	t.Parallel()
+
+	// N runs start and end inside packed bytes to exercise the partial-byte
+	// path next to whole-byte counting.
+	seq1 := "ACGTA" + strings.Repeat("N", 7) + "ggccttaa" + "NN" + "T"
+	seq2 := strings.Repeat("acgt", 1000)
+	data := writeTwoBit(t,
+		nucleotide.TwoBitSequence{Name: "one", Sequence: seq1},
+		nucleotide.TwoBitSequence{Name: "two", Sequence: seq2},
+	)
+
+	res, err := nucleotide.NewCounter().Count2bit(context.Background(), asMultipartFile(string(data)))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(res.Records) != 2 {
+		t.Fatalf("expected 2 records, got %d", len(res.Records))
+	}
+	want1 := nucleotide.NucleotideCount{A: 4, C: 3, G: 3, T: 4, N: 9}
+	if res.Records[0].ID != "one" || res.Records[0].Count != want1 || res.Records[0].Masked != 8 {
+		t.Errorf("record one = %+v, want %+v", res.Records[0], want1)
+	}
+	want2 := nucleotide.NucleotideCount{A: 1000, C: 1000, G: 1000, T: 1000}
+	if res.Records[1].Count != want2 || res.Records[1].Masked != 4000 {
+		t.Errorf("record two = %+v", res.Records[1])
+	}
+	if res.Total != want1.Add(want2) || res.Masked != 4008 {
+		t.Errorf("total = %+v masked %d", res.Total, res.Masked)
+	}
+}
+
+func TestCount2bit_BigEndian(t *testing.T) {
+	t.Parallel()
+
+	// Hand-built big-endian file with one record "ACGT" preceded by a gap
+	// between the index and the record.
+	var buf bytes.Buffer
+	be := binary.BigEndian
+	buf.Write(be.AppendUint32(nil, 0x1A412743))
+	buf.Write(be.AppendUint32(nil, 0))
+	buf.Write(be.AppendUint32(nil, 1))
+	buf.Write(be.AppendUint32(nil, 0))
+	buf.WriteByte(1)
+	buf.WriteString("x")
+	buf.Write(be.AppendUint32(nil, 24))
+	buf.Write([]byte{0, 0}) // padding
+	buf.Write(be.AppendUint32(nil, 4))
+	buf.Write(be.AppendUint32(nil, 0)) // N blocks
+	buf.Write(be.AppendUint32(nil, 0)) // mask blocks
+	buf.Write(be.AppendUint32(nil, 0)) // reserved
+	buf.WriteByte(0b10_01_11_00)       // A C G T
+
+	res, err := nucleotide.NewCounter().Count2bit(context.Background(), asMultipartFile(buf.String()))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := nucleotide.NucleotideCount{A: 1, C: 1, G: 1, T: 1}
+	if len(res.Records) != 1 || res.Records[0].ID != "x" || res.Total != want {
+		t.Errorf("result = %+v", res)
+	}
+}
+
+func TestCount2bit_Invalid(t *testing.T) {
+	t.Parallel()
+
+	valid := writeTwoBit(t, nucleotide.TwoBitSequence{Name: "s", Sequence: strings.Repeat("ACGT", 100)})
+
+	tests := map[string][]byte{
+		"bad signature": []byte("not a 2bit file at all"),
+		"truncated":     valid[:len(valid)-10],
+		"short header":  valid[:8],
+	}
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := nucleotide.NewCounter().Count2bit(context.Background(), asMultipartFile(string(input)))
+			if !errors.Is(err, nucleotide.ErrInvalid2bit) {
+				t.Errorf("expected ErrInvalid2bit, got %v", err)
+			}
+		})
+	}
+}
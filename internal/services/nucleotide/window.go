@@ -0,0 +1,222 @@
+package nucleotide
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/Viktor2805/nucount/pkg/nucount"
+)
+
+const (
+	// MaxWindowSize bounds the memory a GC profile keeps per record.
+	MaxWindowSize = 10_000_000
+	// DefaultMaxWindows bounds the windows a GC profile returns, over all
+	// records, which the response holds in memory.
+	DefaultMaxWindows = 1_000_000
+)
+
+var (
+	// ErrInvalidWindow is returned for unusable window options.
+	ErrInvalidWindow = errors.New("invalid window options")
+	// ErrTooManyWindows is returned when the input yields more windows than
+	// WindowOptions.MaxWindows allows.
+	ErrTooManyWindows = errors.New("too many windows")
+)
+
+// WindowOptions configures a sliding-window analysis. Windows start every Step
+// bases and span Size bases; Step defaults to Size.
+type WindowOptions struct {
+	Size       int
+	Step       int
+	MaxWindows int // bound on windows returned, 0 means DefaultMaxWindows
+}
+
+func (o WindowOptions) validate() (WindowOptions, error) {
+	if o.Step == 0 {
+		o.Step = o.Size
+	}
+	if o.MaxWindows <= 0 {
+		o.MaxWindows = DefaultMaxWindows
+	}
+	if o.Size <= 0 || o.Size > MaxWindowSize {
+		return o, fmt.Errorf("%w: window size must be between 1 and %d", ErrInvalidWindow, MaxWindowSize)
+	}
+	if o.Step < 0 {
+		return o, fmt.Errorf("%w: step must be positive", ErrInvalidWindow)
+	}
+	return o, nil
+}
+
+// Window holds the composition of a single window. Coordinates are 0-based and
+// half-open; the last window of a record may be shorter than the window size.
+type Window struct {
+	Start          int
+	End            int
+	GCContent      float64 // percentage of G+C among A/C/G/T in the window
+	GCSkew         float64 // (G-C)/(G+C) in the window
+	CumulativeSkew float64 // running sum of GCSkew along the record
+}
+
+// RecordProfile holds the windows computed for one FASTA record.
+type RecordProfile struct {
+	ID          string
+	Description string
+	Length      int
+	Windows     []Window
+}
+
+// GCProfile is the result of a sliding-window GC analysis.
+type GCProfile struct {
+	Options WindowOptions
+	Records []RecordProfile
+}
+
+// GCProfile computes GC content and GC skew along every record of the FASTA
+// stream r. Only the bases of the current window are kept in memory.
+func (s *Counter) GCProfile(ctx context.Context, r io.Reader, opts WindowOptions, with ...Option) (GCProfile, error) {
+	opts, err := opts.validate()
+	if err != nil {
+		return GCProfile{}, err
+	}
+
+	in, err := s.opts.apply(with).open(ctx, r)
+	if err != nil {
+		return GCProfile{}, err
+	}
+	defer in.Close()
+
+	p := newWindowProfiler(opts)
+	err = nucount.ScanFASTA(in, in.records(p))
+	if ferr := p.flush(); err == nil {
+		err = ferr
+	}
+
+	return GCProfile{Options: opts, Records: p.records}, err
+}
+
+// Base classes kept in the window ring.
+const (
+	classAT = iota
+	classG
+	classC
+	classOther
+	numClasses
+)
+
+var classLUT = func() (lut [nucount.NumSymbols]uint8) {
+	for sym := range lut {
+		lut[sym] = classOther
+	}
+	lut[nucount.SymbolA], lut[nucount.SymbolT] = classAT, classAT
+	lut[nucount.SymbolG], lut[nucount.SymbolC] = classG, classC
+	return lut
+}()
+
+// windowProfiler is a nucount.FASTAHandler that slides a window over each
+// record. The ring holds the classes of the last Size bases, so a window is
+// emitted as soon as its last base has been read.
+type windowProfiler struct {
+	opts    WindowOptions
+	records []RecordProfile
+
+	cur       RecordProfile
+	open      bool
+	ring      []uint8
+	classes   [numClasses]int
+	pos       int
+	nextStart int
+	cumSkew   float64
+	windows   int // emitted over all records
+}
+
+func newWindowProfiler(opts WindowOptions) *windowProfiler {
+	return &windowProfiler{opts: opts, ring: make([]uint8, opts.Size)}
+}
+
+func (p *windowProfiler) Header(text []byte) error {
+	if err := p.flush(); err != nil {
+		return err
+	}
+	p.cur.ID, p.cur.Description = nucount.ParseHeader(text)
+	p.open = true
+	return nil
+}
+
+func (p *windowProfiler) Sequence(seg []byte) error {
+	p.open = true
+	size := p.opts.Size
+
+	for _, b := range seg {
+		sym := nucount.SymbolOf(b)
+		if sym == nucount.SymbolSpace {
+			continue
+		}
+
+		slot := p.pos % size
+		if p.pos >= size {
+			p.classes[p.ring[slot]]--
+		}
+		cls := classLUT[sym]
+		p.ring[slot] = cls
+		p.classes[cls]++
+		p.pos++
+
+		if p.pos == p.nextStart+size {
+			if err := p.emit(p.nextStart, p.pos, p.classes); err != nil {
+				return err
+			}
+			p.nextStart += p.opts.Step
+		}
+	}
+	return nil
+}
+
+// flush emits the trailing partial window and finishes the current record.
+func (p *windowProfiler) flush() error {
+	if !p.open {
+		return nil
+	}
+
+	if p.nextStart < p.pos && (len(p.cur.Windows) == 0 || p.cur.Windows[len(p.cur.Windows)-1].End < p.pos) {
+		var classes [numClasses]int
+		for i := p.nextStart; i < p.pos; i++ {
+			classes[p.ring[i%p.opts.Size]]++
+		}
+		if err := p.emit(p.nextStart, p.pos, classes); err != nil {
+			return err
+		}
+	}
+
+	p.cur.Length = p.pos
+	p.records = append(p.records, p.cur)
+
+	p.cur = RecordProfile{}
+	p.open = false
+	p.classes = [numClasses]int{}
+	p.pos = 0
+	p.nextStart = 0
+	p.cumSkew = 0
+	return nil
+}
+
+func (p *windowProfiler) emit(start, end int, classes [numClasses]int) error {
+	if p.windows == p.opts.MaxWindows {
+		return fmt.Errorf("%w: limit is %d, use a larger step", ErrTooManyWindows, p.opts.MaxWindows)
+	}
+	p.windows++
+
+	g, c := classes[classG], classes[classC]
+	skew := ratio(g-c, g+c)
+	p.cumSkew += skew
+
+	p.cur.Windows = append(p.cur.Windows, Window{
+		Start:          start,
+		End:            end,
+		GCContent:      ratio(g+c, g+c+classes[classAT]) * 100,
+		GCSkew:         skew,
+		CumulativeSkew: p.cumSkew,
+	})
+	return nil
+}
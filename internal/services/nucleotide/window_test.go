@@ -0,0 +1,124 @@
+package nucleotide_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math"
+	"testing"
+
+	nucleotide "github.com/Viktor2805/nucount/internal/services/nucleotide"
+)
+
+func TestGCProfile(t *testing.T) {
+	counter := nucleotide.NewCounter()
+
+	input := ">r1 first\nGGGG\nCCAA\nTT\n>r2\nGC\n"
+
+	got, err := counter.GCProfile(context.Background(), asMultipartFile(input), nucleotide.WindowOptions{Size: 4, Step: 2})
+	if err != nil {
+		t.Fatalf("GCProfile() error: %v", err)
+	}
+	if got.Options.Step != 2 || len(got.Records) != 2 {
+		t.Fatalf("GCProfile() = %+v, want 2 records with step 2", got)
+	}
+
+	r1 := got.Records[0]
+	if r1.ID != "r1" || r1.Description != "first" || r1.Length != 10 {
+		t.Fatalf("unexpected record: %+v", r1)
+	}
+
+	want := []nucleotide.Window{
+		{Start: 0, End: 4, GCContent: 100, GCSkew: 1, CumulativeSkew: 1},
+		{Start: 2, End: 6, GCContent: 100, GCSkew: 0, CumulativeSkew: 1},
+		{Start: 4, End: 8, GCContent: 50, GCSkew: -1, CumulativeSkew: 0},
+		{Start: 6, End: 10, GCContent: 0, GCSkew: 0, CumulativeSkew: 0},
+	}
+	assertWindows(t, r1.Windows, want)
+
+	// The second record is shorter than the window and yields one partial window.
+	assertWindows(t, got.Records[1].Windows, []nucleotide.Window{
+		{Start: 0, End: 2, GCContent: 100, GCSkew: 0, CumulativeSkew: 0},
+	})
+}
+
+func TestGCProfile_TrailingPartialWindow(t *testing.T) {
+	counter := nucleotide.NewCounter()
+
+	got, err := counter.GCProfile(context.Background(), asMultipartFile(">r\nAAAAAGGG\n"), nucleotide.WindowOptions{Size: 5, Step: 3})
+	if err != nil {
+		t.Fatalf("GCProfile() error: %v", err)
+	}
+
+	assertWindows(t, got.Records[0].Windows, []nucleotide.Window{
+		{Start: 0, End: 5, GCContent: 0, GCSkew: 0, CumulativeSkew: 0},
+		{Start: 3, End: 8, GCContent: 60, GCSkew: 1, CumulativeSkew: 1},
+	})
+}
+
+func TestGCProfile_StepLargerThanWindow(t *testing.T) {
+	counter := nucleotide.NewCounter()
+
+	f := &limitedFile{Reader: bytes.NewReader([]byte(">r\nGGAACCTTGN\n")), limit: 3}
+
+	got, err := counter.GCProfile(context.Background(), f, nucleotide.WindowOptions{Size: 2, Step: 4})
+	if err != nil {
+		t.Fatalf("GCProfile() error: %v", err)
+	}
+
+	assertWindows(t, got.Records[0].Windows, []nucleotide.Window{
+		{Start: 0, End: 2, GCContent: 100, GCSkew: 1, CumulativeSkew: 1},
+		{Start: 4, End: 6, GCContent: 100, GCSkew: -1, CumulativeSkew: 0},
+		{Start: 8, End: 10, GCContent: 100, GCSkew: 1, CumulativeSkew: 1},
+	})
+}
+
+func TestGCProfile_TooManyWindows(t *testing.T) {
+	counter := nucleotide.NewCounter()
+	input := ">r1\nACGTACGT\n>r2\nACGT\n"
+
+	got, err := counter.GCProfile(context.Background(), asMultipartFile(input), nucleotide.WindowOptions{Size: 2, MaxWindows: 6})
+	if err != nil {
+		t.Fatalf("GCProfile() error: %v", err)
+	}
+	if len(got.Records[0].Windows)+len(got.Records[1].Windows) != 6 {
+		t.Fatalf("got %+v, want 6 windows", got.Records)
+	}
+
+	_, err = counter.GCProfile(context.Background(), asMultipartFile(input), nucleotide.WindowOptions{Size: 2, MaxWindows: 5})
+	if !errors.Is(err, nucleotide.ErrTooManyWindows) {
+		t.Fatalf("GCProfile() error = %v, want ErrTooManyWindows", err)
+	}
+}
+
+func TestGCProfile_InvalidOptions(t *testing.T) {
+	counter := nucleotide.NewCounter()
+
+	for _, opts := range []nucleotide.WindowOptions{
+		{Size: 0},
+		{Size: 10, Step: -1},
+		{Size: nucleotide.MaxWindowSize + 1},
+	} {
+		_, err := counter.GCProfile(context.Background(), asMultipartFile(">r\nACGT\n"), opts)
+		if !errors.Is(err, nucleotide.ErrInvalidWindow) {
+			t.Errorf("GCProfile(%+v) error = %v, want ErrInvalidWindow", opts, err)
+		}
+	}
+}
+
+func assertWindows(t *testing.T, got, want []nucleotide.Window) {
+	t.Helper()
+
+	if len(got) != len(want) {
+		t.Fatalf("got %d windows %+v, want %d", len(got), got, len(want))
+	}
+	for i := range want {
+		g, w := got[i], want[i]
+		if g.Start != w.Start || g.End != w.End ||
+			math.Abs(g.GCContent-w.GCContent) > 1e-9 ||
+			math.Abs(g.GCSkew-w.GCSkew) > 1e-9 ||
+			math.Abs(g.CumulativeSkew-w.CumulativeSkew) > 1e-9 {
+			t.Errorf("window %d = %+v, want %+v", i, g, w)
+		}
+	}
+}
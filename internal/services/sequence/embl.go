@@ -0,0 +1,119 @@
+package sequence
+
+import (
+	"io"
+	"strings"
+
+	"github.com/Viktor2805/nucount/internal/models"
+)
+
+// emblValueColumn is where the value of an EMBL line starts, after the
+// two-letter line code and three spaces.
+const emblValueColumn = 5
+
+// EMBLReader parses EMBL flat files. It keeps the ID, AC, DE and OS metadata,
+// the FT feature table and the SQ sequence, which ends the record; other line
+// types are skipped.
+type EMBLReader struct {
+	records
+}
+
+func NewEMBLReader(r io.Reader) *EMBLReader {
+	return &EMBLReader{records: newRecords(r)}
+}
+
+func (e *EMBLReader) Next() (*models.Sequence, error) {
+	if err := e.skipBases(); err != nil {
+		return nil, err
+	}
+
+	var (
+		b         recordBuilder
+		started   bool
+		primaryAC bool
+		sv        string
+	)
+	build := func() (*models.Sequence, error) {
+		if b.accession == "" {
+			return nil, e.lines.errorf("record has neither AC nor ID accession")
+		}
+		if sv != "" {
+			b.version = b.accession + "." + sv
+		}
+		return b.build(), nil
+	}
+
+	for {
+		line, err := e.lines.next()
+		if err == io.EOF {
+			if started {
+				return nil, e.lines.errorf("record is not terminated by //")
+			}
+			return nil, io.EOF
+		}
+		if err != nil {
+			return nil, err
+		}
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "//") {
+			if !started {
+				return nil, e.lines.errorf("// without an ID line")
+			}
+			rec, err := build()
+			if err != nil {
+				return nil, err
+			}
+			return e.endRecord(rec), nil
+		}
+
+		code := line[:min(2, len(line))]
+		if !started && code != "ID" {
+			return nil, e.lines.errorf("expected ID, got %q", code)
+		}
+		started = true
+
+		value := ""
+		if len(line) > emblValueColumn {
+			value = strings.TrimSpace(line[emblValueColumn:])
+		}
+
+		switch code {
+		case "ID":
+			// ID   X56734; SV 1; linear; mRNA; STD; PLN; 1859 BP.
+			for i, field := range strings.Split(value, ";") {
+				field = strings.TrimSpace(field)
+				if i == 0 && b.accession == "" {
+					b.accession = field
+				}
+				if v, ok := strings.CutPrefix(field, "SV "); ok {
+					sv = strings.TrimSpace(v)
+				}
+			}
+		case "AC":
+			// The first accession of the first AC line is the primary one.
+			if first, _, _ := strings.Cut(value, ";"); first != "" && !primaryAC {
+				b.accession = strings.TrimSpace(first)
+				primaryAC = true
+			}
+		case "DE":
+			b.describe(value)
+		case "OS":
+			if b.species == "" {
+				b.species = value
+			}
+		case "FT":
+			if err := b.features.line("  " + line[2:]); err != nil {
+				return nil, e.lines.errorf("%s", err)
+			}
+		case "SQ":
+			rec, err := build()
+			if err != nil {
+				return nil, err
+			}
+			return e.startBases(rec), nil
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package sequence_test
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/Viktor2805/nucount/internal/services/sequence"
+)
+
+const emblRecord = `ID   X56734; SV 1; linear; mRNA; STD; PLN; 24 BP.
+XX
+AC   X56734; S46826;
+XX
+DE   Trifolium repens mRNA for non-cyanogenic beta-glucosidase
+DE   (partial).
+XX
+OS   Trifolium repens (white clover)
+OC   Eukaryota; Viridiplantae; Streptophyta.
+XX
+FH   Key             Location/Qualifiers
+FH
+FT   source          1..24
+FT                   /organism="Trifolium repens"
+FT                   /mol_type="mRNA"
+FT   CDS             <1..>24
+FT                   /gene="lin2"
+XX
+SQ   Sequence 24 BP; 6 A; 6 C; 6 G; 6 T; 0 other;
+     aaccggtt aaccggtt aaccggtt                                          24
+//
+`
+
+func TestEMBLReader(t *testing.T) {
+	r := sequence.NewEMBLReader(strings.NewReader(emblRecord))
+
+	rec, err := r.Next()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if rec.Accession != "X56734.1" {
+		t.Errorf("accession = %q", rec.Accession)
+	}
+	if got := deref(rec.Description); got != "Trifolium repens mRNA for non-cyanogenic beta-glucosidase (partial)." {
+		t.Errorf("description = %q", got)
+	}
+	if got := deref(rec.Species); got != "Trifolium repens (white clover)" {
+		t.Errorf("species = %q", got)
+	}
+	if rec.Chromosome != nil {
+		t.Errorf("chromosome = %q", *rec.Chromosome)
+	}
+	if bases, err := io.ReadAll(r.Bases()); err != nil || string(bases) != "AACCGGTTAACCGGTTAACCGGTT" {
+		t.Errorf("sequence = %q, %v", bases, err)
+	}
+
+	if len(rec.Features) != 2 {
+		t.Fatalf("expected 2 features, got %d", len(rec.Features))
+	}
+	if f := rec.Features[1]; f.Key != "CDS" || f.Location != "<1..>24" {
+		t.Errorf("CDS = %+v", f)
+	}
+	if v, _ := rec.Features[0].Qualifiers.Get("mol_type"); v != "mRNA" {
+		t.Errorf("mol_type = %q", v)
+	}
+
+	if _, err := r.Next(); err != io.EOF {
+		t.Errorf("expected io.EOF, got %v", err)
+	}
+}
+
+func TestEMBLReader_Invalid(t *testing.T) {
+	_, err := sequence.NewEMBLReader(strings.NewReader("LOCUS       X 4 bp\n//\n")).Next()
+	if !errors.Is(err, sequence.ErrInvalidFlatFile) {
+		t.Errorf("expected ErrInvalidFlatFile, got %v", err)
+	}
+}
@@ -0,0 +1,296 @@
+package sequence
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/Viktor2805/nucount/internal/models"
+)
+
+// MaxFlatFileLineLength bounds the memory used for a single flat-file line.
+const MaxFlatFileLineLength = 1 << 20
+
+// ErrInvalidFlatFile is returned for GenBank or EMBL input that cannot be
+// parsed.
+var ErrInvalidFlatFile = errors.New("invalid flat file")
+
+// RecordReader streams the records of a sequence flat file.
+type RecordReader interface {
+	// Next parses the following record up to its sequence, which is read
+	// from Bases. It returns io.EOF when the input is exhausted between
+	// records.
+	Next() (*models.Sequence, error)
+	// Bases reads the bases of the record last returned by Next, upper-cased,
+	// a line at a time. Next skips those left unread.
+	Bases() io.Reader
+}
+
+// records is the state the flat-file readers keep between records.
+type records struct {
+	lines *lineReader
+	bases *baseReader
+}
+
+func newRecords(r io.Reader) records {
+	return records{lines: newLineReader(r), bases: &baseReader{err: io.EOF}}
+}
+
+func (r *records) Bases() io.Reader {
+	return r.bases
+}
+
+// skipBases reads past the bases of the previous record left unread.
+func (r *records) skipBases() error {
+	_, err := io.Copy(io.Discard, r.bases)
+	return err
+}
+
+// startBases returns rec, whose bases follow on the next lines.
+func (r *records) startBases(rec *models.Sequence) *models.Sequence {
+	r.bases = &baseReader{lines: r.lines}
+	return rec
+}
+
+// endRecord returns rec, which has no bases.
+func (r *records) endRecord(rec *models.Sequence) *models.Sequence {
+	r.bases = &baseReader{err: io.EOF}
+	return rec
+}
+
+// baseReader reads the bases of an ORIGIN or SQ section from its indented
+// lines, dropping the position numbers and the spaces between groups of ten
+// bases, up to the // line ending the record.
+type baseReader struct {
+	lines *lineReader
+	buf   []byte
+	next  []byte // buf's backing array, reused
+	err   error
+}
+
+func (r *baseReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		line, err := r.lines.next()
+		switch {
+		case err == io.EOF:
+			r.err = r.lines.errorf("record is not terminated by //")
+		case err != nil:
+			r.err = err
+		case strings.HasPrefix(line, "//"):
+			r.err = io.EOF
+		case strings.HasPrefix(line, " "):
+			r.next = appendBases(r.next[:0], line)
+			r.buf = r.next
+		}
+	}
+
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+// appendBases appends the letters of an ORIGIN or SQ line to dst,
+// upper-cased.
+func appendBases(dst []byte, line string) []byte {
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case c >= 'a' && c <= 'z':
+			dst = append(dst, c-'a'+'A')
+		case c >= 'A' && c <= 'Z', c == '-', c == '*':
+			dst = append(dst, c)
+		}
+	}
+	return dst
+}
+
+// lineReader reads lines of any length up to MaxFlatFileLineLength, without
+// their terminator.
+type lineReader struct {
+	r    *bufio.Reader
+	line int
+	buf  []byte
+}
+
+func newLineReader(r io.Reader) *lineReader {
+	return &lineReader{r: bufio.NewReaderSize(r, 64<<10)}
+}
+
+func (l *lineReader) next() (string, error) {
+	l.buf = l.buf[:0]
+	for {
+		chunk, err := l.r.ReadSlice('\n')
+		l.buf = append(l.buf, chunk...)
+		if len(l.buf) > MaxFlatFileLineLength {
+			return "", l.errorf("line exceeds %d bytes", MaxFlatFileLineLength)
+		}
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		if err == io.EOF && len(l.buf) > 0 {
+			err = nil
+		}
+		if err != nil {
+			return "", err
+		}
+		l.line++
+		return string(bytes.TrimRight(l.buf, "\r\n")), nil
+	}
+}
+
+func (l *lineReader) errorf(format string, args ...any) error {
+	return fmt.Errorf("%w: line %d: %s", ErrInvalidFlatFile, l.line, fmt.Sprintf(format, args...))
+}
+
+// Column layout shared by the GenBank and EMBL feature tables once the EMBL
+// "FT" line code has been blanked out.
+const (
+	featureKeyColumn   = 5
+	featureValueColumn = 21
+)
+
+// featureTable parses feature table lines into models.Feature values.
+type featureTable struct {
+	features []models.Feature
+	cur      *models.Feature
+	qual     *models.Qualifier
+	raw      strings.Builder // value of qual as written, quotes included
+}
+
+// line consumes one feature table line laid out with the key at
+// featureKeyColumn and locations and qualifiers at featureValueColumn.
+func (t *featureTable) line(l string) error {
+	if strings.TrimSpace(l) == "" {
+		return nil
+	}
+
+	if len(l) > featureKeyColumn && l[featureKeyColumn] != ' ' && !t.quoteOpen() {
+		t.finishFeature()
+		fields := strings.Fields(l)
+		f := models.Feature{Key: fields[0]}
+		if len(fields) > 1 {
+			f.Location = strings.Join(fields[1:], "")
+		}
+		t.features = append(t.features, f)
+		t.cur = &t.features[len(t.features)-1]
+		return nil
+	}
+
+	if t.cur == nil {
+		return errors.New("feature table continuation before the first feature")
+	}
+
+	text := strings.TrimSpace(l)
+	switch {
+	case strings.HasPrefix(text, "/") && !t.quoteOpen():
+		t.finishQualifier()
+		name, value, hasValue := strings.Cut(text[1:], "=")
+		t.cur.Qualifiers = append(t.cur.Qualifiers, models.Qualifier{Name: name})
+		t.qual = &t.cur.Qualifiers[len(t.cur.Qualifiers)-1]
+		if hasValue {
+			t.raw.WriteString(value)
+		}
+	case t.qual != nil:
+		// Amino acid translations are wrapped without separators, prose is
+		// wrapped at spaces.
+		if t.qual.Name != "translation" {
+			t.raw.WriteByte(' ')
+		}
+		t.raw.WriteString(text)
+	default:
+		t.cur.Location += text
+	}
+	return nil
+}
+
+// quoteOpen reports whether the current qualifier value has an unbalanced
+// quote, i.e. continues on the next line whatever that line looks like.
+func (t *featureTable) quoteOpen() bool {
+	return t.qual != nil && strings.Count(t.raw.String(), `"`)%2 == 1
+}
+
+func (t *featureTable) finishQualifier() {
+	if t.qual == nil {
+		return
+	}
+	v := t.raw.String()
+	if len(v) >= 2 && v[0] == '"' && v[len(v)-1] == '"' {
+		v = strings.ReplaceAll(v[1:len(v)-1], `""`, `"`)
+	}
+	t.qual.Value = v
+	t.qual = nil
+	t.raw.Reset()
+}
+
+func (t *featureTable) finishFeature() {
+	t.finishQualifier()
+	t.cur = nil
+}
+
+// result returns the parsed features and resets the table for the next record.
+func (t *featureTable) result() []models.Feature {
+	t.finishFeature()
+	f := t.features
+	t.features = nil
+	return f
+}
+
+// recordBuilder collects the fields shared by both flat-file formats.
+type recordBuilder struct {
+	accession   string
+	version     string
+	species     string
+	description strings.Builder
+	features    featureTable
+}
+
+func (b *recordBuilder) describe(text string) {
+	if b.description.Len() > 0 {
+		b.description.WriteByte(' ')
+	}
+	b.description.WriteString(strings.TrimSpace(text))
+}
+
+// build returns the record without its bases. The accession is versioned
+// when a version is known, e.g. "CM034354.1".
+func (b *recordBuilder) build() *models.Sequence {
+	rec := &models.Sequence{
+		Accession: b.accession,
+		StartPos:  1,
+		Features:  b.features.result(),
+	}
+	if b.version != "" {
+		rec.Accession = b.version
+	}
+	if b.description.Len() > 0 {
+		rec.Description = ptr(b.description.String())
+	}
+
+	species := b.species
+	for _, f := range rec.Features {
+		if f.Key != "source" {
+			continue
+		}
+		if v, ok := f.Qualifiers.Get("chromosome"); ok && v != "" {
+			rec.Chromosome = ptr(v)
+		}
+		if v, ok := f.Qualifiers.Get("organism"); ok && species == "" {
+			species = v
+		}
+		break
+	}
+	if species != "" {
+		rec.Species = ptr(species)
+	}
+	return rec
+}
+
+func ptr(s string) *string {
+	return &s
+}
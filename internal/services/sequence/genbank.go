@@ -0,0 +1,122 @@
+package sequence
+
+import (
+	"io"
+	"strings"
+
+	"github.com/Viktor2805/nucount/internal/models"
+)
+
+// genbankValueColumn is where the value of a GenBank keyword line starts.
+const genbankValueColumn = 12
+
+// GenBankReader parses GenBank flat files. It keeps the LOCUS, DEFINITION,
+// ACCESSION, VERSION and ORGANISM metadata, the FEATURES table and the ORIGIN
+// sequence, which ends the record; other sections are skipped.
+type GenBankReader struct {
+	records
+}
+
+func NewGenBankReader(r io.Reader) *GenBankReader {
+	return &GenBankReader{records: newRecords(r)}
+}
+
+func (g *GenBankReader) Next() (*models.Sequence, error) {
+	if err := g.skipBases(); err != nil {
+		return nil, err
+	}
+
+	var (
+		b       recordBuilder
+		started bool
+		section string
+	)
+	build := func() (*models.Sequence, error) {
+		if b.accession == "" {
+			return nil, g.lines.errorf("record has neither ACCESSION nor LOCUS name")
+		}
+		return b.build(), nil
+	}
+
+	for {
+		line, err := g.lines.next()
+		if err == io.EOF {
+			if started {
+				return nil, g.lines.errorf("record is not terminated by //")
+			}
+			return nil, io.EOF
+		}
+		if err != nil {
+			return nil, err
+		}
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "//") {
+			if !started {
+				return nil, g.lines.errorf("// without a LOCUS line")
+			}
+			rec, err := build()
+			if err != nil {
+				return nil, err
+			}
+			return g.endRecord(rec), nil
+		}
+
+		if line[0] != ' ' {
+			section = strings.Fields(line)[0]
+			if !started && section != "LOCUS" {
+				return nil, g.lines.errorf("expected LOCUS, got %q", section)
+			}
+			started = true
+			if section == "ORIGIN" {
+				rec, err := build()
+				if err != nil {
+					return nil, err
+				}
+				return g.startBases(rec), nil
+			}
+
+			value := genbankValue(line)
+			switch section {
+			case "LOCUS":
+				// The locus name stands in for a missing ACCESSION.
+				if fields := strings.Fields(value); len(fields) > 0 && b.accession == "" {
+					b.accession = fields[0]
+				}
+			case "DEFINITION":
+				b.describe(value)
+			case "ACCESSION":
+				if fields := strings.Fields(value); len(fields) > 0 {
+					b.accession = fields[0]
+				}
+			case "VERSION":
+				if fields := strings.Fields(value); len(fields) > 0 {
+					b.version = fields[0]
+				}
+			}
+			continue
+		}
+
+		switch section {
+		case "DEFINITION":
+			b.describe(line)
+		case "SOURCE":
+			if strings.HasPrefix(strings.TrimSpace(line), "ORGANISM") {
+				b.species = genbankValue(line)
+			}
+		case "FEATURES":
+			if err := b.features.line(line); err != nil {
+				return nil, g.lines.errorf("%s", err)
+			}
+		}
+	}
+}
+
+func genbankValue(line string) string {
+	if len(line) <= genbankValueColumn {
+		return ""
+	}
+	return strings.TrimSpace(line[genbankValueColumn:])
+}
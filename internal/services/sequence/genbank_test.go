@@ -0,0 +1,145 @@
+package sequence_test
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/Viktor2805/nucount/internal/models"
+	"github.com/Viktor2805/nucount/internal/services/sequence"
+)
+
+const genbankRecord = `LOCUS       SCU49845     20 bp    DNA             PLN       21-JUN-1999
+DEFINITION  Saccharomyces cerevisiae TCP1-beta gene, partial cds, and Axl2p
+            (AXL2) gene, complete cds.
+ACCESSION   U49845
+VERSION     U49845.1  GI:1293613
+KEYWORDS    .
+SOURCE      Saccharomyces cerevisiae (baker's yeast)
+  ORGANISM  Saccharomyces cerevisiae
+            Eukaryota; Fungi; Ascomycota; Saccharomycotina; Saccharomycetes;
+            Saccharomycetales; Saccharomycetaceae; Saccharomyces.
+FEATURES             Location/Qualifiers
+     source          1..20
+                     /organism="Saccharomyces cerevisiae"
+                     /db_xref="taxon:4932"
+                     /chromosome="IX"
+     CDS             complement(join(1..5,
+                     10..20))
+                     /note="a ""quoted"" note that wraps
+                     over two lines"
+                     /translation="MKLLSS
+                     LLL"
+                     /pseudo
+ORIGIN
+        1 gatcctccat atacaacggt
+//
+LOCUS       SECOND       4 bp    DNA             PLN       21-JUN-1999
+ORIGIN
+        1 ACGT
+//
+`
+
+func TestGenBankReader(t *testing.T) {
+	r := sequence.NewGenBankReader(strings.NewReader(genbankRecord))
+
+	rec, err := r.Next()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if rec.Accession != "U49845.1" {
+		t.Errorf("accession = %q", rec.Accession)
+	}
+	if got := deref(rec.Description); got != "Saccharomyces cerevisiae TCP1-beta gene, partial cds, and Axl2p (AXL2) gene, complete cds." {
+		t.Errorf("description = %q", got)
+	}
+	if got := deref(rec.Species); got != "Saccharomyces cerevisiae" {
+		t.Errorf("species = %q", got)
+	}
+	if got := deref(rec.Chromosome); got != "IX" {
+		t.Errorf("chromosome = %q", got)
+	}
+	if bases, err := io.ReadAll(iotest.OneByteReader(r.Bases())); err != nil || string(bases) != "GATCCTCCATATACAACGGT" || rec.StartPos != 1 {
+		t.Errorf("sequence = %q from %d, %v", bases, rec.StartPos, err)
+	}
+
+	if len(rec.Features) != 2 {
+		t.Fatalf("expected 2 features, got %d", len(rec.Features))
+	}
+	cds := rec.Features[1]
+	if cds.Key != "CDS" || cds.Location != "complement(join(1..5,10..20))" {
+		t.Errorf("CDS = %q %q", cds.Key, cds.Location)
+	}
+	want := models.Qualifiers{
+		{Name: "note", Value: `a "quoted" note that wraps over two lines`},
+		{Name: "translation", Value: "MKLLSSLLL"},
+		{Name: "pseudo"},
+	}
+	if len(cds.Qualifiers) != len(want) {
+		t.Fatalf("qualifiers = %+v", cds.Qualifiers)
+	}
+	for i := range want {
+		if cds.Qualifiers[i] != want[i] {
+			t.Errorf("qualifier %d = %+v, want %+v", i, cds.Qualifiers[i], want[i])
+		}
+	}
+
+	// Without ACCESSION the LOCUS name is used.
+	rec, err = r.Next()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rec.Accession != "SECOND" || rec.Description != nil || len(rec.Features) != 0 {
+		t.Errorf("second record = %+v", rec)
+	}
+	if bases, err := io.ReadAll(r.Bases()); err != nil || string(bases) != "ACGT" {
+		t.Errorf("second sequence = %q, %v", bases, err)
+	}
+
+	if _, err := r.Next(); err != io.EOF {
+		t.Errorf("expected io.EOF, got %v", err)
+	}
+}
+
+func TestGenBankReader_Invalid(t *testing.T) {
+	tests := map[string]string{
+		"missing terminator": "LOCUS       X 4 bp\nORIGIN\n        1 acgt\n",
+		"no LOCUS":           ">seq\nACGT\n",
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			r := sequence.NewGenBankReader(strings.NewReader(input))
+			_, err := r.Next()
+			if err == nil {
+				_, err = io.ReadAll(r.Bases())
+			}
+			if !errors.Is(err, sequence.ErrInvalidFlatFile) {
+				t.Errorf("expected ErrInvalidFlatFile, got %v", err)
+			}
+		})
+	}
+}
+
+func TestGenBankReader_SkipsUnreadBases(t *testing.T) {
+	r := sequence.NewGenBankReader(strings.NewReader(genbankRecord))
+	for _, want := range []string{"U49845.1", "SECOND"} {
+		rec, err := r.Next()
+		if err != nil || rec.Accession != want {
+			t.Fatalf("record = %+v, %v; want %s", rec, err, want)
+		}
+	}
+	if _, err := r.Next(); err != io.EOF {
+		t.Errorf("expected io.EOF, got %v", err)
+	}
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
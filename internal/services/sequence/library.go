@@ -0,0 +1,346 @@
+// Package sequence parses annotated sequence files and stores their records.
+package sequence
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Viktor2805/nucount/internal/helpers"
+	"github.com/Viktor2805/nucount/internal/models"
+	scope "github.com/Viktor2805/nucount/internal/repository/scope"
+	repository "github.com/Viktor2805/nucount/internal/repository/sequence"
+	"github.com/Viktor2805/nucount/internal/services/nucleotide"
+
+	"gorm.io/gorm"
+)
+
+// MaxUploadSize bounds the size of an imported file.
+const MaxUploadSize = 2 << 30
+
+// DefaultMaxDecompressedSize bounds how far a compressed import may expand.
+const DefaultMaxDecompressedSize = 8 << 30
+
+// MaxSubsequenceLength bounds the number of bases returned by Subsequence.
+const MaxSubsequenceLength = 16 << 20
+
+var (
+	// ErrUnsupportedFormat is returned when asked to import a format without a
+	// flat-file parser.
+	ErrUnsupportedFormat = errors.New("unsupported sequence file format")
+	// ErrNotFound is returned when a requested sequence is not stored.
+	ErrNotFound = errors.New("sequence not found")
+	// ErrInvalidCursor is returned when listing from a cursor that List did
+	// not return.
+	ErrInvalidCursor = errors.New("invalid cursor")
+	// ErrInvalidRange is returned when asked for a subsequence outside of the
+	// sequence, or a longer one than MaxSubsequenceLength.
+	ErrInvalidRange = errors.New("invalid range")
+	// ErrInvalidMetadata is returned when an update would leave a sequence
+	// without an accession.
+	ErrInvalidMetadata = errors.New("accession must not be empty")
+)
+
+// Service imports, exports and manages stored sequences. A sequence is
+// referred to by ref: its ID, or else its accession.
+type Service interface {
+	Import(ctx context.Context, r io.Reader, format helpers.Format) ([]Imported, error)
+	Export2bit(ctx context.Context, w io.Writer, accessions []string) error
+	// List returns up to limit sequences matching filter, ordered by ID and
+	// without their bases and features, from the cursor of a previous page
+	// ("" for the first). The cursor of the next page is "" on the last.
+	List(filter repository.Filter, limit int, cursor string) ([]models.Sequence, string, error)
+	// Get returns a sequence without its bases, and with its features if
+	// withFeatures is set.
+	Get(ref string, withFeatures bool) (*models.Sequence, error)
+	// Update changes the metadata of a sequence and returns it as Get does.
+	Update(ref string, m Metadata) (*models.Sequence, error)
+	Delete(ref string) error
+	// Subsequence returns a sequence, as Get does, and its bases from start
+	// to end, both included, in the coordinates of the sequence. A start or
+	// end <= 0 is the start or end of the sequence.
+	Subsequence(ref string, start, end int) (*models.Sequence, string, error)
+}
+
+// Metadata is an update of the metadata of a sequence. Nil fields are left
+// as they are; an empty species, chromosome or description is cleared.
+type Metadata struct {
+	Accession   *string
+	Species     *string
+	Chromosome  *string
+	Description *string
+}
+
+// Imported summarizes one stored record.
+type Imported struct {
+	ID          uint
+	Accession   string
+	Species     *string
+	Chromosome  *string
+	Description *string
+	Length      int
+	Features    int
+}
+
+// Library imports GenBank and EMBL files into the sequence repository and
+// exports stored sequences. A file is imported in a single transaction: either
+// every record is stored or none is.
+type Library struct {
+	repo repository.SequenceRepositoryI
+	opts options
+}
+
+func NewLibrary(repo repository.SequenceRepositoryI, opts ...Option) Service {
+	return &Library{repo: repo, opts: newOptions(opts)}
+}
+
+// NewRecordReader returns the flat-file parser of format.
+func NewRecordReader(r io.Reader, format helpers.Format) (RecordReader, error) {
+	switch format {
+	case helpers.FormatGenBank:
+		return NewGenBankReader(r), nil
+	case helpers.FormatEMBL:
+		return NewEMBLReader(r), nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedFormat, format)
+	}
+}
+
+// Import parses r and stores each record with its features. The bases of a
+// record are passed from the parser to the repository as they are read, so
+// only a chunk of them is held in memory. The import is rolled back if ctx is
+// cancelled before it completes.
+func (s *Library) Import(ctx context.Context, r io.Reader, format helpers.Format) ([]Imported, error) {
+	dec, err := s.opts.open(r)
+	if err != nil {
+		return nil, err
+	}
+	defer dec.Close()
+
+	records, err := NewRecordReader(dec, format)
+	if err != nil {
+		return nil, err
+	}
+
+	var imported []Imported
+	err = s.repo.Transaction(func(repo repository.SequenceRepositoryI) error {
+		for {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			rec, err := records.Next()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if err := repo.Create(rec, records.Bases()); err != nil {
+				return fmt.Errorf("store %s: %w", rec.Accession, err)
+			}
+			imported = append(imported, Imported{
+				ID:          rec.ID,
+				Accession:   rec.Accession,
+				Species:     rec.Species,
+				Chromosome:  rec.Chromosome,
+				Description: rec.Description,
+				Length:      rec.Length,
+				Features:    len(rec.Features),
+			})
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return imported, nil
+}
+
+// Export2bit writes the stored sequences with the given accessions, or every
+// stored sequence when none are given, to w as a UCSC 2bit file named by
+// accession. The bases are streamed from the repository a sequence at a time;
+// the export stops before the next sequence once ctx is cancelled.
+func (s *Library) Export2bit(ctx context.Context, w io.Writer, accessions []string) error {
+	stored, err := s.repo.FindByAccessions(accessions)
+	if err != nil {
+		return err
+	}
+
+	if missing := missingAccessions(accessions, stored); len(missing) > 0 {
+		return fmt.Errorf("%w: %s", ErrNotFound, strings.Join(missing, ", "))
+	}
+
+	seqs := make([]nucleotide.TwoBitSource, len(stored))
+	for i := range stored {
+		rec := &stored[i]
+		seqs[i] = nucleotide.TwoBitSource{Name: rec.Accession, Open: func() (io.Reader, error) {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			return s.repo.Bases(ctx, rec), nil
+		}}
+	}
+	return nucleotide.WriteTwoBitFrom(w, seqs)
+}
+
+func (s *Library) List(filter repository.Filter, limit int, cursor string) ([]models.Sequence, string, error) {
+	if cursor != "" {
+		after, err := decodeCursor(cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		filter.AfterID = after
+	}
+
+	// One more than the page tells whether there is a next page.
+	sequences, err := s.repo.List(filter, scope.CursorPage(limit+1))
+	if err != nil {
+		return nil, "", err
+	}
+	if len(sequences) <= limit {
+		return sequences, "", nil
+	}
+	sequences = sequences[:limit]
+	return sequences, encodeCursor(sequences[limit-1].ID), nil
+}
+
+// encodeCursor returns the cursor of the page after the sequence id.
+func encodeCursor(id uint) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatUint(uint64(id), 10)))
+}
+
+func decodeCursor(cursor string) (uint, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, ErrInvalidCursor
+	}
+	id, err := strconv.ParseUint(string(b), 10, 0)
+	if err != nil {
+		return 0, ErrInvalidCursor
+	}
+	return uint(id), nil
+}
+
+func (s *Library) Get(ref string, withFeatures bool) (*models.Sequence, error) {
+	seq, err := s.find(ref)
+	if err != nil || !withFeatures {
+		return seq, err
+	}
+	if seq.Features, err = s.repo.Features(seq.ID); err != nil {
+		return nil, err
+	}
+	return seq, nil
+}
+
+// find looks up a sequence by reference.
+func (s *Library) find(ref string) (*models.Sequence, error) {
+	var (
+		seq *models.Sequence
+		err error
+	)
+	if id, perr := strconv.ParseUint(ref, 10, 0); perr == nil {
+		seq, err = s.repo.FindByID(uint(id))
+	} else {
+		seq, err = s.repo.FindByAccession(ref)
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	return seq, err
+}
+
+func (s *Library) Update(ref string, m Metadata) (*models.Sequence, error) {
+	if m.Accession != nil && strings.TrimSpace(*m.Accession) == "" {
+		return nil, ErrInvalidMetadata
+	}
+
+	seq, err := s.find(ref)
+	if err != nil {
+		return nil, err
+	}
+	if m.Accession != nil {
+		seq.Accession = strings.TrimSpace(*m.Accession)
+	}
+	setOptional(&seq.Species, m.Species)
+	setOptional(&seq.Chromosome, m.Chromosome)
+	setOptional(&seq.Description, m.Description)
+	seq.UpdatedAt = time.Now()
+
+	if err := s.repo.Update(seq); err != nil {
+		return nil, err
+	}
+	return seq, nil
+}
+
+// setOptional applies the update v of a nullable field; "" clears it.
+func setOptional(field **string, v *string) {
+	switch {
+	case v == nil:
+	case *v == "":
+		*field = nil
+	default:
+		*field = v
+	}
+}
+
+func (s *Library) Delete(ref string) error {
+	seq, err := s.find(ref)
+	if err != nil {
+		return err
+	}
+	ok, err := s.repo.Delete(seq.ID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *Library) Subsequence(ref string, start, end int) (*models.Sequence, string, error) {
+	seq, err := s.find(ref)
+	if err != nil {
+		return nil, "", err
+	}
+	if start <= 0 {
+		start = seq.StartPos
+	}
+	if end <= 0 {
+		end = seq.EndPos
+	}
+	if start < seq.StartPos || end < start || end > seq.EndPos {
+		return nil, "", fmt.Errorf("%w: %d-%d is not within %d-%d", ErrInvalidRange, start, end, seq.StartPos, seq.EndPos)
+	}
+	if end-start+1 > MaxSubsequenceLength {
+		return nil, "", fmt.Errorf("%w: longer than %d bases", ErrInvalidRange, MaxSubsequenceLength)
+	}
+
+	bases, err := s.repo.Subsequence(seq, start-seq.StartPos, end-seq.StartPos+1)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, "", ErrNotFound
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	return seq, bases, nil
+}
+
+func missingAccessions(requested []string, stored []models.Sequence) []string {
+	found := make(map[string]bool, len(stored))
+	for _, rec := range stored {
+		found[rec.Accession] = true
+	}
+
+	var missing []string
+	for _, acc := range requested {
+		if !found[acc] {
+			missing = append(missing, acc)
+		}
+	}
+	return missing
+}
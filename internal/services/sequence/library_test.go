@@ -0,0 +1,385 @@
+package sequence_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/Viktor2805/nucount/internal/helpers"
+	"github.com/Viktor2805/nucount/internal/models"
+	repository "github.com/Viktor2805/nucount/internal/repository/sequence"
+	"github.com/Viktor2805/nucount/internal/services/nucleotide"
+	"github.com/Viktor2805/nucount/internal/services/sequence"
+
+	"gorm.io/gorm"
+)
+
+type nopCloser struct{ *bytes.Reader }
+
+func (n nopCloser) Close() error { return nil }
+
+func asMultipartFile(s string) nopCloser {
+	return nopCloser{bytes.NewReader([]byte(s))}
+}
+
+// memoryRepo keeps the records of committed transactions only.
+type memoryRepo struct {
+	stored  []*models.Sequence
+	pending []*models.Sequence
+}
+
+func (m *memoryRepo) Create(s *models.Sequence, bases io.Reader) error {
+	b, err := io.ReadAll(bases)
+	if err != nil {
+		return err
+	}
+	s.Sequence, s.Length, s.EndPos = string(b), len(b), s.StartPos+len(b)-1
+	s.ID = uint(len(m.stored) + len(m.pending) + 1)
+	m.pending = append(m.pending, s)
+	return nil
+}
+
+func (m *memoryRepo) FindByAccessions(accessions []string) ([]models.Sequence, error) {
+	var found []models.Sequence
+	for _, s := range m.stored {
+		if len(accessions) == 0 || slices.Contains(accessions, s.Accession) {
+			found = append(found, metadata(s))
+		}
+	}
+	return found, nil
+}
+
+// List ignores scopes: the Library must cut the page itself.
+func (m *memoryRepo) List(filter repository.Filter, _ ...func(*gorm.DB) *gorm.DB) ([]models.Sequence, error) {
+	var found []models.Sequence
+	for _, s := range m.stored {
+		if s.ID <= filter.AfterID ||
+			filter.Species != "" && (s.Species == nil || !strings.EqualFold(*s.Species, filter.Species)) ||
+			filter.Chromosome != "" && (s.Chromosome == nil || *s.Chromosome != filter.Chromosome) ||
+			!strings.HasPrefix(s.Accession, filter.AccessionPrefix) {
+			continue
+		}
+		found = append(found, metadata(s))
+	}
+	return found, nil
+}
+
+func (m *memoryRepo) FindByID(id uint) (*models.Sequence, error) {
+	return m.find(func(s *models.Sequence) bool { return s.ID == id })
+}
+
+func (m *memoryRepo) FindByAccession(accession string) (*models.Sequence, error) {
+	return m.find(func(s *models.Sequence) bool { return s.Accession == accession })
+}
+
+func (m *memoryRepo) find(match func(*models.Sequence) bool) (*models.Sequence, error) {
+	for _, s := range m.stored {
+		if match(s) {
+			found := metadata(s)
+			return &found, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+// metadata returns s as loaded without its bases and features.
+func metadata(s *models.Sequence) models.Sequence {
+	found := *s
+	found.Length = len(found.Sequence)
+	found.EndPos = found.StartPos + found.Length - 1
+	found.Sequence, found.Features = "", nil
+	return found
+}
+
+func (m *memoryRepo) Features(sequenceID uint) ([]models.Feature, error) {
+	for _, s := range m.stored {
+		if s.ID == sequenceID {
+			return s.Features, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *memoryRepo) Update(seq *models.Sequence) error {
+	for _, s := range m.stored {
+		if s.ID == seq.ID {
+			s.Accession, s.Species, s.Chromosome, s.Description = seq.Accession, seq.Species, seq.Chromosome, seq.Description
+			s.UpdatedAt = seq.UpdatedAt
+		}
+	}
+	return nil
+}
+
+func (m *memoryRepo) Delete(id uint) (bool, error) {
+	for i, s := range m.stored {
+		if s.ID == id {
+			m.stored = slices.Delete(m.stored, i, i+1)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *memoryRepo) Subsequence(seq *models.Sequence, start, end int) (string, error) {
+	for _, s := range m.stored {
+		if s.ID == seq.ID {
+			return s.Sequence[start:end], nil
+		}
+	}
+	return "", gorm.ErrRecordNotFound
+}
+
+func (m *memoryRepo) Bases(_ context.Context, seq *models.Sequence) io.Reader {
+	for _, s := range m.stored {
+		if s.ID == seq.ID {
+			return strings.NewReader(s.Sequence)
+		}
+	}
+	return iotest.ErrReader(gorm.ErrRecordNotFound)
+}
+
+func (m *memoryRepo) Transaction(fn func(repo repository.SequenceRepositoryI) error) error {
+	m.pending = nil
+	if err := fn(m); err != nil {
+		m.pending = nil
+		return err
+	}
+	m.stored = append(m.stored, m.pending...)
+	m.pending = nil
+	return nil
+}
+
+func TestLibrary_Import(t *testing.T) {
+	repo := &memoryRepo{}
+	imp := sequence.NewLibrary(repo)
+
+	got, err := imp.Import(context.Background(), asMultipartFile(genbankRecord), helpers.FormatGenBank)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(got) != 2 || len(repo.stored) != 2 {
+		t.Fatalf("expected 2 imported records, got %d (stored %d)", len(got), len(repo.stored))
+	}
+	if got[0].ID != 1 || got[0].Accession != "U49845.1" || got[0].Length != 20 || got[0].Features != 2 {
+		t.Errorf("first record = %+v", got[0])
+	}
+	if got[1].Accession != "SECOND" || got[1].Length != 4 {
+		t.Errorf("second record = %+v", got[1])
+	}
+}
+
+func TestLibrary_ImportIsAtomic(t *testing.T) {
+	repo := &memoryRepo{}
+	imp := sequence.NewLibrary(repo)
+
+	// The second record lacks its terminator.
+	input := emblRecord + strings.TrimSuffix(emblRecord, "//\n")
+	_, err := imp.Import(context.Background(), asMultipartFile(input), helpers.FormatEMBL)
+	if !errors.Is(err, sequence.ErrInvalidFlatFile) {
+		t.Fatalf("expected ErrInvalidFlatFile, got %v", err)
+	}
+	if len(repo.stored) != 0 {
+		t.Errorf("expected nothing stored, got %d records", len(repo.stored))
+	}
+}
+
+func TestLibrary_UnsupportedFormat(t *testing.T) {
+	_, err := sequence.NewLibrary(&memoryRepo{}).Import(context.Background(), asMultipartFile(">a\nACGT\n"), helpers.FormatFASTA)
+	if !errors.Is(err, sequence.ErrUnsupportedFormat) {
+		t.Errorf("expected ErrUnsupportedFormat, got %v", err)
+	}
+}
+
+func TestLibrary_Export2bit(t *testing.T) {
+	repo := &memoryRepo{stored: []*models.Sequence{
+		{ID: 1, Accession: "A.1", Sequence: "ACGTnnACGT"},
+		{ID: 2, Accession: "B.1", Sequence: "GGCC"},
+		{ID: 3, Accession: "C.1", Sequence: "TTaa"},
+	}}
+	lib := sequence.NewLibrary(repo)
+
+	var buf bytes.Buffer
+	if err := lib.Export2bit(context.Background(), &buf, []string{"C.1", "A.1"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	r, err := nucleotide.NewTwoBitReader(&buf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, want := range []models.Sequence{{Accession: "A.1", Sequence: "ACGTnnACGT"}, {Accession: "C.1", Sequence: "TTaa"}} {
+		rec, err := r.Next()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		bases, err := r.Sequence()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if rec.Name != want.Accession || string(bases) != want.Sequence {
+			t.Errorf("record %s = %q, want %s = %q", rec.Name, bases, want.Accession, want.Sequence)
+		}
+	}
+
+	err = lib.Export2bit(context.Background(), &bytes.Buffer{}, []string{"A.1", "missing"})
+	if !errors.Is(err, sequence.ErrNotFound) || !strings.Contains(err.Error(), "missing") {
+		t.Errorf("expected ErrNotFound naming the accession, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := lib.Export2bit(ctx, &bytes.Buffer{}, nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("cancelled export: err = %v", err)
+	}
+}
+
+func ptr(s string) *string { return &s }
+
+func storedLibrary() (*memoryRepo, sequence.Service) {
+	repo := &memoryRepo{stored: []*models.Sequence{
+		{ID: 1, Accession: "CM034354.1", Species: ptr("Mytilus edulis"), Chromosome: ptr("1"), Sequence: "ACGTACGTAC", StartPos: 1},
+		{ID: 2, Accession: "CM034355.1", Species: ptr("Mytilus edulis"), Chromosome: ptr("2"), Sequence: "GGCC", StartPos: 1},
+		{ID: 3, Accession: "U49845.1", Species: ptr("Saccharomyces cerevisiae"), Sequence: "TTTTAAAA", StartPos: 101,
+			Features: []models.Feature{{ID: 7, SequenceID: 3, Key: "gene", Location: "101..108"}}},
+		{ID: 5, Accession: "CM034360.1", Species: ptr("Mytilus edulis"), Chromosome: ptr("1"), Sequence: "A", StartPos: 1},
+	}}
+	return repo, sequence.NewLibrary(repo)
+}
+
+func TestLibrary_List(t *testing.T) {
+	_, lib := storedLibrary()
+	filter := repository.Filter{Species: "mytilus EDULIS", AccessionPrefix: "CM0343"}
+
+	var ids []uint
+	cursor, pages := "", 0
+	for {
+		page, next, err := lib.List(filter, 2, cursor)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		for _, s := range page {
+			ids = append(ids, s.ID)
+		}
+		pages++
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+	if !slices.Equal(ids, []uint{1, 2, 5}) || pages != 2 {
+		t.Errorf("listed %v in %d pages, want [1 2 5] in 2", ids, pages)
+	}
+
+	page, next, err := lib.List(repository.Filter{Chromosome: "1"}, 2, "")
+	if err != nil || len(page) != 2 || next != "" {
+		t.Errorf("a full last page: got %d sequences, next %q, error %v", len(page), next, err)
+	}
+
+	if _, _, err := lib.List(filter, 2, "not a cursor"); !errors.Is(err, sequence.ErrInvalidCursor) {
+		t.Errorf("expected ErrInvalidCursor, got %v", err)
+	}
+}
+
+func TestLibrary_Get(t *testing.T) {
+	_, lib := storedLibrary()
+
+	byID, err := lib.Get("3", false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if byID.Accession != "U49845.1" || byID.EndPos != 108 || byID.Sequence != "" || byID.Features != nil {
+		t.Errorf("by ID = %+v", byID)
+	}
+
+	byAccession, err := lib.Get("U49845.1", true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if byAccession.ID != 3 || len(byAccession.Features) != 1 {
+		t.Errorf("by accession = %+v", byAccession)
+	}
+
+	for _, ref := range []string{"4", "X00000.1"} {
+		if _, err := lib.Get(ref, false); !errors.Is(err, sequence.ErrNotFound) {
+			t.Errorf("Get(%q): expected ErrNotFound, got %v", ref, err)
+		}
+	}
+}
+
+func TestLibrary_Update(t *testing.T) {
+	repo, lib := storedLibrary()
+
+	got, err := lib.Update("CM034354.1", sequence.Metadata{
+		Accession:   ptr(" CM034354.2 "),
+		Chromosome:  ptr(""),
+		Description: ptr("chromosome 1"),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	stored := repo.stored[0]
+	if got.Accession != "CM034354.2" || stored.Accession != "CM034354.2" {
+		t.Errorf("accession = %q, stored %q", got.Accession, stored.Accession)
+	}
+	if stored.Chromosome != nil || stored.Species == nil || *stored.Species != "Mytilus edulis" {
+		t.Errorf("chromosome = %v, species = %v: expected cleared and unchanged", stored.Chromosome, stored.Species)
+	}
+	if stored.Description == nil || *stored.Description != "chromosome 1" || stored.UpdatedAt.IsZero() {
+		t.Errorf("description = %v, updated at %v", stored.Description, stored.UpdatedAt)
+	}
+
+	if _, err := lib.Update("1", sequence.Metadata{Accession: ptr(" ")}); !errors.Is(err, sequence.ErrInvalidMetadata) {
+		t.Errorf("expected ErrInvalidMetadata, got %v", err)
+	}
+}
+
+func TestLibrary_Delete(t *testing.T) {
+	repo, lib := storedLibrary()
+
+	if err := lib.Delete("CM034355.1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(repo.stored) != 3 {
+		t.Errorf("expected 3 sequences left, got %d", len(repo.stored))
+	}
+	if err := lib.Delete("2"); !errors.Is(err, sequence.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestLibrary_Subsequence(t *testing.T) {
+	_, lib := storedLibrary()
+
+	tests := []struct {
+		ref        string
+		start, end int
+		want       string
+	}{
+		{"1", 2, 5, "CGTA"},
+		{"1", 10, 10, "C"},
+		{"1", 0, 0, "ACGTACGTAC"},
+		{"1", 9, 0, "AC"},
+		{"U49845.1", 105, 0, "AAAA"}, // starts at position 101
+	}
+	for _, tt := range tests {
+		_, got, err := lib.Subsequence(tt.ref, tt.start, tt.end)
+		if err != nil || got != tt.want {
+			t.Errorf("Subsequence(%s, %d, %d) = %q, %v; want %q", tt.ref, tt.start, tt.end, got, err, tt.want)
+		}
+	}
+
+	for _, r := range [][2]int{{5, 4}, {1, 11}, {11, 0}} {
+		if _, _, err := lib.Subsequence("1", r[0], r[1]); !errors.Is(err, sequence.ErrInvalidRange) {
+			t.Errorf("Subsequence(1, %d, %d): expected ErrInvalidRange, got %v", r[0], r[1], err)
+		}
+	}
+	if _, _, err := lib.Subsequence("U49845.1", 1, 0); !errors.Is(err, sequence.ErrInvalidRange) {
+		t.Errorf("before the start position: expected ErrInvalidRange, got %v", err)
+	}
+}
@@ -0,0 +1,36 @@
+package sequence
+
+import (
+	"io"
+
+	"github.com/Viktor2805/nucount/internal/helpers"
+)
+
+// Option configures the Library.
+type Option func(*options)
+
+type options struct {
+	maxDecompressedSize int64
+}
+
+func newOptions(opts []Option) options {
+	o := options{maxDecompressedSize: DefaultMaxDecompressedSize}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// WithMaxDecompressedSize sets the number of bytes a compressed upload may
+// expand to. A value <= 0 disables the limit.
+func WithMaxDecompressedSize(n int64) Option {
+	return func(o *options) {
+		o.maxDecompressedSize = n
+	}
+}
+
+// open returns a reader of the decompressed content of r.
+func (o options) open(r io.Reader) (io.ReadCloser, error) {
+	dec, _, err := helpers.Decompress(r, o.maxDecompressedSize)
+	return dec, err
+}
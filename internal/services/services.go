@@ -2,18 +2,53 @@
 package services
 
 import (
-	"golang/internal/repository"
-	nucleotide "golang/internal/services/nucleotide"
+	"github.com/Viktor2805/nucount/internal/config"
+	"github.com/Viktor2805/nucount/internal/logger"
+	"github.com/Viktor2805/nucount/internal/repository"
+	"github.com/Viktor2805/nucount/internal/services/analysis"
+	"github.com/Viktor2805/nucount/internal/services/job"
+	nucleotide "github.com/Viktor2805/nucount/internal/services/nucleotide"
+	"github.com/Viktor2805/nucount/internal/services/sequence"
+	"github.com/Viktor2805/nucount/internal/services/webhook"
+
+	"go.uber.org/zap"
 )
 
 // Services interface
 type Services struct {
-	NucleotideService nucleotide.Service
+	NucleotideService   nucleotide.Service
+	KmerService         nucleotide.KmerService
+	DinucleotideService nucleotide.DinucleotideService
+	SequenceService     sequence.Service
+	JobService          job.Service
+	WebhookService      webhook.Service
+	AnalysisService     analysis.Service
 }
 
 // NewServices initializes and returns a new Services instance with all required components.
-func NewServices(repos *repository.Repositories) *Services {
+func NewServices(repos *repository.Repositories, cfg *config.Config) *Services {
+	opts := []nucleotide.Option{
+		nucleotide.WithMaxDecompressedSize(cfg.Upload.MaxDecompressedSize),
+		nucleotide.WithWorkers(cfg.Upload.CountWorkers),
+	}
+
 	return &Services{
-		NucleotideService: nucleotide.NewCounter(),
+		NucleotideService:   nucleotide.NewCounter(opts...),
+		KmerService:         nucleotide.NewKmerCounter(opts...),
+		DinucleotideService: nucleotide.NewDinucleotideAnalyzer(opts...),
+		SequenceService: sequence.NewLibrary(
+			repos.SequenceRepo,
+			sequence.WithMaxDecompressedSize(cfg.Upload.MaxDecompressedSize),
+		),
+		JobService: job.NewQueue(repos.JobRepo, job.WithPollInterval(cfg.Jobs.PollInterval)),
+		WebhookService: webhook.NewHooks(
+			repos.WebhookRepo,
+			cfg.Webhooks.Secrets,
+			webhook.WithAllowedNetworks(cfg.Webhooks.AllowedNetworks...),
+		),
+		AnalysisService: analysis.NewHistory(
+			repos.AnalysisRepo,
+			analysis.WithLogger(logger.L().With(zap.String("component", "analyses"))),
+		),
 	}
 }
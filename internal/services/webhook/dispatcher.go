@@ -0,0 +1,161 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Viktor2805/nucount/internal/models"
+	repository "github.com/Viktor2805/nucount/internal/repository/webhook"
+
+	"go.uber.org/zap"
+)
+
+// maxResponse is how much of a callback's response body is read, so that
+// the connection can be reused. It is not kept.
+const maxResponse = 1024
+
+// Dispatcher sends due deliveries. Every replica runs a Dispatcher; the
+// repository makes sure that each delivery is sent by one of them at a time.
+type Dispatcher struct {
+	repo    repository.WebhookRepositoryI
+	secrets map[string]string
+	client  *http.Client
+	opts    options
+}
+
+// NewDispatcher returns a Dispatcher signing deliveries with secrets, which
+// maps client names to their secrets.
+func NewDispatcher(repo repository.WebhookRepositoryI, secrets map[string]string, opts ...Option) *Dispatcher {
+	o := newOptions(opts)
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// Callbacks are dialled directly: through a proxy, the dialer would only
+	// see the proxy's address.
+	transport.Proxy = nil
+	transport.DialContext = (&net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control:   dialControl(o.allowed),
+	}).DialContext
+
+	return &Dispatcher{
+		repo:    repo,
+		secrets: secrets,
+		client: &http.Client{
+			Transport: transport,
+			// A redirect counts as a failure rather than being followed with
+			// a GET that drops the payload.
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		opts: o,
+	}
+}
+
+// Run sends deliveries until ctx is done. An attempt interrupted this way is
+// counted, and the delivery is retried once its claim expires.
+func (d *Dispatcher) Run(ctx context.Context) {
+	for ctx.Err() == nil {
+		delivery, err := d.repo.Claim(d.opts.lease())
+		if err != nil {
+			d.opts.logger.Error("claim webhook delivery failed", zap.Error(err))
+		}
+		if delivery != nil {
+			d.deliver(ctx, delivery)
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(d.opts.pollInterval):
+		}
+	}
+}
+
+// deliver sends a claimed delivery and records the attempt.
+func (d *Dispatcher) deliver(ctx context.Context, delivery *models.WebhookDelivery) {
+	log := d.opts.logger.With(
+		zap.Stringer("delivery", delivery.ID),
+		zap.Stringer("job", delivery.JobID),
+		zap.Int("attempt", delivery.Attempts),
+	)
+
+	attempt := &models.WebhookAttempt{Attempt: delivery.Attempts}
+	start := time.Now()
+	sendErr := d.send(ctx, delivery, attempt)
+	attempt.DurationMs = time.Since(start).Milliseconds()
+	if sendErr != nil && ctx.Err() != nil {
+		return
+	}
+
+	now := time.Now()
+	delivery.NextAttemptAt = now
+	switch {
+	case sendErr == nil:
+		delivery.Status = models.DeliveryDelivered
+		delivery.DeliveredAt = &now
+	case delivery.Attempts >= d.opts.maxAttempts, errors.Is(sendErr, ErrForbiddenAddress):
+		delivery.Status = models.DeliveryFailed
+	default:
+		delivery.NextAttemptAt = now.Add(d.opts.retryDelay(delivery.Attempts))
+	}
+	if sendErr != nil {
+		attempt.Error = sendErr.Error()
+	}
+
+	ok, err := d.repo.Record(delivery, attempt)
+	switch {
+	case err != nil:
+		log.Error("store webhook attempt failed", zap.Error(err))
+	case !ok:
+		log.Warn("webhook delivery replayed or claimed by another dispatcher")
+	case sendErr != nil:
+		log.Warn("webhook delivery failed", zap.String("status", string(delivery.Status)), zap.Error(sendErr))
+	default:
+		log.Info("webhook delivered", zap.Int("status_code", attempt.StatusCode))
+	}
+}
+
+// send POSTs the signed payload of delivery and fills in the status code of
+// attempt. It fails unless the callback responds with a 2xx status.
+func (d *Dispatcher) send(ctx context.Context, delivery *models.WebhookDelivery, attempt *models.WebhookAttempt) error {
+	secret, ok := d.secrets[delivery.Client]
+	if !ok {
+		return fmt.Errorf("%w %q", ErrUnknownClient, delivery.Client)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, d.opts.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return err
+	}
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, delivery.Event)
+	req.Header.Set(HeaderDelivery, delivery.ID.String())
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(secret, timestamp, delivery.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxResponse))
+	attempt.StatusCode = resp.StatusCode
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("callback responded with %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,335 @@
+package webhook_test
+
+import (
+	"crypto/hmac"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Viktor2805/nucount/internal/models"
+	"github.com/Viktor2805/nucount/internal/services/webhook"
+	"github.com/Viktor2805/nucount/internal/testutil"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// memoryRepo keeps deliveries in memory. Claims ignore leases: a claimed
+// delivery is due again only once its attempt is recorded.
+type memoryRepo struct {
+	mu         sync.Mutex
+	deliveries []*models.WebhookDelivery
+	claimed    map[uuid.UUID]bool
+	delays     []time.Duration // retry delays, as recorded
+}
+
+func newMemoryRepo() *memoryRepo {
+	return &memoryRepo{claimed: map[uuid.UUID]bool{}}
+}
+
+// add stores the delivery of a finished job with a callback to url.
+func (m *memoryRepo) add(t *testing.T, url string) *models.WebhookDelivery {
+	t.Helper()
+
+	now := time.Now()
+	d, err := webhook.NewDelivery(&models.Job{
+		ID:             uuid.New(),
+		Analysis:       "count",
+		Status:         models.JobSucceeded,
+		Result:         models.JSON(`{"A":1}`),
+		FinishedAt:     &now,
+		CallbackURL:    url,
+		CallbackClient: "orchestrator",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.deliveries = append(m.deliveries, d)
+	return d
+}
+
+func (m *memoryRepo) FindByID(id uuid.UUID) (*models.WebhookDelivery, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, d := range m.deliveries {
+		if d.ID == id {
+			c := *d
+			c.History = append([]models.WebhookAttempt(nil), d.History...)
+			return &c, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (m *memoryRepo) FindByJob(jobID uuid.UUID) ([]models.WebhookDelivery, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var found []models.WebhookDelivery
+	for _, d := range m.deliveries {
+		if d.JobID == jobID {
+			found = append(found, *d)
+		}
+	}
+	return found, nil
+}
+
+func (m *memoryRepo) Claim(time.Duration) (*models.WebhookDelivery, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, d := range m.deliveries {
+		if d.Status == models.DeliveryPending && !d.NextAttemptAt.After(time.Now()) && !m.claimed[d.ID] {
+			m.claimed[d.ID] = true
+			d.Attempts++
+			c := *d
+			c.History = nil
+			return &c, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *memoryRepo) Record(d *models.WebhookDelivery, a *models.WebhookAttempt) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, s := range m.deliveries {
+		if s.ID == d.ID && s.Attempts == d.Attempts && s.Status == models.DeliveryPending {
+			delete(m.claimed, d.ID)
+			if d.Status == models.DeliveryPending {
+				m.delays = append(m.delays, time.Until(d.NextAttemptAt))
+			}
+			s.Status, s.NextAttemptAt, s.DeliveredAt = d.Status, d.NextAttemptAt, d.DeliveredAt
+			a.DeliveryID = d.ID
+			s.History = append(s.History, *a)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *memoryRepo) Replay(id uuid.UUID) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, d := range m.deliveries {
+		if d.ID == id && d.Status != models.DeliveryPending {
+			d.Status, d.Attempts, d.NextAttemptAt, d.DeliveredAt = models.DeliveryPending, 0, time.Now(), nil
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// runUntil runs d until cond holds for the stored delivery or the test times
+// out.
+func runUntil(t *testing.T, d *webhook.Dispatcher, repo *memoryRepo, id uuid.UUID, cond func(*models.WebhookDelivery) bool) *models.WebhookDelivery {
+	t.Helper()
+	return testutil.RunUntil(t, d.Run, func() (*models.WebhookDelivery, error) { return repo.FindByID(id) }, cond)
+}
+
+func settled(d *models.WebhookDelivery) bool {
+	return d.Status != models.DeliveryPending
+}
+
+var secrets = map[string]string{"orchestrator": "shh"}
+
+// loopback allows callbacks to the test servers.
+var loopback = webhook.WithAllowedNetworks(netip.MustParsePrefix("127.0.0.0/8"), netip.MustParsePrefix("::1/128"))
+
+func TestDispatcher_Delivers(t *testing.T) {
+	type request struct {
+		header http.Header
+		body   []byte
+	}
+	received := make(chan request, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- request{header: r.Header, body: body}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	repo := newMemoryRepo()
+	submitted := repo.add(t, srv.URL+"/hooks")
+	d := webhook.NewDispatcher(repo, secrets, loopback, webhook.WithPollInterval(time.Millisecond))
+
+	delivery := runUntil(t, d, repo, submitted.ID, settled)
+	if delivery.Status != models.DeliveryDelivered || delivery.DeliveredAt == nil || delivery.Attempts != 1 {
+		t.Errorf("delivery = %+v", delivery)
+	}
+	if len(delivery.History) != 1 || delivery.History[0].StatusCode != http.StatusNoContent || delivery.History[0].Error != "" {
+		t.Errorf("history = %+v", delivery.History)
+	}
+
+	req := <-received
+	timestamp, err := strconv.ParseInt(req.header.Get(webhook.HeaderTimestamp), 10, 64)
+	if err != nil {
+		t.Fatalf("invalid timestamp header: %v", err)
+	}
+	// Verify the request the way a receiver would.
+	want := webhook.Sign("shh", timestamp, req.body)
+	if !hmac.Equal([]byte(req.header.Get(webhook.HeaderSignature)), []byte(want)) {
+		t.Errorf("signature = %s, want %s", req.header.Get(webhook.HeaderSignature), want)
+	}
+	if req.header.Get(webhook.HeaderEvent) != webhook.EventJobSucceeded ||
+		req.header.Get(webhook.HeaderDelivery) != submitted.ID.String() ||
+		req.header.Get("Content-Type") != "application/json" {
+		t.Errorf("headers = %v", req.header)
+	}
+
+	var p webhook.Payload
+	if err := json.Unmarshal(req.body, &p); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if p.JobID != submitted.JobID || p.Status != models.JobSucceeded || string(p.Result) != `{"A":1}` {
+		t.Errorf("payload = %+v", p)
+	}
+}
+
+func TestDispatcher_RetriesWithBackoff(t *testing.T) {
+	var mu sync.Mutex
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		if calls <= 3 {
+			http.Error(w, "busy", http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	repo := newMemoryRepo()
+	submitted := repo.add(t, srv.URL)
+	d := webhook.NewDispatcher(repo, secrets,
+		loopback,
+		webhook.WithPollInterval(time.Millisecond),
+		webhook.WithBackoff(20*time.Millisecond, 50*time.Millisecond),
+	)
+
+	delivery := runUntil(t, d, repo, submitted.ID, settled)
+	if delivery.Status != models.DeliveryDelivered || delivery.Attempts != 4 {
+		t.Fatalf("delivery = %+v", delivery)
+	}
+	for i, a := range delivery.History {
+		wantCode := http.StatusServiceUnavailable
+		if i == 3 {
+			wantCode = http.StatusOK
+		}
+		if a.Attempt != i+1 || a.StatusCode != wantCode || (wantCode != http.StatusOK) != (a.Error != "") {
+			t.Errorf("attempt %d = %+v", i+1, a)
+		}
+	}
+	// The backoff doubles after every failure, up to the maximum.
+	want := []time.Duration{20 * time.Millisecond, 40 * time.Millisecond, 50 * time.Millisecond}
+	if len(repo.delays) != len(want) {
+		t.Fatalf("got %d retries, want %d", len(repo.delays), len(want))
+	}
+	for i, delay := range repo.delays {
+		if delay > want[i] || delay < want[i]/2 {
+			t.Errorf("retry %d after %v, want %v", i+1, delay, want[i])
+		}
+	}
+}
+
+func TestDispatcher_GivesUpAndReplays(t *testing.T) {
+	var mu sync.Mutex
+	status := http.StatusInternalServerError
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	repo := newMemoryRepo()
+	submitted := repo.add(t, srv.URL)
+	d := webhook.NewDispatcher(repo, secrets,
+		loopback,
+		webhook.WithPollInterval(time.Millisecond),
+		webhook.WithBackoff(time.Millisecond, time.Millisecond),
+		webhook.WithMaxAttempts(3),
+	)
+
+	delivery := runUntil(t, d, repo, submitted.ID, settled)
+	if delivery.Status != models.DeliveryFailed || delivery.Attempts != 3 || len(delivery.History) != 3 {
+		t.Fatalf("delivery = %+v", delivery)
+	}
+
+	mu.Lock()
+	status = http.StatusOK
+	mu.Unlock()
+	if _, err := webhook.NewHooks(repo, secrets).Replay(submitted.ID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	delivery = runUntil(t, d, repo, submitted.ID, settled)
+	if delivery.Status != models.DeliveryDelivered || delivery.Attempts != 1 || len(delivery.History) != 4 {
+		t.Errorf("replayed delivery = %+v", delivery)
+	}
+}
+
+func TestDispatcher_RedirectFails(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Errorf("redirect followed with %s", r.Method)
+		}
+		http.Redirect(w, r, "/elsewhere", http.StatusFound)
+	}))
+	defer srv.Close()
+
+	repo := newMemoryRepo()
+	submitted := repo.add(t, srv.URL)
+	d := webhook.NewDispatcher(repo, secrets, loopback, webhook.WithPollInterval(time.Millisecond), webhook.WithMaxAttempts(1))
+
+	delivery := runUntil(t, d, repo, submitted.ID, settled)
+	if delivery.Status != models.DeliveryFailed || delivery.History[0].StatusCode != http.StatusFound {
+		t.Errorf("delivery = %+v", delivery)
+	}
+}
+
+func TestDispatcher_UnknownClient(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("delivery sent without a secret")
+	}))
+	defer srv.Close()
+
+	repo := newMemoryRepo()
+	submitted := repo.add(t, srv.URL)
+	d := webhook.NewDispatcher(repo, nil, loopback, webhook.WithPollInterval(time.Millisecond), webhook.WithMaxAttempts(1))
+
+	delivery := runUntil(t, d, repo, submitted.ID, settled)
+	if delivery.Status != models.DeliveryFailed || delivery.History[0].StatusCode != 0 || delivery.History[0].Error == "" {
+		t.Errorf("delivery = %+v", delivery)
+	}
+}
+
+func TestDispatcher_RefusesPrivateAddresses(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("delivery sent to a loopback address")
+	}))
+	defer srv.Close()
+
+	// The host name is only resolved to a loopback address when dialling.
+	for _, url := range []string{srv.URL, strings.Replace(srv.URL, "127.0.0.1", "localhost", 1)} {
+		repo := newMemoryRepo()
+		submitted := repo.add(t, url)
+		d := webhook.NewDispatcher(repo, secrets, webhook.WithPollInterval(time.Millisecond), webhook.WithMaxAttempts(3))
+
+		// A refused address is not retried.
+		delivery := runUntil(t, d, repo, submitted.ID, settled)
+		if delivery.Status != models.DeliveryFailed || len(delivery.History) != 1 ||
+			!strings.Contains(delivery.History[0].Error, webhook.ErrForbiddenAddress.Error()) {
+			t.Errorf("delivery to %s = %+v", url, delivery)
+		}
+	}
+}
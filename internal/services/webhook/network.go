@@ -0,0 +1,52 @@
+package webhook
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"syscall"
+)
+
+// ErrForbiddenAddress is returned for a callback to an address that is not
+// public: loopback, private, link-local, multicast or unspecified addresses,
+// unless they are in a network allowed with WithAllowedNetworks.
+var ErrForbiddenAddress = errors.New("callback address is not public")
+
+// reserved are the IPv4 networks that netip does not classify but that are
+// not reachable on the internet either.
+var reserved = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),     // this network
+	netip.MustParsePrefix("100.64.0.0/10"), // carrier-grade NAT
+}
+
+// checkAddr returns ErrForbiddenAddress unless addr is a public unicast
+// address or is in one of the allowed networks.
+func checkAddr(addr netip.Addr, allowed []netip.Prefix) error {
+	addr = addr.Unmap().WithZone("")
+	for _, p := range allowed {
+		if p.Contains(addr) {
+			return nil
+		}
+	}
+	forbidden := !addr.IsGlobalUnicast() || addr.IsPrivate()
+	for _, p := range reserved {
+		forbidden = forbidden || p.Contains(addr)
+	}
+	if forbidden {
+		return fmt.Errorf("%w: %s", ErrForbiddenAddress, addr)
+	}
+	return nil
+}
+
+// dialControl is the net.Dialer Control of the Dispatcher. It runs once the
+// callback's host name is resolved, for every address dialled, so a name that
+// resolves to a private address is refused as well as a private IP in the URL.
+func dialControl(allowed []netip.Prefix) func(network, address string, c syscall.RawConn) error {
+	return func(_, address string, _ syscall.RawConn) error {
+		ap, err := netip.ParseAddrPort(address)
+		if err != nil {
+			return err
+		}
+		return checkAddr(ap.Addr(), allowed)
+	}
+}
@@ -0,0 +1,119 @@
+package webhook
+
+import (
+	"net/netip"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Defaults of the Dispatcher options.
+const (
+	DefaultPollInterval = time.Second
+	DefaultTimeout      = 10 * time.Second
+	DefaultBackoff      = 30 * time.Second
+	DefaultMaxBackoff   = time.Hour
+	DefaultMaxAttempts  = 10
+)
+
+// Option configures a Dispatcher.
+type Option func(*options)
+
+type options struct {
+	pollInterval time.Duration
+	timeout      time.Duration
+	backoff      time.Duration
+	maxBackoff   time.Duration
+	maxAttempts  int
+	allowed      []netip.Prefix
+	logger       *zap.Logger
+}
+
+func newOptions(opts []Option) options {
+	o := options{
+		pollInterval: DefaultPollInterval,
+		timeout:      DefaultTimeout,
+		backoff:      DefaultBackoff,
+		maxBackoff:   DefaultMaxBackoff,
+		maxAttempts:  DefaultMaxAttempts,
+		logger:       zap.NewNop(),
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// retryDelay is the wait after the given failed attempt: the backoff, doubled
+// for every attempt after the first, up to the maximum backoff.
+func (o options) retryDelay(attempt int) time.Duration {
+	d := o.backoff
+	for i := 1; i < attempt && d < o.maxBackoff; i++ {
+		d *= 2
+	}
+	return min(d, o.maxBackoff)
+}
+
+// lease is how long a claimed delivery is held back from other dispatchers:
+// long enough for the request to time out and its attempt to be recorded.
+func (o options) lease() time.Duration {
+	return 2 * o.timeout
+}
+
+// WithPollInterval sets how long an idle dispatcher waits before looking for
+// a due delivery again.
+func WithPollInterval(d time.Duration) Option {
+	return func(o *options) {
+		if d > 0 {
+			o.pollInterval = d
+		}
+	}
+}
+
+// WithTimeout sets how long a callback may take to respond.
+func WithTimeout(d time.Duration) Option {
+	return func(o *options) {
+		if d > 0 {
+			o.timeout = d
+		}
+	}
+}
+
+// WithBackoff sets the wait before the first retry of a failed delivery,
+// which doubles with every further retry up to limit.
+func WithBackoff(first, limit time.Duration) Option {
+	return func(o *options) {
+		if first > 0 {
+			o.backoff = first
+		}
+		if limit > 0 {
+			o.maxBackoff = limit
+		}
+	}
+}
+
+// WithMaxAttempts sets how many times a delivery is tried before it is given
+// up on. It can still be replayed.
+func WithMaxAttempts(n int) Option {
+	return func(o *options) {
+		if n > 0 {
+			o.maxAttempts = n
+		}
+	}
+}
+
+// WithAllowedNetworks allows callbacks to addresses in networks that are
+// refused otherwise, e.g. a private network the receivers run in. By default
+// only public addresses are allowed, see ErrForbiddenAddress.
+func WithAllowedNetworks(networks ...netip.Prefix) Option {
+	return func(o *options) {
+		o.allowed = append(o.allowed, networks...)
+	}
+}
+
+// WithLogger sets the logger of delivery outcomes and database errors.
+func WithLogger(l *zap.Logger) Option {
+	return func(o *options) {
+		o.logger = l
+	}
+}
@@ -0,0 +1,230 @@
+// Package webhook POSTs the outcome of jobs to callback URLs registered with
+// them. Deliveries are stored in Postgres together with every attempt at
+// sending them, are retried with exponential backoff and can be replayed.
+//
+// Each request is signed with the secret of the client that registered the
+// callback: the X-Webhook-Signature header is "sha256=" followed by the hex
+// HMAC-SHA256 of the X-Webhook-Timestamp header, a dot and the body. A receiver
+// recomputes it, compares it in constant time and rejects old timestamps, so
+// that a captured request cannot be replayed later.
+//
+// Registering a callback is signed the same way by the client, with the
+// callback URL and the SHA-256 of the upload in place of the body, so that
+// nobody else can have requests sent on its behalf, nor reuse a registration
+// for another upload. Callbacks are only sent to public addresses.
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Viktor2805/nucount/internal/models"
+	repository "github.com/Viktor2805/nucount/internal/repository/webhook"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrNotFound is returned when a requested delivery does not exist.
+	ErrNotFound = errors.New("webhook delivery not found")
+	// ErrPending is returned when replaying a delivery that has not been
+	// delivered or given up on yet.
+	ErrPending = errors.New("webhook delivery is still pending")
+	// ErrUnknownClient is returned for a callback of a client without a secret.
+	ErrUnknownClient = errors.New("unknown webhook client")
+	// ErrUnsigned is returned for a callback registration that is not signed
+	// with the client's secret, or was signed more than MaxSkew ago.
+	ErrUnsigned = errors.New("callback registration is not signed with the client's secret")
+	// ErrUndigested is returned for a callback registration without the
+	// SHA-256 of the upload it is bound to.
+	ErrUndigested = errors.New("callback registration requires the X-Content-SHA256 of the upload")
+	// ErrInvalidURL is returned for a callback URL that is not an absolute
+	// http or https URL.
+	ErrInvalidURL = errors.New("invalid callback URL, expected an absolute http or https URL")
+)
+
+// Events announced by deliveries.
+const (
+	EventJobSucceeded = "job.succeeded"
+	EventJobFailed    = "job.failed"
+)
+
+// MaxSkew is how far the timestamp of a signed callback registration may be
+// from the time it is validated.
+const MaxSkew = 5 * time.Minute
+
+// Headers of a delivery request, and of a request registering a callback.
+const (
+	HeaderSignature = "X-Webhook-Signature"
+	HeaderTimestamp = "X-Webhook-Timestamp" // Unix time in seconds
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderDelivery  = "X-Webhook-Delivery" // delivery ID, the same for every attempt
+)
+
+// Payload is the body of a delivery request.
+type Payload struct {
+	Event      string              `json:"event"`
+	JobID      uuid.UUID           `json:"job_id"`
+	Analysis   string              `json:"analysis"`
+	Params     map[string][]string `json:"params"`
+	Filename   string              `json:"filename,omitempty"`
+	Status     models.JobStatus    `json:"status"`
+	Result     models.JSON         `json:"result,omitempty"` // response of the analysis endpoint
+	Error      string              `json:"error,omitempty"`
+	CreatedAt  time.Time           `json:"created_at"`
+	FinishedAt *time.Time          `json:"finished_at,omitempty"`
+}
+
+// NewDelivery returns the delivery announcing the outcome of a finished job,
+// due now, or nil if no callback was registered with the job.
+func NewDelivery(job *models.Job) (*models.WebhookDelivery, error) {
+	if job.CallbackURL == "" {
+		return nil, nil
+	}
+
+	event := EventJobSucceeded
+	if job.Status != models.JobSucceeded {
+		event = EventJobFailed
+	}
+	params := job.Params
+	if params == nil {
+		params = models.JobParams{}
+	}
+	payload, err := json.Marshal(Payload{
+		Event:      event,
+		JobID:      job.ID,
+		Analysis:   job.Analysis,
+		Params:     params,
+		Filename:   job.Filename,
+		Status:     job.Status,
+		Result:     job.Result,
+		Error:      job.Error,
+		CreatedAt:  job.CreatedAt,
+		FinishedAt: job.FinishedAt,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("encode webhook payload: %w", err)
+	}
+
+	return &models.WebhookDelivery{
+		ID:            uuid.New(),
+		JobID:         job.ID,
+		Client:        job.CallbackClient,
+		URL:           job.CallbackURL,
+		Event:         event,
+		Payload:       payload,
+		Status:        models.DeliveryPending,
+		NextAttemptAt: time.Now(),
+	}, nil
+}
+
+// Sign returns the X-Webhook-Signature of a request with body sent at
+// timestamp, in Unix seconds.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Registration returns what the HeaderSignature of a callback registration
+// signs: the callback URL, a newline and the hex SHA-256 of the upload of the
+// job. Binding the signature to the upload keeps a registration seen in
+// transit from being replayed with another file.
+func Registration(callbackURL, digest string) []byte {
+	return []byte(callbackURL + "\n" + strings.ToLower(digest))
+}
+
+type Service interface {
+	// Validate checks a callback to register with a job, signed by client at
+	// timestamp, the Unix seconds of the HeaderTimestamp of the registration.
+	// The signature is the HeaderSignature of the registration: the Sign of
+	// the Registration of callbackURL and digest, the declared SHA-256 of the
+	// upload, with the client's secret. The upload must then be checked
+	// against digest.
+	Validate(client, callbackURL, digest, timestamp, signature string) error
+	Get(id uuid.UUID) (*models.WebhookDelivery, error)
+	ListByJob(jobID uuid.UUID) ([]models.WebhookDelivery, error)
+	// Replay sends a delivered or failed delivery again as soon as possible,
+	// with a fresh round of attempts.
+	Replay(id uuid.UUID) (*models.WebhookDelivery, error)
+}
+
+// Hooks looks up and replays deliveries; a Dispatcher sends them.
+type Hooks struct {
+	repo    repository.WebhookRepositoryI
+	secrets map[string]string
+	opts    options
+}
+
+// NewHooks returns the webhook service. secrets maps client names to the
+// secrets their deliveries are signed with. Of the options, only
+// WithAllowedNetworks applies; pass the Dispatcher's.
+func NewHooks(repo repository.WebhookRepositoryI, secrets map[string]string, opts ...Option) Service {
+	return &Hooks{repo: repo, secrets: secrets, opts: newOptions(opts)}
+}
+
+// Validate refuses a callback URL naming an IP address that is not public
+// right away; the Dispatcher checks the addresses host names resolve to when
+// it connects.
+func (h *Hooks) Validate(client, callbackURL, digest, timestamp, signature string) error {
+	secret, ok := h.secrets[client]
+	if !ok {
+		return fmt.Errorf("%w %q", ErrUnknownClient, client)
+	}
+	if digest == "" {
+		return ErrUndigested
+	}
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || time.Since(time.Unix(ts, 0)).Abs() > MaxSkew ||
+		!hmac.Equal([]byte(signature), []byte(Sign(secret, ts, Registration(callbackURL, digest)))) {
+		return ErrUnsigned
+	}
+
+	u, err := url.Parse(callbackURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ErrInvalidURL
+	}
+	if addr, err := netip.ParseAddr(u.Hostname()); err == nil {
+		return checkAddr(addr, h.opts.allowed)
+	}
+	return nil
+}
+
+func (h *Hooks) Get(id uuid.UUID) (*models.WebhookDelivery, error) {
+	delivery, err := h.repo.FindByID(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	return delivery, err
+}
+
+func (h *Hooks) ListByJob(jobID uuid.UUID) ([]models.WebhookDelivery, error) {
+	return h.repo.FindByJob(jobID)
+}
+
+func (h *Hooks) Replay(id uuid.UUID) (*models.WebhookDelivery, error) {
+	ok, err := h.repo.Replay(id)
+	if err != nil {
+		return nil, err
+	}
+	delivery, err := h.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return delivery, ErrPending
+	}
+	return delivery, nil
+}
@@ -0,0 +1,164 @@
+package webhook_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/netip"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Viktor2805/nucount/internal/models"
+	"github.com/Viktor2805/nucount/internal/services/webhook"
+
+	"github.com/google/uuid"
+)
+
+func TestSign(t *testing.T) {
+	got := webhook.Sign("shh", 1700000000, []byte(`{"event":"job.succeeded"}`))
+	want := "sha256=42ae9abcb4ca9f10d3f82200baf78ddbd10a10e6139d010dfae163d06e294882"
+	if got != want {
+		t.Errorf("Sign = %s, want %s", got, want)
+	}
+}
+
+func TestNewDelivery(t *testing.T) {
+	finishedAt := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	j := &models.Job{
+		ID:             uuid.New(),
+		Analysis:       "kmers",
+		Params:         models.JobParams{"k": {"3"}},
+		Status:         models.JobFailed,
+		Error:          "too many distinct k-mers",
+		FinishedAt:     &finishedAt,
+		CallbackURL:    "https://orchestrator.example/hooks",
+		CallbackClient: "orchestrator",
+	}
+
+	d, err := webhook.NewDelivery(j)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if d.JobID != j.ID || d.Event != webhook.EventJobFailed || d.Status != models.DeliveryPending ||
+		d.URL != j.CallbackURL || d.Client != j.CallbackClient {
+		t.Errorf("delivery = %+v", d)
+	}
+
+	var p webhook.Payload
+	if err := json.Unmarshal(d.Payload, &p); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if p.Event != webhook.EventJobFailed || p.JobID != j.ID || p.Params["k"][0] != "3" ||
+		p.Error != j.Error || !p.FinishedAt.Equal(finishedAt) || p.Result != nil {
+		t.Errorf("payload = %+v", p)
+	}
+
+	j.CallbackURL = ""
+	if d, err := webhook.NewDelivery(j); d != nil || err != nil {
+		t.Errorf("NewDelivery without callback = %v, %v, want nil", d, err)
+	}
+}
+
+func TestHooks_Validate(t *testing.T) {
+	hooks := webhook.NewHooks(newMemoryRepo(), map[string]string{"orchestrator": "shh"},
+		webhook.WithAllowedNetworks(netip.MustParsePrefix("10.1.0.0/16")),
+	)
+
+	tests := []struct {
+		client string
+		url    string
+		want   error
+	}{
+		{"orchestrator", "https://orchestrator.example/hooks?run=1", nil},
+		{"orchestrator", "http://10.1.0.1:8080/", nil},
+		{"orchestrator", "http://8.8.8.8/", nil},
+		{"unknown", "https://orchestrator.example/hooks", webhook.ErrUnknownClient},
+		{"", "https://orchestrator.example/hooks", webhook.ErrUnknownClient},
+		{"orchestrator", "", webhook.ErrInvalidURL},
+		{"orchestrator", "/hooks", webhook.ErrInvalidURL},
+		{"orchestrator", "ftp://orchestrator.example/", webhook.ErrInvalidURL},
+		{"orchestrator", "https:///hooks", webhook.ErrInvalidURL},
+		{"orchestrator", "http://10.0.0.1:8080/", webhook.ErrForbiddenAddress},
+		{"orchestrator", "http://127.0.0.1/", webhook.ErrForbiddenAddress},
+		{"orchestrator", "http://169.254.169.254/latest/meta-data/", webhook.ErrForbiddenAddress},
+		{"orchestrator", "http://[::1]:8080/", webhook.ErrForbiddenAddress},
+		{"orchestrator", "http://[::ffff:192.168.0.1]/", webhook.ErrForbiddenAddress},
+		{"orchestrator", "http://0.0.0.0/", webhook.ErrForbiddenAddress},
+		{"orchestrator", "http://100.64.0.1/", webhook.ErrForbiddenAddress},
+	}
+	const digest = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+	for _, tt := range tests {
+		now := time.Now().Unix()
+		signature := webhook.Sign("shh", now, webhook.Registration(tt.url, digest))
+		err := hooks.Validate(tt.client, tt.url, digest, strconv.FormatInt(now, 10), signature)
+		if !errors.Is(err, tt.want) {
+			t.Errorf("Validate(%q, %q) = %v, want %v", tt.client, tt.url, err, tt.want)
+		}
+	}
+}
+
+func TestHooks_ValidateSignature(t *testing.T) {
+	hooks := webhook.NewHooks(newMemoryRepo(), map[string]string{"orchestrator": "shh", "other": "psst"})
+	url := "https://orchestrator.example/hooks"
+	const (
+		digest = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+		other  = "a3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+	)
+	now := time.Now().Unix()
+	registration := webhook.Registration(url, digest)
+
+	tests := []struct {
+		name      string
+		timestamp int64
+		signature string
+		want      error
+	}{
+		{"signed", now, webhook.Sign("shh", now, registration), nil},
+		{"signed a minute ago", now - 60, webhook.Sign("shh", now-60, registration), nil},
+		{"signed with the digest in upper case", now, webhook.Sign("shh", now, webhook.Registration(url, strings.ToUpper(digest))), nil},
+		{"unsigned", now, "", webhook.ErrUnsigned},
+		{"URL only", now, webhook.Sign("shh", now, []byte(url)), webhook.ErrUnsigned},
+		{"other URL", now, webhook.Sign("shh", now, webhook.Registration(url+"/other", digest)), webhook.ErrUnsigned},
+		{"other upload", now, webhook.Sign("shh", now, webhook.Registration(url, other)), webhook.ErrUnsigned},
+		{"other client's secret", now, webhook.Sign("psst", now, registration), webhook.ErrUnsigned},
+		{"other timestamp", now - 1, webhook.Sign("shh", now, registration), webhook.ErrUnsigned},
+		{"too old", now - 600, webhook.Sign("shh", now-600, registration), webhook.ErrUnsigned},
+		{"too far ahead", now + 600, webhook.Sign("shh", now+600, registration), webhook.ErrUnsigned},
+	}
+	for _, tt := range tests {
+		err := hooks.Validate("orchestrator", url, digest, strconv.FormatInt(tt.timestamp, 10), tt.signature)
+		if !errors.Is(err, tt.want) {
+			t.Errorf("%s: Validate = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+	if err := hooks.Validate("orchestrator", url, digest, "", webhook.Sign("shh", now, registration)); !errors.Is(err, webhook.ErrUnsigned) {
+		t.Errorf("Validate without timestamp = %v, want ErrUnsigned", err)
+	}
+	if err := hooks.Validate("orchestrator", url, "", strconv.FormatInt(now, 10), webhook.Sign("shh", now, []byte(url))); !errors.Is(err, webhook.ErrUndigested) {
+		t.Errorf("Validate without digest = %v, want ErrUndigested", err)
+	}
+}
+
+func TestHooks_Replay(t *testing.T) {
+	repo := newMemoryRepo()
+	hooks := webhook.NewHooks(repo, nil)
+	d := repo.add(t, "http://127.0.0.1:1/")
+
+	if _, err := hooks.Replay(d.ID); !errors.Is(err, webhook.ErrPending) {
+		t.Errorf("Replay of a pending delivery = %v, want ErrPending", err)
+	}
+	if _, err := hooks.Replay(uuid.New()); !errors.Is(err, webhook.ErrNotFound) {
+		t.Errorf("Replay of a missing delivery = %v, want ErrNotFound", err)
+	}
+
+	repo.deliveries[0].Status = models.DeliveryFailed
+	repo.deliveries[0].Attempts = 3
+	replayed, err := hooks.Replay(d.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if replayed.Status != models.DeliveryPending || replayed.Attempts != 0 {
+		t.Errorf("replayed delivery = %+v", replayed)
+	}
+}
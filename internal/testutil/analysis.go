@@ -0,0 +1,56 @@
+package testutil
+
+import (
+	"net/url"
+	"sync"
+
+	"github.com/Viktor2805/nucount/internal/models"
+	repository "github.com/Viktor2805/nucount/internal/repository/analysis"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// AnalysisRepo keeps analyses in memory. It does not list them.
+type AnalysisRepo struct {
+	mu       sync.Mutex
+	Analyses []*models.Analysis
+	Err      error // returned by Create
+}
+
+func (m *AnalysisRepo) Create(a *models.Analysis) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return m.Err
+	}
+	m.Analyses = append(m.Analyses, a)
+	return nil
+}
+
+func (m *AnalysisRepo) FindByID(id uuid.UUID) (*models.Analysis, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, a := range m.Analyses {
+		if a.ID == id {
+			return a, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (m *AnalysisRepo) FindLatest(name string, params models.JobParams, sha256 string) (*models.Analysis, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := len(m.Analyses) - 1; i >= 0; i-- {
+		a := m.Analyses[i]
+		if a.Analysis == name && a.SHA256 == sha256 && url.Values(a.Params).Encode() == url.Values(params).Encode() {
+			return a, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (m *AnalysisRepo) List(repository.Filter, ...func(*gorm.DB) *gorm.DB) ([]models.Analysis, int64, error) {
+	return nil, 0, nil
+}
@@ -0,0 +1,43 @@
+// Package testutil holds the fakes and helpers shared by the tests of several
+// packages.
+package testutil
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// RunUntil runs run in the background until cond holds for the value returned
+// by get, and returns that value. It fails the test if get fails or cond does
+// not hold within 5 seconds. run is stopped, and waited for, before RunUntil
+// returns.
+func RunUntil[T any](t testing.TB, run func(ctx context.Context), get func() (T, error), cond func(T) bool) T {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		run(ctx)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		v, err := get()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if cond(v) {
+			return v
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatal("timed out waiting for the condition")
+	var zero T
+	return zero
+}
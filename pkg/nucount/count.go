@@ -0,0 +1,131 @@
+package nucount
+
+import "io"
+
+// Count holds the counts of A, C, G and T together with the remaining IUPAC
+// symbols, alignment gaps and characters that are not valid in a nucleotide
+// sequence.
+type Count struct {
+	A int
+	C int
+	G int
+	T int
+	U int
+
+	// IUPAC ambiguity codes
+	N int
+	R int // A or G
+	Y int // C or T
+	S int // G or C
+	W int // A or T
+	K int // G or T
+	M int // A or C
+	B int // not A
+	D int // not C
+	H int // not G
+	V int // not T
+
+	Gap     int // '-' and '.'
+	Invalid int // any other non-whitespace character
+}
+
+// Total returns the number of A, C, G and T.
+func (n Count) Total() int {
+	return n.A + n.C + n.G + n.T
+}
+
+// Ambiguous returns the number of IUPAC ambiguity codes, N included.
+func (n Count) Ambiguous() int {
+	return n.N + n.R + n.Y + n.S + n.W + n.K + n.M + n.B + n.D + n.H + n.V
+}
+
+// Length returns the number of sequence characters of every kind.
+func (n Count) Length() int {
+	return n.Total() + n.U + n.Ambiguous() + n.Gap + n.Invalid
+}
+
+// Add returns the element-wise sum of two counts.
+func (n Count) Add(o Count) Count {
+	return Count{
+		A: n.A + o.A, C: n.C + o.C, G: n.G + o.G, T: n.T + o.T, U: n.U + o.U,
+		N: n.N + o.N, R: n.R + o.R, Y: n.Y + o.Y, S: n.S + o.S, W: n.W + o.W, K: n.K + o.K,
+		M: n.M + o.M, B: n.B + o.B, D: n.D + o.D, H: n.H + o.H, V: n.V + o.V,
+		Gap: n.Gap + o.Gap, Invalid: n.Invalid + o.Invalid,
+	}
+}
+
+// RecordCount holds the counts of a single FASTA record.
+type RecordCount struct {
+	ID          string
+	Description string
+	Count
+}
+
+// CountResult holds the totals of a FASTA stream together with the counts of
+// its records.
+type CountResult struct {
+	Total   Count
+	Records []RecordCount
+}
+
+// CountFASTA counts the sequence characters of the FASTA stream r.
+func CountFASTA(r io.Reader) (Count, error) {
+	var c Counter
+	err := ScanFASTA(r, &c)
+	return c.Count(), err
+}
+
+// CountRecords counts the sequence characters of every record of the FASTA
+// stream r separately. Sequence data found before the first header is
+// reported as a record with an empty ID.
+func CountRecords(r io.Reader) (CountResult, error) {
+	var c RecordCounter
+	err := ScanFASTA(r, &c)
+	return c.Result(), err
+}
+
+// RecordCounter is a FASTAHandler that counts every record separately, like
+// CountRecords. The zero value is ready to use.
+type RecordCounter struct {
+	result  CountResult
+	cur     RecordCount
+	counter Counter
+	open    bool
+}
+
+// Header starts a record.
+func (c *RecordCounter) Header(text []byte) error {
+	c.flush()
+	c.cur.ID, c.cur.Description = ParseHeader(text)
+	c.open = true
+	return nil
+}
+
+// Sequence counts the bytes of a sequence line of the current record.
+func (c *RecordCounter) Sequence(seg []byte) error {
+	c.open = true
+	c.counter.Write(seg)
+	return nil
+}
+
+// Result finishes the current record and returns the counts so far.
+func (c *RecordCounter) Result() CountResult {
+	c.flush()
+	return c.result
+}
+
+// flush finishes the current record and adds it to the result.
+func (c *RecordCounter) flush() {
+	if !c.open {
+		return
+	}
+
+	c.cur.Count = c.counter.Count()
+
+	c.result.Records = append(c.result.Records, c.cur)
+	c.result.Total = c.result.Total.Add(c.cur.Count)
+
+	c.cur = RecordCount{}
+	c.counter.Reset()
+	c.open = false
+}
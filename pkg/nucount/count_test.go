@@ -0,0 +1,83 @@
+package nucount_test
+
+import (
+	"bytes"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Viktor2805/nucount/pkg/nucount"
+)
+
+func TestCounter(t *testing.T) {
+	var c nucount.Counter
+	c.Write([]byte("ACGTU acgtu\tNRYSWKMBDHV-.X\r"))
+	c.Add(nucount.SymbolOf('G'))
+
+	want := nucount.Count{
+		A: 2, C: 2, G: 3, T: 2, U: 2,
+		N: 1, R: 1, Y: 1, S: 1, W: 1, K: 1, M: 1, B: 1, D: 1, H: 1, V: 1,
+		Gap: 2, Invalid: 1,
+	}
+	if got := c.Count(); got != want {
+		t.Errorf("Count() = %+v, want %+v", got, want)
+	}
+
+	c.Reset()
+	if got := c.Count(); got != (nucount.Count{}) {
+		t.Errorf("Count() after Reset = %+v", got)
+	}
+}
+
+func TestCountRecords(t *testing.T) {
+	res, err := nucount.CountRecords(strings.NewReader("AC\n>chr1 first\nACGTNN\n>chr2\nGG\n>empty\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []nucount.RecordCount{
+		{Count: nucount.Count{A: 1, C: 1}},
+		{ID: "chr1", Description: "first", Count: nucount.Count{A: 1, C: 1, G: 1, T: 1, N: 2}},
+		{ID: "chr2", Count: nucount.Count{G: 2}},
+		{ID: "empty"},
+	}
+	if !reflect.DeepEqual(res.Records, want) {
+		t.Errorf("records = %+v, want %+v", res.Records, want)
+	}
+	if wantTotal := (nucount.Count{A: 2, C: 2, G: 3, T: 1, N: 2}); res.Total != wantTotal {
+		t.Errorf("total = %+v, want %+v", res.Total, wantTotal)
+	}
+}
+
+func TestCountFASTAParallel(t *testing.T) {
+	// Several chunks, with headers and lines crossing chunk boundaries.
+	rng := rand.New(rand.NewSource(1))
+	var b bytes.Buffer
+	for b.Len() < 10<<20 {
+		b.WriteString(">record ")
+		for range rng.Intn(300) {
+			b.WriteByte("ACGT >N"[rng.Intn(7)])
+		}
+		b.WriteByte('\n')
+		for range rng.Intn(100) {
+			for range rng.Intn(200) {
+				b.WriteByte("ACGTNacgt-"[rng.Intn(10)])
+			}
+			b.WriteString("\r\n")
+		}
+	}
+
+	want, err := nucount.CountFASTA(bytes.NewReader(b.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, workers := range []int{0, 1, 2, 5} {
+		got, err := nucount.CountFASTAParallel(bytes.NewReader(b.Bytes()), workers)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("%d workers: %+v, want %+v", workers, got, want)
+		}
+	}
+}
@@ -0,0 +1,28 @@
+// Package nucount counts the nucleotides of FASTA and FASTQ streams and derives
+// composition metrics from the counts. It is the counting engine of the DNA
+// analyzer service, which wraps it with decompression, progress reporting and
+// its HTTP API, and of the nucount command.
+//
+// The API is reader-based and streams its input: memory use does not depend
+// on the size of the input, except for the record being iterated over.
+//
+//   - [CountFASTA], [CountRecords] and [CountFASTQ] count a whole stream;
+//     [CountFASTAParallel] spreads a FASTA stream over several goroutines.
+//   - [FASTARecords] and [FASTQRecords] iterate over the records of a stream.
+//   - [Counter] counts raw sequence bytes and [SymbolOf] classifies them, for
+//     analyses built on [ScanFASTA] or the record iterators.
+//   - [Count.Composition] derives GC and AT content, skews, the
+//     purine/pyrimidine ratio and the N fraction.
+//
+// Input must be decompressed; the counts of gzip, bzip2 or zstd files are
+// those of their content.
+//
+// # Versioning
+//
+// The package is a Go module of its own,
+// github.com/Viktor2805/nucount/pkg/nucount, versioned with semantic
+// versioning by tags of the form pkg/nucount/vX.Y.Z; see CHANGELOG.md. Within
+// a major version exported identifiers are neither removed nor changed
+// incompatibly, and the counts and metrics of an input do not change except to
+// fix a bug.
+package nucount
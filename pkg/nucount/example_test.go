@@ -0,0 +1,106 @@
+package nucount_test
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/Viktor2805/nucount/pkg/nucount"
+)
+
+func ExampleCountFASTA() {
+	fasta := ">chr1 example\nACGTNN\nGGCC\n>chr2\nAATT\n"
+
+	n, err := nucount.CountFASTA(strings.NewReader(fasta))
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("A=%d C=%d G=%d T=%d N=%d length=%d\n", n.A, n.C, n.G, n.T, n.N, n.Length())
+	fmt.Printf("GC %.1f%%\n", n.GCContent())
+	// Output:
+	// A=3 C=3 G=3 T=3 N=2 length=14
+	// GC 50.0%
+}
+
+func ExampleCountRecords() {
+	fasta := ">chr1 example\nACGTNN\nGGCC\n>chr2\nAATT\n"
+
+	res, err := nucount.CountRecords(strings.NewReader(fasta))
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, rec := range res.Records {
+		fmt.Printf("%s %q: %d bases, GC %.1f%%\n", rec.ID, rec.Description, rec.Length(), rec.GCContent())
+	}
+	// Output:
+	// chr1 "example": 10 bases, GC 75.0%
+	// chr2 "": 4 bases, GC 0.0%
+}
+
+func ExampleCount_Composition() {
+	n := nucount.Count{A: 30, C: 20, G: 30, T: 20}
+
+	c := n.Composition()
+	fmt.Printf("GC %.0f%%, AT %.0f%%, GC skew %.1f, purine/pyrimidine %.1f\n",
+		c.GCContent, c.ATContent, c.GCSkew, c.PurinePyrimidineRatio)
+	// Output:
+	// GC 50%, AT 50%, GC skew 0.2, purine/pyrimidine 1.5
+}
+
+func ExampleFASTARecords() {
+	fasta := ">seq1 first\nACGT\nAC\n>seq2\nGGG\n"
+
+	for rec, err := range nucount.FASTARecords(strings.NewReader(fasta)) {
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println(rec.ID, string(rec.Seq))
+	}
+	// Output:
+	// seq1 ACGTAC
+	// seq2 GGG
+}
+
+func ExampleFASTQRecords() {
+	fastq := "@read1\nACGT\n+\nIIII\n@read2\nGGN\n+\n##I\n"
+
+	for rec, err := range nucount.FASTQRecords(strings.NewReader(fastq)) {
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println(rec.ID, string(rec.Seq), string(rec.Qual))
+	}
+	// Output:
+	// read1 ACGT IIII
+	// read2 GGN ##I
+}
+
+func ExampleCountFASTQ() {
+	fastq := "@read1\nACGT\n+\nIIII\n@read2\nGGN\n+\n##I\n"
+
+	st, err := nucount.CountFASTQ(strings.NewReader(fastq))
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("%d reads of %d-%d bases, Phred+%d, Q30 %.2f\n", st.Reads, st.MinLength, st.MaxLength, st.PhredOffset, st.Q30)
+	// Output:
+	// 2 reads of 3-4 bases, Phred+33, Q30 0.71
+}
+
+// A Counter counts sequence bytes of any origin, such as the records of an
+// iterator.
+func ExampleCounter() {
+	fasta := ">seq1\nACGT\n>seq2\nGGCCN\n"
+
+	var all nucount.Counter
+	for rec, err := range nucount.FASTARecords(strings.NewReader(fasta)) {
+		if err != nil {
+			log.Fatal(err)
+		}
+		all.Write(rec.Seq)
+	}
+	n := all.Count()
+	fmt.Println(n.Total(), n.N, n.GCContent())
+	// Output:
+	// 8 1 75
+}
@@ -0,0 +1,195 @@
+package nucount
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+)
+
+// bufSize is the size of the read buffer of the scanners.
+const bufSize = 4 << 20
+
+// MaxFASTAHeaderLength bounds the memory used for a single FASTA header line.
+const MaxFASTAHeaderLength = 16 << 20
+
+// ErrInvalidFASTA is returned for FASTA input that cannot be scanned, such as
+// a header line longer than MaxFASTAHeaderLength.
+var ErrInvalidFASTA = errors.New("invalid FASTA")
+
+// FASTAHandler receives the events produced by ScanFASTA.
+//
+// Header is called once per '>' line with the text after '>', without the line
+// terminator. Sequence is called with the bytes of a sequence line, without
+// the '\n' but with a '\r' of a CRLF terminator; a line that spans two reads is
+// delivered in several calls. The slices are only valid during the call. An
+// error returned by either method stops the scan and is returned by ScanFASTA.
+type FASTAHandler interface {
+	Header(text []byte) error
+	Sequence(seg []byte) error
+}
+
+// ScanFASTA streams r through a fixed size buffer and reports headers and
+// sequence segments to h. Memory use does not depend on the input size: a
+// header line is held in full, and one longer than MaxFASTAHeaderLength is
+// an error wrapping ErrInvalidFASTA.
+func ScanFASTA(r io.Reader, h FASTAHandler) error {
+	buf := make([]byte, bufSize)
+
+	var hdr []byte
+	inHeader := false
+	lineStart := true
+
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			p := buf[:n]
+			i := 0
+
+			for i < len(p) {
+				if inHeader {
+					j := bytes.IndexByte(p[i:], '\n')
+					end := i + j
+					if j < 0 {
+						end = len(p)
+					}
+					if len(hdr)+end-i > MaxFASTAHeaderLength {
+						return fmt.Errorf("%w: header line exceeds %d bytes", ErrInvalidFASTA, MaxFASTAHeaderLength)
+					}
+					hdr = append(hdr, p[i:end]...)
+					if j < 0 {
+						break
+					}
+					if err := h.Header(bytes.TrimSuffix(hdr, []byte{'\r'})); err != nil {
+						return err
+					}
+					hdr = hdr[:0]
+					inHeader = false
+					lineStart = true
+					i += j + 1
+					continue
+				}
+
+				if lineStart && p[i] == '>' {
+					inHeader = true
+					lineStart = false
+					i++
+					continue
+				}
+
+				j := bytes.IndexByte(p[i:], '\n')
+				if j < 0 {
+					if err := h.Sequence(p[i:]); err != nil {
+						return err
+					}
+					lineStart = false
+					break
+				}
+				if j > 0 {
+					if err := h.Sequence(p[i : i+j]); err != nil {
+						return err
+					}
+				}
+				lineStart = true
+				i += j + 1
+			}
+		}
+		if err == io.EOF {
+			if inHeader {
+				return h.Header(bytes.TrimSuffix(hdr, []byte{'\r'}))
+			}
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// ParseHeader splits the text of a FASTA or FASTQ header line into the record
+// ID, its first word, and the free text description that follows it.
+func ParseHeader(text []byte) (id, description string) {
+	text = bytes.TrimSpace(text)
+	i := bytes.IndexAny(text, " \t")
+	if i < 0 {
+		return string(text), ""
+	}
+	return string(text[:i]), string(bytes.TrimSpace(text[i+1:]))
+}
+
+// Record is a FASTA or FASTQ record. The slices of a record yielded by an
+// iterator are reused by the iterator: they are only valid until the next
+// record, unless cloned.
+type Record struct {
+	ID          string
+	Description string
+	Seq         []byte // sequence without line breaks or whitespace
+	Qual        []byte // FASTQ quality characters, one per byte of Seq; nil for FASTA
+}
+
+// Clone returns a copy of rec that does not share its slices.
+func (rec Record) Clone() Record {
+	rec.Seq = bytes.Clone(rec.Seq)
+	rec.Qual = bytes.Clone(rec.Qual)
+	return rec
+}
+
+// errStopped stops the scan of an iterator whose consumer stopped.
+var errStopped = errors.New("iteration stopped")
+
+// FASTARecords returns an iterator over the records of the FASTA stream r.
+// Sequence data found before the first header is yielded as a record with an
+// empty ID. A read error is yielded once, with an empty record, and ends the
+// iteration. Memory use is that of the longest record.
+func FASTARecords(r io.Reader) iter.Seq2[Record, error] {
+	return func(yield func(Record, error) bool) {
+		it := fastaIterator{yield: yield}
+		err := ScanFASTA(r, &it)
+		if err == nil {
+			err = it.flush()
+		}
+		if err != nil && err != errStopped {
+			yield(Record{}, err)
+		}
+	}
+}
+
+// fastaIterator collects the sequence of a record and yields it at the next
+// header or at the end of the input.
+type fastaIterator struct {
+	yield func(Record, error) bool
+	rec   Record
+	open  bool
+}
+
+func (it *fastaIterator) Header(text []byte) error {
+	if err := it.flush(); err != nil {
+		return err
+	}
+	it.rec.ID, it.rec.Description = ParseHeader(text)
+	it.open = true
+	return nil
+}
+
+func (it *fastaIterator) Sequence(seg []byte) error {
+	it.open = true
+	for _, b := range seg {
+		if symbolLUT[b] != SymbolSpace {
+			it.rec.Seq = append(it.rec.Seq, b)
+		}
+	}
+	return nil
+}
+
+func (it *fastaIterator) flush() error {
+	if !it.open {
+		return nil
+	}
+	if !it.yield(it.rec, nil) {
+		return errStopped
+	}
+	it.rec = Record{Seq: it.rec.Seq[:0]}
+	it.open = false
+	return nil
+}
@@ -0,0 +1,163 @@
+package nucount_test
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/Viktor2805/nucount/pkg/nucount"
+)
+
+func collect(t *testing.T, r io.Reader) ([]nucount.Record, error) {
+	t.Helper()
+	var recs []nucount.Record
+	for rec, err := range nucount.FASTARecords(r) {
+		if err != nil {
+			return recs, err
+		}
+		recs = append(recs, rec.Clone())
+	}
+	return recs, nil
+}
+
+func TestFASTARecords(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []nucount.Record
+	}{
+		{
+			name:  "records",
+			input: ">chr1 Homo sapiens\tchromosome 1\nACGT\nNNAC\n>chr2\nGG\n",
+			want: []nucount.Record{
+				{ID: "chr1", Description: "Homo sapiens\tchromosome 1", Seq: []byte("ACGTNNAC")},
+				{ID: "chr2", Seq: []byte("GG")},
+			},
+		},
+		{
+			name:  "whitespace and CRLF",
+			input: ">a\r\nAC GT\r\n\r\nac\tgt\r\n",
+			want:  []nucount.Record{{ID: "a", Seq: []byte("ACGTacgt")}},
+		},
+		{
+			name:  "sequence before the first header",
+			input: "ACGT\n>b\n",
+			want:  []nucount.Record{{Seq: []byte("ACGT")}, {ID: "b"}},
+		},
+		{
+			name:  "header without line terminator",
+			input: ">a\nAC\n>b",
+			want:  []nucount.Record{{ID: "a", Seq: []byte("AC")}, {ID: "b"}},
+		},
+		{name: "empty", input: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// One byte per read, so that lines and headers span reads.
+			got, err := collect(t, iotest.OneByteReader(strings.NewReader(tt.input)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d records, want %d: %+v", len(got), len(tt.want), got)
+			}
+			for i := range got {
+				g, w := got[i], tt.want[i]
+				if g.ID != w.ID || g.Description != w.Description || string(g.Seq) != string(w.Seq) || g.Qual != nil {
+					t.Errorf("record %d = %+v, want %+v", i, g, w)
+				}
+			}
+		})
+	}
+}
+
+func TestFASTARecords_Break(t *testing.T) {
+	var ids []string
+	for rec, err := range nucount.FASTARecords(strings.NewReader(">a\nA\n>b\nC\n>c\nG\n")) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, rec.ID)
+		if rec.ID == "b" {
+			break
+		}
+	}
+	if want := []string{"a", "b"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("ids = %v, want %v", ids, want)
+	}
+}
+
+func TestFASTARecords_ReadError(t *testing.T) {
+	errRead := errors.New("read failure")
+	r := io.MultiReader(strings.NewReader(">a\nACGT\n>b\nAC"), iotest.ErrReader(errRead))
+
+	got, err := collect(t, r)
+	if !errors.Is(err, errRead) {
+		t.Fatalf("err = %v, want %v", err, errRead)
+	}
+	if len(got) != 1 || got[0].ID != "a" {
+		t.Errorf("records before the error = %+v, want a", got)
+	}
+}
+
+// stopHandler fails at the second header.
+type stopHandler struct {
+	headers int
+}
+
+var errStop = errors.New("stop")
+
+func (h *stopHandler) Header([]byte) error {
+	if h.headers++; h.headers == 2 {
+		return errStop
+	}
+	return nil
+}
+
+func (h *stopHandler) Sequence([]byte) error { return nil }
+
+func TestScanFASTA_HandlerError(t *testing.T) {
+	h := &stopHandler{}
+	if err := nucount.ScanFASTA(strings.NewReader(">a\nAC\n>b\nGT\n>c\n"), h); !errors.Is(err, errStop) {
+		t.Fatalf("err = %v, want %v", err, errStop)
+	}
+	if h.headers != 2 {
+		t.Errorf("headers = %d, want 2", h.headers)
+	}
+}
+
+func TestParseHeader(t *testing.T) {
+	tests := []struct {
+		text, id, description string
+	}{
+		{"chr1", "chr1", ""},
+		{" chr1  Homo sapiens ", "chr1", "Homo sapiens"},
+		{"read/1\tlane 2", "read/1", "lane 2"},
+		{"", "", ""},
+	}
+	for _, tt := range tests {
+		id, description := nucount.ParseHeader([]byte(tt.text))
+		if id != tt.id || description != tt.description {
+			t.Errorf("ParseHeader(%q) = %q, %q, want %q, %q", tt.text, id, description, tt.id, tt.description)
+		}
+	}
+}
+
+func TestScanFASTA_LongHeader(t *testing.T) {
+	long := ">" + strings.Repeat("x", nucount.MaxFASTAHeaderLength)
+	// The header is refused while it is read, with or without a newline.
+	for _, input := range []string{long + "x", long + "x\nACGT\n"} {
+		_, err := collect(t, iotest.HalfReader(strings.NewReader(input)))
+		if !errors.Is(err, nucount.ErrInvalidFASTA) {
+			t.Errorf("err = %v, want ErrInvalidFASTA", err)
+		}
+	}
+
+	recs, err := collect(t, strings.NewReader(long+"\nACGT\n"))
+	if err != nil || len(recs) != 1 || len(recs[0].ID) != nucount.MaxFASTAHeaderLength {
+		t.Errorf("header of the maximum length: %d records, %v", len(recs), err)
+	}
+}
@@ -0,0 +1,284 @@
+package nucount
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"sort"
+)
+
+// MaxFASTQLineLength bounds the memory used for a single FASTQ line.
+const MaxFASTQLineLength = 16 << 20
+
+// ErrInvalidFASTQ is returned for input that does not follow the 4-line FASTQ
+// layout.
+var ErrInvalidFASTQ = errors.New("invalid FASTQ")
+
+// Phred quality offsets.
+const (
+	PhredOffset33 = 33
+	PhredOffset64 = 64
+)
+
+// FASTQRecords returns an iterator over the reads of the FASTQ stream r. An
+// error, such as one wrapping ErrInvalidFASTQ, is yielded once, with an empty
+// record, and ends the iteration.
+func FASTQRecords(r io.Reader) iter.Seq2[Record, error] {
+	return func(yield func(Record, error) bool) {
+		sc := newFASTQScanner(r)
+		var raw fastqRecord
+		for {
+			err := sc.next(&raw)
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				yield(Record{}, err)
+				return
+			}
+			rec := Record{Seq: raw.seq, Qual: raw.qual}
+			rec.ID, rec.Description = ParseHeader(raw.header)
+			if !yield(rec, nil) {
+				return
+			}
+		}
+	}
+}
+
+// LengthBin is the number of reads of one length.
+type LengthBin struct {
+	Length int
+	Reads  int
+}
+
+// FASTQStats holds base counts and quality statistics of a FASTQ file.
+type FASTQStats struct {
+	Bases              Count
+	Reads              int
+	PhredOffset        int // 33 or 64, detected from the quality characters
+	MinLength          int
+	MaxLength          int
+	LengthDistribution []LengthBin // sorted by Length
+	MeanQuality        []float64   // mean Phred score at each read position
+	Q20                float64     // fraction of bases with quality >= 20
+	Q30                float64     // fraction of bases with quality >= 30
+	GCDistribution     [101]int    // reads by GC percentage rounded to an integer
+}
+
+// MeanLength returns the average read length.
+func (s FASTQStats) MeanLength() float64 {
+	return ratio(s.Bases.Length(), s.Reads)
+}
+
+// CountFASTQ counts the bases of the FASTQ stream r and collects per-read and
+// per-position quality statistics.
+func CountFASTQ(r io.Reader) (FASTQStats, error) {
+	var c FASTQCounter
+	for rec, err := range FASTQRecords(r) {
+		if err != nil {
+			return FASTQStats{}, err
+		}
+		c.Add(rec)
+	}
+	return c.Stats(), nil
+}
+
+// FASTQCounter collects the statistics of FASTQ reads, like CountFASTQ. It
+// works on raw quality characters: the Phred offset is only known, and
+// applied, once every read has been seen. The zero value is ready to use.
+type FASTQCounter struct {
+	counter  Counter
+	reads    int
+	lengths  map[int]int
+	posSum   []int64
+	posReads []int64
+	qualHist [256]int64
+	gcHist   [101]int
+}
+
+// Add adds a read.
+func (c *FASTQCounter) Add(rec Record) {
+	var read Counter
+	read.Write(rec.Seq)
+	c.counter.Merge(&read)
+
+	if c.lengths == nil {
+		c.lengths = make(map[int]int)
+	}
+	c.reads++
+	c.lengths[len(rec.Seq)]++
+
+	for len(c.posSum) < len(rec.Qual) {
+		c.posSum = append(c.posSum, 0)
+		c.posReads = append(c.posReads, 0)
+	}
+	for i, q := range rec.Qual {
+		c.posSum[i] += int64(q)
+		c.posReads[i]++
+		c.qualHist[q]++
+	}
+
+	if n := read.Count(); n.Total() > 0 {
+		c.gcHist[int(n.GCContent()+0.5)]++
+	}
+}
+
+// Stats returns the statistics of the reads so far.
+func (c *FASTQCounter) Stats() FASTQStats {
+	st := FASTQStats{
+		Bases:          c.counter.Count(),
+		Reads:          c.reads,
+		PhredOffset:    detectPhredOffset(&c.qualHist),
+		GCDistribution: c.gcHist,
+	}
+
+	for length, reads := range c.lengths {
+		st.LengthDistribution = append(st.LengthDistribution, LengthBin{Length: length, Reads: reads})
+	}
+	sort.Slice(st.LengthDistribution, func(i, j int) bool {
+		return st.LengthDistribution[i].Length < st.LengthDistribution[j].Length
+	})
+	if n := len(st.LengthDistribution); n > 0 {
+		st.MinLength = st.LengthDistribution[0].Length
+		st.MaxLength = st.LengthDistribution[n-1].Length
+	}
+
+	st.MeanQuality = make([]float64, len(c.posSum))
+	for i := range c.posSum {
+		st.MeanQuality[i] = float64(c.posSum[i])/float64(c.posReads[i]) - float64(st.PhredOffset)
+	}
+
+	var total, q20, q30 int64
+	for q, n := range c.qualHist {
+		total += n
+		if q-st.PhredOffset >= 20 {
+			q20 += n
+		}
+		if q-st.PhredOffset >= 30 {
+			q30 += n
+		}
+	}
+	if total > 0 {
+		st.Q20 = float64(q20) / float64(total)
+		st.Q30 = float64(q30) / float64(total)
+	}
+
+	return st
+}
+
+// detectPhredOffset guesses the quality encoding: characters below ';' only
+// occur in Phred+33, characters above 'J' only in Phred+64. Phred+33 is assumed
+// when the range is inconclusive.
+func detectPhredOffset(hist *[256]int64) int {
+	lo, hi := -1, -1
+	for q, n := range hist {
+		if n == 0 {
+			continue
+		}
+		if lo < 0 {
+			lo = q
+		}
+		hi = q
+	}
+
+	switch {
+	case lo < 0, lo < ';':
+		return PhredOffset33
+	case hi > 'J':
+		return PhredOffset64
+	default:
+		return PhredOffset33
+	}
+}
+
+// fastqRecord is one FASTQ read. Its slices are reused by the next call to
+// fastqScanner.next.
+type fastqRecord struct {
+	header []byte // text after '@'
+	seq    []byte
+	qual   []byte
+}
+
+// fastqScanner reads 4-line FASTQ records: '@' header, sequence, '+' separator
+// and a quality line of the same length as the sequence.
+type fastqScanner struct {
+	r    *bufio.Reader
+	line int
+	sep  []byte
+}
+
+func newFASTQScanner(r io.Reader) *fastqScanner {
+	return &fastqScanner{r: bufio.NewReaderSize(r, bufSize)}
+}
+
+// next reads the following record into rec. It returns io.EOF when the input
+// is exhausted between records.
+func (s *fastqScanner) next(rec *fastqRecord) error {
+	var err error
+
+	for {
+		rec.header, err = s.readLine(rec.header)
+		if err != nil {
+			return err
+		}
+		if len(rec.header) > 0 {
+			break
+		}
+	}
+	if rec.header[0] != '@' {
+		return fmt.Errorf("%w: line %d: header must start with '@'", ErrInvalidFASTQ, s.line)
+	}
+	rec.header = rec.header[1:]
+
+	if rec.seq, err = s.readLine(rec.seq); err != nil {
+		return s.truncated(err)
+	}
+	if s.sep, err = s.readLine(s.sep); err != nil {
+		return s.truncated(err)
+	}
+	if len(s.sep) == 0 || s.sep[0] != '+' {
+		return fmt.Errorf("%w: line %d: separator must start with '+'", ErrInvalidFASTQ, s.line)
+	}
+	if rec.qual, err = s.readLine(rec.qual); err != nil {
+		return s.truncated(err)
+	}
+	if len(rec.qual) != len(rec.seq) {
+		return fmt.Errorf("%w: line %d: quality length %d differs from sequence length %d",
+			ErrInvalidFASTQ, s.line, len(rec.qual), len(rec.seq))
+	}
+	return nil
+}
+
+func (s *fastqScanner) truncated(err error) error {
+	if err == io.EOF {
+		return fmt.Errorf("%w: line %d: truncated record", ErrInvalidFASTQ, s.line)
+	}
+	return err
+}
+
+// readLine reads the next line into dst without its line terminator. The last
+// line of the input does not need a terminator.
+func (s *fastqScanner) readLine(dst []byte) ([]byte, error) {
+	dst = dst[:0]
+	for {
+		chunk, err := s.r.ReadSlice('\n')
+		dst = append(dst, chunk...)
+		if len(dst) > MaxFASTQLineLength {
+			return dst, fmt.Errorf("%w: line %d exceeds %d bytes", ErrInvalidFASTQ, s.line+1, MaxFASTQLineLength)
+		}
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		if err == io.EOF && len(dst) > 0 {
+			err = nil
+		}
+		if err != nil {
+			return dst, err
+		}
+		s.line++
+		return bytes.TrimRight(dst, "\r\n"), nil
+	}
+}
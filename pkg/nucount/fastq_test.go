@@ -0,0 +1,82 @@
+package nucount_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Viktor2805/nucount/pkg/nucount"
+)
+
+func TestFASTQRecords(t *testing.T) {
+	input := "@r1 lane 1\nACGT\n+\nIIII\n\n@r2\r\nGN\r\n+r2\r\n#I\r\n"
+
+	var got []nucount.Record
+	for rec, err := range nucount.FASTQRecords(strings.NewReader(input)) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, rec.Clone())
+	}
+
+	want := []nucount.Record{
+		{ID: "r1", Description: "lane 1", Seq: []byte("ACGT"), Qual: []byte("IIII")},
+		{ID: "r2", Seq: []byte("GN"), Qual: []byte("#I")},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d records, want %d", len(got), len(want))
+	}
+	for i := range got {
+		g, w := got[i], want[i]
+		if g.ID != w.ID || g.Description != w.Description || string(g.Seq) != string(w.Seq) || string(g.Qual) != string(w.Qual) {
+			t.Errorf("record %d = %+v, want %+v", i, g, w)
+		}
+	}
+}
+
+func TestFASTQRecords_Invalid(t *testing.T) {
+	for _, input := range []string{
+		">r1\nACGT\n+\nIIII\n",
+		"@r1\nACGT\n-\nIIII\n",
+		"@r1\nACGT\n+\nIII\n",
+		"@r1\nACGT\n",
+	} {
+		var err error
+		for _, err = range nucount.FASTQRecords(strings.NewReader(input)) {
+			if err != nil {
+				break
+			}
+		}
+		if !errors.Is(err, nucount.ErrInvalidFASTQ) {
+			t.Errorf("%q: err = %v, want ErrInvalidFASTQ", input, err)
+		}
+	}
+}
+
+func TestFASTQCounter(t *testing.T) {
+	input := "@r1\nACGT\n+\nIIII\n@r2\nGGCCN\n+\n!!!!!\n"
+	want, err := nucount.CountFASTQ(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Adding the reads one at a time gives the same statistics.
+	var c nucount.FASTQCounter
+	for rec, err := range nucount.FASTQRecords(strings.NewReader(input)) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.Add(rec)
+	}
+	got := c.Stats()
+
+	if got.Reads != 2 || got.Bases != want.Bases || got.Q20 != want.Q20 || got.GCDistribution != want.GCDistribution {
+		t.Errorf("stats = %+v, want %+v", got, want)
+	}
+	if want := (nucount.Count{A: 1, C: 3, G: 3, T: 1, N: 1}); got.Bases != want {
+		t.Errorf("bases = %+v, want %+v", got.Bases, want)
+	}
+	if got.Q20 != 4.0/9 || got.GCDistribution[50] != 1 || got.GCDistribution[100] != 1 {
+		t.Errorf("Q20 = %v, GC distribution = %v", got.Q20, got.GCDistribution)
+	}
+}
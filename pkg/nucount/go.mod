@@ -0,0 +1,3 @@
+module github.com/Viktor2805/nucount/pkg/nucount
+
+go 1.23
@@ -0,0 +1,61 @@
+package nucount
+
+// Composition holds the metrics derived from a Count.
+type Composition struct {
+	GCContent             float64 // percentage of G+C among A/C/G/T
+	ATContent             float64 // percentage of A+T among A/C/G/T
+	GCSkew                float64 // (G-C)/(G+C)
+	ATSkew                float64 // (A-T)/(A+T)
+	PurinePyrimidineRatio float64 // (A+G)/(C+T)
+	NFraction             float64 // N over all sequence characters
+}
+
+// Composition computes the derived composition metrics of n. Metrics whose
+// denominator is zero are reported as 0.
+func (n Count) Composition() Composition {
+	return Composition{
+		GCContent:             n.GCContent(),
+		ATContent:             n.ATContent(),
+		GCSkew:                n.GCSkew(),
+		ATSkew:                n.ATSkew(),
+		PurinePyrimidineRatio: n.PurinePyrimidineRatio(),
+		NFraction:             n.NFraction(),
+	}
+}
+
+// GCContent returns the percentage of G and C among the counted nucleotides.
+func (n Count) GCContent() float64 {
+	return ratio(n.G+n.C, n.Total()) * 100
+}
+
+// ATContent returns the percentage of A and T among the counted nucleotides.
+func (n Count) ATContent() float64 {
+	return ratio(n.A+n.T, n.Total()) * 100
+}
+
+// GCSkew returns (G-C)/(G+C).
+func (n Count) GCSkew() float64 {
+	return ratio(n.G-n.C, n.G+n.C)
+}
+
+// ATSkew returns (A-T)/(A+T).
+func (n Count) ATSkew() float64 {
+	return ratio(n.A-n.T, n.A+n.T)
+}
+
+// PurinePyrimidineRatio returns (A+G)/(C+T).
+func (n Count) PurinePyrimidineRatio() float64 {
+	return ratio(n.A+n.G, n.C+n.T)
+}
+
+// NFraction returns the fraction of N among all sequence characters.
+func (n Count) NFraction() float64 {
+	return ratio(n.N, n.Length())
+}
+
+func ratio(num, den int) float64 {
+	if den == 0 {
+		return 0
+	}
+	return float64(num) / float64(den)
+}
@@ -0,0 +1,114 @@
+package nucount
+
+import (
+	"bytes"
+	"io"
+	"sync"
+)
+
+// chunkSize is the amount of input handed to a counting worker at a time.
+const chunkSize = bufSize
+
+// chunk is a slice of the input together with the line state at its first
+// byte, which the reader derives from the end of the previous chunk.
+type chunk struct {
+	data      []byte
+	lineStart bool // data starts a new line
+	inHeader  bool // data continues a '>' line
+}
+
+// CountFASTAParallel counts the FASTA stream r like CountFASTA, with a reader
+// goroutine feeding fixed-size chunks to workers goroutines that each keep
+// their own counts. At most 2*workers chunks of 4 MiB are in memory at a time.
+// workers <= 1 counts on the calling goroutine.
+func CountFASTAParallel(r io.Reader, workers int) (Count, error) {
+	if workers <= 1 {
+		return CountFASTA(r)
+	}
+
+	jobs := make(chan chunk, workers)
+	free := make(chan []byte, 2*workers)
+	for i := 0; i < cap(free); i++ {
+		free <- make([]byte, chunkSize)
+	}
+
+	counters := make([]Counter, workers)
+	var wg sync.WaitGroup
+	for i := range counters {
+		wg.Add(1)
+		go func(c *Counter) {
+			defer wg.Done()
+			for ch := range jobs {
+				countChunk(c, ch)
+				free <- ch.data[:cap(ch.data)]
+			}
+		}(&counters[i])
+	}
+
+	var readErr error
+	state := chunk{lineStart: true}
+	for {
+		buf := <-free
+		n, err := io.ReadFull(r, buf)
+		if n > 0 {
+			ch := chunk{data: buf[:n], lineStart: state.lineStart, inHeader: state.inHeader}
+			state = endState(ch)
+			jobs <- ch
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			readErr = err
+			break
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	var total Counter
+	for i := range counters {
+		total.Merge(&counters[i])
+	}
+	return total.Count(), readErr
+}
+
+// endState returns the line state after the last byte of c.
+func endState(c chunk) chunk {
+	i := bytes.LastIndexByte(c.data, '\n')
+	switch {
+	case i < 0:
+		// The whole chunk continues one line.
+		return chunk{inHeader: c.inHeader || c.lineStart && c.data[0] == '>'}
+	case i == len(c.data)-1:
+		return chunk{lineStart: true}
+	default:
+		return chunk{inHeader: c.data[i+1] == '>'}
+	}
+}
+
+// countChunk adds the sequence bytes of c to t, skipping header text and line
+// terminators exactly like ScanFASTA.
+func countChunk(t *Counter, c chunk) {
+	data, lineStart, inHeader := c.data, c.lineStart, c.inHeader
+	for len(data) > 0 {
+		if lineStart && data[0] == '>' {
+			inHeader = true
+		}
+
+		line := data
+		nl := bytes.IndexByte(data, '\n')
+		if nl >= 0 {
+			line = data[:nl]
+		}
+		if !inHeader {
+			t.Write(line)
+		}
+		if nl < 0 {
+			return
+		}
+
+		data = data[nl+1:]
+		lineStart, inHeader = true, false
+	}
+}
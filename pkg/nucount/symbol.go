@@ -0,0 +1,103 @@
+package nucount
+
+// Symbol is the class of a byte of sequence text.
+type Symbol uint8
+
+// Symbol classes. Letters are classified case-insensitively.
+const (
+	SymbolInvalid Symbol = iota // any other byte
+	SymbolA
+	SymbolC
+	SymbolG
+	SymbolT
+	SymbolU
+	SymbolN
+	SymbolR     // A or G
+	SymbolY     // C or T
+	SymbolS     // G or C
+	SymbolW     // A or T
+	SymbolK     // G or T
+	SymbolM     // A or C
+	SymbolB     // not A
+	SymbolD     // not C
+	SymbolH     // not G
+	SymbolV     // not T
+	SymbolGap   // '-' and '.'
+	SymbolSpace // ' ', '\t' and '\r' within a sequence line; not counted
+
+	// NumSymbols is the number of symbol classes, for tables indexed by Symbol.
+	NumSymbols = int(SymbolSpace) + 1
+)
+
+var symbolLUT = func() (lut [256]Symbol) {
+	for sym, letter := range "?ACGTUNRYSWKMBDHV" {
+		if Symbol(sym) == SymbolInvalid {
+			continue
+		}
+		lut[letter] = Symbol(sym)
+		lut[letter+'a'-'A'] = Symbol(sym)
+	}
+	lut['-'], lut['.'] = SymbolGap, SymbolGap
+	lut['\r'], lut[' '], lut['\t'] = SymbolSpace, SymbolSpace, SymbolSpace
+	return lut
+}()
+
+// SymbolOf returns the class of the sequence byte b.
+func SymbolOf(b byte) Symbol {
+	return symbolLUT[b]
+}
+
+// Counter counts sequence bytes by symbol class. The zero value is ready to
+// use. Counter is also a FASTAHandler that counts the sequence of all records
+// together.
+type Counter struct {
+	n [NumSymbols]int
+}
+
+// Write counts the bytes of p, which are all sequence text: line breaks count
+// as invalid. It never fails.
+func (c *Counter) Write(p []byte) (int, error) {
+	for _, b := range p {
+		c.n[symbolLUT[b]]++
+	}
+	return len(p), nil
+}
+
+// Add counts one byte of class sym.
+func (c *Counter) Add(sym Symbol) {
+	c.n[sym]++
+}
+
+// Merge adds the counts of o to c.
+func (c *Counter) Merge(o *Counter) {
+	for i, v := range o.n {
+		c.n[i] += v
+	}
+}
+
+// Count returns the counts so far.
+func (c *Counter) Count() Count {
+	t := &c.n
+	return Count{
+		A: t[SymbolA], C: t[SymbolC], G: t[SymbolG], T: t[SymbolT], U: t[SymbolU],
+		N: t[SymbolN], R: t[SymbolR], Y: t[SymbolY], S: t[SymbolS], W: t[SymbolW], K: t[SymbolK],
+		M: t[SymbolM], B: t[SymbolB], D: t[SymbolD], H: t[SymbolH], V: t[SymbolV],
+		Gap: t[SymbolGap], Invalid: t[SymbolInvalid],
+	}
+}
+
+// Reset discards the counts.
+func (c *Counter) Reset() {
+	*c = Counter{}
+}
+
+// Header ignores the header of a record.
+func (c *Counter) Header([]byte) error {
+	return nil
+}
+
+// Sequence counts the bytes of a sequence line.
+func (c *Counter) Sequence(seg []byte) error {
+	c.Write(seg)
+	return nil
+}